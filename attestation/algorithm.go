@@ -0,0 +1,75 @@
+package attestation
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/openpubkey/openpubkey/pktoken"
+)
+
+// cicHeader holds the client instance claims header fields describing the signer's committed key
+type cicHeader struct {
+	Algorithm string `json:"alg"`
+	PublicKey struct {
+		KeyType string `json:"kty"`
+		Curve   string `json:"crv"`
+		Modulus string `json:"n"`
+	} `json:"upk"`
+}
+
+// SigningAlgorithms describes the algorithms protecting an attestation, read from its PK token
+type SigningAlgorithms struct {
+	// ProviderAlgorithm is the alg the OP signed the ID token with, e.g. RS256
+	ProviderAlgorithm string
+	// SignatureAlgorithm is the alg of the signer's committed key, which signed the payload digest, e.g. ES256
+	SignatureAlgorithm string
+	// KeyType is the committed key's type and curve or size, e.g. "EC P-256" or "RSA 2048"
+	KeyType string
+}
+
+// ReadSigningAlgorithms reads the OP and signer algorithms and the signer's key type from pkToken's headers.
+// It does not verify the PK token, so the values are only trustworthy once it has verified.
+func ReadSigningAlgorithms(pkToken *pktoken.PKToken) (*SigningAlgorithms, error) {
+	op, err := parseTokenHeader(pkToken.OpToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OP token header: %w", err)
+	}
+
+	var cic cicHeader
+	if err := decodeTokenHeader(pkToken.CicToken, &cic); err != nil {
+		return nil, fmt.Errorf("failed to parse PK token client instance claims: %w", err)
+	}
+	keyType, err := describeKeyType(cic)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningAlgorithms{
+		ProviderAlgorithm:  op.Algorithm,
+		SignatureAlgorithm: cic.Algorithm,
+		KeyType:            keyType,
+	}, nil
+}
+
+// describeKeyType names the committed key's type with its curve, or its modulus size for RSA keys
+func describeKeyType(cic cicHeader) (string, error) {
+	key := cic.PublicKey
+	switch key.KeyType {
+	case "":
+		return "", fmt.Errorf("client instance claims have no public key")
+	case "EC", "OKP":
+		if key.Curve == "" {
+			return key.KeyType, nil
+		}
+		return key.KeyType + " " + key.Curve, nil
+	case "RSA":
+		modulus, err := base64.RawURLEncoding.DecodeString(key.Modulus)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode RSA public key modulus: %w", err)
+		}
+		return fmt.Sprintf("RSA %d", new(big.Int).SetBytes(modulus).BitLen()), nil
+	default:
+		return key.KeyType, nil
+	}
+}
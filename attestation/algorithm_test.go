@@ -0,0 +1,107 @@
+package attestation
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestDescribeKeyType(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	modulus := base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes())
+
+	tests := []struct {
+		name    string
+		kty     string
+		crv     string
+		n       string
+		want    string
+		wantErr string
+	}{
+		{name: "EC key", kty: "EC", crv: "P-256", want: "EC P-256"},
+		{name: "Ed25519 key", kty: "OKP", crv: "Ed25519", want: "OKP Ed25519"},
+		{name: "EC key without a curve", kty: "EC", want: "EC"},
+		{name: "RSA key", kty: "RSA", n: modulus, want: "RSA 2048"},
+		{name: "RSA key with a malformed modulus", kty: "RSA", n: "not base64!", wantErr: "failed to decode RSA public key modulus"},
+		{name: "unknown key type", kty: "oct", want: "oct"},
+		{name: "no public key", wantErr: "client instance claims have no public key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cic cicHeader
+			cic.PublicKey.KeyType, cic.PublicKey.Curve, cic.PublicKey.Modulus = tt.kty, tt.crv, tt.n
+			got, err := describeKeyType(cic)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("describeKeyType: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("describeKeyType = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSigningAlgorithms(t *testing.T) {
+	op := newTestOP(t)
+	pkToken, _ := op.pkToken(t, nil)
+	algorithms, err := ReadSigningAlgorithms(pkToken)
+	if err != nil {
+		t.Fatalf("ReadSigningAlgorithms: %v", err)
+	}
+	// The OpenPubkey client signs with an ECDSA P-256 key, and the mock OP with RSA
+	want := SigningAlgorithms{ProviderAlgorithm: "RS256", SignatureAlgorithm: "ES256", KeyType: "EC P-256"}
+	if *algorithms != want {
+		t.Errorf("ReadSigningAlgorithms = %+v, want %+v", *algorithms, want)
+	}
+
+	pkToken.CicToken = []byte("not a token")
+	if _, err := ReadSigningAlgorithms(pkToken); err == nil {
+		t.Error("expected an error for malformed client instance claims")
+	}
+}
+
+func TestVerifySignatureAlgorithm(t *testing.T) {
+	op := newTestOP(t)
+	attestation := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+
+	tests := []struct {
+		name         string
+		allowed      []string
+		wantVerified bool
+		wantError    bool
+	}{
+		{name: "no allowlist"},
+		{name: "allowed algorithm", allowed: []string{"EdDSA", "ES256"}, wantVerified: true},
+		{name: "algorithm not allowed", allowed: []string{"EdDSA"}, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := op.verifyOptions()
+			opts.AllowedSignatureAlgorithms = tt.allowed
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.Algorithms == nil || result.Algorithms.SignatureAlgorithm != "ES256" {
+				t.Errorf("Algorithms = %+v, want the signer's ES256", result.Algorithms)
+			}
+			if result.SignatureAlgorithmVerified != tt.wantVerified {
+				t.Errorf("SignatureAlgorithmVerified = %v, want %v", result.SignatureAlgorithmVerified, tt.wantVerified)
+			}
+			if hasError(result, "Signature algorithm verification failed") != tt.wantError {
+				t.Errorf("signature algorithm error = %v, want %v (errors %q)", !tt.wantError, tt.wantError, result.Errors)
+			}
+			if result.IsVerificationSuccessful() == tt.wantError {
+				t.Errorf("IsVerificationSuccessful = %v with errors %q", !tt.wantError, result.Errors)
+			}
+		})
+	}
+}
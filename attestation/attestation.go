@@ -1,17 +1,17 @@
 package attestation
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/openpubkey/openpubkey/discover"
 	"github.com/openpubkey/openpubkey/pktoken"
 )
 
@@ -21,13 +21,241 @@ const (
 
 // AttestationPayload represents the attestation data (protected by the signature)
 type AttestationPayload struct {
-	CommitSHA           string `json:"commit_sha"`
-	Timestamp           string `json:"timestamp"`
-	Url                 string `json:"url"`
-	Content             []byte `json:"content"`
-	ContentDigest       string `json:"content_digest"`
-	ContentSize         int64  `json:"content_size"`
-	PreviousAttestation []byte `json:"previous_attestation"`
+	CommitSHA              string        `json:"commit_sha"`
+	Timestamp              string        `json:"timestamp"`
+	Url                    string        `json:"url"`
+	Content                []byte        `json:"content"`
+	ContentDigest          string        `json:"content_digest"`
+	ContentSize            int64         `json:"content_size"`
+	PreviousAttestation    []byte        `json:"previous_attestation"`
+	ContentEncoding        string        `json:"content_encoding,omitempty"`
+	ContentType            string        `json:"content_type,omitempty"`
+	AuthScheme             string        `json:"auth_scheme,omitempty"`
+	TLSCertFingerprints    []string      `json:"tls_cert_fingerprints,omitempty"`
+	TLSLeafIssuer          string        `json:"tls_leaf_issuer,omitempty"`
+	PageCount              int           `json:"page_count,omitempty"`
+	FinalPageURL           string        `json:"final_page_url,omitempty"`
+	PageURLs               []string      `json:"page_urls,omitempty"`
+	Normalization          string        `json:"normalization,omitempty"`
+	ResponseHeadersDigest  string        `json:"response_headers_digest,omitempty"`
+	StatementType          string        `json:"statement_type,omitempty"`
+	Head                   *HeadMetadata `json:"head,omitempty"`
+	ContentDigestMultihash string        `json:"content_digest_multihash,omitempty"`
+	FetchStartedAt         string        `json:"fetch_started_at,omitempty"`
+	FetchDurationMs        int64         `json:"fetch_duration_ms,omitempty"`
+	NotBefore              string        `json:"not_before,omitempty"`
+	NotAfter               string        `json:"not_after,omitempty"`
+	ContentCompression     string        `json:"content_compression,omitempty"`
+	Method                 string        `json:"method,omitempty"`
+	RequestBodyDigest      string        `json:"request_body_digest,omitempty"`
+	RequestContentType     string        `json:"request_content_type,omitempty"`
+	ETag                   string        `json:"etag,omitempty"`
+	LastModified           string        `json:"last_modified,omitempty"`
+}
+
+// HeadMetadata records the response to a HEAD request, attested instead of content by StatementTypeURLHead payloads
+type HeadMetadata struct {
+	StatusCode    int    `json:"status_code"`
+	ContentLength int64  `json:"content_length"`
+	ETag          string `json:"etag,omitempty"`
+	LastModified  string `json:"last_modified,omitempty"`
+}
+
+// Statement types, telling verifiers what kind of content an oracle attested
+const (
+	// StatementTypeURLContent is the content of a URL, and is assumed for payloads without a statement type
+	StatementTypeURLContent = "url-content"
+	// StatementTypeJWKSSnapshot is an OIDC issuer's JSON Web Key Set
+	StatementTypeJWKSSnapshot = "jwks-snapshot"
+	// StatementTypeURLHead is the metadata of a URL from a HEAD request, with no content or content digest
+	StatementTypeURLHead = "url-head"
+)
+
+// statementType returns the payload's statement type, treating an empty one as StatementTypeURLContent
+func (ap *AttestationPayload) statementType() string {
+	if ap.StatementType == "" {
+		return StatementTypeURLContent
+	}
+	return ap.StatementType
+}
+
+// PayloadOption sets optional fields on an attestation payload
+type PayloadOption func(*AttestationPayload)
+
+// WithContentEncoding records the Content-Encoding the content was served with before it was decoded
+func WithContentEncoding(encoding string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.ContentEncoding = encoding
+	}
+}
+
+// WithContentType records the Content-Type the content was served with
+func WithContentType(contentType string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.ContentType = contentType
+	}
+}
+
+// WithAuthScheme records that the content was fetched with authentication, without recording credentials
+func WithAuthScheme(scheme string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.AuthScheme = scheme
+	}
+}
+
+// WithTLSCertificates records the server certificate chain fingerprints and leaf issuer seen during download
+func WithTLSCertificates(fingerprints []string, leafIssuer string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.TLSCertFingerprints = fingerprints
+		ap.TLSLeafIssuer = leafIssuer
+	}
+}
+
+// WithPagination records how many pages were concatenated into the content and the last page's URL
+func WithPagination(pageCount int, finalPageURL string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.PageCount = pageCount
+		ap.FinalPageURL = finalPageURL
+	}
+}
+
+// WithPageURLs records every page URL that was concatenated into the content
+func WithPageURLs(pageURLs []string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.PageURLs = pageURLs
+	}
+}
+
+// WithNormalization records the normalization applied to the content before it was digested
+func WithNormalization(normalization string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.Normalization = normalization
+	}
+}
+
+// WithResponseHeadersDigest records the digest of the response status code and significant headers
+func WithResponseHeadersDigest(digest string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.ResponseHeadersDigest = digest
+	}
+}
+
+// WithStatementType sets the statement type, replacing the StatementTypeURLContent default
+func WithStatementType(statementType string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.StatementType = statementType
+	}
+}
+
+// WithHead records the metadata of a HEAD response
+func WithHead(head *HeadMetadata) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.Head = head
+	}
+}
+
+// WithContentDigestMultihash records the content digest as a base64 multihash alongside the canonical content
+// digest, for SBOM tooling
+func WithContentDigestMultihash(multihash string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.ContentDigestMultihash = multihash
+	}
+}
+
+// WithFetchTiming records when the download started, to the nanosecond, and how long it took. The timing is
+// signed with the rest of the payload, as it is part of the oracle's account of the fetch.
+func WithFetchTiming(startedAt time.Time, duration time.Duration) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.FetchStartedAt = startedAt.UTC().Format(time.RFC3339Nano)
+		ap.FetchDurationMs = duration.Milliseconds()
+	}
+}
+
+// withFetchTiming restores recorded fetch timing fields as they were signed
+func withFetchTiming(startedAt string, durationMs int64) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.FetchStartedAt = startedAt
+		ap.FetchDurationMs = durationMs
+	}
+}
+
+// WithValidity records the window the oracle declares the attestation valid in. Verifiers reject the
+// attestation outside it, whatever their own freshness policy.
+func WithValidity(notBefore time.Time, notAfter time.Time) PayloadOption {
+	return withValidity(notBefore.UTC().Format(time.RFC3339), notAfter.UTC().Format(time.RFC3339))
+}
+
+// withValidity restores a recorded validity window as it was signed
+func withValidity(notBefore string, notAfter string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.NotBefore = notBefore
+		ap.NotAfter = notAfter
+	}
+}
+
+// WithContentCompression records how the stored content is compressed (see CompressContent). The content
+// digest and size remain those of the uncompressed content.
+func WithContentCompression(compression string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.ContentCompression = compression
+	}
+}
+
+// WithRequest records the HTTP method content was requested with, empty for GET, and the digest and
+// Content-Type of the request body, so the attestation captures exactly what was asked for
+func WithRequest(method string, bodyDigest string, contentType string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.Method = method
+		ap.RequestBodyDigest = bodyDigest
+		ap.RequestContentType = contentType
+	}
+}
+
+// WithCacheValidators records the response's ETag and Last-Modified headers, so the next run can make a
+// conditional request and skip attesting unchanged content
+func WithCacheValidators(etag string, lastModified string) PayloadOption {
+	return func(ap *AttestationPayload) {
+		ap.ETag = etag
+		ap.LastModified = lastModified
+	}
+}
+
+// payloadOptions returns the options that set every optional field of the payload, so the verifier rebuilds
+// it with the one CreateAttestationPayload signature the generator uses. New fields must be added here and
+// to DownloadResult.PayloadOptions.
+func (ap *AttestationPayload) payloadOptions() []PayloadOption {
+	return []PayloadOption{
+		WithContentEncoding(ap.ContentEncoding),
+		WithContentType(ap.ContentType),
+		WithAuthScheme(ap.AuthScheme),
+		WithTLSCertificates(ap.TLSCertFingerprints, ap.TLSLeafIssuer),
+		WithPagination(ap.PageCount, ap.FinalPageURL),
+		WithPageURLs(ap.PageURLs),
+		WithNormalization(ap.Normalization),
+		WithResponseHeadersDigest(ap.ResponseHeadersDigest),
+		WithStatementType(ap.StatementType),
+		WithHead(ap.Head),
+		WithContentDigestMultihash(ap.ContentDigestMultihash),
+		withFetchTiming(ap.FetchStartedAt, ap.FetchDurationMs),
+		withValidity(ap.NotBefore, ap.NotAfter),
+		WithContentCompression(ap.ContentCompression),
+		WithRequest(ap.Method, ap.RequestBodyDigest, ap.RequestContentType),
+		WithCacheValidators(ap.ETag, ap.LastModified),
+	}
+}
+
+// rebuild recreates the payload from its fields with CreateAttestationPayload, as the oracle built it
+func (ap *AttestationPayload) rebuild() (*AttestationPayload, error) {
+	return CreateAttestationPayload(
+		ap.Timestamp,
+		ap.CommitSHA,
+		ap.PreviousAttestation,
+		ap.Url,
+		ap.Content,
+		ap.ContentDigest,
+		ap.ContentSize,
+		ap.payloadOptions()...,
+	)
 }
 
 // AttestationDetails represents the details of the previous attestation
@@ -36,11 +264,15 @@ type AttestationDetails struct {
 	ArtifactURL string `json:"artifact_url"` // stable for max 30 days
 }
 
-// Attestation represents the complete attestation
+// Attestation represents the complete attestation. TimestampToken is an optional RFC 3161 timestamp token
+// over Signature, from RequestTimestamp, and TransparencyLog an optional Rekor entry from SubmitToRekor.
 type Attestation struct {
-	Payload   AttestationPayload `json:"payload"`
-	PKToken   *pktoken.PKToken   `json:"pk_token"`
-	Signature []byte             `json:"signature"`
+	Payload         AttestationPayload `json:"payload"`
+	PKToken         *pktoken.PKToken   `json:"pk_token"`
+	Signature       []byte             `json:"signature"`
+	Cosigners       []Cosigner         `json:"cosigners,omitempty"`
+	TimestampToken  []byte             `json:"timestamp_token,omitempty"`
+	TransparencyLog *RekorEntry        `json:"transparency_log,omitempty"`
 }
 
 // Hash generates a SHA256 digest of the attestation payload
@@ -54,11 +286,41 @@ func (ap *AttestationPayload) Hash() ([]byte, error) {
 	return digest[:], nil
 }
 
+// Digest returns the sha256 digest of the compact JSON serialization of the whole attestation
+// (payload, PK token and signature). This is the digest AttestationDetails and chain links refer to.
+func (a *Attestation) Digest() (string, error) {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal attestation: %w", err)
+	}
+	return ContentDigest(data), nil
+}
+
+// TLSCertDigest returns the sha256 digest of the server's leaf certificate, or "" for content not fetched over https.
+// The leaf is the first of the signed TLSCertFingerprints, so it is covered by Hash.
+func (ap *AttestationPayload) TLSCertDigest() string {
+	if len(ap.TLSCertFingerprints) == 0 {
+		return ""
+	}
+	return ap.TLSCertFingerprints[0]
+}
+
 func LoadAttestation(attestationFile string) (*Attestation, error) {
-	data, err := os.ReadFile(attestationFile)
+	file, err := os.Open(attestationFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read attestation file: %w", err)
 	}
+	defer file.Close()
+
+	return LoadAttestationReader(file)
+}
+
+// LoadAttestationReader reads an attestation from r, e.g. os.Stdin in a pipeline
+func LoadAttestationReader(r io.Reader) (*Attestation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attestation: %w", err)
+	}
 
 	var attestation Attestation
 	if err := json.Unmarshal(data, &attestation); err != nil {
@@ -68,6 +330,125 @@ func LoadAttestation(attestationFile string) (*Attestation, error) {
 	return &attestation, nil
 }
 
+// FieldError reports a missing or invalid attestation field found by LoadAttestationStrict
+type FieldError struct {
+	Field  string // JSON path of the field, e.g. payload.content_digest
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// LoadAttestationStrict reads an attestation like LoadAttestation and validates its structure, returning a
+// *FieldError for every missing or invalid field. With disallowUnknownFields, fields this version does not
+// know, e.g. from a newer or corrupted file, are also rejected.
+func LoadAttestationStrict(attestationFile string, disallowUnknownFields bool) (*Attestation, error) {
+	file, err := os.Open(attestationFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attestation file: %w", err)
+	}
+	defer file.Close()
+
+	return LoadAttestationStrictReader(file, disallowUnknownFields)
+}
+
+// LoadAttestationStrictReader reads and validates an attestation from r like LoadAttestationStrict
+func LoadAttestationStrictReader(r io.Reader, disallowUnknownFields bool) (*Attestation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attestation: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if disallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	var attestation Attestation
+	if err := decoder.Decode(&attestation); err != nil {
+		return nil, fmt.Errorf("failed to parse attestation: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("failed to parse attestation: unexpected data after the attestation")
+	}
+
+	// Presence is checked on the raw object, as a missing payload decodes to its zero value
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse attestation: %w", err)
+	}
+	if err := validateAttestation(&attestation, fields); err != nil {
+		return nil, fmt.Errorf("invalid attestation: %w", err)
+	}
+
+	return &attestation, nil
+}
+
+// validateAttestation returns a *FieldError, joined together, for each required field that is missing or invalid
+func validateAttestation(attestation *Attestation, fields map[string]json.RawMessage) error {
+	var errs []error
+	missing := func(field string) {
+		errs = append(errs, &FieldError{Field: field, Reason: "missing"})
+	}
+	present := func(field string) bool {
+		raw, ok := fields[field]
+		return ok && string(raw) != "null"
+	}
+
+	if !present("pk_token") {
+		missing("pk_token")
+	}
+	if len(attestation.Signature) == 0 {
+		missing("signature")
+	}
+	if !present("payload") {
+		missing("payload")
+		return errors.Join(errs...)
+	}
+
+	payload := &attestation.Payload
+	if payload.CommitSHA == "" {
+		missing("payload.commit_sha")
+	}
+	if payload.Url == "" {
+		missing("payload.url")
+	}
+	if payload.Timestamp == "" {
+		missing("payload.timestamp")
+	} else if _, err := time.Parse(time.RFC3339, payload.Timestamp); err != nil {
+		errs = append(errs, &FieldError{Field: "payload.timestamp", Reason: "not an RFC 3339 timestamp"})
+	}
+	if _, err := time.Parse(time.RFC3339, payload.NotBefore); payload.NotBefore != "" && err != nil {
+		errs = append(errs, &FieldError{Field: "payload.not_before", Reason: "not an RFC 3339 timestamp"})
+	}
+	if _, err := time.Parse(time.RFC3339, payload.NotAfter); payload.NotAfter != "" && err != nil {
+		errs = append(errs, &FieldError{Field: "payload.not_after", Reason: "not an RFC 3339 timestamp"})
+	}
+	if payload.ContentSize < 0 {
+		errs = append(errs, &FieldError{Field: "payload.content_size", Reason: "negative"})
+	}
+	// Head-only attestations record metadata instead of content
+	if payload.statementType() == StatementTypeURLHead {
+		if payload.Head == nil {
+			missing("payload.head")
+		}
+	} else if payload.ContentDigest == "" {
+		missing("payload.content_digest")
+	} else if !strings.HasPrefix(payload.ContentDigest, "sha256:") {
+		errs = append(errs, &FieldError{Field: "payload.content_digest", Reason: "not a sha256: digest"})
+	}
+	for i, cosigner := range attestation.Cosigners {
+		if cosigner.PKToken == nil {
+			missing(fmt.Sprintf("cosigners[%d].pk_token", i))
+		}
+		if len(cosigner.Signature) == 0 {
+			missing(fmt.Sprintf("cosigners[%d].signature", i))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 func LoadAttestationDetails(attestationDetailsFile string) (*AttestationDetails, error) {
 	data, err := os.ReadFile(attestationDetailsFile)
 	if err != nil {
@@ -82,9 +463,24 @@ func LoadAttestationDetails(attestationDetailsFile string) (*AttestationDetails,
 	return &attestationDetails, nil
 }
 
-// CreateAttestationPayload creates a new attestation payload with the given parameters
-func CreateAttestationPayload(timestamp string, commitSHA string, previousAttestation []byte, url string, content []byte, contentDigest string, contentSize int64) (*AttestationPayload, error) {
-	return &AttestationPayload{
+// SaveAttestationDetails writes attestation details to disk in the format read by LoadAttestationDetails
+func SaveAttestationDetails(attestationDetails *AttestationDetails, attestationDetailsFile string) error {
+	data, err := json.MarshalIndent(attestationDetails, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal attestation details: %w", err)
+	}
+
+	if err := WriteFileAtomic(attestationDetailsFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write attestation details file: %w", err)
+	}
+
+	return nil
+}
+
+// CreateAttestationPayload creates a new attestation payload with the given parameters.
+// The statement type defaults to StatementTypeURLContent.
+func CreateAttestationPayload(timestamp string, commitSHA string, previousAttestation []byte, url string, content []byte, contentDigest string, contentSize int64, opts ...PayloadOption) (*AttestationPayload, error) {
+	payload := &AttestationPayload{
 		CommitSHA:           commitSHA,
 		Timestamp:           timestamp,
 		Url:                 url,
@@ -92,91 +488,58 @@ func CreateAttestationPayload(timestamp string, commitSHA string, previousAttest
 		ContentDigest:       contentDigest,
 		ContentSize:         contentSize,
 		PreviousAttestation: previousAttestation,
-	}, nil
-}
-
-// DownloadContent downloads content from a URL and returns the content, digest, and size
-func DownloadContent(url string) ([]byte, string, int64, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, "", 0, fmt.Errorf("failed to download content from %s: %w", url, err)
+		StatementType:       StatementTypeURLContent,
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, "", 0, fmt.Errorf("HTTP request failed with status: %d", resp.StatusCode)
+	for _, opt := range opts {
+		opt(payload)
 	}
+	return payload, nil
+}
 
-	content, err := io.ReadAll(resp.Body)
+// CreateAttestationPayloadFromContent creates an attestation payload whose digest and size are derived from
+// content, the same way DownloadContent computes them, so they cannot disagree with it. Use
+// CreateAttestationPayload when the digest was computed while streaming.
+func CreateAttestationPayloadFromContent(timestamp string, commitSHA string, previousAttestation []byte, url string, content []byte, opts ...PayloadOption) (*AttestationPayload, error) {
+	content, contentDigest, contentSize, err := readContent(bytes.NewReader(content), false)
 	if err != nil {
-		return nil, "", 0, fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to digest content: %w", err)
 	}
+	return CreateAttestationPayload(timestamp, commitSHA, previousAttestation, url, content, contentDigest, contentSize, opts...)
+}
 
-	// Calculate SHA256 digest
-	digest := sha256.Sum256(content)
-	// hex encode
-	digestStr := "sha256:" + hex.EncodeToString(digest[:])
-	return content, digestStr, int64(len(content)), nil
+// CreateAttestationFromBytes creates an attestation payload for content that is already in memory, computing
+// its digest and size the same way DownloadContent does. sourceURL records where the content came from.
+func CreateAttestationFromBytes(content []byte, sourceURL string, timestamp string, commitSHA string, previousAttestation []byte, opts ...PayloadOption) (*AttestationPayload, error) {
+	return CreateAttestationPayloadFromContent(timestamp, commitSHA, previousAttestation, sourceURL, content, opts...)
 }
 
-// CheckContentChanges checks if content has changed by comparing with a previous attestation
-func CheckContentChanges(currentDigest string, previousAttestationFile string) (bool, error) {
+// CheckContentChanges checks if download has changed by comparing it with a previous attestation: its content
+// digest, or for StatementTypeURLHead downloads its head metadata. With no previous attestation file the content
+// counts as changed; a file that cannot be loaded is an error.
+func CheckContentChanges(download *DownloadResult, previousAttestationFile string) (bool, error) {
 	// If no previous attestation file provided, assume changes
 	if previousAttestationFile == "" {
 		return true, nil
 	}
 
-	// Load previous attestation
 	prevAttestation, err := LoadAttestation(previousAttestationFile)
 	if err != nil {
-		// If we can't load the previous attestation, assume changes
-		return true, nil
-	}
-
-	// Compare content digests
-	if prevAttestation.Payload.ContentDigest != currentDigest {
-		return false, nil
+		return false, err
 	}
 
-	return true, nil
-}
-
-func GetJWKSContent() ([]byte, error) {
-	jwks, err := discover.GetJwksByIssuer(context.TODO(), githubIssuer, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get JWKS: %w", err)
+	if download.StatementType == StatementTypeURLHead || prevAttestation.Payload.statementType() == StatementTypeURLHead {
+		// Head statements have no content digest, so compare the metadata instead
+		if download.StatementType != prevAttestation.Payload.statementType() || download.Head == nil || prevAttestation.Payload.Head == nil {
+			return true, nil
+		}
+		return *download.Head != *prevAttestation.Payload.Head, nil
 	}
-	return jwks, nil
-}
 
-type IDTokenClaims struct {
-	JobWorkflowSHA string `json:"job_workflow_sha"`
-	IAT            int64  `json:"iat"`
-	WorkflowRef    string `json:"workflow_ref"`
-	RunID          string `json:"run_id"`
-	Timestamp      string `json:"timestamp"`
+	// Compare content digests
+	return prevAttestation.Payload.ContentDigest != download.ContentDigest, nil
 }
 
-// extractClaimsFromIDToken extracts job_workflow_sha and iat claims from the PK token payload
-func ExtractClaimsFromIDToken(pkToken *pktoken.PKToken) (claims *IDTokenClaims, err error) {
-	claims = &IDTokenClaims{}
-
-	if err := json.Unmarshal(pkToken.Payload, &claims); err != nil {
-		return nil, fmt.Errorf("failed to parse PK token payload: %w", err)
-	}
-
-	if claims.JobWorkflowSHA == "" {
-		return nil, fmt.Errorf("job_workflow_sha claim not found in ID token")
-	}
-
-	if claims.IAT == 0 {
-		return nil, fmt.Errorf("iat claim not found in ID token")
-	}
-	if claims.WorkflowRef == "" {
-		return nil, fmt.Errorf("workflow_ref claim not found in ID token")
-	}
-
-	// Convert IAT (issued at) timestamp to ISO 8601 format
-	claims.Timestamp = time.Unix(claims.IAT, 0).UTC().Format(time.RFC3339)
-	return claims, nil
+// GetJWKSContent fetches the GitHub Actions issuer's JWKS
+func GetJWKSContent(ctx context.Context) ([]byte, error) {
+	return GetIssuerJWKS(ctx, githubIssuer)
 }
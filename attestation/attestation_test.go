@@ -0,0 +1,647 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestAttestationDetailsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		details AttestationDetails
+	}{
+		{
+			name:    "digest and artifact URL",
+			details: AttestationDetails{Digest: ContentDigest([]byte("attestation")), ArtifactURL: "https://api.github.com/repos/o/r/actions/artifacts/1/zip"},
+		},
+		{name: "empty details", details: AttestationDetails{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "attestation_details.json")
+			if err := SaveAttestationDetails(&tt.details, path); err != nil {
+				t.Fatalf("SaveAttestationDetails: %v", err)
+			}
+			loaded, err := LoadAttestationDetails(path)
+			if err != nil {
+				t.Fatalf("LoadAttestationDetails: %v", err)
+			}
+			if *loaded != tt.details {
+				t.Errorf("loaded %+v, want %+v", *loaded, tt.details)
+			}
+		})
+	}
+}
+
+func TestLoadAttestationDetailsErrors(t *testing.T) {
+	dir := t.TempDir()
+	malformed := filepath.Join(dir, "malformed.json")
+	if err := os.WriteFile(malformed, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing.json"), malformed} {
+		if _, err := LoadAttestationDetails(path); err == nil {
+			t.Errorf("LoadAttestationDetails(%s) succeeded, want an error", filepath.Base(path))
+		}
+	}
+	if err := SaveAttestationDetails(&AttestationDetails{}, filepath.Join(dir, "missing", "details.json")); err == nil {
+		t.Errorf("SaveAttestationDetails into a missing directory succeeded, want an error")
+	}
+}
+
+func TestTLSCertDigest(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("content")) })
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(handler)
+	defer plainServer.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+
+	tests := []struct {
+		name       string
+		url        string
+		wantDigest string
+	}{
+		{name: "https records the server leaf", url: tlsServer.URL, wantDigest: ContentDigest(tlsServer.Certificate().Raw)},
+		{name: "plain http records nothing", url: plainServer.URL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := DownloadContentContext(context.Background(), tt.url, DownloadOptions{AllowHTTP: true, RootCAs: roots})
+			if err != nil {
+				t.Fatalf("DownloadContentContext: %v", err)
+			}
+			payload, err := CreateAttestationPayload("2024-01-01T00:00:00Z", "", nil, result.URL, result.Content, result.ContentDigest, result.ContentSize, result.PayloadOptions()...)
+			if err != nil {
+				t.Fatalf("CreateAttestationPayload: %v", err)
+			}
+			if got := payload.TLSCertDigest(); got != tt.wantDigest {
+				t.Fatalf("TLSCertDigest() = %q, want %q", got, tt.wantDigest)
+			}
+			if tt.wantDigest == "" {
+				return
+			}
+
+			// The digest is signed: swapping it changes the payload hash
+			hash, err := payload.Hash()
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			payload.TLSCertFingerprints = []string{ContentDigest([]byte("another certificate"))}
+			swapped, err := payload.Hash()
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if bytes.Equal(hash, swapped) {
+				t.Error("payload hash does not cover the leaf certificate digest")
+			}
+		})
+	}
+}
+
+func TestAttestationDigest(t *testing.T) {
+	op := newTestOP(t)
+	original := op.attest(t, testDownload("https://example.com/jwks", []byte(`{"keys":[]}`)), nil)
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("failed to marshal attestation: %v", err)
+	}
+	want, err := original.Digest()
+	if err != nil {
+		t.Fatalf("Digest: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		edit      func(a *Attestation)
+		wantEqual bool
+	}{
+		{name: "equal attestation", edit: func(a *Attestation) {}, wantEqual: true},
+		{name: "mutated payload", edit: func(a *Attestation) { a.Payload.ContentDigest = ContentDigest([]byte("other")) }},
+		{name: "mutated signature", edit: func(a *Attestation) { a.Signature = append([]byte(nil), 0) }},
+		{name: "cosigner added", edit: func(a *Attestation) { a.Cosigners = []Cosigner{{Signature: []byte("cosignature")}} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Each case starts from an independent copy of the original
+			copied, err := LoadAttestationReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("LoadAttestationReader: %v", err)
+			}
+			tt.edit(copied)
+			got, err := copied.Digest()
+			if err != nil {
+				t.Fatalf("Digest: %v", err)
+			}
+			if !strings.HasPrefix(got, "sha256:") {
+				t.Errorf("Digest() = %q, want a sha256: digest", got)
+			}
+			if (got == want) != tt.wantEqual {
+				t.Errorf("Digest() = %s, original %s, want equal %v", got, want, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestCheckContentChanges(t *testing.T) {
+	content := []byte(`{"keys":[]}`)
+	head := &HeadMetadata{StatusCode: 200, ContentLength: 11, ETag: `"v1"`}
+	dir := t.TempDir()
+	writePrevious := func(name string, payload AttestationPayload) string {
+		data, err := json.Marshal(&Attestation{Payload: payload})
+		if err != nil {
+			t.Fatalf("failed to marshal attestation: %v", err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("failed to write previous attestation: %v", err)
+		}
+		return path
+	}
+	previousFile := writePrevious("previous.json", AttestationPayload{Url: "https://example.com/jwks", ContentDigest: ContentDigest(content)})
+	previousHead := writePrevious("previous-head.json", AttestationPayload{Url: "https://example.com/jwks", StatementType: StatementTypeURLHead, Head: head})
+	malformed := filepath.Join(dir, "malformed.json")
+	if err := os.WriteFile(malformed, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("failed to write malformed attestation: %v", err)
+	}
+
+	contentDownload := func(content []byte) *DownloadResult {
+		return &DownloadResult{ContentDigest: ContentDigest(content)}
+	}
+	headDownload := func(head HeadMetadata) *DownloadResult {
+		return &DownloadResult{FetchMeta: FetchMeta{StatementType: StatementTypeURLHead, Head: &head}}
+	}
+	changedHead := *head
+	changedHead.ETag = `"v2"`
+
+	tests := []struct {
+		name         string
+		download     *DownloadResult
+		previousFile string
+		wantChanged  bool
+		wantErr      string
+	}{
+		{name: "unchanged content", download: contentDownload(content), previousFile: previousFile},
+		{name: "changed content", download: contentDownload([]byte(`{"keys":[{}]}`)), previousFile: previousFile, wantChanged: true},
+		{name: "no previous attestation", download: contentDownload(content), wantChanged: true},
+		{name: "unchanged head", download: headDownload(*head), previousFile: previousHead},
+		{name: "changed head", download: headDownload(changedHead), previousFile: previousHead, wantChanged: true},
+		{name: "head after content", download: headDownload(*head), previousFile: previousFile, wantChanged: true},
+		{name: "content after head", download: contentDownload(content), previousFile: previousHead, wantChanged: true},
+		{name: "missing previous attestation", download: contentDownload(content), previousFile: filepath.Join(dir, "missing.json"), wantErr: "failed to read attestation file"},
+		{name: "unreadable previous attestation", download: contentDownload(content), previousFile: malformed, wantErr: "failed to parse attestation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed, err := CheckContentChanges(tt.download, tt.previousFile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckContentChanges: %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("CheckContentChanges() = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestCreateAttestationFromBytesMatchesDownload(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "JSON", content: []byte(`{"keys":[]}`)},
+		{name: "empty", content: []byte{}},
+		{name: "binary", content: bytes.Repeat([]byte{0, 0xff, 0x7f, '\n'}, 64*1024)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write(tt.content) }))
+			defer server.Close()
+			download, err := DownloadContentContext(context.Background(), server.URL, DownloadOptions{AllowHTTP: true})
+			if err != nil {
+				t.Fatalf("DownloadContentContext: %v", err)
+			}
+
+			payload, err := CreateAttestationFromBytes(tt.content, download.URL, "2024-01-01T00:00:00Z", "", nil)
+			if err != nil {
+				t.Fatalf("CreateAttestationFromBytes: %v", err)
+			}
+			if payload.ContentDigest != download.ContentDigest || payload.ContentSize != download.ContentSize {
+				t.Errorf("payload has %s (%d bytes), download has %s (%d bytes)", payload.ContentDigest, payload.ContentSize, download.ContentDigest, download.ContentSize)
+			}
+			if !bytes.Equal(payload.Content, tt.content) || payload.Url != download.URL {
+				t.Errorf("payload records %d bytes from %s, want %d bytes from %s", len(payload.Content), payload.Url, len(tt.content), download.URL)
+			}
+			if payload.StatementType != StatementTypeURLContent {
+				t.Errorf("StatementType = %q, want %q", payload.StatementType, StatementTypeURLContent)
+			}
+		})
+	}
+}
+
+func TestStatementTypeCoveredByHash(t *testing.T) {
+	payload, err := CreateAttestationPayloadFromContent("2024-01-01T00:00:00Z", "", nil, "https://example.com/jwks", []byte(`{"keys":[]}`))
+	if err != nil {
+		t.Fatalf("CreateAttestationPayloadFromContent: %v", err)
+	}
+	if payload.StatementType != StatementTypeURLContent {
+		t.Errorf("default StatementType = %q, want %q", payload.StatementType, StatementTypeURLContent)
+	}
+	hash, _ := payload.Hash()
+	payload.StatementType = StatementTypeJWKSSnapshot
+	retyped, _ := payload.Hash()
+	if bytes.Equal(hash, retyped) {
+		t.Error("payload hash does not cover the statement type")
+	}
+}
+
+func TestCreateAttestationPayloadFromContent(t *testing.T) {
+	previous := []byte(`{"previous":"details"}`)
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "empty", content: []byte{}},
+		{name: "text", content: []byte("hello world")},
+		{name: "binary", content: []byte{0x00, 0xff, 0x10, 0x80}},
+		{name: "large", content: bytes.Repeat([]byte("abcdefgh"), 64*1024)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := CreateAttestationPayloadFromContent("2024-01-01T00:00:00Z", "abc123", previous, "https://example.com/file", tt.content)
+			if err != nil {
+				t.Fatalf("CreateAttestationPayloadFromContent: %v", err)
+			}
+			if want := ContentDigest(tt.content); payload.ContentDigest != want {
+				t.Errorf("ContentDigest = %s, want %s", payload.ContentDigest, want)
+			}
+			if payload.ContentSize != int64(len(tt.content)) {
+				t.Errorf("ContentSize = %d, want %d", payload.ContentSize, len(tt.content))
+			}
+			if !bytes.Equal(payload.Content, tt.content) || !bytes.Equal(payload.PreviousAttestation, previous) {
+				t.Error("payload does not record the content and previous attestation it was given")
+			}
+			if payload.Timestamp != "2024-01-01T00:00:00Z" || payload.CommitSHA != "abc123" || payload.Url != "https://example.com/file" {
+				t.Errorf("payload records %s, %s, %s", payload.Timestamp, payload.CommitSHA, payload.Url)
+			}
+		})
+	}
+}
+
+func TestPayloadRebuildMatchesGenerator(t *testing.T) {
+	content := []byte(`{"keys":[]}`)
+	started := time.Date(2024, 1, 1, 0, 0, 0, 123456789, time.UTC)
+	tests := []struct {
+		name     string
+		download *DownloadResult
+		opts     []PayloadOption
+	}{
+		{
+			name:     "plain download",
+			download: &DownloadResult{FetchMeta: FetchMeta{URL: "https://example.com/jwks"}},
+		},
+		{
+			name: "every download field",
+			download: &DownloadResult{
+				FetchMeta: FetchMeta{
+					URL:                   "https://example.com/jwks",
+					ContentEncoding:       "gzip",
+					ContentType:           "application/json",
+					AuthScheme:            "bearer",
+					TLSCertFingerprints:   []string{"aa", "bb"},
+					TLSLeafIssuer:         "CN=Test CA",
+					PageCount:             2,
+					FinalPageURL:          "https://example.com/jwks?page=2",
+					PageURLs:              []string{"https://example.com/jwks", "https://example.com/jwks?page=2"},
+					Normalization:         "json-canonical",
+					ResponseHeadersDigest: "cc",
+					StatementType:         StatementTypeJWKSSnapshot,
+					Head:                  &HeadMetadata{StatusCode: 200, ContentLength: 11, ETag: `"v1"`},
+					Method:                "POST",
+					RequestBodyDigest:     "dd",
+					RequestContentType:    "application/json",
+					ETag:                  `"v1"`,
+					LastModified:          "Mon, 01 Jan 2024 00:00:00 GMT",
+					FetchStartedAt:        started,
+					FetchDuration:         1500 * time.Millisecond,
+				},
+				ContentDigestMultihash: "1220ee",
+				ContentCompression:     ContentCompressionGzip,
+			},
+		},
+		{
+			name:     "validity window",
+			download: &DownloadResult{FetchMeta: FetchMeta{URL: "https://example.com/jwks"}},
+			opts:     []PayloadOption{WithValidity(started, started.Add(time.Hour))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.download.Content = content
+			tt.download.ContentDigest = ContentDigest(content)
+			tt.download.ContentSize = int64(len(content))
+			opts := append(tt.download.PayloadOptions(), tt.opts...)
+			generated, err := CreateAttestationPayload("2024-01-01T00:00:00Z", "abc123", []byte(`{"digest":"ff"}`), tt.download.URL, tt.download.Content, tt.download.ContentDigest, tt.download.ContentSize, opts...)
+			if err != nil {
+				t.Fatalf("CreateAttestationPayload: %v", err)
+			}
+
+			rebuilt, err := generated.rebuild()
+			if err != nil {
+				t.Fatalf("rebuild: %v", err)
+			}
+			if !reflect.DeepEqual(rebuilt, generated) {
+				t.Errorf("rebuilt payload %+v\ndiffers from generated %+v", rebuilt, generated)
+			}
+			generatedHash, err := generated.Hash()
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			rebuiltHash, err := rebuilt.Hash()
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if !bytes.Equal(generatedHash, rebuiltHash) {
+				t.Errorf("rebuilt payload hashes to %x, generated to %x", rebuiltHash, generatedHash)
+			}
+		})
+	}
+}
+
+// TestPayloadRebuildCoversEveryField fails when a payload field is added without a matching payloadOptions entry
+func TestPayloadRebuildCoversEveryField(t *testing.T) {
+	var payload AttestationPayload
+	value := reflect.ValueOf(&payload).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value.Type().Field(i).Name)
+		case reflect.Int, reflect.Int64:
+			field.SetInt(int64(i + 1))
+		case reflect.Slice:
+			if field.Type().Elem().Kind() == reflect.Uint8 {
+				field.SetBytes([]byte(value.Type().Field(i).Name))
+			} else {
+				field.Set(reflect.ValueOf([]string{value.Type().Field(i).Name}))
+			}
+		case reflect.Pointer:
+			field.Set(reflect.New(field.Type().Elem()))
+		default:
+			t.Fatalf("field %s has unhandled kind %s", value.Type().Field(i).Name, field.Kind())
+		}
+	}
+
+	rebuilt, err := payload.rebuild()
+	if err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	rebuiltValue := reflect.ValueOf(rebuilt).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		if !reflect.DeepEqual(rebuiltValue.Field(i).Interface(), value.Field(i).Interface()) {
+			t.Errorf("rebuild does not restore %s", value.Type().Field(i).Name)
+		}
+	}
+}
+
+func TestLoadAttestationReader(t *testing.T) {
+	op := newTestOP(t)
+	signed, err := json.Marshal(op.attest(t, testDownload("https://example.com/jwks", []byte(`{"keys":[]}`)), nil))
+	if err != nil {
+		t.Fatalf("failed to marshal attestation: %v", err)
+	}
+	tests := []struct {
+		name    string
+		reader  io.Reader
+		wantErr string
+	}{
+		{name: "signed attestation", reader: bytes.NewReader(signed)},
+		{name: "truncated", reader: bytes.NewReader(signed[:len(signed)/2]), wantErr: "failed to parse attestation"},
+		{name: "empty", reader: strings.NewReader(""), wantErr: "failed to parse attestation"},
+		{name: "read error", reader: iotest.ErrReader(errors.New("broken pipe")), wantErr: "failed to read attestation: broken pipe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation, err := LoadAttestationReader(tt.reader)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadAttestationReader: %v", err)
+			}
+			result := verifyTestAttestation(t, attestation, op.verifyOptions())
+			if len(result.Errors) > 0 {
+				t.Errorf("attestation read from a reader did not verify: %v", result.Errors)
+			}
+		})
+	}
+}
+
+func TestLoadAttestationStrict(t *testing.T) {
+	op := newTestOP(t)
+	signed, err := json.Marshal(op.attest(t, testDownload("https://example.com/jwks", []byte(`{"keys":[]}`)), nil))
+	if err != nil {
+		t.Fatalf("failed to marshal attestation: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		edit         func(fields map[string]any)
+		raw          string
+		allowUnknown bool
+		wantFields   []string
+		wantErr      string
+	}{
+		{name: "complete attestation"},
+		{
+			name:       "missing PK token",
+			edit:       func(fields map[string]any) { delete(fields, "pk_token") },
+			wantFields: []string{"pk_token"},
+		},
+		{
+			name:       "null PK token",
+			edit:       func(fields map[string]any) { fields["pk_token"] = nil },
+			wantFields: []string{"pk_token"},
+		},
+		{
+			name:       "missing payload",
+			edit:       func(fields map[string]any) { delete(fields, "payload") },
+			wantFields: []string{"payload"},
+		},
+		{
+			name: "missing PK token and signature",
+			edit: func(fields map[string]any) {
+				delete(fields, "pk_token")
+				delete(fields, "signature")
+			},
+			wantFields: []string{"pk_token", "signature"},
+		},
+		{
+			name: "missing and invalid payload fields",
+			edit: func(fields map[string]any) {
+				payload := fields["payload"].(map[string]any)
+				delete(payload, "url")
+				payload["timestamp"] = "yesterday"
+				payload["content_digest"] = "md5:abc"
+			},
+			wantFields: []string{"payload.url", "payload.timestamp", "payload.content_digest"},
+		},
+		{
+			name:    "extra field",
+			edit:    func(fields map[string]any) { fields["signatures"] = []string{} },
+			wantErr: `unknown field "signatures"`,
+		},
+		{
+			name:    "extra payload field",
+			edit:    func(fields map[string]any) { fields["payload"].(map[string]any)["content_hash"] = "abc" },
+			wantErr: `unknown field "content_hash"`,
+		},
+		{
+			name:         "extra field allowed",
+			edit:         func(fields map[string]any) { fields["signatures"] = []string{} },
+			allowUnknown: true,
+		},
+		{
+			name:    "trailing data",
+			raw:     string(signed) + `{}`,
+			wantErr: "unexpected data after the attestation",
+		},
+		{
+			name:    "not an object",
+			raw:     `[]`,
+			wantErr: "failed to parse attestation",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.raw)
+			if tt.raw == "" {
+				var fields map[string]any
+				if err := json.Unmarshal(signed, &fields); err != nil {
+					t.Fatalf("failed to unmarshal attestation: %v", err)
+				}
+				if tt.edit != nil {
+					tt.edit(fields)
+				}
+				if data, err = json.Marshal(fields); err != nil {
+					t.Fatalf("failed to marshal attestation: %v", err)
+				}
+			}
+			path := filepath.Join(t.TempDir(), "attestation.json")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatalf("failed to write attestation: %v", err)
+			}
+
+			attestation, err := LoadAttestationStrict(path, !tt.allowUnknown)
+			switch {
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+			case tt.wantFields != nil:
+				if err == nil {
+					t.Fatalf("expected errors for %v, got none", tt.wantFields)
+				}
+				var fieldErr *FieldError
+				if !errors.As(err, &fieldErr) {
+					t.Errorf("expected a FieldError, got %v", err)
+				}
+				joined, _ := errors.Unwrap(err).(interface{ Unwrap() []error })
+				if joined == nil || len(joined.Unwrap()) != len(tt.wantFields) {
+					t.Fatalf("expected %d field errors, got %v", len(tt.wantFields), err)
+				}
+				for i, field := range tt.wantFields {
+					if got := joined.Unwrap()[i].(*FieldError).Field; got != field {
+						t.Errorf("field error %d is for %s, want %s", i, got, field)
+					}
+				}
+			default:
+				if err != nil {
+					t.Fatalf("LoadAttestationStrict: %v", err)
+				}
+				if attestation.PKToken == nil || attestation.Payload.Url != "https://example.com/jwks" {
+					t.Error("strictly loaded attestation is incomplete")
+				}
+			}
+		})
+	}
+}
+
+func TestFetchTimingRoundTrip(t *testing.T) {
+	op := newTestOP(t)
+	download := testDownload("https://example.com/jwks", []byte(`{"keys":[]}`))
+	download.FetchStartedAt = time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.FixedZone("CET", 3600))
+	download.FetchDuration = 1500*time.Millisecond + 700*time.Microsecond
+
+	tests := []struct {
+		name      string
+		edit      func(payload *AttestationPayload)
+		wantError string
+	}{
+		{name: "as signed"},
+		{
+			name:      "duration edited after signing",
+			edit:      func(payload *AttestationPayload) { payload.FetchDurationMs = 1 },
+			wantError: "Oracle generated digest does not match signed message",
+		},
+		{
+			name:      "start edited after signing",
+			edit:      func(payload *AttestationPayload) { payload.FetchStartedAt = "2024-01-01T11:00:00Z" },
+			wantError: "Oracle generated digest does not match signed message",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed := op.attest(t, download, nil)
+			if tt.edit != nil {
+				tt.edit(&signed.Payload)
+			}
+			data, err := json.Marshal(signed)
+			if err != nil {
+				t.Fatalf("failed to marshal attestation: %v", err)
+			}
+			attestation, err := LoadAttestationReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("LoadAttestationReader: %v", err)
+			}
+			result := verifyTestAttestation(t, attestation, op.verifyOptions())
+			if tt.wantError != "" {
+				if !hasError(result, tt.wantError) {
+					t.Errorf("expected error %q, got %v", tt.wantError, result.Errors)
+				}
+				return
+			}
+			if len(result.Errors) > 0 {
+				t.Fatalf("attestation with fetch timing did not verify: %v", result.Errors)
+			}
+			if attestation.Payload.FetchStartedAt != "2024-01-01T11:00:00.123456789Z" || attestation.Payload.FetchDurationMs != 1500 {
+				t.Errorf("fetch timing = %s, %dms, want 2024-01-01T11:00:00.123456789Z, 1500ms",
+					attestation.Payload.FetchStartedAt, attestation.Payload.FetchDurationMs)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package attestation
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/openpubkey/openpubkey/pktoken"
+)
+
+// BuildAttestation signs an attestation of download with a pre-obtained PK token and the signer bound to it,
+// so attestations can be built with any OpenPubkey provider, e.g. locally or with a mock OP in tests. The
+// commit SHA and timestamp are taken from the PK token claims, read with extractor. previousAttestation is the
+// marshaled AttestationDetails of the attestation to chain from, nil for none, and opts are applied after
+// download.PayloadOptions.
+func BuildAttestation(pkToken *pktoken.PKToken, signer crypto.Signer, extractor ClaimsExtractor, download *DownloadResult, previousAttestation []byte, opts ...PayloadOption) (*Attestation, error) {
+	claims, err := extractor.ExtractClaims(pkToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract claims from ID token: %w", err)
+	}
+
+	payloadOpts := append(download.PayloadOptions(), opts...)
+	payload, err := CreateAttestationPayload(claims.Timestamp, claims.JobWorkflowSHA, previousAttestation, download.URL, download.Content, download.ContentDigest, download.ContentSize, payloadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attestation payload: %w", err)
+	}
+
+	digest, err := payload.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate attestation digest: %w", err)
+	}
+	signedMsg, err := pkToken.NewSignedMessage(digest, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	return &Attestation{
+		Payload:   *payload,
+		PKToken:   pkToken,
+		Signature: signedMsg,
+	}, nil
+}
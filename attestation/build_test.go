@@ -0,0 +1,121 @@
+package attestation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openpubkey/openpubkey/pktoken"
+)
+
+// failingExtractor fails to extract claims from any PK token
+type failingExtractor struct{}
+
+func (failingExtractor) ExtractClaims(*pktoken.PKToken) (*IDTokenClaims, error) {
+	return nil, errors.New("missing claims")
+}
+
+func TestBuildAttestation(t *testing.T) {
+	op := newTestOP(t)
+	download := testDownload("https://example.com/jwks", []byte(`{"keys":[]}`))
+	previous := op.attest(t, testDownload("https://example.com/jwks", []byte(`{"keys":["old"]}`)), nil)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	iat := time.Now().Add(-time.Minute).Truncate(time.Second)
+	timestamp := iat.UTC().Format(time.RFC3339)
+	notAfter := iat.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		extractor ClaimsExtractor
+		signer    func(bound crypto.Signer) crypto.Signer
+		previous  []byte
+		opts      []PayloadOption
+		check     func(t *testing.T, payload *AttestationPayload)
+		wantErr   string
+		wantError string
+	}{
+		{name: "url content"},
+		{
+			name:     "chained to a previous attestation",
+			previous: previousDetails(t, previous, "https://example.com/artifacts/1"),
+			check: func(t *testing.T, payload *AttestationPayload) {
+				if !strings.Contains(string(payload.PreviousAttestation), "https://example.com/artifacts/1") {
+					t.Errorf("PreviousAttestation = %s, want the previous attestation details", payload.PreviousAttestation)
+				}
+			},
+		},
+		{
+			name: "options after the download's",
+			opts: []PayloadOption{WithValidity(iat, notAfter), WithStatementType(StatementTypeJWKSSnapshot)},
+			check: func(t *testing.T, payload *AttestationPayload) {
+				if payload.NotAfter != notAfter.UTC().Format(time.RFC3339) || payload.StatementType != StatementTypeJWKSSnapshot {
+					t.Errorf("NotAfter %q and statement type %q, want the options' values", payload.NotAfter, payload.StatementType)
+				}
+			},
+		},
+		{name: "claims not extracted", extractor: failingExtractor{}, wantErr: "failed to extract claims from ID token: missing claims"},
+		// Any signer signs, but only the one bound to the PK token verifies
+		{
+			name:      "signer not bound to the PK token",
+			signer:    func(crypto.Signer) crypto.Signer { return otherKey },
+			wantError: "Signed message verification failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]any{"iat": iat.Unix()}
+			pkToken, signer := op.pkToken(t, claims)
+			if tt.signer != nil {
+				signer = tt.signer(signer)
+			}
+			extractor := tt.extractor
+			if extractor == nil {
+				extractor = GithubExtractor{}
+			}
+			attestation, err := BuildAttestation(pkToken, signer, extractor, download, tt.previous, tt.opts...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildAttestation: %v", err)
+			}
+
+			// The commit SHA and timestamp come from the PK token
+			payload := &attestation.Payload
+			if payload.CommitSHA != testWorkflowSHA || payload.Timestamp != timestamp {
+				t.Errorf("commit SHA %q and timestamp %q, want %q and %s", payload.CommitSHA, payload.Timestamp, testWorkflowSHA, timestamp)
+			}
+			if payload.Url != download.URL || payload.ContentDigest != download.ContentDigest || payload.ContentSize != download.ContentSize {
+				t.Errorf("payload %+v does not attest the download", payload)
+			}
+			if attestation.PKToken != pkToken {
+				t.Error("attestation does not carry the given PK token")
+			}
+			if tt.check != nil {
+				tt.check(t, payload)
+			}
+
+			result := verifyTestAttestation(t, attestation, op.verifyOptions())
+			if tt.wantError != "" {
+				if !hasError(result, tt.wantError) {
+					t.Errorf("expected error %q, got %q", tt.wantError, result.Errors)
+				}
+				return
+			}
+			if !result.OracleDigestVerified || !result.SignedMessageVerified || !result.PKTokenVerified {
+				t.Errorf("built attestation did not verify: %q", result.Errors)
+			}
+		})
+	}
+}
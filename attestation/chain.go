@@ -0,0 +1,99 @@
+package attestation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// maxPreviousChain bounds how many previous attestation links a chain walk follows
+const maxPreviousChain = 1000
+
+// previousChain walks the chain of previous attestations. Each link is only trusted through the digest
+// recorded by the attestation before it, starting from a verified attestation.
+type previousChain struct {
+	// fetch downloads the attestation at an artifact URL
+	fetch func(artifactURL string) (*Attestation, error)
+	// digest returns an attestation's digest, (*Attestation).Digest outside tests
+	digest func(attestation *Attestation) (string, error)
+}
+
+// newPreviousChain returns a chain walker downloading artifacts with a GitHub client for token. Artifact URLs
+// come from attestations, so those on other hosts than the API are fetched with the client opts build,
+// which applies its private address guard, proxy and CA roots.
+func newPreviousChain(token string, opts DownloadOptions) (*previousChain, error) {
+	httpClient, err := opts.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	client := NewGitHubClient(token)
+	client.OtherHTTPClient = httpClient
+	return &previousChain{
+		fetch:  client.FetchArtifactAttestation,
+		digest: (*Attestation).Digest,
+	}, nil
+}
+
+// verify walks the previous attestation links from attestation, downloading each previous attestation from
+// its recorded artifact URL and checking it has the recorded digest and is not newer than the attestation
+// linking to it. Every digest walked is kept in a visited set, so a link back to an attestation already
+// walked, including a link to itself, is rejected as a cycle instead of followed forever.
+//
+// The walk ends at an attestation without a previous link, or whose previous artifact has expired. It
+// returns how many links were verified, and reports unavailable when the first link's artifact has expired.
+func (c *previousChain) verify(attestation *Attestation) (int, bool, error) {
+	digest, err := c.digest(attestation)
+	if err != nil {
+		return 0, false, err
+	}
+	visited := map[string]bool{digest: true}
+
+	current := attestation
+	links := 0
+	for len(current.Payload.PreviousAttestation) > 0 {
+		if links == maxPreviousChain {
+			return links, false, fmt.Errorf("previous attestation chain is longer than %d links", maxPreviousChain)
+		}
+
+		var details AttestationDetails
+		if err := json.Unmarshal(current.Payload.PreviousAttestation, &details); err != nil {
+			return links, false, fmt.Errorf("link %d: failed to parse previous attestation details: %w", links+1, err)
+		}
+		if visited[details.Digest] {
+			return links, false, fmt.Errorf("link %d: previous attestation %s was already visited, the chain has a cycle", links+1, details.Digest)
+		}
+		visited[details.Digest] = true
+		if details.ArtifactURL == "" {
+			return links, false, fmt.Errorf("link %d: previous attestation details have no artifact URL", links+1)
+		}
+
+		previous, err := c.fetch(details.ArtifactURL)
+		var badStatus *BadStatusError
+		if errors.As(err, &badStatus) && (badStatus.Code == http.StatusNotFound || badStatus.Code == http.StatusGone) {
+			return links, links == 0, nil
+		}
+		if err != nil {
+			return links, false, fmt.Errorf("link %d: %w", links+1, err)
+		}
+
+		previousDigest, err := c.digest(previous)
+		if err != nil {
+			return links, false, fmt.Errorf("link %d: %w", links+1, err)
+		}
+		if !digestsEqual(previousDigest, details.Digest) {
+			return links, false, fmt.Errorf("link %d: previous artifact digest %s does not match recorded digest %s", links+1, previousDigest, details.Digest)
+		}
+		newer, err := isNewer(previous.Payload.Timestamp, current.Payload.Timestamp)
+		if err != nil {
+			return links, false, fmt.Errorf("link %d: %w", links+1, err)
+		}
+		if newer {
+			return links, false, fmt.Errorf("link %d: previous attestation timestamp %s is newer than %s", links+1, previous.Payload.Timestamp, current.Payload.Timestamp)
+		}
+
+		links++
+		current = previous
+	}
+	return links, false, nil
+}
@@ -0,0 +1,213 @@
+package attestation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chainLink is an unsigned attestation identified by name, linking to the attestation previous names
+type chainLink struct {
+	name      string
+	timestamp string
+	previous  string
+}
+
+// fakeChain builds unsigned attestations for links, identified by name instead of by digest so links can
+// form cycles, which real digests cannot without a sha256 fixed point
+func fakeChain(t *testing.T, links []chainLink) (*previousChain, map[string]*Attestation) {
+	t.Helper()
+	attestations := make(map[string]*Attestation, len(links))
+	for _, link := range links {
+		attestation := &Attestation{Payload: AttestationPayload{Timestamp: link.timestamp, ContentDigest: link.name}}
+		if link.previous != "" {
+			details, err := json.Marshal(AttestationDetails{Digest: link.previous, ArtifactURL: "artifact://" + link.previous})
+			if err != nil {
+				t.Fatalf("failed to marshal details: %v", err)
+			}
+			attestation.Payload.PreviousAttestation = details
+		}
+		attestations[link.name] = attestation
+	}
+
+	chain := &previousChain{
+		fetch: func(artifactURL string) (*Attestation, error) {
+			attestation, ok := attestations[strings.TrimPrefix(artifactURL, "artifact://")]
+			if !ok {
+				return nil, &BadStatusError{Code: http.StatusNotFound}
+			}
+			return attestation, nil
+		},
+		digest: func(attestation *Attestation) (string, error) {
+			return attestation.Payload.ContentDigest, nil
+		},
+	}
+	return chain, attestations
+}
+
+func TestPreviousChainLinks(t *testing.T) {
+	tests := []struct {
+		name            string
+		links           []chainLink
+		start           string
+		wantLinks       int
+		wantUnavailable bool
+		wantErr         string
+	}{
+		{
+			name: "unbroken chain",
+			links: []chainLink{
+				{"a", "2026-01-01T00:00:00Z", ""},
+				{"b", "2026-01-02T00:00:00Z", "a"},
+				{"c", "2026-01-03T00:00:00Z", "b"},
+			},
+			start:     "c",
+			wantLinks: 2,
+		},
+		{
+			name:    "self-referential link",
+			links:   []chainLink{{"a", "2026-01-01T00:00:00Z", "a"}},
+			start:   "a",
+			wantErr: "link 1: previous attestation a was already visited, the chain has a cycle",
+		},
+		{
+			name: "cycle through two attestations",
+			links: []chainLink{
+				{"a", "2026-01-01T00:00:00Z", "b"},
+				{"b", "2026-01-01T00:00:00Z", "a"},
+			},
+			start:   "b",
+			wantErr: "link 2: previous attestation b was already visited, the chain has a cycle",
+		},
+		{
+			name: "cycle further back in the chain",
+			links: []chainLink{
+				{"a", "2026-01-01T00:00:00Z", "b"},
+				{"b", "2026-01-01T00:00:00Z", "a"},
+				{"c", "2026-01-02T00:00:00Z", "b"},
+			},
+			start:   "c",
+			wantErr: "link 3: previous attestation b was already visited",
+		},
+		{
+			name: "out-of-order timestamp",
+			links: []chainLink{
+				{"a", "2026-01-03T00:00:00Z", ""},
+				{"b", "2026-01-02T00:00:00Z", "a"},
+			},
+			start:   "b",
+			wantErr: "link 1: previous attestation timestamp 2026-01-03T00:00:00Z is newer than 2026-01-02T00:00:00Z",
+		},
+		{
+			name: "out-of-order timestamp further back",
+			links: []chainLink{
+				{"a", "2026-01-02T12:00:00Z", ""},
+				{"b", "2026-01-02T00:00:00Z", "a"},
+				{"c", "2026-01-03T00:00:00Z", "b"},
+			},
+			start:   "c",
+			wantErr: "link 2: previous attestation timestamp",
+		},
+		{
+			name:            "expired first artifact",
+			links:           []chainLink{{"b", "2026-01-02T00:00:00Z", "a"}},
+			start:           "b",
+			wantUnavailable: true,
+		},
+		{
+			name: "expired later artifact ends the walk",
+			links: []chainLink{
+				{"b", "2026-01-02T00:00:00Z", "a"},
+				{"c", "2026-01-03T00:00:00Z", "b"},
+			},
+			start:     "c",
+			wantLinks: 1,
+		},
+		{
+			name:  "no previous link",
+			links: []chainLink{{"a", "2026-01-01T00:00:00Z", ""}},
+			start: "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, attestations := fakeChain(t, tt.links)
+			links, unavailable, err := chain.verify(attestations[tt.start])
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if links != tt.wantLinks || unavailable != tt.wantUnavailable {
+				t.Errorf("verify = %d links, unavailable %v; want %d, %v", links, unavailable, tt.wantLinks, tt.wantUnavailable)
+			}
+		})
+	}
+}
+
+func TestVerifyPreviousChain(t *testing.T) {
+	op := newTestOP(t)
+	artifacts := make(map[string]*Attestation)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attestation, ok := artifacts[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(attestation)
+	}))
+	defer server.Close()
+
+	// Signs an attestation linking to previous, published at path, optionally with a shifted iat
+	publish := func(path string, previous *Attestation, iatShift time.Duration) *Attestation {
+		var details []byte
+		if previous != nil {
+			details = previousDetails(t, previous, server.URL+"/"+strings.TrimPrefix(previous.Payload.Url, "https://example.com/"))
+		}
+		claims := map[string]any{"iat": time.Now().Add(iatShift).Unix()}
+		attestation := op.attestWithClaims(t, claims, testDownload("https://example.com/"+path, []byte(path)), details)
+		artifacts["/"+path] = attestation
+		return attestation
+	}
+
+	first := publish("first", nil, -2*time.Hour)
+	second := publish("second", first, -time.Hour)
+	third := publish("third", second, 0)
+	future := publish("future", nil, time.Hour)
+	outOfOrder := publish("out-of-order", future, 0)
+
+	tests := []struct {
+		name        string
+		attestation *Attestation
+		wantLinks   int
+		wantErr     string
+	}{
+		{name: "whole chain verifies", attestation: third, wantLinks: 2},
+		{name: "previous newer than current", attestation: outOfOrder, wantErr: "Previous artifact verification failed: link 1: previous attestation timestamp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := op.verifyOptions()
+			opts.CheckPreviousArtifact = true
+			opts.ArtifactOptions.BlockPrivateAddresses = false
+			result := verifyTestAttestation(t, tt.attestation, opts)
+			if tt.wantErr != "" {
+				if !hasError(result, tt.wantErr) {
+					t.Fatalf("expected an error starting %q, got %q", tt.wantErr, result.Errors)
+				}
+				return
+			}
+			if !result.PreviousArtifactVerified || result.PreviousChainLength != tt.wantLinks {
+				t.Errorf("PreviousArtifactVerified %v with %d links, want %d links (errors %q)", result.PreviousArtifactVerified, result.PreviousChainLength, tt.wantLinks, result.Errors)
+			}
+		})
+	}
+}
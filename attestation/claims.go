@@ -0,0 +1,198 @@
+package attestation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openpubkey/openpubkey/pktoken"
+)
+
+// IDTokenClaims are the provider-neutral claims an attestation is checked against. Claims a token does not
+// carry are left empty; only the ones every attestation check depends on are required by the extractors.
+type IDTokenClaims struct {
+	JobWorkflowSHA       string `json:"job_workflow_sha"`
+	JobWorkflowRef       string `json:"job_workflow_ref"`
+	IAT                  int64  `json:"iat"`
+	WorkflowRef          string `json:"workflow_ref"`
+	RunID                string `json:"run_id"`
+	Timestamp            string `json:"timestamp"`
+	Repository           string `json:"repository"`
+	RepositoryOwner      string `json:"repository_owner"`
+	Ref                  string `json:"ref"`
+	SHA                  string `json:"sha"`
+	Actor                string `json:"actor"`
+	EventName            string `json:"event_name"`
+	Subject              string `json:"sub"`
+	Environment          string `json:"environment"`
+	RefType              string `json:"ref_type"`
+	RefProtected         string `json:"ref_protected"`
+	Workflow             string `json:"workflow"`
+	WorkflowSHA          string `json:"workflow_sha"`
+	HeadRef              string `json:"head_ref"`
+	BaseRef              string `json:"base_ref"`
+	RunNumber            string `json:"run_number"`
+	RunAttempt           string `json:"run_attempt"`
+	RepositoryID         string `json:"repository_id"`
+	RepositoryOwnerID    string `json:"repository_owner_id"`
+	RepositoryVisibility string `json:"repository_visibility"`
+	ActorID              string `json:"actor_id"`
+	RunnerEnvironment    string `json:"runner_environment"`
+}
+
+// GitHubActionsClaims are the GitHub Actions OIDC token claims url-oracle uses. GitHub tokens carry every
+// IDTokenClaims claim under the same name, except Timestamp, which is derived from iat.
+type GitHubActionsClaims IDTokenClaims
+
+// ParseGitHubActionsClaims parses the claims of a GitHub Actions ID token payload, requiring the ones
+// every attestation check depends on
+func ParseGitHubActionsClaims(payload []byte) (*GitHubActionsClaims, error) {
+	var claims GitHubActionsClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse PK token payload: %w", err)
+	}
+
+	if claims.JobWorkflowSHA == "" {
+		return nil, fmt.Errorf("job_workflow_sha claim not found in ID token")
+	}
+	if claims.IAT == 0 {
+		return nil, fmt.Errorf("iat claim not found in ID token")
+	}
+	if claims.WorkflowRef == "" {
+		return nil, fmt.Errorf("workflow_ref claim not found in ID token")
+	}
+
+	// Convert IAT (issued at) timestamp to ISO 8601 format
+	claims.Timestamp = time.Unix(claims.IAT, 0).UTC().Format(time.RFC3339)
+	return &claims, nil
+}
+
+// ClaimsExtractor extracts attestation claims from a PK token issued by a particular provider
+type ClaimsExtractor interface {
+	ExtractClaims(pkToken *pktoken.PKToken) (*IDTokenClaims, error)
+}
+
+var (
+	claimsExtractorsMu sync.RWMutex
+	// claimsExtractors maps each registered provider to the extractor for its ID token claims
+	claimsExtractors = map[string]ClaimsExtractor{
+		ProviderGithub: GithubExtractor{},
+		ProviderGitlab: GitlabExtractor{},
+	}
+)
+
+// RegisterClaimsExtractor registers the claims extractor used for the named provider,
+// replacing any extractor already registered under that name
+func RegisterClaimsExtractor(provider string, extractor ClaimsExtractor) {
+	claimsExtractorsMu.Lock()
+	defer claimsExtractorsMu.Unlock()
+	claimsExtractors[provider] = extractor
+}
+
+// GetClaimsExtractor returns the claims extractor registered for the named provider
+func GetClaimsExtractor(provider string) (ClaimsExtractor, error) {
+	claimsExtractorsMu.RLock()
+	defer claimsExtractorsMu.RUnlock()
+	extractor, ok := claimsExtractors[provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+	return extractor, nil
+}
+
+// ExtractProviderClaims extracts the attestation claims from a PK token issued by the named provider
+func ExtractProviderClaims(provider string, pkToken *pktoken.PKToken) (*IDTokenClaims, error) {
+	extractor, err := GetClaimsExtractor(provider)
+	if err != nil {
+		return nil, err
+	}
+	return extractor.ExtractClaims(pkToken)
+}
+
+// GithubExtractor extracts claims from GitHub Actions ID tokens
+type GithubExtractor struct{}
+
+// ExtractClaims implements ClaimsExtractor
+func (GithubExtractor) ExtractClaims(pkToken *pktoken.PKToken) (*IDTokenClaims, error) {
+	return ExtractClaimsFromIDToken(pkToken)
+}
+
+// ExtractClaimsFromIDToken extracts the GitHub Actions claims from the PK token payload
+func ExtractClaimsFromIDToken(pkToken *pktoken.PKToken) (*IDTokenClaims, error) {
+	github, err := ParseGitHubActionsClaims(pkToken.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := IDTokenClaims(*github)
+	return &claims, nil
+}
+
+// GitlabExtractor extracts claims from GitLab CI ID tokens.
+// The CI config reference and SHA play the role of GitHub's workflow reference and SHA.
+type GitlabExtractor struct{}
+
+// ExtractClaims implements ClaimsExtractor
+func (GitlabExtractor) ExtractClaims(pkToken *pktoken.PKToken) (*IDTokenClaims, error) {
+	var gitlabClaims struct {
+		CiConfigRefURI string `json:"ci_config_ref_uri"`
+		CiConfigSHA    string `json:"ci_config_sha"`
+		PipelineID     string `json:"pipeline_id"`
+		IAT            int64  `json:"iat"`
+		ProjectPath    string `json:"project_path"`
+		NamespacePath  string `json:"namespace_path"`
+		Ref            string `json:"ref"`
+		SHA            string `json:"sha"`
+		UserLogin      string `json:"user_login"`
+		PipelineSource string `json:"pipeline_source"`
+
+		Subject           string `json:"sub"`
+		Environment       string `json:"environment"`
+		RefType           string `json:"ref_type"`
+		RefProtected      string `json:"ref_protected"`
+		ProjectID         string `json:"project_id"`
+		NamespaceID       string `json:"namespace_id"`
+		UserID            string `json:"user_id"`
+		RunnerEnvironment string `json:"runner_environment"`
+	}
+
+	if err := json.Unmarshal(pkToken.Payload, &gitlabClaims); err != nil {
+		return nil, fmt.Errorf("failed to parse PK token payload: %w", err)
+	}
+
+	if gitlabClaims.CiConfigSHA == "" {
+		return nil, fmt.Errorf("ci_config_sha claim not found in ID token")
+	}
+	if gitlabClaims.IAT == 0 {
+		return nil, fmt.Errorf("iat claim not found in ID token")
+	}
+	if gitlabClaims.CiConfigRefURI == "" {
+		return nil, fmt.Errorf("ci_config_ref_uri claim not found in ID token")
+	}
+
+	// GitLab's project and namespace paths stand in for GitHub's repository and owner
+	return &IDTokenClaims{
+		JobWorkflowSHA:  gitlabClaims.CiConfigSHA,
+		JobWorkflowRef:  gitlabClaims.CiConfigRefURI,
+		IAT:             gitlabClaims.IAT,
+		WorkflowRef:     gitlabClaims.CiConfigRefURI,
+		RunID:           gitlabClaims.PipelineID,
+		Timestamp:       time.Unix(gitlabClaims.IAT, 0).UTC().Format(time.RFC3339),
+		Repository:      gitlabClaims.ProjectPath,
+		RepositoryOwner: gitlabClaims.NamespacePath,
+		Ref:             gitlabClaims.Ref,
+		SHA:             gitlabClaims.SHA,
+		Actor:           gitlabClaims.UserLogin,
+		EventName:       gitlabClaims.PipelineSource,
+
+		Subject:           gitlabClaims.Subject,
+		Environment:       gitlabClaims.Environment,
+		RefType:           gitlabClaims.RefType,
+		RefProtected:      gitlabClaims.RefProtected,
+		RepositoryID:      gitlabClaims.ProjectID,
+		RepositoryOwnerID: gitlabClaims.NamespaceID,
+		ActorID:           gitlabClaims.UserID,
+		RunnerEnvironment: gitlabClaims.RunnerEnvironment,
+	}, nil
+}
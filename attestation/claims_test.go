@@ -0,0 +1,303 @@
+package attestation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openpubkey/openpubkey/pktoken"
+)
+
+// fakeExtractor reads the GitHub claims but reports its own workflow ref, counting its calls
+type fakeExtractor struct {
+	workflowRef string
+	calls       *int
+}
+
+func (f fakeExtractor) ExtractClaims(pkToken *pktoken.PKToken) (*IDTokenClaims, error) {
+	*f.calls++
+	claims, err := GithubExtractor{}.ExtractClaims(pkToken)
+	if err != nil {
+		return nil, err
+	}
+	claims.JobWorkflowRef = f.workflowRef
+	return claims, nil
+}
+
+func TestRegisteredClaimsExtractor(t *testing.T) {
+	op := newTestOP(t)
+	var calls int
+	RegisterClaimsExtractor("fake-ci", fakeExtractor{workflowRef: "fake-ci/pipeline", calls: &calls})
+
+	extractor, err := GetClaimsExtractor("fake-ci")
+	if err != nil {
+		t.Fatalf("GetClaimsExtractor: %v", err)
+	}
+	pkToken, signer := op.pkToken(t, nil)
+	attestation, err := BuildAttestation(pkToken, signer, extractor, testDownload("https://example.com/", []byte("content")), nil)
+	if err != nil {
+		t.Fatalf("BuildAttestation: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("BuildAttestation called the extractor %d times, want 1", calls)
+	}
+
+	tests := []struct {
+		name        string
+		provider    string
+		workflowRef string
+		wantRef     bool
+	}{
+		{name: "registered provider uses its extractor", provider: "fake-ci", workflowRef: "fake-ci/pipeline", wantRef: true},
+		{name: "registered provider ignores the GitHub claim", provider: "fake-ci", workflowRef: testWorkflowRef},
+		{name: "GitHub provider uses the GitHub claim", provider: ProviderGithub, workflowRef: testWorkflowRef, wantRef: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			opts := op.verifyOptions()
+			opts.Provider = tt.provider
+			opts.ExpectedWorkflowRef = tt.workflowRef
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.WorkflowRefVerified != tt.wantRef {
+				t.Errorf("WorkflowRefVerified = %v, want %v (errors %q)", result.WorkflowRefVerified, tt.wantRef, result.Errors)
+			}
+			if wantCalls := tt.provider == "fake-ci"; (calls > 0) != wantCalls {
+				t.Errorf("fake extractor called %d times for provider %s", calls, tt.provider)
+			}
+		})
+	}
+}
+
+func TestVerifyOptionsClaimsExtractor(t *testing.T) {
+	op := newTestOP(t)
+	attestation := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+
+	var calls int
+	opts := op.verifyOptions()
+	opts.ClaimsExtractor = fakeExtractor{workflowRef: "override/pipeline", calls: &calls}
+	opts.ExpectedWorkflowRef = "override/pipeline"
+	result := verifyTestAttestation(t, attestation, opts)
+	if calls == 0 || !result.WorkflowRefVerified {
+		t.Errorf("override extractor called %d times, WorkflowRefVerified %v (errors %q)", calls, result.WorkflowRefVerified, result.Errors)
+	}
+}
+
+func TestGetClaimsExtractorUnknownProvider(t *testing.T) {
+	if _, err := GetClaimsExtractor("unknown-ci"); err == nil || !strings.Contains(err.Error(), "unsupported provider") {
+		t.Fatalf("expected an unsupported provider error, got %v", err)
+	}
+}
+
+func TestParseGitHubActionsClaims(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		wantErr string
+	}{
+		{
+			name:    "required claims",
+			payload: `{"job_workflow_sha": "abc", "iat": 1700000000, "workflow_ref": "o/r/.github/workflows/w.yml@refs/heads/main"}`,
+		},
+		{name: "missing job_workflow_sha", payload: `{"iat": 1700000000, "workflow_ref": "ref"}`, wantErr: "job_workflow_sha claim not found"},
+		{name: "missing iat", payload: `{"job_workflow_sha": "abc", "workflow_ref": "ref"}`, wantErr: "iat claim not found"},
+		{name: "missing workflow_ref", payload: `{"job_workflow_sha": "abc", "iat": 1700000000}`, wantErr: "workflow_ref claim not found"},
+		{name: "not JSON", payload: `not json`, wantErr: "failed to parse PK token payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGitHubActionsClaims([]byte(tt.payload))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseGitHubActionsClaims: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// githubTokenPayload is the payload of a GitHub Actions ID token for a push to main
+const githubTokenPayload = `{
+  "jti": "example-id",
+  "sub": "repo:octo-org/octo-repo:ref:refs/heads/main",
+  "aud": "https://github.com/octo-org",
+  "ref": "refs/heads/main",
+  "sha": "example-sha",
+  "repository": "octo-org/octo-repo",
+  "repository_owner": "octo-org",
+  "repository_owner_id": "65",
+  "repository_id": "74",
+  "repository_visibility": "private",
+  "actor_id": "12",
+  "actor": "octocat",
+  "workflow": "example-workflow",
+  "workflow_ref": "octo-org/octo-repo/.github/workflows/example-workflow.yml@refs/heads/main",
+  "workflow_sha": "example-sha",
+  "head_ref": "",
+  "base_ref": "",
+  "event_name": "workflow_dispatch",
+  "ref_type": "branch",
+  "ref_protected": "true",
+  "environment": "prod",
+  "job_workflow_ref": "octo-org/octo-automation/.github/workflows/oidc.yml@refs/heads/main",
+  "job_workflow_sha": "job-sha",
+  "runner_environment": "github-hosted",
+  "run_id": "example-run-id",
+  "run_number": "10",
+  "run_attempt": "2",
+  "iss": "https://token.actions.githubusercontent.com",
+  "nbf": 1632492967,
+  "exp": 1632493867,
+  "iat": 1632493567
+}`
+
+func TestParseGitHubActionsClaimsFixture(t *testing.T) {
+	claims, err := ParseGitHubActionsClaims([]byte(githubTokenPayload))
+	if err != nil {
+		t.Fatalf("ParseGitHubActionsClaims: %v", err)
+	}
+	want := GitHubActionsClaims{
+		JobWorkflowRef:       "octo-org/octo-automation/.github/workflows/oidc.yml@refs/heads/main",
+		JobWorkflowSHA:       "job-sha",
+		WorkflowRef:          "octo-org/octo-repo/.github/workflows/example-workflow.yml@refs/heads/main",
+		RunID:                "example-run-id",
+		Repository:           "octo-org/octo-repo",
+		RepositoryOwner:      "octo-org",
+		Ref:                  "refs/heads/main",
+		SHA:                  "example-sha",
+		Actor:                "octocat",
+		EventName:            "workflow_dispatch",
+		IAT:                  1632493567,
+		Timestamp:            "2021-09-24T14:26:07Z",
+		Subject:              "repo:octo-org/octo-repo:ref:refs/heads/main",
+		Environment:          "prod",
+		RefType:              "branch",
+		RefProtected:         "true",
+		Workflow:             "example-workflow",
+		WorkflowSHA:          "example-sha",
+		RunNumber:            "10",
+		RunAttempt:           "2",
+		RepositoryID:         "74",
+		RepositoryOwnerID:    "65",
+		RepositoryVisibility: "private",
+		ActorID:              "12",
+		RunnerEnvironment:    "github-hosted",
+	}
+	if *claims != want {
+		t.Errorf("ParseGitHubActionsClaims() = %+v\nwant %+v", *claims, want)
+	}
+}
+
+func TestExtractClaimsFromIDToken(t *testing.T) {
+	op := newTestOP(t)
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+		check  func(t *testing.T, claims *IDTokenClaims)
+	}{
+		{
+			name: "default claims",
+			check: func(t *testing.T, claims *IDTokenClaims) {
+				if claims.JobWorkflowRef != testWorkflowRef || claims.JobWorkflowSHA != testWorkflowSHA || claims.Repository != "owner/repo" {
+					t.Errorf("claims %+v do not carry the token's workflow", *claims)
+				}
+			},
+		},
+		{
+			name:   "issued-at becomes the RFC 3339 timestamp",
+			claims: map[string]any{"actor": "octocat", "event_name": "schedule"},
+			check: func(t *testing.T, claims *IDTokenClaims) {
+				if claims.Timestamp != time.Unix(claims.IAT, 0).UTC().Format(time.RFC3339) {
+					t.Errorf("Timestamp = %s for iat %d", claims.Timestamp, claims.IAT)
+				}
+				if claims.Actor != "octocat" || claims.EventName != "schedule" {
+					t.Errorf("actor %q and event %q, want octocat and schedule", claims.Actor, claims.EventName)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkToken, _ := op.pkToken(t, tt.claims)
+			claims, err := ExtractClaimsFromIDToken(pkToken)
+			if err != nil {
+				t.Fatalf("ExtractClaimsFromIDToken: %v", err)
+			}
+			tt.check(t, claims)
+		})
+	}
+}
+
+func TestExtractClaimsFromIDTokenPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    IDTokenClaims
+		wantErr string
+	}{
+		{
+			name:    "rich payload",
+			payload: githubTokenPayload,
+			want: IDTokenClaims{
+				JobWorkflowSHA:       "job-sha",
+				JobWorkflowRef:       "octo-org/octo-automation/.github/workflows/oidc.yml@refs/heads/main",
+				IAT:                  1632493567,
+				WorkflowRef:          "octo-org/octo-repo/.github/workflows/example-workflow.yml@refs/heads/main",
+				RunID:                "example-run-id",
+				Timestamp:            "2021-09-24T14:26:07Z",
+				Repository:           "octo-org/octo-repo",
+				RepositoryOwner:      "octo-org",
+				Ref:                  "refs/heads/main",
+				SHA:                  "example-sha",
+				Actor:                "octocat",
+				EventName:            "workflow_dispatch",
+				Subject:              "repo:octo-org/octo-repo:ref:refs/heads/main",
+				Environment:          "prod",
+				RefType:              "branch",
+				RefProtected:         "true",
+				Workflow:             "example-workflow",
+				WorkflowSHA:          "example-sha",
+				RunNumber:            "10",
+				RunAttempt:           "2",
+				RepositoryID:         "74",
+				RepositoryOwnerID:    "65",
+				RepositoryVisibility: "private",
+				ActorID:              "12",
+				RunnerEnvironment:    "github-hosted",
+			},
+		},
+		{
+			name:    "optional claims missing",
+			payload: `{"job_workflow_sha": "abc", "iat": 1700000000, "workflow_ref": "o/r/.github/workflows/w.yml@refs/heads/main"}`,
+			want: IDTokenClaims{
+				JobWorkflowSHA: "abc",
+				IAT:            1700000000,
+				WorkflowRef:    "o/r/.github/workflows/w.yml@refs/heads/main",
+				Timestamp:      "2023-11-14T22:13:20Z",
+			},
+		},
+		{name: "required claim missing", payload: `{"iat": 1700000000, "workflow_ref": "ref", "actor": "octocat"}`, wantErr: "job_workflow_sha claim not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ExtractClaimsFromIDToken(&pktoken.PKToken{Payload: []byte(tt.payload)})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractClaimsFromIDToken: %v", err)
+			}
+			if *claims != tt.want {
+				t.Errorf("ExtractClaimsFromIDToken() = %+v\nwant %+v", *claims, tt.want)
+			}
+		})
+	}
+}
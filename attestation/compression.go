@@ -0,0 +1,53 @@
+package attestation
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// ContentCompressionGzip stores payload content gzip-compressed
+const ContentCompressionGzip = "gzip"
+
+// CompressContent gzip-compresses content for storage in a payload with ContentCompressionGzip
+func CompressContent(content []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buffer, gzip.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+	if _, err := writer.Write(content); err != nil {
+		return nil, fmt.Errorf("failed to compress content: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress content: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// DecodedContent returns the stored content as it was digested, decompressing it when the payload records a
+// content compression. Decompression stops a byte past the recorded content size, so a payload can't expand
+// into more than the size it declares (the size check then fails).
+func (ap *AttestationPayload) DecodedContent() ([]byte, error) {
+	switch ap.ContentCompression {
+	case "":
+		return ap.Content, nil
+	case ContentCompressionGzip:
+		if ap.Content == nil {
+			return nil, nil
+		}
+		reader, err := gzip.NewReader(bytes.NewReader(ap.Content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress content: %w", err)
+		}
+		defer reader.Close()
+		content, err := io.ReadAll(io.LimitReader(reader, ap.ContentSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress content: %w", err)
+		}
+		return content, nil
+	default:
+		return nil, fmt.Errorf("unsupported content compression %q", ap.ContentCompression)
+	}
+}
@@ -0,0 +1,195 @@
+package attestation
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// compressTestContent gzip-compresses content, failing the test on error
+func compressTestContent(t *testing.T, content []byte) []byte {
+	t.Helper()
+	compressed, err := CompressContent(content)
+	if err != nil {
+		t.Fatalf("CompressContent: %v", err)
+	}
+	return compressed
+}
+
+func TestDecodedContent(t *testing.T) {
+	content := []byte(strings.Repeat(`{"kty":"RSA","kid":"key","n":"AQAB","e":"AQAB"},`, 100))
+	compressed := compressTestContent(t, content)
+	if len(compressed) >= len(content) {
+		t.Fatalf("compressed content is %d bytes, no smaller than %d", len(compressed), len(content))
+	}
+
+	tests := []struct {
+		name    string
+		payload AttestationPayload
+		want    []byte
+		wantErr string
+	}{
+		{name: "uncompressed", payload: AttestationPayload{Content: content, ContentSize: int64(len(content))}, want: content},
+		{
+			name:    "gzip",
+			payload: AttestationPayload{Content: compressed, ContentSize: int64(len(content)), ContentCompression: ContentCompressionGzip},
+			want:    content,
+		},
+		{name: "gzip without stored content", payload: AttestationPayload{ContentCompression: ContentCompressionGzip}},
+		{
+			name:    "empty content",
+			payload: AttestationPayload{Content: compressTestContent(t, nil), ContentCompression: ContentCompressionGzip},
+			want:    []byte{},
+		},
+		// Decompression stops a byte past the declared size, so the size check catches the expansion
+		{
+			name:    "larger than the recorded size",
+			payload: AttestationPayload{Content: compressed, ContentSize: 10, ContentCompression: ContentCompressionGzip},
+			want:    content[:11],
+		},
+		{
+			name:    "not gzip",
+			payload: AttestationPayload{Content: content, ContentSize: int64(len(content)), ContentCompression: ContentCompressionGzip},
+			wantErr: "failed to decompress content",
+		},
+		{
+			name:    "truncated gzip",
+			payload: AttestationPayload{Content: compressed[:len(compressed)/2], ContentSize: int64(len(content)), ContentCompression: ContentCompressionGzip},
+			wantErr: "failed to decompress content",
+		},
+		{
+			name:    "unsupported compression",
+			payload: AttestationPayload{Content: compressed, ContentCompression: "zstd"},
+			wantErr: `unsupported content compression "zstd"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.payload.DecodedContent()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodedContent: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("DecodedContent() = %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestCompressedContentVerification(t *testing.T) {
+	op := newTestOP(t)
+	jwks := []byte(`{"keys":[` + strings.Repeat(fixtureJWK1+",", 20) + fixtureJWK1 + `]}`)
+
+	tests := []struct {
+		name     string
+		download func() *DownloadResult
+	}{
+		{name: "url content", download: func() *DownloadResult {
+			return testDownload("https://example.com/config", []byte(strings.Repeat("setting=value\n", 200)))
+		}},
+		{
+			name: "JWKS snapshot",
+			download: func() *DownloadResult {
+				download := testDownload("https://issuer.example.com", jwks)
+				download.StatementType = StatementTypeJWKSSnapshot
+				return download
+			},
+		},
+		{
+			name: "normalized content",
+			download: func() *DownloadResult {
+				download := testDownload("https://example.com/data.json", []byte(`{"a":1,"b":[1,2,3]}`))
+				download.Normalization = NormalizeJSON
+				return download
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := tt.download()
+			compressed := tt.download()
+			compressed.Content = compressTestContent(t, compressed.Content)
+			compressed.ContentCompression = ContentCompressionGzip
+
+			plainResult := verifyTestAttestation(t, op.attest(t, plain, nil), op.verifyOptions())
+			compressedAttestation := op.attest(t, compressed, nil)
+			compressedResult := verifyTestAttestation(t, compressedAttestation, op.verifyOptions())
+			if !plainResult.IsVerificationSuccessful() {
+				t.Fatalf("uncompressed attestation did not verify: %q", plainResult.Errors)
+			}
+			if !reflect.DeepEqual(compressedResult, plainResult) {
+				t.Errorf("compressed verification result %+v\ndiffers from uncompressed %+v", compressedResult, plainResult)
+			}
+			if compressedAttestation.Payload.ContentDigest != plain.ContentDigest || compressedAttestation.Payload.ContentSize != plain.ContentSize {
+				t.Errorf("compressed payload records digest %s and size %d, want those of the uncompressed content, %s and %d",
+					compressedAttestation.Payload.ContentDigest, compressedAttestation.Payload.ContentSize, plain.ContentDigest, plain.ContentSize)
+			}
+		})
+	}
+}
+
+func TestCompressedContentTampering(t *testing.T) {
+	op := newTestOP(t)
+	content := []byte(strings.Repeat("setting=value\n", 200))
+	download := testDownload("https://example.com/config", content)
+	download.Content = compressTestContent(t, content)
+	download.ContentCompression = ContentCompressionGzip
+
+	tests := []struct {
+		name      string
+		edit      func(t *testing.T, payload *AttestationPayload)
+		wantError string
+	}{
+		{
+			name: "other content compressed",
+			edit: func(t *testing.T, payload *AttestationPayload) {
+				payload.Content = compressTestContent(t, []byte("setting=other\n"))
+			},
+			wantError: "Content size",
+		},
+		{
+			name: "other content of the same size",
+			edit: func(t *testing.T, payload *AttestationPayload) {
+				payload.Content = compressTestContent(t, bytes.Replace(content, []byte("value"), []byte("VALUE"), 1))
+			},
+			wantError: "Content digest",
+		},
+		{
+			name: "expanding beyond the recorded size",
+			edit: func(t *testing.T, payload *AttestationPayload) {
+				payload.Content = compressTestContent(t, bytes.Repeat(content, 1000))
+			},
+			wantError: "Content size 2801 does not match recorded content size 2800",
+		},
+		{
+			name:      "corrupt gzip",
+			edit:      func(t *testing.T, payload *AttestationPayload) { payload.Content = payload.Content[:10] },
+			wantError: "Content decompression failed",
+		},
+		{
+			name:      "compression dropped",
+			edit:      func(t *testing.T, payload *AttestationPayload) { payload.ContentCompression = "" },
+			wantError: "Content size",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The tampered payloads are signed, so only the content checks can catch them
+			attestation := op.attestEdited(t, download, func(payload *AttestationPayload) { tt.edit(t, payload) })
+			result := verifyTestAttestation(t, attestation, op.verifyOptions())
+			if !hasError(result, tt.wantError) {
+				t.Errorf("expected error %q, got %q", tt.wantError, result.Errors)
+			}
+			if result.IsVerificationSuccessful() {
+				t.Error("verification succeeded for tampered compressed content")
+			}
+		})
+	}
+}
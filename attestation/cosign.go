@@ -0,0 +1,77 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openpubkey/openpubkey/pktoken"
+)
+
+// Cosigner is an additional OpenPubkey signature over the payload digest, made with a PK token from another
+// OP so that trust does not rest on a single provider
+type Cosigner struct {
+	// Provider is the OIDC provider that issued PKToken, e.g. ProviderGitlab
+	Provider  string           `json:"provider"`
+	PKToken   *pktoken.PKToken `json:"pk_token"`
+	Signature []byte           `json:"signature"`
+}
+
+// signerThreshold returns how many signers must verify, all of them when SignerThreshold is not set
+func (o VerifyOptions) signerThreshold(signerCount int) int {
+	if o.SignerThreshold <= 0 {
+		return signerCount
+	}
+	return o.SignerThreshold
+}
+
+// signerIdentity returns the issuer and subject pkToken was issued to, which identify a signer however many
+// PK tokens it obtains
+func signerIdentity(pkToken *pktoken.PKToken) (string, error) {
+	var claims struct {
+		Issuer  string `json:"iss"`
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(pkToken.Payload, &claims); err != nil {
+		return "", fmt.Errorf("failed to parse PK token claims: %w", err)
+	}
+	if claims.Issuer == "" || claims.Subject == "" {
+		return "", fmt.Errorf("PK token has no issuer or subject")
+	}
+	return claims.Issuer + " " + claims.Subject, nil
+}
+
+// verifyCosigner checks a cosigner's PK token against its provider's issuer and that it signed digest,
+// returning the cosigner's signer identity. The provider must differ from the primary signer's, so a
+// second token from the OP the primary signer trusts adds nothing.
+func verifyCosigner(ctx context.Context, cosigner Cosigner, digest []byte, opts VerifyOptions) (string, error) {
+	if cosigner.PKToken == nil {
+		return "", fmt.Errorf("cosigner has no PK token")
+	}
+	if cosigner.Provider == opts.provider() {
+		return "", fmt.Errorf("cosigner provider %s is the primary signer's provider", cosigner.Provider)
+	}
+
+	providerVerifier, ok := opts.CosignerVerifiers[cosigner.Provider]
+	if !ok {
+		var err error
+		if providerVerifier, err = NewProviderVerifier(cosigner.Provider, ""); err != nil {
+			return "", err
+		}
+	}
+	if err := verifyPKToken(ctx, providerVerifier, cosigner.PKToken); err != nil {
+		return "", fmt.Errorf("PK Token verification failed: %w", err)
+	}
+
+	msg, err := cosigner.PKToken.VerifySignedMessage(cosigner.Signature)
+	if err != nil {
+		return "", fmt.Errorf("signed message verification failed: %w", err)
+	}
+	if err := verifySignerBinding(cosigner.PKToken, cosigner.Signature); err != nil {
+		return "", fmt.Errorf("signer binding verification failed: %w", err)
+	}
+	if err := compareSignedDigest(msg, digest); err != nil {
+		return "", err
+	}
+	return signerIdentity(cosigner.PKToken)
+}
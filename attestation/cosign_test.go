@@ -0,0 +1,191 @@
+package attestation
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/openpubkey/openpubkey/providers"
+	"github.com/openpubkey/openpubkey/verifier"
+)
+
+// cosign returns a cosigner entry for provider, signed by op over message
+func (op *testOP) cosign(t *testing.T, provider string, message []byte) Cosigner {
+	t.Helper()
+	pkToken, signer := op.pkToken(t, nil)
+	signature, err := pkToken.NewSignedMessage(message, signer)
+	if err != nil {
+		t.Fatalf("failed to sign message: %v", err)
+	}
+	return Cosigner{Provider: provider, PKToken: pkToken, Signature: signature}
+}
+
+// newCosignOP returns a test OP with its own issuer, so its signers are distinct from the default test OP's
+func newCosignOP(t *testing.T) *testOP {
+	t.Helper()
+	opts := providers.DefaultMockProviderOpts()
+	opts.Issuer = "https://cosigner.example.com"
+	return newTestOPWith(t, opts)
+}
+
+func TestVerifySignerThreshold(t *testing.T) {
+	op := newTestOP(t)
+	cosignOP := newCosignOP(t)
+	otherDigest := sha256.Sum256([]byte("another payload"))
+
+	tests := []struct {
+		name          string
+		cosigners     func(digest []byte) []Cosigner
+		threshold     int
+		wantMet       bool
+		wantVerified  int
+		wantSigners   int
+		wantError     string
+		wantCosignErr string
+	}{
+		{
+			name:         "primary signer only",
+			cosigners:    func([]byte) []Cosigner { return nil },
+			wantMet:      true,
+			wantVerified: 1,
+			wantSigners:  1,
+		},
+		{
+			name:         "2 of 2",
+			cosigners:    func(digest []byte) []Cosigner { return []Cosigner{cosignOP.cosign(t, ProviderGitlab, digest)} },
+			wantMet:      true,
+			wantVerified: 2,
+			wantSigners:  2,
+		},
+		{
+			name:         "2 of 2 explicitly",
+			cosigners:    func(digest []byte) []Cosigner { return []Cosigner{cosignOP.cosign(t, ProviderGitlab, digest)} },
+			threshold:    2,
+			wantMet:      true,
+			wantVerified: 2,
+			wantSigners:  2,
+		},
+		{
+			name:          "2 of 2 with a cosigner of another payload",
+			cosigners:     func([]byte) []Cosigner { return []Cosigner{cosignOP.cosign(t, ProviderGitlab, otherDigest[:])} },
+			wantVerified:  1,
+			wantSigners:   2,
+			wantError:     "Only 1 of 2 signers verified, 2 required",
+			wantCosignErr: "Cosigner 1 (gitlab) verification failed: signed digest",
+		},
+		{
+			name:          "1 of 2 with a cosigner of another payload",
+			cosigners:     func([]byte) []Cosigner { return []Cosigner{cosignOP.cosign(t, ProviderGitlab, otherDigest[:])} },
+			threshold:     1,
+			wantMet:       true,
+			wantVerified:  1,
+			wantSigners:   2,
+			wantCosignErr: "Cosigner 1 (gitlab) verification failed: signed digest",
+		},
+		{
+			name:          "2 of 2 with a cosigner the provider did not issue",
+			cosigners:     func(digest []byte) []Cosigner { return []Cosigner{op.cosign(t, ProviderGitlab, digest)} },
+			wantVerified:  1,
+			wantSigners:   2,
+			wantError:     "Only 1 of 2 signers verified, 2 required",
+			wantCosignErr: "Cosigner 1 (gitlab) verification failed: PK Token verification failed",
+		},
+		{
+			name:          "2 of 2 with a cosigner without a PK token",
+			cosigners:     func(digest []byte) []Cosigner { return []Cosigner{{Provider: ProviderGitlab}} },
+			wantVerified:  1,
+			wantSigners:   2,
+			wantError:     "Cosigner 1 (gitlab) verification failed: cosigner has no PK token",
+			wantCosignErr: "Cosigner 1 (gitlab) verification failed: cosigner has no PK token",
+		},
+		{
+			name: "3 of 3 with the same cosigner twice",
+			cosigners: func(digest []byte) []Cosigner {
+				cosigner := cosignOP.cosign(t, ProviderGitlab, digest)
+				return []Cosigner{cosigner, cosigner}
+			},
+			threshold:     3,
+			wantVerified:  2,
+			wantSigners:   3,
+			wantError:     "Cosigner 2 (gitlab) verification failed: signer https://cosigner.example.com",
+			wantCosignErr: "Cosigner 2 (gitlab) verification failed: signer https://cosigner.example.com",
+		},
+		{
+			name: "3 of 3 with two tokens of the same signer",
+			cosigners: func(digest []byte) []Cosigner {
+				return []Cosigner{cosignOP.cosign(t, ProviderGitlab, digest), cosignOP.cosign(t, ProviderGitlab, digest)}
+			},
+			threshold:     3,
+			wantVerified:  2,
+			wantSigners:   3,
+			wantError:     "Only 2 of 3 signers verified, 3 required",
+			wantCosignErr: "Cosigner 2 (gitlab) verification failed: signer https://cosigner.example.com",
+		},
+		{
+			name:          "2 of 2 with a cosigner from the primary signer's provider",
+			cosigners:     func(digest []byte) []Cosigner { return []Cosigner{op.cosign(t, ProviderGithub, digest)} },
+			wantVerified:  1,
+			wantSigners:   2,
+			wantError:     "Only 1 of 2 signers verified, 2 required",
+			wantCosignErr: "Cosigner 1 (github) verification failed: cosigner provider github is the primary signer's provider",
+		},
+		{
+			name:         "threshold above the signer count",
+			cosigners:    func(digest []byte) []Cosigner { return []Cosigner{cosignOP.cosign(t, ProviderGitlab, digest)} },
+			threshold:    3,
+			wantVerified: 2,
+			wantSigners:  2,
+			wantError:    "Signer threshold 3 exceeds the 2 signers",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := op.attest(t, testDownload("https://example.com/jwks", []byte(`{"keys":[]}`)), nil)
+			digest, err := attestation.Payload.Hash()
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			attestation.Cosigners = tt.cosigners(digest)
+
+			opts := op.verifyOptions()
+			opts.SignerThreshold = tt.threshold
+			opts.CosignerVerifiers = map[string]verifier.ProviderVerifier{ProviderGitlab: cosignOP.verifier()}
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.SignerThresholdMet != tt.wantMet {
+				t.Errorf("SignerThresholdMet = %v, want %v (errors: %v)", result.SignerThresholdMet, tt.wantMet, result.Errors)
+			}
+			if result.SignersVerified != tt.wantVerified || result.SignerCount != tt.wantSigners {
+				t.Errorf("%d of %d signers verified, want %d of %d", result.SignersVerified, result.SignerCount, tt.wantVerified, tt.wantSigners)
+			}
+			if tt.wantError == "" && len(result.Errors) > 0 {
+				t.Errorf("unexpected errors: %v", result.Errors)
+			}
+			if tt.wantError != "" && !hasError(result, tt.wantError) {
+				t.Errorf("expected an error starting with %q, got %v", tt.wantError, result.Errors)
+			}
+			if tt.wantCosignErr == "" && len(result.CosignerErrors) > 0 {
+				t.Errorf("unexpected cosigner errors: %v", result.CosignerErrors)
+			}
+			if tt.wantCosignErr != "" && (len(result.CosignerErrors) != 1 || !strings.HasPrefix(result.CosignerErrors[0], tt.wantCosignErr)) {
+				t.Errorf("expected a cosigner error starting with %q, got %v", tt.wantCosignErr, result.CosignerErrors)
+			}
+		})
+	}
+}
+
+func TestVerifyCosignerRepeatsPrimarySigner(t *testing.T) {
+	op := newTestOP(t)
+	attestation := op.attest(t, testDownload("https://example.com/jwks", []byte(`{"keys":[]}`)), nil)
+	// The primary signer's own token and signature, copied in under another provider's name
+	attestation.Cosigners = []Cosigner{{Provider: ProviderGitlab, PKToken: attestation.PKToken, Signature: attestation.Signature}}
+
+	opts := op.verifyOptions()
+	opts.CosignerVerifiers = map[string]verifier.ProviderVerifier{ProviderGitlab: op.verifier()}
+	result := verifyTestAttestation(t, attestation, opts)
+	if result.SignerThresholdMet || result.SignersVerified != 1 {
+		t.Errorf("copied primary signer counted: %d of %d signers verified, threshold met %v", result.SignersVerified, result.SignerCount, result.SignerThresholdMet)
+	}
+	if !hasError(result, "Cosigner 1 (gitlab) verification failed: signer "+op.issuer()) {
+		t.Errorf("expected a repeated signer error, got %v", result.Errors)
+	}
+}
@@ -0,0 +1,65 @@
+package attestation
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// FieldChange is a payload field that differs between two attestations, named by its JSON field name
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// AttestationDiff reports the payload fields that differ between two attestations
+type AttestationDiff struct {
+	Changes         []FieldChange `json:"changes"`
+	ContentChanged  bool          `json:"content_changed"`  // the content digest differs
+	PreviousChanged bool          `json:"previous_changed"` // the previous attestation pointer differs
+}
+
+// Diff compares a's payload with other's, reporting the URL, final page URL, timestamp, content digest,
+// size and type, and previous attestation fields that changed from a to other. It does not verify either
+// attestation.
+func (a *Attestation) Diff(other *Attestation) *AttestationDiff {
+	oldPayload, newPayload := &a.Payload, &other.Payload
+	fields := []FieldChange{
+		{"url", oldPayload.Url, newPayload.Url},
+		{"final_page_url", oldPayload.FinalPageURL, newPayload.FinalPageURL},
+		{"timestamp", oldPayload.Timestamp, newPayload.Timestamp},
+		{"content_digest", oldPayload.ContentDigest, newPayload.ContentDigest},
+		{"content_size", strconv.FormatInt(oldPayload.ContentSize, 10), strconv.FormatInt(newPayload.ContentSize, 10)},
+		{"content_type", oldPayload.ContentType, newPayload.ContentType},
+	}
+
+	diff := &AttestationDiff{
+		Changes:         []FieldChange{},
+		ContentChanged:  oldPayload.ContentDigest != newPayload.ContentDigest,
+		PreviousChanged: !bytes.Equal(oldPayload.PreviousAttestation, newPayload.PreviousAttestation),
+	}
+	for _, field := range fields {
+		if field.Old != field.New {
+			diff.Changes = append(diff.Changes, field)
+		}
+	}
+	if diff.PreviousChanged {
+		diff.Changes = append(diff.Changes, FieldChange{
+			Field: "previous_attestation",
+			Old:   previousDigest(oldPayload.PreviousAttestation),
+			New:   previousDigest(newPayload.PreviousAttestation),
+		})
+	}
+	return diff
+}
+
+// previousDigest returns the digest a previous attestation pointer references, or the raw pointer when it
+// is not attestation details
+func previousDigest(previous []byte) string {
+	var details AttestationDetails
+	if err := json.Unmarshal(previous, &details); err != nil || details.Digest == "" {
+		return string(previous)
+	}
+	return details.Digest
+}
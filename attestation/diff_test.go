@@ -0,0 +1,105 @@
+package attestation
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAttestationDiff(t *testing.T) {
+	v1 := []byte(`{"keys":[]}`)
+	v2 := []byte(`{"keys":[{"kid":"1"}]}`)
+	previousDigest := ContentDigest([]byte("previous attestation"))
+	previous, err := json.Marshal(AttestationDetails{Digest: previousDigest, ArtifactURL: "https://example.com/artifact"})
+	if err != nil {
+		t.Fatalf("failed to marshal details: %v", err)
+	}
+	base := AttestationPayload{
+		Url:           "https://example.com/jwks",
+		Timestamp:     "2026-01-10T12:00:00Z",
+		Content:       v1,
+		ContentDigest: ContentDigest(v1),
+		ContentSize:   int64(len(v1)),
+		ContentType:   "application/json",
+	}
+
+	tests := []struct {
+		name                string
+		edit                func(payload *AttestationPayload)
+		wantChanges         []FieldChange
+		wantContentChanged  bool
+		wantPreviousChanged bool
+	}{
+		{name: "identical", edit: func(*AttestationPayload) {}, wantChanges: []FieldChange{}},
+		{
+			name:        "later attestation of the same content",
+			edit:        func(payload *AttestationPayload) { payload.Timestamp = "2026-01-11T12:00:00Z" },
+			wantChanges: []FieldChange{{"timestamp", "2026-01-10T12:00:00Z", "2026-01-11T12:00:00Z"}},
+		},
+		{
+			name: "changed content",
+			edit: func(payload *AttestationPayload) {
+				payload.Content = v2
+				payload.ContentDigest = ContentDigest(v2)
+				payload.ContentSize = int64(len(v2))
+			},
+			wantChanges: []FieldChange{
+				{"content_digest", ContentDigest(v1), ContentDigest(v2)},
+				{"content_size", "11", "22"},
+			},
+			wantContentChanged: true,
+		},
+		{
+			name: "redirected fetch",
+			edit: func(payload *AttestationPayload) {
+				payload.FinalPageURL = "https://example.com/jwks?page=2"
+				payload.ContentType = "text/plain"
+			},
+			wantChanges: []FieldChange{
+				{"final_page_url", "", "https://example.com/jwks?page=2"},
+				{"content_type", "application/json", "text/plain"},
+			},
+		},
+		{
+			name:                "chained to a previous attestation",
+			edit:                func(payload *AttestationPayload) { payload.PreviousAttestation = previous },
+			wantChanges:         []FieldChange{{"previous_attestation", "", previousDigest}},
+			wantPreviousChanged: true,
+		},
+		{
+			name:                "previous pointer that is not attestation details",
+			edit:                func(payload *AttestationPayload) { payload.PreviousAttestation = []byte("legacy") },
+			wantChanges:         []FieldChange{{"previous_attestation", "", "legacy"}},
+			wantPreviousChanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newPayload := base
+			tt.edit(&newPayload)
+			diff := (&Attestation{Payload: base}).Diff(&Attestation{Payload: newPayload})
+
+			if !reflect.DeepEqual(diff.Changes, tt.wantChanges) {
+				t.Errorf("Changes = %+v, want %+v", diff.Changes, tt.wantChanges)
+			}
+			if diff.ContentChanged != tt.wantContentChanged {
+				t.Errorf("ContentChanged = %v, want %v", diff.ContentChanged, tt.wantContentChanged)
+			}
+			if diff.PreviousChanged != tt.wantPreviousChanged {
+				t.Errorf("PreviousChanged = %v, want %v", diff.PreviousChanged, tt.wantPreviousChanged)
+			}
+		})
+	}
+}
+
+func TestAttestationDiffJSON(t *testing.T) {
+	// An unchanged diff reports an empty list of changes rather than null
+	payload := AttestationPayload{Url: "https://example.com/jwks", ContentDigest: ContentDigest(nil)}
+	data, err := json.Marshal((&Attestation{Payload: payload}).Diff(&Attestation{Payload: payload}))
+	if err != nil {
+		t.Fatalf("failed to marshal diff: %v", err)
+	}
+	if want := `{"changes":[],"content_changed":false,"previous_changed":false}`; string(data) != want {
+		t.Errorf("diff JSON = %s, want %s", data, want)
+	}
+}
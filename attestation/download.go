@@ -0,0 +1,1091 @@
+package attestation
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// acceptEncoding is sent explicitly so the transport never decompresses transparently
+// and the oracle always knows which encoding it decoded
+const acceptEncoding = "gzip, deflate"
+
+// defaultRequestContentType is the Content-Type request bodies are sent with when none is given
+const defaultRequestContentType = "application/json"
+
+// fileScheme is the URL scheme used to record content read from the local filesystem
+const fileScheme = "file"
+
+// DownloadOptions configures how content is downloaded
+type DownloadOptions struct {
+	// ExpectedContentTypes is an allowlist of media types (e.g. application/json) the response must match
+	ExpectedContentTypes []string
+	// BasicAuth sends HTTP basic credentials with the request
+	BasicAuth *BasicAuth
+	// BearerToken sends an Authorization: Bearer header with the request
+	BearerToken string
+	// AllowHTTP permits plain http:// URLs, only https:// is accepted otherwise
+	AllowHTTP bool
+	// AllowFile permits file:// URLs and absolute local paths
+	AllowFile bool
+	// AllowURLCredentials permits user:password@ credentials embedded in the URL, which are sent as basic auth
+	// unless BasicAuth or BearerToken is set, and never recorded with the URL
+	AllowURLCredentials bool
+	// BlockPrivateAddresses refuses connections to loopback, private, link-local and unique-local
+	// addresses. The check is made on the resolved address of every connection, so redirects are covered.
+	// Requests sent through a proxy connect to the proxy instead, so their target host is resolved and
+	// checked before each request, redirects included, and the configured proxy itself may be private.
+	BlockPrivateAddresses bool
+	// PinnedCert is the sha256 digest of the server's leaf certificate or of its public key (SPKI).
+	// Connections to servers presenting a different leaf are refused.
+	PinnedCert string
+	// MaxSize fails the download when the decoded content is larger than this many bytes, disabled when 0.
+	// A HEAD request is made first so an oversized advertised Content-Length fails before downloading.
+	MaxSize int64
+	// FollowPagination follows Link rel="next" headers, or fills in PagePlaceholder in the URL,
+	// and attests the concatenated pages
+	FollowPagination bool
+	// MaxPages bounds FollowPagination, defaults to 100
+	MaxPages int
+	// Normalize canonicalizes the content before it is digested, e.g. NormalizeJSON
+	Normalize string
+	// DigestOnly streams the body through the digest without keeping it, leaving DownloadResult.Content nil
+	DigestOnly bool
+	// RecordFetchTiming records when the download started and how long it took in DownloadResult
+	RecordFetchTiming bool
+	// HeadOnly makes a HEAD request and records its metadata in DownloadResult.Head instead of downloading
+	// content, for StatementTypeURLHead attestations of resources too large to fetch
+	HeadOnly bool
+	// Transport is the base transport for downloads, defaults to NewDownloadTransport(). It is cloned, never modified.
+	Transport *http.Transport
+	// ProxyURL sends downloads through this proxy instead of the one from HTTP_PROXY/HTTPS_PROXY
+	ProxyURL string
+	// RootCAs replaces the system roots for verifying the server's certificate, e.g. for a private CA
+	RootCAs *x509.CertPool
+	// Method is the HTTP method content is requested with, defaults to GET. Other methods, e.g. POST for a
+	// GraphQL or JSON-RPC query, are recorded in the payload with the digest of RequestBody.
+	Method string
+	// RequestBody is sent with the request, e.g. a GraphQL query, and requires a Method other than GET
+	RequestBody []byte
+	// RequestContentType is the Content-Type of RequestBody, defaults to application/json
+	RequestContentType string
+	// IfNoneMatch and IfModifiedSince make the request conditional on the content having changed since an
+	// earlier response with this ETag or Last-Modified, e.g. from a previous attestation. A 304 Not Modified
+	// response fails with ErrNotModified.
+	IfNoneMatch     string
+	IfModifiedSince string
+	// MaxErrorSnippet is how much of a non-200 response body BadStatusError keeps, defaults to 512 bytes.
+	// Set it negative to keep none, e.g. when error pages may echo credentials.
+	MaxErrorSnippet int
+}
+
+// BasicAuth holds HTTP basic authentication credentials
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authentication schemes recorded in the payload. Credentials themselves are never recorded.
+const (
+	AuthSchemeBasic  = "basic"
+	AuthSchemeBearer = "bearer"
+)
+
+// authScheme returns the authentication scheme the options will use, or "" for none
+func (o DownloadOptions) authScheme() string {
+	switch {
+	case o.BearerToken != "":
+		return AuthSchemeBearer
+	case o.BasicAuth != nil:
+		return AuthSchemeBasic
+	default:
+		return ""
+	}
+}
+
+// method returns Method, or GET when it is not set
+func (o DownloadOptions) method() string {
+	if o.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(o.Method)
+}
+
+// requestContentType returns RequestContentType, or the default when it is not set
+func (o DownloadOptions) requestContentType() string {
+	if o.RequestContentType == "" {
+		return defaultRequestContentType
+	}
+	return o.RequestContentType
+}
+
+// Environment variables SetAuthFromEnv reads credentials from, so they stay out of flags and process listings
+const (
+	EnvBearerToken = "URL_ORACLE_BEARER_TOKEN"
+	EnvBasicAuth   = "URL_ORACLE_BASIC_AUTH" // as user:pass
+)
+
+// SetAuthFromEnv sets BearerToken from EnvBearerToken for AuthSchemeBearer, or BasicAuth from EnvBasicAuth
+// for AuthSchemeBasic. Errors name the variable but never include its value.
+func (o *DownloadOptions) SetAuthFromEnv(scheme string) error {
+	switch scheme {
+	case AuthSchemeBearer:
+		token := os.Getenv(EnvBearerToken)
+		if token == "" {
+			return fmt.Errorf("environment variable %s is not set", EnvBearerToken)
+		}
+		o.BearerToken = token
+	case AuthSchemeBasic:
+		username, password, ok := strings.Cut(os.Getenv(EnvBasicAuth), ":")
+		if !ok {
+			return fmt.Errorf("environment variable %s must be set to user:pass", EnvBasicAuth)
+		}
+		o.BasicAuth = &BasicAuth{Username: username, Password: password}
+	default:
+		return fmt.Errorf("unsupported authentication scheme %q, expected %s or %s", scheme, AuthSchemeBearer, AuthSchemeBasic)
+	}
+	return nil
+}
+
+// badStatusError returns the BadStatusError for resp, with any credentials the error page echoes redacted
+func (o DownloadOptions) badStatusError(resp *http.Response) *BadStatusError {
+	badStatus := newBadStatusError(resp, o.maxErrorSnippet())
+	var secrets []string
+	if o.BearerToken != "" {
+		secrets = append(secrets, o.BearerToken)
+	}
+	if o.BasicAuth != nil {
+		credentials := o.BasicAuth.Username + ":" + o.BasicAuth.Password
+		secrets = append(secrets, base64.StdEncoding.EncodeToString([]byte(credentials)))
+		if o.BasicAuth.Password != "" {
+			secrets = append(secrets, o.BasicAuth.Password)
+		}
+	}
+	for _, secret := range secrets {
+		badStatus.BodySnippet = strings.ReplaceAll(badStatus.BodySnippet, secret, "[REDACTED]")
+	}
+	return badStatus
+}
+
+// maxErrorSnippet returns MaxErrorSnippet, or the default when it is not set
+func (o DownloadOptions) maxErrorSnippet() int {
+	if o.MaxErrorSnippet == 0 {
+		return defaultMaxBodySnippet
+	}
+	return o.MaxErrorSnippet
+}
+
+// Download transport timeouts
+const (
+	dialTimeout           = 30 * time.Second
+	tlsHandshakeTimeout   = 10 * time.Second
+	responseHeaderTimeout = 30 * time.Second
+)
+
+// NewDownloadTransport returns the default download transport. It uses the proxy from the environment and,
+// unlike http.DefaultTransport, bounds how long the server may take to send response headers.
+func NewDownloadTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = tlsHandshakeTimeout
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+	return transport
+}
+
+// httpClient returns the client used for downloads, built on Transport with the proxy, private address
+// guard and certificate pin applied
+func (o DownloadOptions) httpClient() (*http.Client, error) {
+	var transport *http.Transport
+	if o.Transport != nil {
+		transport = o.Transport.Clone()
+	} else {
+		transport = NewDownloadTransport()
+	}
+	if o.ProxyURL != "" {
+		proxy, err := url.Parse(o.ProxyURL)
+		if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %s", o.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if o.BlockPrivateAddresses {
+		guard := &privateAddressGuard{proxy: transport.Proxy}
+		transport.Proxy = guard.proxyFor
+		transport.DialContext = guard.dialContext
+	}
+	if o.PinnedCert != "" || o.RootCAs != nil {
+		tlsConfig := &tls.Config{}
+		if transport.TLSClientConfig != nil {
+			tlsConfig = transport.TLSClientConfig.Clone()
+		}
+		if o.PinnedCert != "" {
+			tlsConfig.VerifyConnection = verifyPinnedCert(o.PinnedCert)
+		}
+		if o.RootCAs != nil {
+			tlsConfig.RootCAs = o.RootCAs
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// LoadCertPool reads a PEM bundle of CA certificates for DownloadOptions.RootCAs
+func LoadCertPool(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// verifyPinnedCert returns a TLS connection check requiring the leaf certificate or its public key to have the
+// pinned sha256 digest. It runs in addition to the normal chain verification.
+func verifyPinnedCert(pin string) func(tls.ConnectionState) error {
+	pin = strings.ToLower(pin)
+	if !strings.HasPrefix(pin, "sha256:") {
+		pin = "sha256:" + pin
+	}
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return fmt.Errorf("%w %s: server presented no certificate", ErrCertPinMismatch, pin)
+		}
+		leaf := state.PeerCertificates[0]
+		if ContentDigest(leaf.Raw) == pin || ContentDigest(leaf.RawSubjectPublicKeyInfo) == pin {
+			return nil
+		}
+		return fmt.Errorf("%w %s: %s presented certificate %s, public key %s",
+			ErrCertPinMismatch, pin, state.ServerName, ContentDigest(leaf.Raw), ContentDigest(leaf.RawSubjectPublicKeyInfo))
+	}
+}
+
+// blockPrivateAddresses is a net.Dialer control function that rejects non-public destination addresses
+func blockPrivateAddresses(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse address %s: %w", address, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("failed to parse IP address %s", host)
+	}
+	if isPrivateAddress(ip) {
+		return fmt.Errorf("%w %s", ErrPrivateAddress, ip)
+	}
+	return nil
+}
+
+// privateAddressGuard applies DownloadOptions.BlockPrivateAddresses. Direct connections are checked on the
+// address the dialer connects to. A request sent through a proxy connects to the proxy, which resolves the
+// target itself, so the target host is resolved and checked before the request is sent instead; every
+// redirect is a new request, so redirects are checked too.
+type privateAddressGuard struct {
+	// proxy is the transport's proxy function, nil for none
+	proxy func(*http.Request) (*url.URL, error)
+	// proxies holds the host:port of every proxy a checked request was sent through, which may be dialed
+	// whatever their address as they are configured by the operator, not taken from the request
+	proxies sync.Map
+}
+
+// proxyFor returns the proxy for req, refusing the request when it goes through a proxy to a non-public host
+func (g *privateAddressGuard) proxyFor(req *http.Request) (*url.URL, error) {
+	if g.proxy == nil {
+		return nil, nil
+	}
+	proxyURL, err := g.proxy(req)
+	if err != nil || proxyURL == nil {
+		return proxyURL, err
+	}
+	if err := checkPublicHost(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	g.proxies.Store(proxyAddress(proxyURL), true)
+	return proxyURL, nil
+}
+
+// dialContext dials address, refusing non-public addresses unless it is a proxy a checked request goes through
+func (g *privateAddressGuard) dialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	if _, ok := g.proxies.Load(address); !ok {
+		dialer.Control = blockPrivateAddresses
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// proxyAddress returns the host:port the transport dials for proxyURL, with the scheme's default port
+func proxyAddress(proxyURL *url.URL) string {
+	port := proxyURL.Port()
+	if port == "" {
+		switch proxyURL.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// checkPublicHost resolves host and refuses it when any of its addresses is not public. Proxied requests are
+// resolved again by the proxy, so a host whose DNS answers change between the two lookups is not caught.
+func checkPublicHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if isPrivateAddress(ip) {
+			return fmt.Errorf("%w %s", ErrPrivateAddress, ip)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if isPrivateAddress(addr.IP) {
+			return fmt.Errorf("%w %s (%s)", ErrPrivateAddress, addr.IP, host)
+		}
+	}
+	return nil
+}
+
+// isPrivateAddress reports whether ip is loopback, RFC1918/unique-local, link-local or unspecified
+func isPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified()
+}
+
+// FetchMeta describes how content was fetched. It is recorded in the attestation payload alongside the
+// content digest.
+type FetchMeta struct {
+	URL             string // source URL as it should be recorded in the payload, the requested URL when empty
+	ContentEncoding string // Content-Encoding the response was served with, empty for identity
+	ContentType     string // Content-Type header the response was served with
+	AuthScheme      string // authentication scheme used for the request, empty when unauthenticated
+	// TLSCertFingerprints are the sha256 fingerprints of the server certificate chain, leaf first
+	TLSCertFingerprints []string
+	// TLSLeafIssuer is the issuer distinguished name of the server's leaf certificate
+	TLSLeafIssuer string
+	// PageCount is the number of pages fetched with FollowPagination, 0 otherwise
+	PageCount int
+	// FinalPageURL is the URL of the last page fetched with FollowPagination
+	FinalPageURL string
+	// PageURLs lists every page fetched with FollowPagination, in order
+	PageURLs []string
+	// Normalization is the normalization applied to the content before it was digested
+	Normalization string
+	// ResponseHeadersDigest is the ResponseHeadersDigest of the response, of the first page when paginating
+	ResponseHeadersDigest string
+	// StatementType is the payload statement type for the content, empty for StatementTypeURLContent
+	StatementType string
+	// Head is the HEAD response metadata when downloaded with HeadOnly
+	Head *HeadMetadata
+	// Method is the HTTP method content was requested with, empty for GET
+	Method string
+	// RequestBodyDigest is the ContentDigest of the request body, empty when none was sent
+	RequestBodyDigest string
+	// RequestContentType is the Content-Type the request body was sent with
+	RequestContentType string
+	// ETag and LastModified are the response's cache validators, for conditional requests (see IfNoneMatch)
+	ETag         string
+	LastModified string
+	// FetchStartedAt and FetchDuration time the whole download, redirects and pages included, with RecordFetchTiming
+	FetchStartedAt time.Time
+	FetchDuration  time.Duration
+}
+
+// DownloadResult holds downloaded content together with its digest and fetch metadata
+type DownloadResult struct {
+	FetchMeta
+	Content       []byte
+	ContentDigest string
+	ContentSize   int64
+	// ContentDigestMultihash is ContentDigest as a multihash, recorded when set (see ContentDigestMultihash)
+	ContentDigestMultihash string
+	// ContentCompression is how Content is compressed for storage (see CompressContent), empty when it is not
+	ContentCompression string
+
+	nextPageURL string
+}
+
+// PayloadOptions returns the payload options recording how the content was downloaded, for CreateAttestationPayload
+func (r *DownloadResult) PayloadOptions() []PayloadOption {
+	opts := []PayloadOption{
+		WithContentEncoding(r.ContentEncoding),
+		WithContentType(r.ContentType),
+		WithAuthScheme(r.AuthScheme),
+		WithTLSCertificates(r.TLSCertFingerprints, r.TLSLeafIssuer),
+		WithPagination(r.PageCount, r.FinalPageURL),
+		WithPageURLs(r.PageURLs),
+		WithNormalization(r.Normalization),
+		WithResponseHeadersDigest(r.ResponseHeadersDigest),
+		WithHead(r.Head),
+		WithContentDigestMultihash(r.ContentDigestMultihash),
+		WithContentCompression(r.ContentCompression),
+		WithRequest(r.Method, r.RequestBodyDigest, r.RequestContentType),
+		WithCacheValidators(r.ETag, r.LastModified),
+	}
+	if !r.FetchStartedAt.IsZero() {
+		opts = append(opts, WithFetchTiming(r.FetchStartedAt, r.FetchDuration))
+	}
+	if r.StatementType != "" {
+		opts = append(opts, WithStatementType(r.StatementType))
+	}
+	return opts
+}
+
+// DownloadContent downloads content from an https URL and returns the decoded content, digest, and size.
+// Other schemes must be enabled through DownloadContentWithOptions.
+func DownloadContent(sourceURL string) (*DownloadResult, error) {
+	return DownloadContentWithOptions(sourceURL, DownloadOptions{})
+}
+
+// DownloadContentWithOptions downloads content from a URL like DownloadContent, applying opts.
+// file:// URLs and absolute local paths are read from disk when opts.AllowFile is set.
+func DownloadContentWithOptions(sourceURL string, opts DownloadOptions) (*DownloadResult, error) {
+	return DownloadContentContext(context.Background(), sourceURL, opts)
+}
+
+// DownloadContentContext downloads content like DownloadContentWithOptions, aborting when ctx is done
+func DownloadContentContext(ctx context.Context, sourceURL string, opts DownloadOptions) (*DownloadResult, error) {
+	started := time.Now()
+	result, err := downloadContent(ctx, sourceURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.RecordFetchTiming {
+		result.FetchStartedAt = started
+		result.FetchDuration = time.Since(started)
+	}
+	return result, nil
+}
+
+// downloadContent downloads content for DownloadContentContext
+func downloadContent(ctx context.Context, sourceURL string, opts DownloadOptions) (*DownloadResult, error) {
+	if err := validateURL(sourceURL, opts); err != nil {
+		return nil, err
+	}
+	if err := validateRequest(sourceURL, opts); err != nil {
+		return nil, err
+	}
+	sourceURL, opts = moveURLCredentials(sourceURL, opts)
+	if opts.HeadOnly {
+		return downloadHead(ctx, sourceURL, opts)
+	}
+	if opts.Normalize != "" {
+		return downloadNormalized(ctx, sourceURL, opts)
+	}
+
+	if path, ok := localPath(sourceURL); ok {
+		return readLocalContent(path, opts.DigestOnly, opts.MaxSize)
+	}
+
+	if opts.FollowPagination {
+		return downloadPages(ctx, sourceURL, opts)
+	}
+
+	sourceURL, err := NormalizeURL(sourceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := opts.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	// A HEAD request says nothing about the response to another method, so only GETs are checked up front
+	if opts.MaxSize > 0 && opts.method() == http.MethodGet {
+		if err := checkAdvertisedSize(ctx, client, sourceURL, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := newDownloadRequest(ctx, opts.method(), sourceURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.IfNoneMatch != "" {
+		req.Header.Set("If-None-Match", opts.IfNoneMatch)
+	}
+	if opts.IfModifiedSince != "" {
+		req.Header.Set("If-Modified-Since", opts.IfModifiedSince)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download content from %s: %w", RedactURL(sourceURL), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && (opts.IfNoneMatch != "" || opts.IfModifiedSince != "") {
+		return nil, fmt.Errorf("%w: %s", ErrNotModified, RedactURL(sourceURL))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, opts.badStatusError(resp)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if err := checkContentType(contentType, opts.ExpectedContentTypes); err != nil {
+		return nil, err
+	}
+
+	// Count the bytes received on the wire, before decoding, to compare them with the Content-Length
+	received := &countingReader{r: resp.Body}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	body, err := decodeContent(received, encoding)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var reader io.Reader = body
+	if opts.MaxSize > 0 {
+		// Read one byte past the limit so oversized content is detected without reading all of it
+		reader = io.LimitReader(body, opts.MaxSize+1)
+	}
+	content, digest, size, err := readContent(reader, opts.DigestOnly)
+	if errors.Is(err, io.ErrUnexpectedEOF) && resp.ContentLength >= 0 {
+		return nil, fmt.Errorf("%w: %s ended early", ErrTruncatedContent, RedactURL(sourceURL))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if opts.MaxSize > 0 && size > opts.MaxSize {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrContentTooLarge, RedactURL(sourceURL), opts.MaxSize)
+	}
+	if err := checkContentLength(received, resp.ContentLength); err != nil {
+		return nil, fmt.Errorf("%s: %w", RedactURL(sourceURL), err)
+	}
+
+	if encoding == "identity" {
+		encoding = ""
+	}
+	result := &DownloadResult{
+		FetchMeta: FetchMeta{
+			URL:                   sourceURL,
+			ContentEncoding:       encoding,
+			ContentType:           contentType,
+			AuthScheme:            opts.authScheme(),
+			ResponseHeadersDigest: ResponseHeadersDigest(resp.StatusCode, resp.Header),
+			ETag:                  resp.Header.Get("ETag"),
+			LastModified:          resp.Header.Get("Last-Modified"),
+		},
+		Content:       content,
+		ContentDigest: digest,
+		ContentSize:   size,
+		nextPageURL:   nextPageURL(resp),
+	}
+	if method := opts.method(); method != http.MethodGet {
+		result.Method = method
+	}
+	if opts.RequestBody != nil {
+		result.RequestBodyDigest = ContentDigest(opts.RequestBody)
+		result.RequestContentType = opts.requestContentType()
+	}
+	// Plain http:// responses have no TLS state, so nothing is recorded for them
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		for _, cert := range resp.TLS.PeerCertificates {
+			result.TLSCertFingerprints = append(result.TLSCertFingerprints, ContentDigest(cert.Raw))
+		}
+		result.TLSLeafIssuer = resp.TLS.PeerCertificates[0].Issuer.String()
+	}
+	return result, nil
+}
+
+// downloadHead makes a HEAD request for sourceURL and records the response metadata without any content
+func downloadHead(ctx context.Context, sourceURL string, opts DownloadOptions) (*DownloadResult, error) {
+	if _, ok := localPath(sourceURL); ok {
+		return nil, fmt.Errorf("head-only downloads are not supported for local files")
+	}
+	if opts.FollowPagination || opts.Normalize != "" {
+		return nil, fmt.Errorf("head-only downloads cannot follow pagination or normalize content")
+	}
+
+	sourceURL, err := NormalizeURL(sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := opts.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	req, err := newDownloadRequest(ctx, http.MethodHead, sourceURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Ask for the identity encoding so Content-Length is the size of the resource itself
+	req.Header.Set("Accept-Encoding", "identity")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send HEAD request to %s: %w", RedactURL(sourceURL), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, opts.badStatusError(resp)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if err := checkContentType(contentType, opts.ExpectedContentTypes); err != nil {
+		return nil, err
+	}
+
+	result := &DownloadResult{FetchMeta: FetchMeta{
+		URL:                   sourceURL,
+		ContentType:           contentType,
+		AuthScheme:            opts.authScheme(),
+		ResponseHeadersDigest: ResponseHeadersDigest(resp.StatusCode, resp.Header),
+		StatementType:         StatementTypeURLHead,
+		Head: &HeadMetadata{
+			StatusCode:    resp.StatusCode,
+			ContentLength: resp.ContentLength,
+			ETag:          resp.Header.Get("ETag"),
+			LastModified:  resp.Header.Get("Last-Modified"),
+		},
+	}}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		for _, cert := range resp.TLS.PeerCertificates {
+			result.TLSCertFingerprints = append(result.TLSCertFingerprints, ContentDigest(cert.Raw))
+		}
+		result.TLSLeafIssuer = resp.TLS.PeerCertificates[0].Issuer.String()
+	}
+	return result, nil
+}
+
+// newDownloadRequest creates a request for sourceURL with the encoding and authentication headers from opts,
+// and the request body from opts for any method but HEAD
+func newDownloadRequest(ctx context.Context, method string, sourceURL string, opts DownloadOptions) (*http.Request, error) {
+	// A bytes.Reader body is replayed on 307 and 308 redirects
+	var body io.Reader
+	if opts.RequestBody != nil && method != http.MethodHead {
+		body = bytes.NewReader(opts.RequestBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, sourceURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", RedactURL(sourceURL), err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", opts.requestContentType())
+	}
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	switch opts.authScheme() {
+	case AuthSchemeBearer:
+		req.Header.Set("Authorization", "Bearer "+opts.BearerToken)
+	case AuthSchemeBasic:
+		req.SetBasicAuth(opts.BasicAuth.Username, opts.BasicAuth.Password)
+	}
+	return req, nil
+}
+
+// checkAdvertisedSize makes a HEAD request and fails if the advertised Content-Length exceeds opts.MaxSize.
+// Servers that reject HEAD or omit the header are left to the size check made while reading the body.
+func checkAdvertisedSize(ctx context.Context, client *http.Client, sourceURL string, opts DownloadOptions) error {
+	req, err := newDownloadRequest(ctx, http.MethodHead, sourceURL, opts)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// Cancellation is not a HEAD failure to fall back from
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return nil
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK && resp.ContentLength > opts.MaxSize {
+		return fmt.Errorf("%w: %s advertises %d bytes, limit is %d", ErrContentTooLarge, RedactURL(sourceURL), resp.ContentLength, opts.MaxSize)
+	}
+	return nil
+}
+
+// checkContentType checks the Content-Type media type against the allowlist, if one is set
+func checkContentType(contentType string, expected []string) error {
+	if len(expected) == 0 {
+		return nil
+	}
+	if contentType == "" {
+		return fmt.Errorf("%w: response has no content type, expected one of %v", ErrUnexpectedContentType, expected)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("failed to parse content type %q: %w", contentType, err)
+	}
+	for _, allowed := range expected {
+		if strings.EqualFold(mediaType, strings.TrimSpace(allowed)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q, expected one of %v", ErrUnexpectedContentType, mediaType, expected)
+}
+
+// validateRequest checks the method, request body, conditional request and expected content type options in
+// opts can be used for sourceURL
+func validateRequest(sourceURL string, opts DownloadOptions) error {
+	if (opts.IfNoneMatch != "" || opts.IfModifiedSince != "") && (opts.FollowPagination || opts.HeadOnly) {
+		return fmt.Errorf("conditional requests cannot be combined with head-only or pagination")
+	}
+	if _, ok := localPath(sourceURL); ok && len(opts.ExpectedContentTypes) > 0 {
+		return fmt.Errorf("expected content types are not supported for local files, which have no content type")
+	}
+	method := opts.method()
+	if method == http.MethodGet {
+		if opts.RequestBody != nil {
+			return fmt.Errorf("a request body requires a method other than GET")
+		}
+		return nil
+	}
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+	case http.MethodHead:
+		return fmt.Errorf("use HeadOnly to attest a HEAD response")
+	default:
+		return fmt.Errorf("unsupported request method %q", opts.Method)
+	}
+	if _, ok := localPath(sourceURL); ok {
+		return fmt.Errorf("request methods other than GET are not supported for local files")
+	}
+	if opts.HeadOnly || opts.FollowPagination {
+		return fmt.Errorf("request methods other than GET cannot be combined with head-only or pagination")
+	}
+	return nil
+}
+
+// validateURL rejects malformed URLs, URLs with embedded credentials and schemes not enabled by opts.
+// Only https is accepted by default.
+func validateURL(sourceURL string, opts DownloadOptions) error {
+	if _, ok := localPath(sourceURL); ok {
+		if !opts.AllowFile {
+			return fmt.Errorf("%w: local file sources are not allowed: %s", ErrUnsupportedScheme, RedactURL(sourceURL))
+		}
+		return nil
+	}
+
+	parsed, err := url.Parse(sourceURL)
+	if err != nil {
+		return fmt.Errorf("%w %s: %v", ErrInvalidURL, RedactURL(sourceURL), urlParseError(err))
+	}
+	if parsed.Scheme == "" {
+		return fmt.Errorf("%w %s: missing scheme, e.g. https://", ErrInvalidURL, RedactURL(sourceURL))
+	}
+	if parsed.User != nil && !opts.AllowURLCredentials {
+		return fmt.Errorf("%w: embedded credentials are not allowed, use basic auth options instead", ErrInvalidURL)
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "https":
+	case "http":
+		if !opts.AllowHTTP {
+			return fmt.Errorf("%w: plain http URLs are not allowed: %s", ErrUnsupportedScheme, RedactURL(sourceURL))
+		}
+	default:
+		return fmt.Errorf("%w %q, only https is allowed", ErrUnsupportedScheme, parsed.Scheme)
+	}
+
+	if parsed.Host == "" {
+		return fmt.Errorf("%w %s: missing host", ErrInvalidURL, RedactURL(sourceURL))
+	}
+	return nil
+}
+
+// moveURLCredentials takes userinfo, which validateURL only lets through with AllowURLCredentials, out of
+// sourceURL and into the basic auth options, so it is sent as a header and never recorded with the URL.
+// Authentication set in opts takes precedence over the userinfo.
+func moveURLCredentials(sourceURL string, opts DownloadOptions) (string, DownloadOptions) {
+	if _, ok := localPath(sourceURL); ok {
+		return sourceURL, opts
+	}
+	parsed, err := url.Parse(sourceURL)
+	if err != nil || parsed.User == nil {
+		return sourceURL, opts
+	}
+	if opts.authScheme() == "" {
+		password, _ := parsed.User.Password()
+		opts.BasicAuth = &BasicAuth{Username: parsed.User.Username(), Password: password}
+	}
+	parsed.User = nil
+	return parsed.String(), opts
+}
+
+// NormalizeURL returns the canonical form of an http(s) URL as recorded in payloads: lowercase scheme and host,
+// no userinfo, no default port, "/" for an empty path and no fragment
+func NormalizeURL(sourceURL string) (string, error) {
+	parsed, err := url.Parse(sourceURL)
+	if err != nil {
+		return "", fmt.Errorf("%w %s: %v", ErrInvalidURL, RedactURL(sourceURL), urlParseError(err))
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.User = nil
+	host := strings.ToLower(parsed.Hostname())
+	port := parsed.Port()
+	if (parsed.Scheme == "https" && port == "443") || (parsed.Scheme == "http" && port == "80") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	parsed.Host = host
+	if parsed.Path == "" {
+		parsed.Path = "/"
+	}
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	return parsed.String(), nil
+}
+
+// localPath returns the filesystem path for file:// URLs and bare absolute paths
+func localPath(sourceURL string) (string, bool) {
+	if filepath.IsAbs(sourceURL) {
+		return sourceURL, true
+	}
+	parsed, err := url.Parse(sourceURL)
+	if err != nil || parsed.Scheme != fileScheme {
+		return "", false
+	}
+	return parsed.Path, true
+}
+
+// RedactURL returns sourceURL without any embedded userinfo, so it can be printed in errors and logs
+func RedactURL(sourceURL string) string {
+	parsed, err := url.Parse(sourceURL)
+	if err == nil {
+		parsed.User = nil
+		return parsed.String()
+	}
+	// Unparseable URLs are cut at the last "@" of their authority instead
+	scheme, rest, found := strings.Cut(sourceURL, "://")
+	if !found {
+		return sourceURL
+	}
+	authority := rest
+	if end := strings.IndexAny(rest, "/?#"); end >= 0 {
+		authority = rest[:end]
+	}
+	if at := strings.LastIndex(authority, "@"); at >= 0 {
+		rest = rest[at+1:]
+	}
+	return scheme + "://" + rest
+}
+
+// urlParseError returns the cause of a url.Parse failure without the *url.Error prefix that repeats the raw URL
+func urlParseError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// ReadContent reads content from a file:// URL or local path, returning the same digest and size
+// outputs as DownloadContent
+func ReadContent(source string) (*DownloadResult, error) {
+	path, ok := localPath(source)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a file:// URL or absolute local path: %s", ErrUnsupportedScheme, source)
+	}
+	return readLocalContent(path, false, 0)
+}
+
+// readLocalContent reads a local file and records it with a file:// URL so verifiers can tell the source type.
+// Files larger than maxSize, when it is positive, are rejected without reading more than maxSize+1 bytes.
+func readLocalContent(path string, digestOnly bool, maxSize int64) (*DownloadResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content from %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory, expected a file", path)
+	}
+
+	if maxSize > 0 && info.Size() > maxSize {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrContentTooLarge, path, maxSize)
+	}
+
+	// The file may have grown since, or report no size as some special files do, so the read is bounded too
+	var r io.Reader = file
+	if maxSize > 0 {
+		r = io.LimitReader(file, maxSize+1)
+	}
+	content, digest, size, err := readContent(r, digestOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content from %s: %w", path, err)
+	}
+	if maxSize > 0 && size > maxSize {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrContentTooLarge, path, maxSize)
+	}
+
+	fileURL := url.URL{Scheme: fileScheme, Path: filepath.ToSlash(path)}
+	return &DownloadResult{
+		FetchMeta:     FetchMeta{URL: fileURL.String()},
+		Content:       content,
+		ContentDigest: digest,
+		ContentSize:   size,
+	}, nil
+}
+
+// readContent hashes r as it is read. Unless digestOnly is set the content is also buffered and returned,
+// otherwise memory use stays bounded regardless of content size.
+func readContent(r io.Reader, digestOnly bool) ([]byte, string, int64, error) {
+	hasher := sha256.New()
+	var buffer bytes.Buffer
+	var sink io.Writer = hasher
+	if !digestOnly {
+		sink = io.MultiWriter(hasher, &buffer)
+	}
+
+	size, err := io.Copy(sink, r)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	digest := "sha256:" + hex.EncodeToString(hasher.Sum(nil))
+	if digestOnly {
+		return nil, digest, size, nil
+	}
+	return buffer.Bytes(), digest, size, nil
+}
+
+// DigestedResponseHeaders are the response headers covered by ResponseHeadersDigest, in digest order
+var DigestedResponseHeaders = []string{"Cache-Control", "Content-Type", "ETag", "Last-Modified"}
+
+// ResponseHeadersDigest returns the sha256 digest of a response's status code and DigestedResponseHeaders.
+// The digested text is the status code on its own line followed by one "name: value" line per header,
+// with lowercase names in DigestedResponseHeaders order, repeated values joined with ", " and an empty
+// value for absent headers.
+func ResponseHeadersDigest(statusCode int, header http.Header) string {
+	var canonical strings.Builder
+	fmt.Fprintf(&canonical, "%d\n", statusCode)
+	for _, name := range DigestedResponseHeaders {
+		values := make([]string, 0, len(header.Values(name)))
+		for _, value := range header.Values(name) {
+			values = append(values, strings.TrimSpace(value))
+		}
+		fmt.Fprintf(&canonical, "%s: %s\n", strings.ToLower(name), strings.Join(values, ", "))
+	}
+	return ContentDigest([]byte(canonical.String()))
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// checkContentLength fails when fewer bytes were received than the advertised Content-Length, which is -1
+// when unknown. Anything a decoder left unread is drained first so only the wire size is compared.
+func checkContentLength(received *countingReader, contentLength int64) error {
+	if contentLength < 0 {
+		return nil
+	}
+	if _, err := io.Copy(io.Discard, received); err != nil {
+		return fmt.Errorf("%w: %v", ErrTruncatedContent, err)
+	}
+	if received.n != contentLength {
+		return fmt.Errorf("%w: received %d of %d bytes", ErrTruncatedContent, received.n, contentLength)
+	}
+	return nil
+}
+
+// ContentDigest returns the sha256 digest of content in the "sha256:<hex>" format used in payloads
+func ContentDigest(content []byte) string {
+	digest := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(digest[:])
+}
+
+// multihashSHA256 is the multihash prefix for a sha2-256 digest: the function code and the digest length
+var multihashSHA256 = []byte{0x12, sha256.Size}
+
+// ContentDigestMultihash converts a "sha256:<hex>" content digest to a base64-encoded sha2-256 multihash,
+// the representation some SBOM tooling expects
+func ContentDigestMultihash(contentDigest string) (string, error) {
+	encoded, ok := strings.CutPrefix(contentDigest, "sha256:")
+	if !ok {
+		return "", fmt.Errorf("content digest %q is not a sha256: digest", contentDigest)
+	}
+	digest, err := hex.DecodeString(encoded)
+	if err != nil || len(digest) != sha256.Size {
+		return "", fmt.Errorf("content digest %q is not a sha256 hex digest", contentDigest)
+	}
+	return base64.StdEncoding.EncodeToString(append(append([]byte{}, multihashSHA256...), digest...)), nil
+}
+
+// decodeContent wraps body with a decoder for the given Content-Encoding
+func decodeContent(body io.Reader, encoding string) (io.ReadCloser, error) {
+	switch encoding {
+	case "", "identity":
+		return io.NopCloser(body), nil
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gzip content: %w", err)
+		}
+		return reader, nil
+	case "deflate":
+		// "deflate" is meant to be zlib-wrapped but some servers send raw deflate streams
+		buffered := bufio.NewReader(body)
+		header, err := buffered.Peek(2)
+		if err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			reader, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode deflate content: %w", err)
+			}
+			return reader, nil
+		}
+		return flate.NewReader(buffered), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
@@ -0,0 +1,54 @@
+package attestation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultDownloadWorkers is the number of concurrent downloads DownloadAll makes when workers is not positive
+const DefaultDownloadWorkers = 4
+
+// DownloadAll downloads every URL with DownloadContentContext using at most workers concurrent downloads.
+// Results are returned in the order of sourceURLs regardless of which download finishes first, so payloads
+// built from them hash the same on every run. If any download fails, the errors of every failed URL are
+// returned joined together, in the order of sourceURLs, and the results are nil.
+func DownloadAll(ctx context.Context, sourceURLs []string, opts DownloadOptions, workers int) ([]*DownloadResult, error) {
+	if workers <= 0 {
+		workers = DefaultDownloadWorkers
+	}
+	if workers > len(sourceURLs) {
+		workers = len(sourceURLs)
+	}
+
+	results := make([]*DownloadResult, len(sourceURLs))
+	errs := make([]error, len(sourceURLs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result, err := DownloadContentContext(ctx, sourceURLs[i], opts)
+				if err != nil {
+					errs[i] = fmt.Errorf("failed to download %s: %w", sourceURLs[i], err)
+					continue
+				}
+				results[i] = result
+			}
+		}()
+	}
+	for i := range sourceURLs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,104 @@
+package attestation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloadAll(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		// Later URLs finish first
+		time.Sleep(time.Duration(10-n) * 5 * time.Millisecond)
+		fmt.Fprintf(w, "content %d", n)
+	}))
+	defer server.Close()
+	urls := func(paths ...string) []string {
+		var urls []string
+		for _, path := range paths {
+			urls = append(urls, server.URL+"/"+path)
+		}
+		return urls
+	}
+
+	tests := []struct {
+		name     string
+		urls     []string
+		workers  int
+		wantMax  int32
+		wantErrs []string
+	}{
+		{name: "one worker", urls: urls("1", "2", "3", "4", "5", "6"), workers: 1, wantMax: 1},
+		{name: "two workers", urls: urls("1", "2", "3", "4", "5", "6"), workers: 2, wantMax: 2},
+		{name: "default workers", urls: urls("1", "2", "3", "4", "5", "6"), workers: 0, wantMax: DefaultDownloadWorkers},
+		{name: "more workers than URLs", urls: urls("1", "2", "3"), workers: 8, wantMax: 3},
+		{name: "no URLs", urls: nil, workers: 2},
+		{
+			name:     "failed URLs are reported in order",
+			urls:     urls("1", "missing", "3", "gone"),
+			workers:  4,
+			wantMax:  4,
+			wantErrs: []string{"failed to download " + server.URL + "/missing", "failed to download " + server.URL + "/gone"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxInFlight.Store(0)
+			results, err := DownloadAll(context.Background(), tt.urls, DownloadOptions{AllowHTTP: true}, tt.workers)
+			if got := maxInFlight.Load(); got > tt.wantMax || (tt.wantErrs == nil && got < tt.wantMax) {
+				t.Errorf("%d downloads ran concurrently, want %d", got, tt.wantMax)
+			}
+			if tt.wantErrs != nil {
+				if results != nil {
+					t.Errorf("got %d results with an error", len(results))
+				}
+				var badStatus *BadStatusError
+				if !errors.As(err, &badStatus) || badStatus.Code != http.StatusNotFound {
+					t.Errorf("expected a 404 BadStatusError, got %v", err)
+				}
+				lines := strings.Split(err.Error(), "\n")
+				if len(lines) != len(tt.wantErrs) {
+					t.Fatalf("expected %d errors, got %q", len(tt.wantErrs), err)
+				}
+				for i, want := range tt.wantErrs {
+					if !strings.HasPrefix(lines[i], want) {
+						t.Errorf("error %d = %q, want prefix %q", i, lines[i], want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DownloadAll: %v", err)
+			}
+			if len(results) != len(tt.urls) {
+				t.Fatalf("got %d results for %d URLs", len(results), len(tt.urls))
+			}
+			for i, result := range results {
+				if result.URL != tt.urls[i] || string(result.Content) != "content "+strings.TrimPrefix(tt.urls[i], server.URL+"/") {
+					t.Errorf("result %d is %q from %s, want the content of %s", i, result.Content, result.URL, tt.urls[i])
+				}
+			}
+		})
+	}
+}
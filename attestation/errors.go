@@ -0,0 +1,69 @@
+package attestation
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Errors returned by the download path, for use with errors.Is
+var (
+	// ErrInvalidURL is returned for malformed URLs and URLs with embedded credentials
+	ErrInvalidURL = errors.New("invalid url")
+	// ErrUnsupportedScheme is returned for URL schemes that are not supported or not enabled
+	ErrUnsupportedScheme = errors.New("unsupported URL scheme")
+	// ErrContentTooLarge is returned when content exceeds DownloadOptions.MaxSize
+	ErrContentTooLarge = errors.New("content exceeds maximum size")
+	// ErrUnexpectedContentType is returned when the content type is not in DownloadOptions.ExpectedContentTypes
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+	// ErrPrivateAddress is returned when DownloadOptions.BlockPrivateAddresses refuses a connection
+	ErrPrivateAddress = errors.New("refusing to connect to non-public address")
+	// ErrTruncatedContent is returned when fewer bytes are received than the response's Content-Length
+	ErrTruncatedContent = errors.New("content is shorter than its Content-Length")
+	// ErrCertPinMismatch is returned when the server certificate does not match DownloadOptions.PinnedCert
+	ErrCertPinMismatch = errors.New("server certificate does not match pin")
+	// ErrNotModified is returned when a conditional request (DownloadOptions.IfNoneMatch or IfModifiedSince)
+	// gets a 304 Not Modified response
+	ErrNotModified = errors.New("content not modified")
+)
+
+// defaultMaxBodySnippet is how much of an error response body is kept for diagnostics by default
+const defaultMaxBodySnippet = 512
+
+// BadStatusError is returned when the server responds with a status other than 200 OK
+type BadStatusError struct {
+	Code int
+	// BodySnippet is the start of the response body, truncated to 512 bytes by default
+	BodySnippet string
+	// Truncated is set when the response body was longer than BodySnippet
+	Truncated bool
+}
+
+func (e *BadStatusError) Error() string {
+	if e.BodySnippet == "" {
+		return fmt.Sprintf("HTTP request failed with status: %d", e.Code)
+	}
+	if e.Truncated {
+		return fmt.Sprintf("HTTP request failed with status: %d: %q (truncated)", e.Code, e.BodySnippet)
+	}
+	return fmt.Sprintf("HTTP request failed with status: %d: %q", e.Code, e.BodySnippet)
+}
+
+// newBadStatusError reads up to maxSnippet bytes of an error response body into a BadStatusError,
+// keeping none when maxSnippet is negative
+func newBadStatusError(resp *http.Response, maxSnippet int) *BadStatusError {
+	badStatus := &BadStatusError{Code: resp.StatusCode}
+	if maxSnippet < 0 {
+		return badStatus
+	}
+	// Read one byte past the limit to tell whether the body was truncated
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, int64(maxSnippet)+1))
+	if len(snippet) > maxSnippet {
+		snippet = snippet[:maxSnippet]
+		badStatus.Truncated = true
+	}
+	badStatus.BodySnippet = strings.TrimSpace(string(snippet))
+	return badStatus
+}
@@ -0,0 +1,155 @@
+package attestation
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownloadErrorTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 2048)))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		}
+	}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("content")) }))
+	defer tlsServer.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+	localFile := filepath.Join(t.TempDir(), "content.txt")
+	if err := os.WriteFile(localFile, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to write local file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		opts    DownloadOptions
+		wantErr error
+	}{
+		{name: "unsupported scheme", url: "ftp://example.com/file", wantErr: ErrUnsupportedScheme},
+		{name: "plain http not enabled", url: server.URL, wantErr: ErrUnsupportedScheme},
+		{name: "local file not enabled", url: localFile, wantErr: ErrUnsupportedScheme},
+		{name: "content too large", url: server.URL + "/large", opts: DownloadOptions{AllowHTTP: true, MaxSize: 1024}, wantErr: ErrContentTooLarge},
+		{
+			name:    "unexpected content type",
+			url:     server.URL,
+			opts:    DownloadOptions{AllowHTTP: true, ExpectedContentTypes: []string{"application/json"}},
+			wantErr: ErrUnexpectedContentType,
+		},
+		{name: "private address", url: server.URL, opts: DownloadOptions{AllowHTTP: true, BlockPrivateAddresses: true}, wantErr: ErrPrivateAddress},
+		{
+			name:    "certificate pin mismatch",
+			url:     tlsServer.URL,
+			opts:    DownloadOptions{RootCAs: roots, PinnedCert: ContentDigest([]byte("pin"))},
+			wantErr: ErrCertPinMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DownloadContentContext(context.Background(), tt.url, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("bad status", func(t *testing.T) {
+		_, err := DownloadContentContext(context.Background(), server.URL+"/missing", DownloadOptions{AllowHTTP: true})
+		var badStatus *BadStatusError
+		if !errors.As(err, &badStatus) {
+			t.Fatalf("expected a BadStatusError, got %v", err)
+		}
+		if badStatus.Code != http.StatusNotFound {
+			t.Errorf("Code = %d, want %d", badStatus.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestBadStatusErrorCode(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        int
+		body        string
+		wantSnippet string
+	}{
+		{name: "forbidden", code: http.StatusForbidden, body: "token lacks scope\n", wantSnippet: "token lacks scope"},
+		{name: "not found", code: http.StatusNotFound, body: "no such object", wantSnippet: "no such object"},
+		{name: "server error without a body", code: http.StatusInternalServerError},
+		{name: "not modified without a conditional request", code: http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.code)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := DownloadContentContext(context.Background(), server.URL, DownloadOptions{AllowHTTP: true})
+			var badStatus *BadStatusError
+			if !errors.As(err, &badStatus) {
+				t.Fatalf("expected a BadStatusError, got %v", err)
+			}
+			if badStatus.Code != tt.code {
+				t.Errorf("Code = %d, want %d", badStatus.Code, tt.code)
+			}
+			if badStatus.BodySnippet != tt.wantSnippet {
+				t.Errorf("BodySnippet = %q, want %q", badStatus.BodySnippet, tt.wantSnippet)
+			}
+		})
+	}
+}
+
+func TestBadStatusErrorBodySnippet(t *testing.T) {
+	long := strings.Repeat("a", 600)
+
+	tests := []struct {
+		name          string
+		body          string
+		maxSnippet    int
+		wantSnippet   string
+		wantTruncated bool
+		wantMessage   string
+	}{
+		{name: "short body kept", body: "bad credentials", wantSnippet: "bad credentials", wantMessage: `status: 401: "bad credentials"`},
+		{name: "truncated to the default 512 bytes", body: long, wantSnippet: long[:512], wantTruncated: true, wantMessage: "(truncated)"},
+		{name: "configured size", body: "bad credentials", maxSnippet: 3, wantSnippet: "bad", wantTruncated: true},
+		{name: "body exactly the configured size", body: "bad", maxSnippet: 3, wantSnippet: "bad"},
+		{name: "snippets disabled", body: "bad credentials", maxSnippet: -1, wantMessage: "HTTP request failed with status: 401"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := DownloadContentContext(context.Background(), server.URL, DownloadOptions{AllowHTTP: true, MaxErrorSnippet: tt.maxSnippet})
+			var badStatus *BadStatusError
+			if !errors.As(err, &badStatus) {
+				t.Fatalf("expected a BadStatusError, got %v", err)
+			}
+			if badStatus.BodySnippet != tt.wantSnippet || badStatus.Truncated != tt.wantTruncated {
+				t.Errorf("snippet %q (truncated %v), want %q (truncated %v)", badStatus.BodySnippet, badStatus.Truncated, tt.wantSnippet, tt.wantTruncated)
+			}
+			if !strings.Contains(badStatus.Error(), tt.wantMessage) {
+				t.Errorf("Error() = %q, want it to contain %q", badStatus.Error(), tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package attestation
+
+import (
+	"context"
+)
+
+// Fetcher fetches the content of a URL for attestation, so new sources such as object stores or git blobs
+// can be attested without changes to payload creation. Fetchers for StatementTypeURLHead return no content.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (content []byte, meta FetchMeta, err error)
+}
+
+// HTTPFetcher is the default Fetcher, downloading with DownloadContentContext. Options.DigestOnly is
+// ignored, as the content is returned.
+type HTTPFetcher struct {
+	Options DownloadOptions
+}
+
+// Fetch downloads sourceURL with the fetcher's options
+func (f *HTTPFetcher) Fetch(ctx context.Context, sourceURL string) ([]byte, FetchMeta, error) {
+	opts := f.Options
+	opts.DigestOnly = false
+	result, err := DownloadContentContext(ctx, sourceURL, opts)
+	if err != nil {
+		return nil, FetchMeta{}, err
+	}
+	return result.Content, result.FetchMeta, nil
+}
+
+// JWKSFetcher fetches a StatementTypeJWKSSnapshot of the OIDC issuer given as the URL
+type JWKSFetcher struct{}
+
+// Fetch fetches the issuer's JWKS with DownloadJWKSSnapshot
+func (JWKSFetcher) Fetch(ctx context.Context, issuer string) ([]byte, FetchMeta, error) {
+	result, err := DownloadJWKSSnapshot(ctx, issuer)
+	if err != nil {
+		return nil, FetchMeta{}, err
+	}
+	return result.Content, result.FetchMeta, nil
+}
+
+// FetchContent fetches sourceURL with fetcher and digests the content, returning the DownloadResult an
+// attestation payload is created from. Head-only results have no content, so no digest or size.
+func FetchContent(ctx context.Context, fetcher Fetcher, sourceURL string) (*DownloadResult, error) {
+	content, meta, err := fetcher.Fetch(ctx, sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	if meta.URL == "" {
+		meta.URL = sourceURL
+	}
+
+	result := &DownloadResult{FetchMeta: meta, Content: content}
+	if meta.StatementType != StatementTypeURLHead {
+		result.ContentDigest = ContentDigest(content)
+		result.ContentSize = int64(len(content))
+	}
+	return result, nil
+}
@@ -0,0 +1,185 @@
+package attestation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeFetcher returns fixed content and metadata, recording the URLs it was asked for
+type fakeFetcher struct {
+	content   []byte
+	meta      FetchMeta
+	err       error
+	requested []string
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, url string) ([]byte, FetchMeta, error) {
+	f.requested = append(f.requested, url)
+	return f.content, f.meta, f.err
+}
+
+func TestFetchContent(t *testing.T) {
+	content := []byte("blob content")
+	tests := []struct {
+		name    string
+		fetcher *fakeFetcher
+		want    *DownloadResult
+		wantErr string
+	}{
+		{
+			name:    "requested URL recorded",
+			fetcher: &fakeFetcher{content: content},
+			want: &DownloadResult{
+				FetchMeta:     FetchMeta{URL: "s3://bucket/key"},
+				Content:       content,
+				ContentDigest: ContentDigest(content),
+				ContentSize:   int64(len(content)),
+			},
+		},
+		{
+			name:    "fetcher URL recorded",
+			fetcher: &fakeFetcher{content: content, meta: FetchMeta{URL: "https://bucket.s3.amazonaws.com/key", ContentType: "text/plain"}},
+			want: &DownloadResult{
+				FetchMeta:     FetchMeta{URL: "https://bucket.s3.amazonaws.com/key", ContentType: "text/plain"},
+				Content:       content,
+				ContentDigest: ContentDigest(content),
+				ContentSize:   int64(len(content)),
+			},
+		},
+		{
+			name:    "empty content",
+			fetcher: &fakeFetcher{},
+			want:    &DownloadResult{FetchMeta: FetchMeta{URL: "s3://bucket/key"}, ContentDigest: ContentDigest(nil)},
+		},
+		{
+			name:    "head only",
+			fetcher: &fakeFetcher{meta: FetchMeta{StatementType: StatementTypeURLHead, Head: &HeadMetadata{StatusCode: http.StatusOK}}},
+			want:    &DownloadResult{FetchMeta: FetchMeta{URL: "s3://bucket/key", StatementType: StatementTypeURLHead, Head: &HeadMetadata{StatusCode: http.StatusOK}}},
+		},
+		{name: "fetch failure", fetcher: &fakeFetcher{err: errors.New("access denied")}, wantErr: "access denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := FetchContent(context.Background(), tt.fetcher, "s3://bucket/key")
+			if !reflect.DeepEqual(tt.fetcher.requested, []string{"s3://bucket/key"}) {
+				t.Errorf("fetcher asked for %v, want s3://bucket/key", tt.fetcher.requested)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchContent: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("FetchContent = %+v, want %+v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchContentAttestation(t *testing.T) {
+	op := newTestOP(t)
+	content := []byte("blob content")
+	download, err := FetchContent(context.Background(), &fakeFetcher{content: content, meta: FetchMeta{ContentType: "text/plain"}}, "s3://bucket/key")
+	if err != nil {
+		t.Fatalf("FetchContent: %v", err)
+	}
+	attestation := op.attest(t, download, nil)
+	result := verifyTestAttestation(t, attestation, op.verifyOptions())
+	if !result.IsVerificationSuccessful() {
+		t.Fatalf("attestation of fetched content did not verify: %q", result.Errors)
+	}
+	if attestation.Payload.Url != "s3://bucket/key" || attestation.Payload.ContentDigest != ContentDigest(content) || attestation.Payload.ContentType != "text/plain" {
+		t.Errorf("payload = %+v, want the fetched URL, digest and content type", attestation.Payload)
+	}
+}
+
+func TestHTTPFetcherMatchesDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page1":
+			w.Header().Set("Link", `</page2>; rel="next"`)
+			w.Write([]byte(`[1]`))
+		case "/page2":
+			w.Write([]byte(`[2]`))
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"b": 1, "a": 2}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		opts    DownloadOptions
+		wantErr string
+	}{
+		{name: "content", path: "/jwks", opts: DownloadOptions{AllowHTTP: true}},
+		{name: "normalized", path: "/jwks", opts: DownloadOptions{AllowHTTP: true, Normalize: "json"}},
+		{name: "paginated", path: "/page1", opts: DownloadOptions{AllowHTTP: true, FollowPagination: true}},
+		{name: "head only", path: "/jwks", opts: DownloadOptions{AllowHTTP: true, HeadOnly: true}},
+		{name: "not found", path: "/missing", opts: DownloadOptions{AllowHTTP: true}, wantErr: "404"},
+		{name: "plain http", path: "/jwks", wantErr: "http"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, wantErr := DownloadContentContext(context.Background(), server.URL+tt.path, tt.opts)
+			got, err := FetchContent(context.Background(), &HTTPFetcher{Options: tt.opts}, server.URL+tt.path)
+			if tt.wantErr != "" {
+				if err == nil || wantErr == nil || err.Error() != wantErr.Error() || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected the download error containing %q, got %v and %v", tt.wantErr, err, wantErr)
+				}
+				return
+			}
+			if err != nil || wantErr != nil {
+				t.Fatalf("FetchContent: %v, DownloadContentContext: %v", err, wantErr)
+			}
+			if !reflect.DeepEqual(got.FetchMeta, want.FetchMeta) || !reflect.DeepEqual(got.Content, want.Content) ||
+				got.ContentDigest != want.ContentDigest || got.ContentSize != want.ContentSize {
+				t.Errorf("HTTPFetcher result %+v differs from download %+v", got, want)
+			}
+		})
+	}
+
+	// The fetcher returns content, so digest-only downloads keep it
+	opts := DownloadOptions{AllowHTTP: true, DigestOnly: true}
+	got, err := FetchContent(context.Background(), &HTTPFetcher{Options: opts}, server.URL+"/jwks")
+	if err != nil {
+		t.Fatalf("FetchContent: %v", err)
+	}
+	if string(got.Content) != `{"b": 1, "a": 2}` || got.ContentDigest != ContentDigest(got.Content) {
+		t.Errorf("digest-only HTTPFetcher returned %q with digest %s", got.Content, got.ContentDigest)
+	}
+}
+
+func TestJWKSFetcherMatchesSnapshot(t *testing.T) {
+	server := newDiscoveryServer(t, `{"keys":[`+fixtureJWK1+`]}`)
+	want, err := DownloadJWKSSnapshot(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("DownloadJWKSSnapshot: %v", err)
+	}
+	got, err := FetchContent(context.Background(), JWKSFetcher{}, server.URL)
+	if err != nil {
+		t.Fatalf("FetchContent: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JWKSFetcher result %+v differs from snapshot %+v", got, want)
+	}
+
+	empty := newDiscoveryServer(t, `{"keys":[]}`)
+	if _, err := FetchContent(context.Background(), JWKSFetcher{}, empty.URL); err == nil || !strings.Contains(err.Error(), "JWKS has no keys") {
+		t.Fatalf("expected an error containing %q, got %v", "JWKS has no keys", err)
+	}
+}
@@ -0,0 +1,293 @@
+package attestation
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const githubAPIURL = "https://api.github.com"
+
+// PreviousAttestationNotFoundError is returned when there is no previous attestation to chain from,
+// which callers should not treat as fatal
+type PreviousAttestationNotFoundError struct {
+	Reason string
+}
+
+func (e *PreviousAttestationNotFoundError) Error() string {
+	return fmt.Sprintf("previous attestation not found: %s", e.Reason)
+}
+
+// GitHubClient fetches attestations stored as workflow run artifacts through the GitHub REST API
+type GitHubClient struct {
+	// BaseURL is the API root, defaults to https://api.github.com
+	BaseURL string
+	// Token authenticates API requests when set. It is only sent to the BaseURL host.
+	Token string
+	// HTTPClient sends API requests, defaults to http.DefaultClient. Requests to any other host, such as an
+	// artifact URL taken from an attestation, use OtherHTTPClient so no credentials it carries leak.
+	HTTPClient *http.Client
+	// OtherHTTPClient sends requests to hosts other than the API host, defaults to http.DefaultClient. Give it
+	// a client guarding against private addresses when the URLs come from attestations.
+	OtherHTTPClient *http.Client
+}
+
+// NewGitHubClient returns a GitHubClient for the public GitHub API
+func NewGitHubClient(token string) *GitHubClient {
+	return &GitHubClient{
+		BaseURL:    githubAPIURL,
+		Token:      token,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+type workflowRun struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type workflowRunsResponse struct {
+	WorkflowRuns []workflowRun `json:"workflow_runs"`
+}
+
+type workflowArtifact struct {
+	ID                 int64     `json:"id"`
+	Name               string    `json:"name"`
+	Expired            bool      `json:"expired"`
+	ArchiveDownloadURL string    `json:"archive_download_url"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type workflowArtifactsResponse struct {
+	Artifacts []workflowArtifact `json:"artifacts"`
+}
+
+// PreviousSelector chooses which earlier run's attestation FetchPreviousAttestationMatching returns.
+// Unset fields match every run, so the zero value selects the most recent successful run.
+type PreviousSelector struct {
+	// RunID selects the run with this ID
+	RunID int64
+	// Before selects runs created before this time
+	Before time.Time
+	// Digest selects the attestation whose Attestation.Digest is this value
+	Digest string
+}
+
+// maxPreviousRuns bounds how many recent runs are searched for a matching attestation
+const maxPreviousRuns = 100
+
+// FetchPreviousAttestation downloads the artifactName attestation from the most recent successful run
+// of workflowFile on branch in repo (owner/name), returning a *PreviousAttestationNotFoundError if there is none
+func (c *GitHubClient) FetchPreviousAttestation(repo string, workflowFile string, branch string, artifactName string) (*Attestation, *AttestationDetails, error) {
+	return c.FetchPreviousAttestationMatching(repo, workflowFile, branch, artifactName, PreviousSelector{})
+}
+
+// FetchPreviousAttestationMatching downloads the artifactName attestation from the most recent of the last
+// 100 successful runs of workflowFile on branch in repo that matches selector, returning a
+// *PreviousAttestationNotFoundError if none does
+func (c *GitHubClient) FetchPreviousAttestationMatching(repo string, workflowFile string, branch string, artifactName string, selector PreviousSelector) (*Attestation, *AttestationDetails, error) {
+	perPage := 1
+	if selector != (PreviousSelector{}) {
+		perPage = maxPreviousRuns
+	}
+	runsPath := fmt.Sprintf("/repos/%s/actions/workflows/%s/runs?%s", repo, url.PathEscape(workflowFile), url.Values{
+		"branch":   {branch},
+		"status":   {"success"},
+		"per_page": {strconv.Itoa(perPage)},
+	}.Encode())
+	var runs workflowRunsResponse
+	if err := c.getJSON(runsPath, &runs); err != nil {
+		return nil, nil, fmt.Errorf("failed to list workflow runs: %w", err)
+	}
+
+	// Runs are listed newest first
+	for _, run := range runs.WorkflowRuns {
+		if selector.RunID != 0 && run.ID != selector.RunID {
+			continue
+		}
+		if !selector.Before.IsZero() && !run.CreatedAt.Before(selector.Before) {
+			continue
+		}
+
+		attestation, details, err := c.fetchRunAttestation(repo, run, artifactName)
+		var notFound *PreviousAttestationNotFoundError
+		if errors.As(err, &notFound) && selector != (PreviousSelector{}) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if selector.Digest != "" && details.Digest != selector.Digest {
+			continue
+		}
+		return attestation, details, nil
+	}
+
+	if selector == (PreviousSelector{}) {
+		return nil, nil, &PreviousAttestationNotFoundError{Reason: fmt.Sprintf("no successful runs of %s on %s", workflowFile, branch)}
+	}
+	return nil, nil, &PreviousAttestationNotFoundError{Reason: fmt.Sprintf("no successful run of %s on %s matches %+v", workflowFile, branch, selector)}
+}
+
+// fetchRunAttestation downloads the artifactName attestation from a workflow run
+func (c *GitHubClient) fetchRunAttestation(repo string, run workflowRun, artifactName string) (*Attestation, *AttestationDetails, error) {
+	artifactsPath := fmt.Sprintf("/repos/%s/actions/runs/%d/artifacts?%s", repo, run.ID, url.Values{"name": {artifactName}}.Encode())
+	var artifacts workflowArtifactsResponse
+	if err := c.getJSON(artifactsPath, &artifacts); err != nil {
+		return nil, nil, fmt.Errorf("failed to list workflow run artifacts: %w", err)
+	}
+	var artifact *workflowArtifact
+	for i := range artifacts.Artifacts {
+		if artifacts.Artifacts[i].Name == artifactName && !artifacts.Artifacts[i].Expired {
+			artifact = &artifacts.Artifacts[i]
+			break
+		}
+	}
+	if artifact == nil {
+		return nil, nil, &PreviousAttestationNotFoundError{Reason: fmt.Sprintf("no %s artifact in run %d", artifactName, run.ID)}
+	}
+
+	archive, err := c.get(artifact.ArchiveDownloadURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download artifact: %w", err)
+	}
+	data, err := readZipEntry(archive, artifactName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to extract artifact: %w", err)
+	}
+
+	var attestation Attestation
+	if err := json.Unmarshal(data, &attestation); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse attestation: %w", err)
+	}
+
+	digest, err := attestation.Digest()
+	if err != nil {
+		return nil, nil, err
+	}
+	details := &AttestationDetails{
+		Digest:      digest,
+		ArtifactURL: artifact.ArchiveDownloadURL,
+	}
+	return &attestation, details, nil
+}
+
+// baseURL returns the API root
+func (c *GitHubClient) baseURL() string {
+	if c.BaseURL == "" {
+		return githubAPIURL
+	}
+	return c.BaseURL
+}
+
+// isAPIHost reports whether requestURL has the scheme and host of the API root, so it may be sent the token
+func (c *GitHubClient) isAPIHost(requestURL string) bool {
+	base, err := url.Parse(c.baseURL())
+	if err != nil {
+		return false
+	}
+	target, err := url.Parse(requestURL)
+	if err != nil {
+		return false
+	}
+	return target.Scheme == base.Scheme && strings.EqualFold(target.Host, base.Host)
+}
+
+func (c *GitHubClient) getJSON(path string, v interface{}) error {
+	data, err := c.get(strings.TrimSuffix(c.baseURL(), "/") + path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+func (c *GitHubClient) get(requestURL string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	// Only the API host is trusted with credentials; URLs may come from unverified attestations
+	httpClient := c.OtherHTTPClient
+	if c.isAPIHost(requestURL) {
+		if c.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+		httpClient = c.HTTPClient
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newBadStatusError(resp, defaultMaxBodySnippet)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return data, nil
+}
+
+// FetchArtifactAttestation downloads an attestation from an AttestationDetails.ArtifactURL. The artifact may be
+// a zip archive holding a single JSON file, as served by the GitHub artifacts API, or the attestation itself.
+func (c *GitHubClient) FetchArtifactAttestation(artifactURL string) (*Attestation, error) {
+	data, err := c.get(artifactURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artifact: %w", err)
+	}
+
+	if bytes.HasPrefix(data, []byte("PK")) {
+		data, err = readZipEntry(data, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract artifact: %w", err)
+		}
+	}
+
+	var attestation Attestation
+	if err := json.Unmarshal(data, &attestation); err != nil {
+		return nil, fmt.Errorf("failed to parse attestation: %w", err)
+	}
+	return &attestation, nil
+}
+
+// readZipEntry returns the contents of the named file in a zip archive, or of its only file when name is empty
+func readZipEntry(archive []byte, name string) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	if name == "" && len(reader.File) != 1 {
+		return nil, fmt.Errorf("expected one file in archive, found %d", len(reader.File))
+	}
+	for _, file := range reader.File {
+		if name != "" && file.Name != name {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("%s not found in archive", name)
+}
@@ -0,0 +1,354 @@
+package attestation
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockGitHub is a GitHub API server serving workflow runs, each with one attestation artifact
+type mockGitHub struct {
+	server *httptest.Server
+	runs   []workflowRun
+	// attestations maps run IDs to the attestation in their artifact, absent for runs without one
+	attestations map[int64]*Attestation
+	expired      map[int64]bool
+
+	mu             sync.Mutex
+	authorizations []string
+	runsPerPage    []string
+}
+
+func newMockGitHub(t *testing.T) *mockGitHub {
+	t.Helper()
+	gh := &mockGitHub{attestations: make(map[int64]*Attestation), expired: make(map[int64]bool)}
+	gh.server = httptest.NewServer(http.HandlerFunc(gh.serve))
+	t.Cleanup(gh.server.Close)
+	return gh
+}
+
+// addRun adds a successful run, newest first, whose artifact holds attestation when it is not nil
+func (gh *mockGitHub) addRun(id int64, created time.Time, attestation *Attestation) {
+	gh.runs = append([]workflowRun{{ID: id, CreatedAt: created}}, gh.runs...)
+	if attestation != nil {
+		gh.attestations[id] = attestation
+	}
+}
+
+func (gh *mockGitHub) client(token string) *GitHubClient {
+	return &GitHubClient{BaseURL: gh.server.URL, Token: token, HTTPClient: gh.server.Client()}
+}
+
+func (gh *mockGitHub) serve(w http.ResponseWriter, r *http.Request) {
+	gh.mu.Lock()
+	gh.authorizations = append(gh.authorizations, r.Header.Get("Authorization"))
+	gh.mu.Unlock()
+
+	var runID int64
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/runs") && strings.Contains(r.URL.Path, "/actions/workflows/"):
+		if r.URL.Query().Get("status") != "success" {
+			http.Error(w, "expected status=success", http.StatusBadRequest)
+			return
+		}
+		gh.mu.Lock()
+		gh.runsPerPage = append(gh.runsPerPage, r.URL.Query().Get("per_page"))
+		gh.mu.Unlock()
+		json.NewEncoder(w).Encode(workflowRunsResponse{WorkflowRuns: gh.runs})
+	case sscanPath(r.URL.Path, "/repos/owner/repo/actions/runs/%d/artifacts", &runID):
+		var artifacts workflowArtifactsResponse
+		if _, ok := gh.attestations[runID]; ok {
+			artifacts.Artifacts = append(artifacts.Artifacts, workflowArtifact{
+				ID:                 runID,
+				Name:               r.URL.Query().Get("name"),
+				Expired:            gh.expired[runID],
+				ArchiveDownloadURL: fmt.Sprintf("%s/download/%d", gh.server.URL, runID),
+			})
+		}
+		json.NewEncoder(w).Encode(artifacts)
+	case sscanPath(r.URL.Path, "/download/%d", &runID):
+		w.Write(zipAttestation(gh.attestations[runID], "attestation.json"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// sscanPath reports whether path matches format, scanning its one integer into id
+func sscanPath(path string, format string, id *int64) bool {
+	n, err := fmt.Sscanf(path, format, id)
+	return err == nil && n == 1 && fmt.Sprintf(format, *id) == path
+}
+
+// zipAttestation returns a zip archive holding attestation as name, like a GitHub artifact download
+func zipAttestation(attestation *Attestation, name string) []byte {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	file, _ := archive.Create(name)
+	json.NewEncoder(file).Encode(attestation)
+	archive.Close()
+	return buf.Bytes()
+}
+
+func testArtifactAttestation(url string) *Attestation {
+	return &Attestation{Payload: AttestationPayload{Url: url, ContentDigest: ContentDigest([]byte(url))}}
+}
+
+func TestFetchPreviousAttestationMatching(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		setup    func(gh *mockGitHub)
+		selector PreviousSelector
+		wantURL  string
+		notFound bool
+	}{
+		{
+			name: "latest run",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now.Add(-2*time.Hour), testArtifactAttestation("https://example.com/1"))
+				gh.addRun(2, now.Add(-time.Hour), testArtifactAttestation("https://example.com/2"))
+			},
+			wantURL: "https://example.com/2",
+		},
+		{
+			name:     "no runs is not found",
+			setup:    func(gh *mockGitHub) {},
+			notFound: true,
+		},
+		{
+			name: "run without artifact is not found",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now, nil)
+			},
+			notFound: true,
+		},
+		{
+			name: "expired artifact is not found",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now, testArtifactAttestation("https://example.com/1"))
+				gh.expired[1] = true
+			},
+			notFound: true,
+		},
+		{
+			name: "select by run ID",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now.Add(-2*time.Hour), testArtifactAttestation("https://example.com/1"))
+				gh.addRun(2, now.Add(-time.Hour), testArtifactAttestation("https://example.com/2"))
+			},
+			selector: PreviousSelector{RunID: 1},
+			wantURL:  "https://example.com/1",
+		},
+		{
+			name: "select before skips newer runs and runs without artifacts",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now.Add(-3*time.Hour), testArtifactAttestation("https://example.com/1"))
+				gh.addRun(2, now.Add(-2*time.Hour), nil)
+				gh.addRun(3, now, testArtifactAttestation("https://example.com/3"))
+			},
+			selector: PreviousSelector{Before: now.Add(-time.Hour)},
+			wantURL:  "https://example.com/1",
+		},
+		{
+			name: "select by digest",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now.Add(-time.Hour), testArtifactAttestation("https://example.com/1"))
+				gh.addRun(2, now, testArtifactAttestation("https://example.com/2"))
+			},
+			selector: PreviousSelector{Digest: mustDigest(testArtifactAttestation("https://example.com/1"))},
+			wantURL:  "https://example.com/1",
+		},
+		{
+			name: "no run matches selector",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now, testArtifactAttestation("https://example.com/1"))
+			},
+			selector: PreviousSelector{RunID: 42},
+			notFound: true,
+		},
+		{
+			name: "select before excludes a run created at that time",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now.Add(-time.Hour), testArtifactAttestation("https://example.com/1"))
+				gh.addRun(2, now, testArtifactAttestation("https://example.com/2"))
+			},
+			selector: PreviousSelector{Before: now},
+			wantURL:  "https://example.com/1",
+		},
+		{
+			name: "select before skips expired artifacts",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now.Add(-2*time.Hour), testArtifactAttestation("https://example.com/1"))
+				gh.addRun(2, now.Add(-time.Hour), testArtifactAttestation("https://example.com/2"))
+				gh.expired[2] = true
+			},
+			selector: PreviousSelector{Before: now},
+			wantURL:  "https://example.com/1",
+		},
+		{
+			name: "select by run ID and before",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now.Add(-2*time.Hour), testArtifactAttestation("https://example.com/1"))
+				gh.addRun(2, now.Add(-time.Hour), testArtifactAttestation("https://example.com/2"))
+			},
+			selector: PreviousSelector{RunID: 2, Before: now.Add(-90 * time.Minute)},
+			notFound: true,
+		},
+		{
+			name: "select by run ID and digest",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now.Add(-time.Hour), testArtifactAttestation("https://example.com/1"))
+				gh.addRun(2, now, testArtifactAttestation("https://example.com/2"))
+			},
+			selector: PreviousSelector{RunID: 2, Digest: mustDigest(testArtifactAttestation("https://example.com/2"))},
+			wantURL:  "https://example.com/2",
+		},
+		{
+			name: "no attestation has the digest",
+			setup: func(gh *mockGitHub) {
+				gh.addRun(1, now.Add(-time.Hour), testArtifactAttestation("https://example.com/1"))
+				gh.addRun(2, now, testArtifactAttestation("https://example.com/2"))
+			},
+			selector: PreviousSelector{Digest: mustDigest(testArtifactAttestation("https://example.com/3"))},
+			notFound: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := newMockGitHub(t)
+			tt.setup(gh)
+
+			attestation, details, err := gh.client("token").FetchPreviousAttestationMatching("owner/repo", "oracle.yml", "main", "attestation.json", tt.selector)
+			var notFound *PreviousAttestationNotFoundError
+			if tt.notFound {
+				if !errors.As(err, &notFound) {
+					t.Fatalf("expected PreviousAttestationNotFoundError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchPreviousAttestationMatching: %v", err)
+			}
+			if attestation.Payload.Url != tt.wantURL {
+				t.Errorf("fetched attestation for %s, want %s", attestation.Payload.Url, tt.wantURL)
+			}
+			if details.Digest != mustDigest(attestation) {
+				t.Errorf("details digest %s is not the attestation digest", details.Digest)
+			}
+			if !strings.HasPrefix(details.ArtifactURL, gh.server.URL+"/download/") {
+				t.Errorf("unexpected artifact URL %s", details.ArtifactURL)
+			}
+		})
+	}
+}
+
+func TestFetchPreviousAttestationRunsPerPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector PreviousSelector
+		want     string
+	}{
+		{name: "latest run", want: "1"},
+		{name: "run ID", selector: PreviousSelector{RunID: 1}, want: "100"},
+		{name: "before", selector: PreviousSelector{Before: time.Now()}, want: "100"},
+		{name: "digest", selector: PreviousSelector{Digest: "abc"}, want: "100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := newMockGitHub(t)
+			gh.client("token").FetchPreviousAttestationMatching("owner/repo", "oracle.yml", "main", "attestation.json", tt.selector)
+			if len(gh.runsPerPage) != 1 || gh.runsPerPage[0] != tt.want {
+				t.Errorf("listed runs with per_page %v, want %s", gh.runsPerPage, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubClientSendsTokenOnlyToAPIHost(t *testing.T) {
+	gh := newMockGitHub(t)
+	gh.addRun(1, time.Now(), testArtifactAttestation("https://example.com/1"))
+
+	var otherAuthorization []string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		otherAuthorization = append(otherAuthorization, r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(testArtifactAttestation("https://example.com/other"))
+	}))
+	defer other.Close()
+
+	client := gh.client("secret")
+	if _, _, err := client.FetchPreviousAttestation("owner/repo", "oracle.yml", "main", "attestation.json"); err != nil {
+		t.Fatalf("FetchPreviousAttestation: %v", err)
+	}
+	for _, authorization := range gh.authorizations {
+		if authorization != "Bearer secret" {
+			t.Errorf("API request sent Authorization %q, want the token", authorization)
+		}
+	}
+
+	// An artifact URL naming another host, e.g. from an unverified attestation, must not receive the token
+	if _, err := client.FetchArtifactAttestation(other.URL + "/artifact"); err != nil {
+		t.Fatalf("FetchArtifactAttestation: %v", err)
+	}
+	if len(otherAuthorization) != 1 || otherAuthorization[0] != "" {
+		t.Errorf("non-API host received Authorization %q", otherAuthorization)
+	}
+}
+
+func TestGitHubClientIsAPIHost(t *testing.T) {
+	tests := []struct {
+		base string
+		url  string
+		want bool
+	}{
+		{"", "https://api.github.com/repos/o/r/actions/artifacts/1/zip", true},
+		{"", "https://API.GITHUB.COM/repos", true},
+		{"", "http://api.github.com/repos", false},
+		{"", "https://api.github.com.evil.example/repos", false},
+		{"", "https://evil.example/https://api.github.com/", false},
+		{"", "https://api.github.com:8443/repos", false},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com/api/v3/repos", true},
+		{"https://ghe.example.com/api/v3", "https://api.github.com/repos", false},
+		{"", "://bad", false},
+	}
+	for _, tt := range tests {
+		client := &GitHubClient{BaseURL: tt.base}
+		if got := client.isAPIHost(tt.url); got != tt.want {
+			t.Errorf("isAPIHost(%q) with base %q = %v, want %v", tt.url, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubClientBadStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := &GitHubClient{BaseURL: server.URL}
+	_, _, err := client.FetchPreviousAttestation("owner/repo", "oracle.yml", "main", "attestation.json")
+	var badStatus *BadStatusError
+	if !errors.As(err, &badStatus) || badStatus.Code != http.StatusForbidden {
+		t.Fatalf("expected a 403 BadStatusError, got %v", err)
+	}
+	var notFound *PreviousAttestationNotFoundError
+	if errors.As(err, &notFound) {
+		t.Errorf("an API failure must not be reported as not found")
+	}
+}
+
+func mustDigest(attestation *Attestation) string {
+	digest, err := attestation.Digest()
+	if err != nil {
+		panic(err)
+	}
+	return digest
+}
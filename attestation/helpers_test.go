@@ -0,0 +1,203 @@
+package attestation
+
+import (
+	"context"
+	"crypto"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openpubkey/openpubkey/client"
+	"github.com/openpubkey/openpubkey/pktoken"
+	"github.com/openpubkey/openpubkey/providers"
+	"github.com/openpubkey/openpubkey/providers/mocks"
+	"github.com/openpubkey/openpubkey/verifier"
+)
+
+const (
+	testWorkflowRef = "owner/repo/.github/workflows/oracle.yml@refs/heads/main"
+	testWorkflowSHA = "0123456789abcdef0123456789abcdef01234567"
+)
+
+// testOP is a mock OpenID provider issuing PK tokens with GitHub Actions claims, so attestations can be
+// signed and verified without the GitHub OIDC environment
+type testOP struct {
+	provider *providers.MockProvider
+	backend  *mocks.MockProviderBackend
+	template *mocks.IDTokenTemplate
+}
+
+func newTestOP(t *testing.T) *testOP {
+	t.Helper()
+	return newTestOPWith(t, providers.DefaultMockProviderOpts())
+}
+
+// newGitHubLikeOP returns a test OP issuing GQ-signed PK tokens committing to the audience, as GitHub Actions
+// does, so they verify with the GitHub provider checks given the OP's keys, e.g. through VerifyOptions.JWKSPath
+func newGitHubLikeOP(t *testing.T) *testOP {
+	t.Helper()
+	return newGitHubLikeOPIssuedBy(t, "")
+}
+
+// newGitHubLikeOPIssuedBy returns a GitHub-like test OP with the given issuer, the mock default when empty
+func newGitHubLikeOPIssuedBy(t *testing.T, issuer string) *testOP {
+	t.Helper()
+	opts := providers.DefaultMockProviderOpts()
+	opts.Issuer = issuer
+	opts.GQSign = true
+	opts.CommitType = providers.CommitTypesEnum.AUD_CLAIM
+	opts.VerifierOpts = providers.ProviderVerifierOpts{
+		CommitType:        providers.CommitTypesEnum.AUD_CLAIM,
+		GQOnly:            true,
+		SkipClientIDCheck: true,
+	}
+	return newTestOPWith(t, opts)
+}
+
+func newTestOPWith(t *testing.T, opts providers.MockProviderOpts) *testOP {
+	t.Helper()
+	provider, backend, template, err := providers.NewMockProvider(opts)
+	if err != nil {
+		t.Fatalf("failed to create mock provider: %v", err)
+	}
+	return &testOP{provider: provider, backend: backend, template: template}
+}
+
+// jwksFile writes the OP's JWKS to a file, returning its path
+func (op *testOP) jwksFile(t *testing.T) string {
+	t.Helper()
+	jwks, err := op.backend.GetPublicKeyFinder().JwksFunc(context.Background(), op.issuer())
+	if err != nil {
+		t.Fatalf("failed to get JWKS: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0644); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+// testClaims are the GitHub Actions claims the test OP issues by default
+func testClaims() map[string]any {
+	return map[string]any{
+		"job_workflow_ref": testWorkflowRef,
+		"job_workflow_sha": testWorkflowSHA,
+		"workflow_ref":     testWorkflowRef,
+		"run_id":           "1",
+		"repository":       "owner/repo",
+		"repository_owner": "owner",
+		"ref":              "refs/heads/main",
+		"sha":              testWorkflowSHA,
+		"event_name":       "push",
+	}
+}
+
+// pkToken issues a PK token carrying testClaims overridden by claims, with the signer it binds
+func (op *testOP) pkToken(t *testing.T, claims map[string]any) (*pktoken.PKToken, crypto.Signer) {
+	t.Helper()
+	extra := testClaims()
+	maps.Copy(extra, claims)
+	op.template.ExtraClaims = extra
+
+	opkClient, err := client.New(op.provider)
+	if err != nil {
+		t.Fatalf("failed to create OpenPubkey client: %v", err)
+	}
+	pkToken, err := opkClient.Auth(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get PK token: %v", err)
+	}
+	return pkToken, opkClient.GetSigner()
+}
+
+// issuer returns the test OP's issuer
+func (op *testOP) issuer() string {
+	return op.provider.Issuer()
+}
+
+// verifier returns a PK token verifier trusting the test OP's keys
+func (op *testOP) verifier() verifier.ProviderVerifier {
+	return providers.NewProviderVerifier(op.issuer(), providers.ProviderVerifierOpts{
+		CommitType:        providers.CommitTypesEnum.NONCE_CLAIM,
+		ClientID:          op.provider.ClientID(),
+		DiscoverPublicKey: op.backend.GetPublicKeyFinder(),
+	})
+}
+
+// verifyOptions returns options that verify attestations signed by the test OP with testClaims
+func (op *testOP) verifyOptions() VerifyOptions {
+	opts := NewVerifyOptions()
+	opts.Issuer = op.issuer()
+	opts.ProviderVerifier = op.verifier()
+	opts.ExpectedWorkflowRef = testWorkflowRef
+	return opts
+}
+
+// attest signs an attestation of download with a fresh PK token carrying testClaims
+func (op *testOP) attest(t *testing.T, download *DownloadResult, previous []byte, opts ...PayloadOption) *Attestation {
+	t.Helper()
+	return op.attestWithClaims(t, nil, download, previous, opts...)
+}
+
+// attestWithClaims signs an attestation of download with a PK token carrying testClaims overridden by claims
+func (op *testOP) attestWithClaims(t *testing.T, claims map[string]any, download *DownloadResult, previous []byte, opts ...PayloadOption) *Attestation {
+	t.Helper()
+	pkToken, signer := op.pkToken(t, claims)
+	attestation, err := BuildAttestation(pkToken, signer, GithubExtractor{}, download, previous, opts...)
+	if err != nil {
+		t.Fatalf("BuildAttestation: %v", err)
+	}
+	return attestation
+}
+
+// attestEdited signs an attestation of download whose payload is edited before signing, as a generator
+// deviating from the oracle would
+func (op *testOP) attestEdited(t *testing.T, download *DownloadResult, edit func(payload *AttestationPayload)) *Attestation {
+	t.Helper()
+	pkToken, signer := op.pkToken(t, nil)
+	attestation, err := BuildAttestation(pkToken, signer, GithubExtractor{}, download, nil)
+	if err != nil {
+		t.Fatalf("BuildAttestation: %v", err)
+	}
+	edit(&attestation.Payload)
+	digest, err := attestation.Payload.Hash()
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	attestation.Signature, err = pkToken.NewSignedMessage(digest, signer)
+	if err != nil {
+		t.Fatalf("failed to sign message: %v", err)
+	}
+	return attestation
+}
+
+// testDownload returns the download result of fetching content from url
+func testDownload(url string, content []byte) *DownloadResult {
+	return &DownloadResult{
+		FetchMeta:     FetchMeta{URL: url},
+		Content:       content,
+		ContentDigest: ContentDigest(content),
+		ContentSize:   int64(len(content)),
+	}
+}
+
+// verifyTestAttestation verifies attestation, failing the test on an error verification cannot report
+func verifyTestAttestation(t *testing.T, attestation *Attestation, opts VerifyOptions) *VerificationResult {
+	t.Helper()
+	result, err := VerifyAttestation(attestation, opts)
+	if err != nil {
+		t.Fatalf("VerifyAttestation: %v", err)
+	}
+	return result
+}
+
+// hasError reports whether any verification error starts with prefix
+func hasError(result *VerificationResult, prefix string) bool {
+	for _, err := range result.Errors {
+		if len(err) >= len(prefix) && err[:len(prefix)] == prefix {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openpubkey/openpubkey/discover"
+	"github.com/openpubkey/openpubkey/pktoken"
+)
+
+// AcceptedIssuer is an OIDC issuer VerifyOptions.AcceptedIssuers accepts PK tokens from, with the provider
+// whose PK token checks apply and where its keys come from. Several entries may share an issuer, e.g. one
+// per JWKS source during a key rotation.
+type AcceptedIssuer struct {
+	Issuer string `json:"issuer"`
+	// Provider is the provider whose PK token checks and claims apply, defaults to VerifyOptions.Provider
+	Provider string `json:"provider,omitempty"`
+	// JWKSPath verifies against this JWKS file instead of the issuer's published keys
+	JWKSPath string `json:"jwks_path,omitempty"`
+	// JWKSURL fetches the issuer's keys from this https URL instead of through OIDC discovery
+	JWKSURL string `json:"jwks_url,omitempty"`
+}
+
+// LoadAcceptedIssuers reads a JSON array of AcceptedIssuer, rejecting unknown fields and entries without
+// an issuer or with more than one JWKS source
+func LoadAcceptedIssuers(path string) ([]AcceptedIssuer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accepted issuers file: %w", err)
+	}
+
+	var accepted []AcceptedIssuer
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&accepted); err != nil {
+		return nil, fmt.Errorf("failed to parse accepted issuers: %w", err)
+	}
+	if len(accepted) == 0 {
+		return nil, fmt.Errorf("accepted issuers file %s lists no issuers", path)
+	}
+	for i, issuer := range accepted {
+		if issuer.Issuer == "" {
+			return nil, fmt.Errorf("accepted issuer %d has no issuer", i+1)
+		}
+		if issuer.JWKSPath != "" && issuer.JWKSURL != "" {
+			return nil, fmt.Errorf("accepted issuer %s has both a JWKS file and a JWKS URL", issuer.Issuer)
+		}
+	}
+	return accepted, nil
+}
+
+// keyFinder returns where the accepted issuer's keys come from, nil for the issuer's published JWKS
+func (a AcceptedIssuer) keyFinder() *discover.PublicKeyFinder {
+	switch {
+	case a.JWKSPath != "":
+		return jwksFileFinder(a.JWKSPath)
+	case a.JWKSURL != "":
+		return jwksURLFinder(a.JWKSURL)
+	default:
+		return nil
+	}
+}
+
+// jwksURLFinder returns a key finder that fetches the JWKS from jwksURL whatever the issuer
+func jwksURLFinder(jwksURL string) *discover.PublicKeyFinder {
+	return &discover.PublicKeyFinder{
+		JwksFunc: func(ctx context.Context, issuer string) ([]byte, error) {
+			result, err := DownloadContentContext(ctx, jwksURL, DownloadOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch JWKS for %s: %w", issuer, err)
+			}
+			return result.Content, nil
+		},
+	}
+}
+
+// verifyAcceptedIssuers verifies pkToken under each accepted issuer whose issuer is the token's iss claim,
+// in order, returning the first it verifies under. The errors of every attempt are returned when none does.
+func (o VerifyOptions) verifyAcceptedIssuers(ctx context.Context, pkToken *pktoken.PKToken, issuer string) (*AcceptedIssuer, error) {
+	var failures []string
+	for i := range o.AcceptedIssuers {
+		accepted := &o.AcceptedIssuers[i]
+		if accepted.Issuer != issuer {
+			continue
+		}
+		provider := accepted.Provider
+		if provider == "" {
+			provider = o.provider()
+		}
+		providerVerifier, err := newProviderVerifier(provider, accepted.Issuer, accepted.keyFinder())
+		if err == nil {
+			err = verifyPKToken(ctx, providerVerifier, pkToken)
+		}
+		if err == nil {
+			return accepted, nil
+		}
+		failures = append(failures, fmt.Sprintf("accepted issuer %d: %v", i+1, err))
+	}
+	if len(failures) == 0 {
+		return nil, fmt.Errorf("no accepted issuer is %s", issuer)
+	}
+	return nil, fmt.Errorf("%s", strings.Join(failures, "; "))
+}
+
+// acceptedIssuerNames returns the distinct accepted issuers, in order
+func (o VerifyOptions) acceptedIssuerNames() string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, accepted := range o.AcceptedIssuers {
+		if !seen[accepted.Issuer] {
+			seen[accepted.Issuer] = true
+			names = append(names, accepted.Issuer)
+		}
+	}
+	return strings.Join(names, ", ")
+}
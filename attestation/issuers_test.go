@@ -0,0 +1,130 @@
+package attestation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadAcceptedIssuers(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []AcceptedIssuer
+		wantErr string
+	}{
+		{
+			name: "issuers with key sources",
+			data: `[
+				{"issuer": "https://token.actions.githubusercontent.com", "provider": "github", "jwks_path": "github.json"},
+				{"issuer": "https://gitlab.com", "jwks_url": "https://gitlab.com/oauth/discovery/keys"},
+				{"issuer": "https://accounts.google.com"}
+			]`,
+			want: []AcceptedIssuer{
+				{Issuer: "https://token.actions.githubusercontent.com", Provider: "github", JWKSPath: "github.json"},
+				{Issuer: "https://gitlab.com", JWKSURL: "https://gitlab.com/oauth/discovery/keys"},
+				{Issuer: "https://accounts.google.com"},
+			},
+		},
+		{name: "empty list", data: `[]`, wantErr: "lists no issuers"},
+		{name: "missing issuer", data: `[{"provider": "github"}]`, wantErr: "accepted issuer 1 has no issuer"},
+		{
+			name:    "two key sources",
+			data:    `[{"issuer": "https://gitlab.com", "jwks_path": "keys.json", "jwks_url": "https://gitlab.com/keys"}]`,
+			wantErr: "has both a JWKS file and a JWKS URL",
+		},
+		{name: "unknown field", data: `[{"issuer": "https://gitlab.com", "jwks": "keys.json"}]`, wantErr: "failed to parse accepted issuers"},
+		{name: "not a list", data: `{"issuer": "https://gitlab.com"}`, wantErr: "failed to parse accepted issuers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "issuers.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatalf("failed to write accepted issuers: %v", err)
+			}
+			got, err := LoadAcceptedIssuers(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadAcceptedIssuers: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("loaded %d issuers, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("issuer %d = %+v, want %+v", i+1, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := LoadAcceptedIssuers(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing accepted issuers file")
+	}
+}
+
+func TestVerifyAcceptedIssuers(t *testing.T) {
+	current := newGitHubLikeOPIssuedBy(t, "https://token.actions.example.com")
+	next := newGitHubLikeOPIssuedBy(t, "https://oidc.next.example.com")
+	// rotated issues tokens under the current issuer with new keys, as after a key rotation
+	rotated := newGitHubLikeOPIssuedBy(t, current.issuer())
+	untrusted := newGitHubLikeOPIssuedBy(t, "https://oidc.untrusted.example.com")
+	currentKeys, nextKeys, rotatedKeys := current.jwksFile(t), next.jwksFile(t), rotated.jwksFile(t)
+
+	accepted := []AcceptedIssuer{
+		{Issuer: current.issuer(), JWKSPath: currentKeys},
+		{Issuer: current.issuer(), JWKSPath: rotatedKeys},
+		{Issuer: next.issuer(), Provider: ProviderGithub, JWKSPath: nextKeys},
+	}
+
+	tests := []struct {
+		name        string
+		op          *testOP
+		accepted    []AcceptedIssuer
+		wantMatched int
+		wantError   string
+	}{
+		{name: "first entry for the issuer", op: current, accepted: accepted, wantMatched: 1},
+		{name: "second key source for the same issuer", op: rotated, accepted: accepted, wantMatched: 2},
+		{name: "another accepted issuer", op: next, accepted: accepted, wantMatched: 3},
+		{
+			name:      "issuer that is not accepted",
+			op:        untrusted,
+			accepted:  accepted,
+			wantError: "Issuer verification failed",
+		},
+		{
+			name:      "no key source verifies the token",
+			op:        rotated,
+			accepted:  accepted[:1],
+			wantError: "PK Token verification failed: accepted issuer 1:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := tt.op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+			opts := NewVerifyOptions()
+			opts.AcceptedIssuers = tt.accepted
+			opts.ExpectedWorkflowRef = testWorkflowRef
+			result := verifyTestAttestation(t, attestation, opts)
+			if tt.wantError != "" {
+				if result.PKTokenVerified || result.MatchedAcceptedIssuer != nil || !hasError(result, tt.wantError) {
+					t.Errorf("expected an unverified PK token and error %q, got %q", tt.wantError, result.Errors)
+				}
+				return
+			}
+			if !result.IsVerificationSuccessful() {
+				t.Fatalf("attestation from an accepted issuer did not verify: %q", result.Errors)
+			}
+			if want := &tt.accepted[tt.wantMatched-1]; result.MatchedAcceptedIssuer == nil || *result.MatchedAcceptedIssuer != *want {
+				t.Errorf("MatchedAcceptedIssuer = %+v, want %+v", result.MatchedAcceptedIssuer, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/openpubkey/openpubkey/discover"
+)
+
+// jwksKey holds the JWK members a snapshot key must have
+type jwksKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+}
+
+// GetIssuerJWKS fetches an OIDC issuer's JWKS through its discovery document
+func GetIssuerJWKS(ctx context.Context, issuer string) ([]byte, error) {
+	return getIssuerJWKS(ctx, issuer, nil)
+}
+
+// getIssuerJWKS fetches an OIDC issuer's JWKS through its discovery document with client, or
+// http.DefaultClient when it is nil
+func getIssuerJWKS(ctx context.Context, issuer string, client *http.Client) ([]byte, error) {
+	jwksContent, err := discover.GetJwksByIssuer(ctx, issuer, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get JWKS for %s: %w", issuer, err)
+	}
+	return jwksContent, nil
+}
+
+// DownloadJWKSSnapshot fetches an issuer's JWKS for a StatementTypeJWKSSnapshot attestation.
+// The issuer is recorded as the result's URL.
+func DownloadJWKSSnapshot(ctx context.Context, issuer string) (*DownloadResult, error) {
+	jwksContent, err := GetIssuerJWKS(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+	return newJWKSSnapshot(issuer, jwksContent)
+}
+
+// DownloadJWKSSnapshotWithOptions is DownloadJWKSSnapshot with the discovery document and JWKS fetched through
+// opts' HTTP client, so its proxy, private address and TLS settings apply. The issuer, the JWKS URI and any
+// redirect must be https whatever opts allows, as the issuer may come from an unverified attestation.
+func DownloadJWKSSnapshotWithOptions(ctx context.Context, issuer string, opts DownloadOptions) (*DownloadResult, error) {
+	if err := validateURL(issuer, DownloadOptions{}); err != nil {
+		return nil, err
+	}
+	client, err := opts.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	client.Transport = httpsOnlyTransport{next: client.Transport}
+
+	jwksContent, err := getIssuerJWKS(ctx, issuer, client)
+	if err != nil {
+		return nil, err
+	}
+	return newJWKSSnapshot(issuer, jwksContent)
+}
+
+// httpsOnlyTransport refuses requests that are not https, which covers redirects and the JWKS URI named by a
+// discovery document as well as the issuer itself
+type httpsOnlyTransport struct {
+	next http.RoundTripper
+}
+
+func (t httpsOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("%w %q for %s, only https is allowed", ErrUnsupportedScheme, req.URL.Scheme, req.URL.Redacted())
+	}
+	return t.next.RoundTrip(req)
+}
+
+// newJWKSSnapshot validates an issuer's JWKS and returns it as a StatementTypeJWKSSnapshot download
+func newJWKSSnapshot(issuer string, jwksContent []byte) (*DownloadResult, error) {
+	if err := ValidateJWKS(jwksContent); err != nil {
+		return nil, err
+	}
+
+	return &DownloadResult{
+		FetchMeta: FetchMeta{
+			URL:           issuer,
+			ContentType:   "application/json",
+			StatementType: StatementTypeJWKSSnapshot,
+		},
+		Content:       jwksContent,
+		ContentDigest: ContentDigest(jwksContent),
+		ContentSize:   int64(len(jwksContent)),
+	}, nil
+}
+
+// ValidateJWKS checks that content is a JSON Web Key Set with at least one key, each with a kty and kid
+func ValidateJWKS(content []byte) error {
+	var keySet jwks
+	if err := json.Unmarshal(content, &keySet); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+	if len(keySet.Keys) == 0 {
+		return fmt.Errorf("JWKS has no keys")
+	}
+	for i, key := range keySet.Keys {
+		var parsed jwksKey
+		if err := json.Unmarshal(key, &parsed); err != nil {
+			return fmt.Errorf("failed to parse JWK %d: %w", i, err)
+		}
+		if parsed.KeyType == "" {
+			return fmt.Errorf("JWK %d has no kty", i)
+		}
+		if parsed.KeyID == "" {
+			return fmt.Errorf("JWK %d has no kid", i)
+		}
+	}
+	return nil
+}
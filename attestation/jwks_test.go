@@ -0,0 +1,179 @@
+package attestation
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newDiscoveryServer serves an OIDC discovery document for its own URL pointing at jwks
+func newDiscoveryServer(t *testing.T, jwks string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": server.URL, "jwks_uri": server.URL + "/jwks"})
+		case "/jwks":
+			w.Write([]byte(jwks))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadJWKSSnapshot(t *testing.T) {
+	tests := []struct {
+		name    string
+		jwks    string
+		wantErr string
+	}{
+		{name: "key set", jwks: `{"keys":[` + fixtureJWK1 + `]}`},
+		{name: "no keys", jwks: `{"keys":[]}`, wantErr: "JWKS has no keys"},
+		{name: "key without a kid", jwks: `{"keys":[{"kty":"RSA","n":"AQAB","e":"AQAB"}]}`, wantErr: "JWK 0 has no kid"},
+		{name: "not JSON", jwks: `<html></html>`, wantErr: "failed to parse JWKS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newDiscoveryServer(t, tt.jwks).URL
+			result, err := DownloadJWKSSnapshot(context.Background(), issuer)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DownloadJWKSSnapshot: %v", err)
+			}
+			if string(result.Content) != tt.jwks || result.ContentDigest != ContentDigest([]byte(tt.jwks)) {
+				t.Errorf("snapshot %q (%s), want the issuer's JWKS", result.Content, result.ContentDigest)
+			}
+			if result.URL != issuer || result.StatementType != StatementTypeJWKSSnapshot {
+				t.Errorf("snapshot of %s with statement type %q, want %s and %q", result.URL, result.StatementType, issuer, StatementTypeJWKSSnapshot)
+			}
+
+			// The fetcher used by --jwks-issuer records the same snapshot
+			fetched, err := FetchContent(context.Background(), JWKSFetcher{}, issuer)
+			if err != nil {
+				t.Fatalf("FetchContent: %v", err)
+			}
+			if fetched.ContentDigest != result.ContentDigest || fetched.StatementType != StatementTypeJWKSSnapshot {
+				t.Errorf("fetched %s (%q), want %s (%q)", fetched.ContentDigest, fetched.StatementType, result.ContentDigest, StatementTypeJWKSSnapshot)
+			}
+		})
+	}
+
+	t.Run("issuer without discovery", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+		if _, err := DownloadJWKSSnapshot(context.Background(), server.URL); err == nil || !strings.Contains(err.Error(), "failed to get JWKS") {
+			t.Fatalf("expected a discovery error, got %v", err)
+		}
+	})
+}
+
+func TestDownloadJWKSSnapshotWithOptions(t *testing.T) {
+	jwks := `{"keys":[` + fixtureJWK1 + `]}`
+	var jwksURI string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": "https://" + r.Host, "jwks_uri": jwksURI})
+		case "/jwks":
+			w.Write([]byte(jwks))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	plain := newDiscoveryServer(t, jwks)
+
+	tests := []struct {
+		name         string
+		issuer       string
+		jwksURI      string
+		blockPrivate bool
+		wantErr      string
+	}{
+		{name: "https issuer", issuer: server.URL, jwksURI: server.URL + "/jwks"},
+		{name: "http issuer", issuer: plain.URL, jwksURI: plain.URL + "/jwks", wantErr: ErrUnsupportedScheme.Error()},
+		{name: "http JWKS URI", issuer: server.URL, jwksURI: plain.URL + "/jwks", wantErr: ErrUnsupportedScheme.Error()},
+		{name: "private address blocked", issuer: server.URL, jwksURI: server.URL + "/jwks", blockPrivate: true, wantErr: ErrPrivateAddress.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwksURI = tt.jwksURI
+			// AllowHTTP does not apply to snapshots, whose issuer comes from the attestation
+			opts := DownloadOptions{AllowHTTP: true, RootCAs: roots, BlockPrivateAddresses: tt.blockPrivate}
+			result, err := DownloadJWKSSnapshotWithOptions(context.Background(), tt.issuer, opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DownloadJWKSSnapshotWithOptions: %v", err)
+			}
+			if string(result.Content) != jwks || result.URL != tt.issuer || result.StatementType != StatementTypeJWKSSnapshot {
+				t.Errorf("snapshot %q of %s (%q), want the issuer's JWKS", result.Content, result.URL, result.StatementType)
+			}
+		})
+	}
+}
+
+func TestVerifyJWKSSnapshotRecheckRequiresHTTPS(t *testing.T) {
+	op := newTestOP(t)
+	issuer := newDiscoveryServer(t, `{"keys":[`+fixtureJWK1+`]}`).URL
+	download, err := DownloadJWKSSnapshot(context.Background(), issuer)
+	if err != nil {
+		t.Fatalf("DownloadJWKSSnapshot: %v", err)
+	}
+	attestation := op.attest(t, download, nil)
+
+	opts := op.verifyOptions()
+	opts.RecheckContent = true
+	opts.RecheckOptions.AllowHTTP = true
+	opts.RecheckOptions.BlockPrivateAddresses = false
+	result := verifyTestAttestation(t, attestation, opts)
+	if result.ContentRecheckVerified || !hasError(result, "Content recheck failed") {
+		t.Errorf("expected the http issuer's recheck to fail, got %v (errors %q)", result.ContentRecheckVerified, result.Errors)
+	}
+}
+
+func TestValidateJWKS(t *testing.T) {
+	tests := []struct {
+		name    string
+		jwks    string
+		wantErr string
+	}{
+		{name: "key set", jwks: `{"keys":[` + fixtureJWK1 + `]}`},
+		{name: "no keys member", jwks: `{}`, wantErr: "JWKS has no keys"},
+		{name: "key without a kty", jwks: `{"keys":[{"kid":"a"}]}`, wantErr: "JWK 0 has no kty"},
+		{name: "second key without a kid", jwks: `{"keys":[` + fixtureJWK1 + `,{"kty":"EC"}]}`, wantErr: "JWK 1 has no kid"},
+		{name: "key is not an object", jwks: `{"keys":["key"]}`, wantErr: "failed to parse JWK 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJWKS([]byte(tt.jwks))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateJWKS: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
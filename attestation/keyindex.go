@@ -0,0 +1,100 @@
+package attestation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// KeyIDIndex maps each kid seen in a series of JWKS snapshot attestations to its JWK
+type KeyIDIndex struct {
+	Issuer    string                    `json:"issuer"`
+	Snapshots int                       `json:"snapshots"`
+	Keys      map[string]*KeyIDIndexKey `json:"keys"`
+}
+
+// KeyIDIndexKey is a JWK and the timestamps of the first and last snapshots that contained it
+type KeyIDIndexKey struct {
+	JWK       json.RawMessage `json:"jwk"`
+	FirstSeen string          `json:"first_seen"`
+	LastSeen  string          `json:"last_seen"`
+}
+
+// BuildKeyIDIndex folds JWKS snapshot attestations, in timestamp order, into a kid index. Keys that have
+// rotated out keep the timestamp of the last snapshot they were in. The attestations are not verified.
+func BuildKeyIDIndex(snapshots []*Attestation) (*KeyIDIndex, error) {
+	ordered := make([]*Attestation, len(snapshots))
+	copy(ordered, snapshots)
+	// RFC3339 UTC timestamps sort lexically
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Payload.Timestamp < ordered[j].Payload.Timestamp
+	})
+
+	index := &KeyIDIndex{Keys: map[string]*KeyIDIndexKey{}}
+	for _, snapshot := range ordered {
+		payload := &snapshot.Payload
+		if payload.statementType() != StatementTypeJWKSSnapshot {
+			return nil, fmt.Errorf("attestation of %s at %s is not a %s", payload.Url, payload.Timestamp, StatementTypeJWKSSnapshot)
+		}
+		if index.Issuer == "" {
+			index.Issuer = payload.Url
+		} else if payload.Url != index.Issuer {
+			return nil, fmt.Errorf("snapshot at %s is for issuer %s, not %s", payload.Timestamp, payload.Url, index.Issuer)
+		}
+		content, err := payload.DecodedContent()
+		if err != nil {
+			return nil, fmt.Errorf("snapshot at %s: %w", payload.Timestamp, err)
+		}
+		if content == nil {
+			return nil, fmt.Errorf("snapshot at %s has no stored content", payload.Timestamp)
+		}
+		if err := ValidateJWKS(content); err != nil {
+			return nil, fmt.Errorf("snapshot at %s: %w", payload.Timestamp, err)
+		}
+
+		var keySet jwks
+		if err := json.Unmarshal(content, &keySet); err != nil {
+			return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+		}
+		for _, key := range keySet.Keys {
+			var id jwkKeyID
+			if err := json.Unmarshal(key, &id); err != nil {
+				return nil, fmt.Errorf("failed to parse JWK: %w", err)
+			}
+
+			compacted, err := compactJSON(key)
+			if err != nil {
+				return nil, err
+			}
+			entry, ok := index.Keys[id.KeyID]
+			if !ok {
+				index.Keys[id.KeyID] = &KeyIDIndexKey{
+					JWK:       compacted,
+					FirstSeen: payload.Timestamp,
+					LastSeen:  payload.Timestamp,
+				}
+				continue
+			}
+			if string(entry.JWK) != string(compacted) {
+				return nil, fmt.Errorf("key %s changed in snapshot at %s", id.KeyID, payload.Timestamp)
+			}
+			entry.LastSeen = payload.Timestamp
+		}
+		index.Snapshots++
+	}
+
+	return index, nil
+}
+
+// compactJSON removes insignificant whitespace so the same JWK compares equal across snapshots
+func compactJSON(data []byte) (json.RawMessage, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to parse JWK: %w", err)
+	}
+	compacted, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JWK: %w", err)
+	}
+	return compacted, nil
+}
@@ -0,0 +1,189 @@
+package attestation
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	// Members are in sorted order, the order the index stores them in
+	indexKeyA        = `{"e":"AQAB","kid":"a","kty":"RSA","n":"AQAB"}`
+	indexKeyB        = `{"e":"AQAB","kid":"b","kty":"RSA","n":"AQAB"}`
+	indexKeyC        = `{"crv":"P-256","kid":"c","kty":"EC","x":"AQAB","y":"AQAB"}`
+	indexKeyAChanged = `{"e":"AQAB","kid":"a","kty":"RSA","n":"AQAC"}`
+)
+
+// jwksSnapshot returns an unsigned JWKS snapshot attestation of issuer at timestamp
+func jwksSnapshot(t *testing.T, issuer string, timestamp string, keys ...string) *Attestation {
+	t.Helper()
+	content := []byte(`{"keys":[` + strings.Join(keys, ",") + `]}`)
+	payload, err := CreateAttestationPayloadFromContent(timestamp, "", nil, issuer, content, WithStatementType(StatementTypeJWKSSnapshot))
+	if err != nil {
+		t.Fatalf("CreateAttestationPayloadFromContent: %v", err)
+	}
+	return &Attestation{Payload: *payload}
+}
+
+func TestBuildKeyIDIndex(t *testing.T) {
+	const issuer = "https://issuer.example.com"
+	first := jwksSnapshot(t, issuer, "2024-01-01T00:00:00Z", indexKeyA, indexKeyB)
+	second := jwksSnapshot(t, issuer, "2024-02-01T00:00:00Z", indexKeyB, indexKeyC)
+	third := jwksSnapshot(t, issuer, "2024-03-01T00:00:00Z", indexKeyC)
+
+	tests := []struct {
+		name      string
+		snapshots []*Attestation
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "overlapping and rotated keys",
+			snapshots: []*Attestation{first, second},
+			want: `{"issuer":"https://issuer.example.com","snapshots":2,"keys":{` +
+				`"a":{"jwk":` + indexKeyA + `,"first_seen":"2024-01-01T00:00:00Z","last_seen":"2024-01-01T00:00:00Z"},` +
+				`"b":{"jwk":` + indexKeyB + `,"first_seen":"2024-01-01T00:00:00Z","last_seen":"2024-02-01T00:00:00Z"},` +
+				`"c":{"jwk":` + indexKeyC + `,"first_seen":"2024-02-01T00:00:00Z","last_seen":"2024-02-01T00:00:00Z"}}}`,
+		},
+		{
+			name:      "snapshots out of order",
+			snapshots: []*Attestation{third, first, second},
+			want: `{"issuer":"https://issuer.example.com","snapshots":3,"keys":{` +
+				`"a":{"jwk":` + indexKeyA + `,"first_seen":"2024-01-01T00:00:00Z","last_seen":"2024-01-01T00:00:00Z"},` +
+				`"b":{"jwk":` + indexKeyB + `,"first_seen":"2024-01-01T00:00:00Z","last_seen":"2024-02-01T00:00:00Z"},` +
+				`"c":{"jwk":` + indexKeyC + `,"first_seen":"2024-02-01T00:00:00Z","last_seen":"2024-03-01T00:00:00Z"}}}`,
+		},
+		{
+			name: "same key formatted differently",
+			snapshots: []*Attestation{
+				first,
+				jwksSnapshot(t, issuer, "2024-02-01T00:00:00Z", `{ "kty": "RSA", "kid": "a", "n": "AQAB", "e": "AQAB" }`),
+			},
+			want: `{"issuer":"https://issuer.example.com","snapshots":2,"keys":{` +
+				`"a":{"jwk":` + indexKeyA + `,"first_seen":"2024-01-01T00:00:00Z","last_seen":"2024-02-01T00:00:00Z"},` +
+				`"b":{"jwk":` + indexKeyB + `,"first_seen":"2024-01-01T00:00:00Z","last_seen":"2024-01-01T00:00:00Z"}}}`,
+		},
+		{
+			name:      "no snapshots",
+			snapshots: nil,
+			want:      `{"issuer":"","snapshots":0,"keys":{}}`,
+		},
+		{
+			name:      "key material changed under the same kid",
+			snapshots: []*Attestation{first, jwksSnapshot(t, issuer, "2024-02-01T00:00:00Z", indexKeyAChanged)},
+			wantErr:   "key a changed in snapshot at 2024-02-01T00:00:00Z",
+		},
+		{
+			name:      "different issuers",
+			snapshots: []*Attestation{first, jwksSnapshot(t, "https://other.example.com", "2024-02-01T00:00:00Z", indexKeyA)},
+			wantErr:   "is for issuer https://other.example.com",
+		},
+		{
+			name:      "not a JWKS snapshot",
+			snapshots: []*Attestation{ledgerAttestation(t, "2024-01-01T00:00:00Z", nil)},
+			wantErr:   "is not a " + StatementTypeJWKSSnapshot,
+		},
+		{
+			name:      "invalid key set",
+			snapshots: []*Attestation{jwksSnapshot(t, issuer, "2024-01-01T00:00:00Z")},
+			wantErr:   "JWKS has no keys",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, err := BuildKeyIDIndex(tt.snapshots)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildKeyIDIndex: %v", err)
+			}
+			got, err := json.Marshal(index)
+			if err != nil {
+				t.Fatalf("failed to marshal index: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("index = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildKeyIDIndexCompressedContent(t *testing.T) {
+	const issuer = "https://issuer.example.com"
+	compressed := func(snapshot *Attestation) *Attestation {
+		content, err := CompressContent(snapshot.Payload.Content)
+		if err != nil {
+			t.Fatalf("CompressContent: %v", err)
+		}
+		snapshot.Payload.Content = content
+		snapshot.Payload.ContentCompression = ContentCompressionGzip
+		return snapshot
+	}
+	plain, err := BuildKeyIDIndex([]*Attestation{
+		jwksSnapshot(t, issuer, "2024-01-01T00:00:00Z", indexKeyA, indexKeyB),
+		jwksSnapshot(t, issuer, "2024-02-01T00:00:00Z", indexKeyB, indexKeyC),
+	})
+	if err != nil {
+		t.Fatalf("BuildKeyIDIndex: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		snapshots []*Attestation
+		wantErr   string
+	}{
+		{
+			name: "every snapshot compressed",
+			snapshots: []*Attestation{
+				compressed(jwksSnapshot(t, issuer, "2024-01-01T00:00:00Z", indexKeyA, indexKeyB)),
+				compressed(jwksSnapshot(t, issuer, "2024-02-01T00:00:00Z", indexKeyB, indexKeyC)),
+			},
+		},
+		{
+			name: "compressed and uncompressed snapshots",
+			snapshots: []*Attestation{
+				jwksSnapshot(t, issuer, "2024-01-01T00:00:00Z", indexKeyA, indexKeyB),
+				compressed(jwksSnapshot(t, issuer, "2024-02-01T00:00:00Z", indexKeyB, indexKeyC)),
+			},
+		},
+		{
+			name: "corrupt compressed snapshot",
+			snapshots: []*Attestation{func() *Attestation {
+				snapshot := compressed(jwksSnapshot(t, issuer, "2024-01-01T00:00:00Z", indexKeyA))
+				snapshot.Payload.Content = snapshot.Payload.Content[:10]
+				return snapshot
+			}()},
+			wantErr: "snapshot at 2024-01-01T00:00:00Z: failed to decompress content",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, err := BuildKeyIDIndex(tt.snapshots)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildKeyIDIndex: %v", err)
+			}
+			if !reflect.DeepEqual(index, plain) {
+				t.Errorf("index of compressed snapshots %+v differs from %+v", index, plain)
+			}
+		})
+	}
+}
+
+func TestBuildKeyIDIndexMissingContent(t *testing.T) {
+	snapshot := jwksSnapshot(t, "https://issuer.example.com", "2024-01-01T00:00:00Z", indexKeyA)
+	snapshot.Payload.Content = nil
+	if _, err := BuildKeyIDIndex([]*Attestation{snapshot}); err == nil || !strings.Contains(err.Error(), "has no stored content") {
+		t.Fatalf("expected a missing content error, got %v", err)
+	}
+}
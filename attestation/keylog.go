@@ -0,0 +1,241 @@
+package attestation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/openpubkey/openpubkey/discover"
+	"github.com/openpubkey/openpubkey/pktoken"
+)
+
+// gqAlgorithm is the alg of ID tokens whose RSA signature was replaced by a GQ signature, as GitHub's are
+const gqAlgorithm = "GQ256"
+
+// Key log layout. Each key is written to keys/<kid>.json and listed in index.json, so a static host
+// can serve kid -> JWK lookups after the OP has rotated the key out of its live JWKS.
+const (
+	keyLogIndexFile = "index.json"
+	keyLogKeysDir   = "keys"
+)
+
+// KeyLogIndex lists every key ever exported to a key log directory
+type KeyLogIndex struct {
+	Issuer    string                  `json:"issuer"`
+	UpdatedAt string                  `json:"updated_at"`
+	Keys      map[string]*KeyLogEntry `json:"keys"`
+}
+
+// KeyLogEntry records where a key is stored and when it was seen in the live JWKS
+type KeyLogEntry struct {
+	Path      string `json:"path"`
+	FirstSeen string `json:"first_seen"`
+	LastSeen  string `json:"last_seen"`
+}
+
+type jwks struct {
+	Keys []json.RawMessage `json:"keys"`
+}
+
+type jwkKeyID struct {
+	KeyID string `json:"kid"`
+}
+
+// ExportKeyLog merges the keys in jwksContent into the key log at dir. Keys already in the log are kept
+// even if they are no longer in the JWKS, and are never rewritten.
+func ExportKeyLog(dir string, issuer string, jwksContent []byte, now time.Time) (*KeyLogIndex, error) {
+	var keySet jwks
+	if err := json.Unmarshal(jwksContent, &keySet); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	index, err := LoadKeyLogIndex(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		index = &KeyLogIndex{Issuer: issuer, Keys: map[string]*KeyLogEntry{}}
+	} else if err != nil {
+		return nil, err
+	}
+	if index.Issuer != issuer {
+		return nil, fmt.Errorf("key log at %s is for issuer %s, not %s", dir, index.Issuer, issuer)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, keyLogKeysDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create key log directory: %w", err)
+	}
+
+	timestamp := now.UTC().Format(time.RFC3339)
+	for _, key := range keySet.Keys {
+		var id jwkKeyID
+		if err := json.Unmarshal(key, &id); err != nil {
+			return nil, fmt.Errorf("failed to parse JWK: %w", err)
+		}
+		if id.KeyID == "" {
+			return nil, fmt.Errorf("JWK has no kid")
+		}
+
+		if entry, ok := index.Keys[id.KeyID]; ok {
+			entry.LastSeen = timestamp
+			continue
+		}
+
+		keyPath := keyLogPath(id.KeyID)
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(keyPath)), key, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write key %s: %w", id.KeyID, err)
+		}
+		index.Keys[id.KeyID] = &KeyLogEntry{
+			Path:      keyPath,
+			FirstSeen: timestamp,
+			LastSeen:  timestamp,
+		}
+	}
+	index.UpdatedAt = timestamp
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal key log index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, keyLogIndexFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write key log index: %w", err)
+	}
+
+	return index, nil
+}
+
+// LoadKeyLogIndex reads the index of the key log at dir
+func LoadKeyLogIndex(dir string) (*KeyLogIndex, error) {
+	data, err := os.ReadFile(filepath.Join(dir, keyLogIndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key log index: %w", err)
+	}
+
+	var index KeyLogIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse key log index: %w", err)
+	}
+	if index.Keys == nil {
+		index.Keys = map[string]*KeyLogEntry{}
+	}
+
+	return &index, nil
+}
+
+// keyLogPath returns the slash-separated path of a key's file, escaping the kid so it is a safe file name
+func keyLogPath(kid string) string {
+	return path.Join(keyLogKeysDir, url.PathEscape(kid)+".json")
+}
+
+// VerifyKeyInLog checks that the key the OP signed the PK token with is recorded in the key log at dir
+func VerifyKeyInLog(dir string, pkToken *pktoken.PKToken) error {
+	index, err := LoadKeyLogIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	issuer, err := pkToken.Issuer()
+	if err != nil {
+		return fmt.Errorf("failed to get PK token issuer: %w", err)
+	}
+	if issuer != index.Issuer {
+		return fmt.Errorf("PK token issuer %s does not match key log issuer %s", issuer, index.Issuer)
+	}
+
+	keyID, err := opKeyID(pkToken.OpToken)
+	if err != nil {
+		return err
+	}
+
+	entry, ok := index.Keys[keyID]
+	if !ok {
+		return fmt.Errorf("signing key %s is not in the key log at %s", keyID, dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(entry.Path))); err != nil {
+		return fmt.Errorf("signing key %s is listed in the key log but its file is missing: %w", keyID, err)
+	}
+
+	return nil
+}
+
+// opKeyID returns the kid of the OP key that signed the ID token. A GQ-signed token's kid is the original
+// token's protected header, base64url encoded, whose kid names the OP key.
+func opKeyID(opToken []byte) (string, error) {
+	header, err := parseTokenHeader(opToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse OP token header: %w", err)
+	}
+	if header.Algorithm == gqAlgorithm {
+		var original tokenHeader
+		if err := decodeTokenHeader([]byte(header.KeyID+"."), &original); err != nil {
+			return "", fmt.Errorf("failed to parse the original header of the GQ-signed OP token: %w", err)
+		}
+		header = &original
+	}
+	if header.KeyID == "" {
+		return "", fmt.Errorf("OP token header has no kid")
+	}
+	return header.KeyID, nil
+}
+
+// keyLogFinder returns a public key finder that serves every key in the key log at dir as the issuer's JWKS
+func keyLogFinder(dir string) *discover.PublicKeyFinder {
+	return &discover.PublicKeyFinder{
+		JwksFunc: func(ctx context.Context, issuer string) ([]byte, error) {
+			index, err := LoadKeyLogIndex(dir)
+			if err != nil {
+				return nil, err
+			}
+			if issuer != index.Issuer {
+				return nil, fmt.Errorf("key log at %s is for issuer %s, not %s", dir, index.Issuer, issuer)
+			}
+
+			keySet := jwks{Keys: make([]json.RawMessage, 0, len(index.Keys))}
+			for kid, entry := range index.Keys {
+				key, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(entry.Path)))
+				if err != nil {
+					return nil, fmt.Errorf("failed to read key %s: %w", kid, err)
+				}
+				keySet.Keys = append(keySet.Keys, key)
+			}
+			return json.Marshal(keySet)
+		},
+	}
+}
+
+// tokenHeader holds the protected header fields of a compact JWS
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Type      string `json:"typ"`
+}
+
+// parseTokenHeader decodes the protected header of a compact JWS without verifying it
+func parseTokenHeader(compact []byte) (*tokenHeader, error) {
+	var header tokenHeader
+	if err := decodeTokenHeader(compact, &header); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// decodeTokenHeader decodes the protected header of a compact JWS into header without verifying it
+func decodeTokenHeader(compact []byte, header any) error {
+	encoded, _, ok := strings.Cut(string(compact), ".")
+	if !ok {
+		return fmt.Errorf("token is not in compact form")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("failed to decode token header: %w", err)
+	}
+	if err := json.Unmarshal(data, header); err != nil {
+		return fmt.Errorf("failed to parse token header: %w", err)
+	}
+	return nil
+}
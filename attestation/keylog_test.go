@@ -0,0 +1,180 @@
+package attestation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testKeyLogIssuer = "https://token.actions.githubusercontent.com"
+	fixtureJWK1      = `{"kty":"RSA","kid":"k1","n":"AQAB","e":"AQAB"}`
+	fixtureJWK2      = `{"kty":"RSA","kid":"k/2","n":"AQAC","e":"AQAB"}`
+	fixtureJWK3      = `{"kty":"RSA","kid":"k3","n":"AQAD","e":"AQAB"}`
+)
+
+func TestExportKeyLogLayout(t *testing.T) {
+	dir := t.TempDir()
+	exported := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if _, err := ExportKeyLog(dir, testKeyLogIssuer, []byte(`{"keys":[`+fixtureJWK1+`,`+fixtureJWK2+`]}`), exported); err != nil {
+		t.Fatalf("ExportKeyLog: %v", err)
+	}
+
+	wantFiles := map[string]string{
+		"keys/k1.json":    fixtureJWK1,
+		"keys/k%2F2.json": fixtureJWK2,
+		"index.json": `{
+  "issuer": "https://token.actions.githubusercontent.com",
+  "updated_at": "2026-01-02T03:04:05Z",
+  "keys": {
+    "k/2": {
+      "path": "keys/k%2F2.json",
+      "first_seen": "2026-01-02T03:04:05Z",
+      "last_seen": "2026-01-02T03:04:05Z"
+    },
+    "k1": {
+      "path": "keys/k1.json",
+      "first_seen": "2026-01-02T03:04:05Z",
+      "last_seen": "2026-01-02T03:04:05Z"
+    }
+  }
+}`,
+	}
+	for name, want := range wantFiles {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s =\n%s\nwant\n%s", name, data, want)
+		}
+	}
+}
+
+func TestExportKeyLogIncremental(t *testing.T) {
+	dir := t.TempDir()
+	first := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+	if _, err := ExportKeyLog(dir, testKeyLogIssuer, []byte(`{"keys":[`+fixtureJWK1+`,`+fixtureJWK2+`]}`), first); err != nil {
+		t.Fatalf("ExportKeyLog: %v", err)
+	}
+	// The OP rotated k/2 out and k3 in
+	index, err := ExportKeyLog(dir, testKeyLogIssuer, []byte(`{"keys":[`+fixtureJWK1+`,`+fixtureJWK3+`]}`), second)
+	if err != nil {
+		t.Fatalf("ExportKeyLog: %v", err)
+	}
+
+	tests := []struct {
+		kid       string
+		firstSeen time.Time
+		lastSeen  time.Time
+	}{
+		{kid: "k1", firstSeen: first, lastSeen: second},
+		{kid: "k/2", firstSeen: first, lastSeen: first},
+		{kid: "k3", firstSeen: second, lastSeen: second},
+	}
+	for _, tt := range tests {
+		entry, ok := index.Keys[tt.kid]
+		if !ok {
+			t.Errorf("key %s is missing from the index", tt.kid)
+			continue
+		}
+		if entry.FirstSeen != tt.firstSeen.Format(time.RFC3339) || entry.LastSeen != tt.lastSeen.Format(time.RFC3339) {
+			t.Errorf("key %s seen %s to %s, want %s to %s", tt.kid, entry.FirstSeen, entry.LastSeen, tt.firstSeen.Format(time.RFC3339), tt.lastSeen.Format(time.RFC3339))
+		}
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(entry.Path))); err != nil {
+			t.Errorf("key %s file: %v", tt.kid, err)
+		}
+	}
+
+	loaded, err := LoadKeyLogIndex(dir)
+	if err != nil {
+		t.Fatalf("LoadKeyLogIndex: %v", err)
+	}
+	if len(loaded.Keys) != 3 || loaded.UpdatedAt != second.Format(time.RFC3339) {
+		t.Errorf("loaded index has %d keys updated at %s, want 3 at %s", len(loaded.Keys), loaded.UpdatedAt, second.Format(time.RFC3339))
+	}
+}
+
+func TestExportKeyLogErrors(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		issuer  string
+		jwks    string
+		wantErr string
+	}{
+		{name: "not JSON", issuer: testKeyLogIssuer, jwks: `not json`, wantErr: "failed to parse JWKS"},
+		{name: "key without kid", issuer: testKeyLogIssuer, jwks: `{"keys":[{"kty":"RSA"}]}`, wantErr: "JWK has no kid"},
+		{name: "another issuer's log", issuer: "https://gitlab.com", jwks: `{"keys":[]}`, wantErr: "is for issuer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if _, err := ExportKeyLog(dir, testKeyLogIssuer, []byte(`{"keys":[`+fixtureJWK1+`]}`), now); err != nil {
+				t.Fatalf("ExportKeyLog: %v", err)
+			}
+			_, err := ExportKeyLog(dir, tt.issuer, []byte(tt.jwks), now)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// exportTestKeyLog exports the OP's JWKS to a new key log directory
+func exportTestKeyLog(t *testing.T, op *testOP) string {
+	t.Helper()
+	jwks, err := os.ReadFile(op.jwksFile(t))
+	if err != nil {
+		t.Fatalf("failed to read JWKS: %v", err)
+	}
+	dir := t.TempDir()
+	if _, err := ExportKeyLog(dir, op.issuer(), jwks, time.Now()); err != nil {
+		t.Fatalf("ExportKeyLog: %v", err)
+	}
+	return dir
+}
+
+func TestVerifyKeyInLog(t *testing.T) {
+	op := newGitHubLikeOP(t)
+	// A log of keys the OP has not signed with
+	otherKeys := t.TempDir()
+	if _, err := ExportKeyLog(otherKeys, op.issuer(), []byte(`{"keys":[`+fixtureJWK1+`]}`), time.Now()); err != nil {
+		t.Fatalf("ExportKeyLog: %v", err)
+	}
+	attestation := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+
+	tests := []struct {
+		name      string
+		keyLogDir string
+		wantError string
+	}{
+		{name: "signing key in the log", keyLogDir: exportTestKeyLog(t, op)},
+		{name: "signing key missing from the log", keyLogDir: otherKeys, wantError: "is not in the key log"},
+		{name: "no key log", keyLogDir: t.TempDir(), wantError: "failed to read key log index"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewVerifyOptions()
+			opts.Issuer = op.issuer()
+			opts.ExpectedWorkflowRef = testWorkflowRef
+			opts.KeyLogDir = tt.keyLogDir
+			result := verifyTestAttestation(t, attestation, opts)
+			if tt.wantError == "" {
+				if !result.KeyInLogVerified || !result.PKTokenVerified || !result.IsVerificationSuccessful() {
+					t.Errorf("KeyInLogVerified %v, PKTokenVerified %v (errors %q)", result.KeyInLogVerified, result.PKTokenVerified, result.Errors)
+				}
+				return
+			}
+			if result.KeyInLogVerified || !hasError(result, "Key log verification failed") {
+				t.Errorf("expected a key log error, got %q", result.Errors)
+			}
+			if !strings.Contains(strings.Join(result.Errors, "\n"), tt.wantError) {
+				t.Errorf("expected an error containing %q, got %q", tt.wantError, result.Errors)
+			}
+		})
+	}
+}
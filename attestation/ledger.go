@@ -0,0 +1,142 @@
+package attestation
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// LedgerEntry is one line of an attestation ledger
+type LedgerEntry struct {
+	Digest         string `json:"digest"`
+	Timestamp      string `json:"timestamp"`
+	URL            string `json:"url"`
+	ContentDigest  string `json:"content_digest"`
+	PreviousDigest string `json:"previous_digest,omitempty"`
+}
+
+// NewLedgerEntry summarises an attestation for the ledger
+func NewLedgerEntry(attestation *Attestation) (*LedgerEntry, error) {
+	digest, err := attestation.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &LedgerEntry{
+		Digest:        digest,
+		Timestamp:     attestation.Payload.Timestamp,
+		URL:           attestation.Payload.Url,
+		ContentDigest: attestation.Payload.ContentDigest,
+	}
+	if len(attestation.Payload.PreviousAttestation) > 0 {
+		var details AttestationDetails
+		if err := json.Unmarshal(attestation.Payload.PreviousAttestation, &details); err != nil {
+			return nil, fmt.Errorf("failed to parse previous attestation details: %w", err)
+		}
+		entry.PreviousDigest = details.Digest
+	}
+	return entry, nil
+}
+
+// AppendToLedger appends an attestation to an append-only ledger file of JSON lines, creating it if needed
+func AppendToLedger(ledgerFile string, attestation *Attestation) error {
+	entry, err := NewLedgerEntry(attestation)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ledger entry: %w", err)
+	}
+
+	file, err := os.OpenFile(ledgerFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open ledger: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to append to ledger: %w", err)
+	}
+	return nil
+}
+
+// LoadLedger reads every entry of a ledger file in order
+func LoadLedger(ledgerFile string) ([]LedgerEntry, error) {
+	file, err := os.Open(ledgerFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %w", err)
+	}
+	defer file.Close()
+
+	var entries []LedgerEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry LedgerEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse ledger line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+	return entries, nil
+}
+
+// VerifyLedger checks that every ledger entry after the first references the entry before it as its
+// previous attestation, returning the entries when the chain is unbroken. A digest seen twice, including an
+// entry referencing itself, is rejected as a cycle, as is an entry older than its previous attestation.
+func VerifyLedger(ledgerFile string) ([]LedgerEntry, error) {
+	entries, err := LoadLedger(ledgerFile)
+	if err != nil {
+		return nil, err
+	}
+	visited := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		if entry.PreviousDigest == entry.Digest {
+			return nil, fmt.Errorf("ledger entry %d references itself as its previous attestation", i+1)
+		}
+		if first, ok := visited[entry.Digest]; ok {
+			return nil, fmt.Errorf("ledger entry %d repeats the digest of entry %d, the chain has a cycle", i+1, first)
+		}
+		visited[entry.Digest] = i + 1
+		if i == 0 {
+			continue
+		}
+
+		previous := entries[i-1]
+		if entry.PreviousDigest != previous.Digest {
+			return nil, fmt.Errorf("ledger entry %d references previous attestation %q, expected %s",
+				i+1, entry.PreviousDigest, previous.Digest)
+		}
+		newer, err := isNewer(previous.Timestamp, entry.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("ledger entry %d: %w", i+1, err)
+		}
+		if newer {
+			return nil, fmt.Errorf("ledger entry %d has timestamp %s, older than its previous attestation's %s",
+				i+1, entry.Timestamp, previous.Timestamp)
+		}
+	}
+	return entries, nil
+}
+
+// isNewer reports whether RFC 3339 timestamp a is after b
+func isNewer(a string, b string) (bool, error) {
+	at, err := time.Parse(time.RFC3339, a)
+	if err != nil {
+		return false, fmt.Errorf("invalid timestamp %q: %w", a, err)
+	}
+	bt, err := time.Parse(time.RFC3339, b)
+	if err != nil {
+		return false, fmt.Errorf("invalid timestamp %q: %w", b, err)
+	}
+	return at.After(bt), nil
+}
@@ -0,0 +1,158 @@
+package attestation
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ledgerAttestation returns an attestation at timestamp linking to previous, nil for the first in a chain
+func ledgerAttestation(t *testing.T, timestamp string, previous *Attestation) *Attestation {
+	t.Helper()
+	var details []byte
+	if previous != nil {
+		digest, err := previous.Digest()
+		if err != nil {
+			t.Fatalf("Digest: %v", err)
+		}
+		if details, err = json.Marshal(AttestationDetails{Digest: digest}); err != nil {
+			t.Fatalf("failed to marshal details: %v", err)
+		}
+	}
+	content := []byte("content at " + timestamp)
+	payload, err := CreateAttestationPayloadFromContent(timestamp, "", details, "https://example.com/jwks", content)
+	if err != nil {
+		t.Fatalf("CreateAttestationPayloadFromContent: %v", err)
+	}
+	return &Attestation{Payload: *payload, Signature: []byte(timestamp)}
+}
+
+func TestAppendToLedger(t *testing.T) {
+	first := ledgerAttestation(t, "2024-01-01T00:00:00Z", nil)
+	second := ledgerAttestation(t, "2024-01-02T00:00:00Z", first)
+	third := ledgerAttestation(t, "2024-01-03T00:00:00Z", second)
+	chain := []*Attestation{first, second, third}
+
+	ledgerFile := filepath.Join(t.TempDir(), "ledger.jsonl")
+	for _, attestation := range chain {
+		if err := AppendToLedger(ledgerFile, attestation); err != nil {
+			t.Fatalf("AppendToLedger: %v", err)
+		}
+	}
+
+	entries, err := VerifyLedger(ledgerFile)
+	if err != nil {
+		t.Fatalf("VerifyLedger: %v", err)
+	}
+	if len(entries) != len(chain) {
+		t.Fatalf("ledger has %d entries, want %d", len(entries), len(chain))
+	}
+	for i, attestation := range chain {
+		digest, _ := attestation.Digest()
+		entry := entries[i]
+		if entry.Digest != digest || entry.Timestamp != attestation.Payload.Timestamp || entry.ContentDigest != attestation.Payload.ContentDigest {
+			t.Errorf("entry %d = %+v, want the attestation at %s", i+1, entry, attestation.Payload.Timestamp)
+		}
+		if i > 0 && entry.PreviousDigest != entries[i-1].Digest {
+			t.Errorf("entry %d links to %s, want %s", i+1, entry.PreviousDigest, entries[i-1].Digest)
+		}
+	}
+	if entries[0].PreviousDigest != "" {
+		t.Errorf("first entry links to %s, want no previous attestation", entries[0].PreviousDigest)
+	}
+}
+
+func TestVerifyLedger(t *testing.T) {
+	entry := func(digest string, timestamp string, previous string) string {
+		data, _ := json.Marshal(LedgerEntry{Digest: digest, Timestamp: timestamp, PreviousDigest: previous})
+		return string(data)
+	}
+
+	tests := []struct {
+		name        string
+		lines       []string
+		wantEntries int
+		wantErr     string
+	}{
+		{
+			name: "unbroken chain",
+			lines: []string{
+				entry("sha256:a", "2024-01-01T00:00:00Z", ""),
+				entry("sha256:b", "2024-01-02T00:00:00Z", "sha256:a"),
+				entry("sha256:c", "2024-01-02T00:00:00Z", "sha256:b"),
+			},
+			wantEntries: 3,
+		},
+		{
+			name:        "blank lines are skipped",
+			lines:       []string{entry("sha256:a", "2024-01-01T00:00:00Z", ""), "", entry("sha256:b", "2024-01-02T00:00:00Z", "sha256:a")},
+			wantEntries: 2,
+		},
+		{
+			name: "broken link",
+			lines: []string{
+				entry("sha256:a", "2024-01-01T00:00:00Z", ""),
+				entry("sha256:b", "2024-01-02T00:00:00Z", "sha256:x"),
+			},
+			wantErr: "ledger entry 2 references previous attestation \"sha256:x\", expected sha256:a",
+		},
+		{
+			name:    "entry referencing itself",
+			lines:   []string{entry("sha256:a", "2024-01-01T00:00:00Z", "sha256:a")},
+			wantErr: "ledger entry 1 references itself",
+		},
+		{
+			name: "repeated digest",
+			lines: []string{
+				entry("sha256:a", "2024-01-01T00:00:00Z", ""),
+				entry("sha256:b", "2024-01-02T00:00:00Z", "sha256:a"),
+				entry("sha256:a", "2024-01-03T00:00:00Z", "sha256:b"),
+			},
+			wantErr: "ledger entry 3 repeats the digest of entry 1",
+		},
+		{
+			name: "entries out of order",
+			lines: []string{
+				entry("sha256:a", "2024-01-02T00:00:00Z", ""),
+				entry("sha256:b", "2024-01-01T00:00:00Z", "sha256:a"),
+			},
+			wantErr: "ledger entry 2 has timestamp 2024-01-01T00:00:00Z, older than",
+		},
+		{
+			name: "invalid timestamp",
+			lines: []string{
+				entry("sha256:a", "yesterday", ""),
+				entry("sha256:b", "2024-01-01T00:00:00Z", "sha256:a"),
+			},
+			wantErr: "invalid timestamp",
+		},
+		{
+			name:    "malformed line",
+			lines:   []string{entry("sha256:a", "2024-01-01T00:00:00Z", ""), "{not json"},
+			wantErr: "failed to parse ledger line 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledgerFile := filepath.Join(t.TempDir(), "ledger.jsonl")
+			if err := os.WriteFile(ledgerFile, []byte(strings.Join(tt.lines, "\n")+"\n"), 0644); err != nil {
+				t.Fatalf("failed to write ledger: %v", err)
+			}
+			entries, err := VerifyLedger(ledgerFile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyLedger: %v", err)
+			}
+			if len(entries) != tt.wantEntries {
+				t.Errorf("VerifyLedger returned %d entries, want %d", len(entries), tt.wantEntries)
+			}
+		})
+	}
+}
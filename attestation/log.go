@@ -0,0 +1,80 @@
+package attestation
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// LogLevel controls how much progress output the command line tools print
+type LogLevel int
+
+const (
+	// LogQuiet prints only errors and results
+	LogQuiet LogLevel = iota
+	// LogNormal also prints progress and warnings
+	LogNormal
+	// LogVerbose also prints details such as decoded encodings and TLS certificates
+	LogVerbose
+)
+
+// Logger separates progress messages, written to Err, from machine-consumable results, written to Out
+type Logger struct {
+	Level LogLevel
+	Out   io.Writer
+	Err   io.Writer
+}
+
+// NewLogger returns a Logger writing results to stdout and everything else to stderr
+func NewLogger(level LogLevel) *Logger {
+	return &Logger{
+		Level: level,
+		Out:   os.Stdout,
+		Err:   os.Stderr,
+	}
+}
+
+// LogLevelFromFlags returns the level selected by --quiet and --verbose, which are mutually exclusive
+func LogLevelFromFlags(quiet bool, verbose bool) (LogLevel, error) {
+	switch {
+	case quiet && verbose:
+		return LogNormal, fmt.Errorf("quiet and verbose flags are mutually exclusive")
+	case quiet:
+		return LogQuiet, nil
+	case verbose:
+		return LogVerbose, nil
+	default:
+		return LogNormal, nil
+	}
+}
+
+// Progressf prints a progress message unless quiet
+func (l *Logger) Progressf(format string, args ...interface{}) {
+	if l.Level >= LogNormal {
+		fmt.Fprintf(l.Err, format, args...)
+	}
+}
+
+// Warnf prints a warning unless quiet
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	if l.Level >= LogNormal {
+		fmt.Fprintf(l.Err, format, args...)
+	}
+}
+
+// Verbosef prints a detail message when verbose
+func (l *Logger) Verbosef(format string, args ...interface{}) {
+	if l.Level >= LogVerbose {
+		fmt.Fprintf(l.Err, format, args...)
+	}
+}
+
+// Errorf always prints an error message
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(l.Err, format, args...)
+}
+
+// Resultf always prints to the result output
+func (l *Logger) Resultf(format string, args ...interface{}) {
+	fmt.Fprintf(l.Out, format, args...)
+}
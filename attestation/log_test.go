@@ -0,0 +1,69 @@
+package attestation
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		name         string
+		level        LogLevel
+		wantProgress string
+	}{
+		{name: "quiet", level: LogQuiet, wantProgress: "error\n"},
+		{name: "normal", level: LogNormal, wantProgress: "progress\nwarning\nerror\n"},
+		{name: "verbose", level: LogVerbose, wantProgress: "progress\nwarning\nverbose\nerror\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, progress bytes.Buffer
+			logger := &Logger{Level: tt.level, Out: &out, Err: &progress}
+			logger.Progressf("%s\n", "progress")
+			logger.Warnf("%s\n", "warning")
+			logger.Verbosef("%s\n", "verbose")
+			logger.Errorf("%s\n", "error")
+			logger.Resultf("%s\n", "result")
+
+			// Results always go to Out and nothing else does, so stdout stays machine-consumable
+			if out.String() != "result\n" {
+				t.Errorf("Out = %q, want %q", out.String(), "result\n")
+			}
+			if progress.String() != tt.wantProgress {
+				t.Errorf("Err = %q, want %q", progress.String(), tt.wantProgress)
+			}
+		})
+	}
+}
+
+func TestLogLevelFromFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		quiet   bool
+		verbose bool
+		want    LogLevel
+		wantErr bool
+	}{
+		{name: "default", want: LogNormal},
+		{name: "quiet", quiet: true, want: LogQuiet},
+		{name: "verbose", verbose: true, want: LogVerbose},
+		{name: "both", quiet: true, verbose: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, err := LogLevelFromFlags(tt.quiet, tt.verbose)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error for --quiet with --verbose")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LogLevelFromFlags: %v", err)
+			}
+			if level != tt.want {
+				t.Errorf("LogLevelFromFlags(%v, %v) = %v, want %v", tt.quiet, tt.verbose, level, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// NormalizeJSON canonicalizes JSON content (sorted object keys, no insignificant whitespace) before it is
+// digested, so formatting-only changes on the server don't change the digest
+const NormalizeJSON = "json"
+
+// NormalizeContent applies the named normalization to content
+func NormalizeContent(content []byte, normalization string) ([]byte, error) {
+	switch normalization {
+	case "":
+		return content, nil
+	case NormalizeJSON:
+		return canonicalJSON(content)
+	default:
+		return nil, fmt.Errorf("unsupported normalization: %s", normalization)
+	}
+}
+
+// canonicalJSON re-encodes JSON with sorted keys and compact whitespace. Numbers are kept as written.
+func canonicalJSON(content []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON content: %w", err)
+	}
+	// More reports false before a stray closing delimiter, so look for the end of the input instead
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("failed to parse JSON content: unexpected data after top-level value")
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to encode JSON content: %w", err)
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+// downloadNormalized downloads content and normalizes it before computing the digest and size
+func downloadNormalized(ctx context.Context, sourceURL string, opts DownloadOptions) (*DownloadResult, error) {
+	rawOpts := opts
+	rawOpts.Normalize = ""
+	rawOpts.DigestOnly = false
+	result, err := DownloadContentContext(ctx, sourceURL, rawOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := NormalizeContent(result.Content, opts.Normalize)
+	if err != nil {
+		return nil, err
+	}
+	result.Content = content
+	result.ContentDigest = ContentDigest(content)
+	result.ContentSize = int64(len(content))
+	result.Normalization = opts.Normalize
+	if opts.DigestOnly {
+		result.Content = nil
+	}
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package attestation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeContent(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		normalization string
+		want          string
+		wantErr       string
+	}{
+		{name: "no normalization", content: `{"b": 1, "a": 2}`, want: `{"b": 1, "a": 2}`},
+		{name: "sorted keys and compact whitespace", content: "{\n  \"b\": 1,\n  \"a\": [ 2, 3 ]\n}\n", normalization: NormalizeJSON, want: `{"a":[2,3],"b":1}`},
+		{name: "nested objects", content: `{"z": {"y": 1, "x": 2}}`, normalization: NormalizeJSON, want: `{"z":{"x":2,"y":1}}`},
+		{name: "numbers kept as written", content: `{"n": 1.50, "big": 12345678901234567890}`, normalization: NormalizeJSON, want: `{"big":12345678901234567890,"n":1.50}`},
+		{name: "HTML characters not escaped", content: `{"s": "<a&b>"}`, normalization: NormalizeJSON, want: `{"s":"<a&b>"}`},
+		{name: "invalid JSON", content: `{"a":`, normalization: NormalizeJSON, wantErr: "failed to parse JSON content"},
+		{name: "data after the top-level value", content: `{"a": 1} {"b": 2}`, normalization: NormalizeJSON, wantErr: "unexpected data after top-level value"},
+		{name: "closing brace after the top-level value", content: `{"a": 1}}`, normalization: NormalizeJSON, wantErr: "unexpected data after top-level value"},
+		{name: "closing bracket after the top-level value", content: `[1]]`, normalization: NormalizeJSON, wantErr: "unexpected data after top-level value"},
+		{name: "trailing whitespace", content: "{\"a\": 1}\n", normalization: NormalizeJSON, want: `{"a":1}`},
+		{name: "unsupported normalization", content: `{}`, normalization: "xml", wantErr: "unsupported normalization: xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeContent([]byte(tt.content), tt.normalization)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeContent: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("NormalizeContent() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadNormalizedDigest(t *testing.T) {
+	bodies := map[string]string{
+		"/pretty":    "{\n  \"keys\": [\n    {\"kid\": \"a\", \"kty\": \"RSA\"}\n  ]\n}\n",
+		"/compact":   `{"keys":[{"kty":"RSA","kid":"a"}]}`,
+		"/different": `{"keys":[{"kty":"RSA","kid":"b"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(bodies[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	download := func(t *testing.T, path string, normalization string) *DownloadResult {
+		t.Helper()
+		result, err := DownloadContentContext(context.Background(), server.URL+path, DownloadOptions{AllowHTTP: true, Normalize: normalization})
+		if err != nil {
+			t.Fatalf("DownloadContentContext: %v", err)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name          string
+		first         string
+		second        string
+		normalization string
+		wantEqual     bool
+	}{
+		{name: "formatting differences normalize to the same digest", first: "/pretty", second: "/compact", normalization: NormalizeJSON, wantEqual: true},
+		{name: "formatting differences without normalization", first: "/pretty", second: "/compact"},
+		{name: "semantic differences still change the digest", first: "/compact", second: "/different", normalization: NormalizeJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := download(t, tt.first, tt.normalization)
+			second := download(t, tt.second, tt.normalization)
+			if (first.ContentDigest == second.ContentDigest) != tt.wantEqual {
+				t.Errorf("digests %s and %s, want equal %v", first.ContentDigest, second.ContentDigest, tt.wantEqual)
+			}
+			if first.Normalization != tt.normalization {
+				t.Errorf("Normalization = %q, want %q", first.Normalization, tt.normalization)
+			}
+			if first.ContentDigest != ContentDigest(first.Content) {
+				t.Errorf("digest %s is not over the returned content", first.ContentDigest)
+			}
+		})
+	}
+}
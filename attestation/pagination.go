@@ -0,0 +1,136 @@
+package attestation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxPages bounds pagination when DownloadOptions.MaxPages is not set
+const defaultMaxPages = 100
+
+// PagePlaceholder in a URL is replaced with the page number (from 1) when following pagination,
+// e.g. https://example.com/items?page={page}
+const PagePlaceholder = "{page}"
+
+// downloadPages downloads sourceURL and every page linked from it with a Link rel="next" header,
+// concatenating the page contents into a single result whose digest covers all pages.
+// If sourceURL contains PagePlaceholder, pages 1, 2, ... are fetched instead until a page is
+// missing (404) or empty. Fetch metadata such as the content type and TLS details is taken from the first page.
+func downloadPages(ctx context.Context, sourceURL string, opts DownloadOptions) (*DownloadResult, error) {
+	maxPages := opts.MaxPages
+	if maxPages <= 0 {
+		maxPages = defaultMaxPages
+	}
+
+	pageOpts := opts
+	pageOpts.FollowPagination = false
+	pageOpts.DigestOnly = false
+
+	templated := strings.Contains(sourceURL, PagePlaceholder)
+
+	var combined *DownloadResult
+	var content []byte
+	pageURL := sourceURL
+	if templated {
+		pageURL = strings.ReplaceAll(sourceURL, PagePlaceholder, "1")
+	}
+	for pageURL != "" {
+		if combined != nil && combined.PageCount >= maxPages {
+			return nil, fmt.Errorf("pagination from %s exceeded %d pages", RedactURL(sourceURL), maxPages)
+		}
+		if opts.MaxSize > 0 {
+			remaining := opts.MaxSize - int64(len(content))
+			if remaining <= 0 {
+				return nil, fmt.Errorf("%w: pages from %s are larger than %d bytes", ErrContentTooLarge, RedactURL(sourceURL), opts.MaxSize)
+			}
+			pageOpts.MaxSize = remaining
+		}
+
+		page, err := DownloadContentContext(ctx, pageURL, pageOpts)
+		var badStatus *BadStatusError
+		if templated && combined != nil && errors.As(err, &badStatus) && badStatus.Code == http.StatusNotFound {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to download page %s: %w", pageURL, err)
+		}
+		if templated && combined != nil && page.ContentSize == 0 {
+			break
+		}
+		content = append(content, page.Content...)
+
+		if combined == nil {
+			combined = page
+		}
+		combined.PageCount++
+		combined.FinalPageURL = pageURL
+		combined.PageURLs = append(combined.PageURLs, pageURL)
+		if templated {
+			pageURL = strings.ReplaceAll(sourceURL, PagePlaceholder, strconv.Itoa(combined.PageCount+1))
+		} else {
+			pageURL = page.nextPageURL
+		}
+	}
+
+	// Record the URL as single page downloads do, so verifiers comparing normalized URLs match it
+	normalized, err := NormalizeURL(sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	combined.URL = normalized
+	combined.Content = content
+	combined.ContentDigest = ContentDigest(content)
+	combined.ContentSize = int64(len(content))
+	combined.nextPageURL = ""
+	if opts.DigestOnly {
+		combined.Content = nil
+	}
+	return combined, nil
+}
+
+// nextPageURL returns the rel="next" target of a response's Link headers, resolved against the request URL
+func nextPageURL(resp *http.Response) string {
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			target, params, ok := strings.Cut(link, ";")
+			if !ok {
+				continue
+			}
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			if !hasRelNext(params) {
+				continue
+			}
+
+			next, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">"))
+			if err != nil {
+				continue
+			}
+			return resp.Request.URL.ResolveReference(next).String()
+		}
+	}
+	return ""
+}
+
+// hasRelNext reports whether Link parameters include rel="next"
+func hasRelNext(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+			continue
+		}
+		for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+			if strings.EqualFold(rel, "next") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package attestation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// pagedServer serves pages "1" to "3" both with Link rel="next" headers under /linked and by number
+// under /numbered?page=N
+func pagedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		if page > "3" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Path == "/linked" && page < "3" {
+			next := string(page[0] + 1)
+			w.Header().Add("Link", fmt.Sprintf(`</linked?page=%s>; rel="next", </linked?page=1>; rel="first"`, next))
+		}
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadPages(t *testing.T) {
+	server := pagedServer(t)
+	upper := strings.Replace(server.URL, "http://", "HTTP://", 1)
+
+	tests := []struct {
+		name        string
+		url         string
+		maxPages    int
+		wantContent string
+		wantURL     string
+		wantPages   int
+		wantErr     string
+	}{
+		{
+			name:        "Link next headers",
+			url:         server.URL + "/linked",
+			wantContent: "123",
+			wantURL:     server.URL + "/linked",
+			wantPages:   3,
+		},
+		{
+			name:        "page placeholder until not found",
+			url:         server.URL + "/numbered?page=" + PagePlaceholder,
+			wantContent: "123",
+			wantURL:     server.URL + "/numbered?page=" + PagePlaceholder,
+			wantPages:   3,
+		},
+		{
+			name:        "recorded URL is normalized",
+			url:         upper,
+			wantContent: "1",
+			wantURL:     server.URL + "/",
+			wantPages:   1,
+		},
+		{
+			name:     "too many pages",
+			url:      server.URL + "/linked",
+			maxPages: 2,
+			wantErr:  "exceeded 2 pages",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DownloadOptions{AllowHTTP: true, FollowPagination: true, MaxPages: tt.maxPages}
+			result, err := DownloadContentContext(context.Background(), tt.url, opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DownloadContentContext: %v", err)
+			}
+			if string(result.Content) != tt.wantContent {
+				t.Errorf("content = %q, want %q", result.Content, tt.wantContent)
+			}
+			if result.ContentDigest != ContentDigest([]byte(tt.wantContent)) {
+				t.Errorf("digest %s does not cover every page", result.ContentDigest)
+			}
+			if result.URL != tt.wantURL {
+				t.Errorf("URL = %s, want %s", result.URL, tt.wantURL)
+			}
+			if result.PageCount != tt.wantPages || len(result.PageURLs) != tt.wantPages {
+				t.Errorf("fetched %d pages (%q), want %d", result.PageCount, result.PageURLs, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestHasRelNext(t *testing.T) {
+	tests := []struct {
+		params string
+		want   bool
+	}{
+		{` rel="next"`, true},
+		{` rel=next`, true},
+		{` REL="Next"`, true},
+		{` rel="prev next"`, true},
+		{` title="next"; rel="last"`, false},
+		{` rel="nextpage"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := hasRelNext(tt.params); got != tt.want {
+			t.Errorf("hasRelNext(%q) = %v, want %v", tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadPagesRecordsPageURLs(t *testing.T) {
+	server := pagedServer(t)
+
+	tests := []struct {
+		name string
+		url  string
+		want []string
+	}{
+		{
+			name: "Link next headers",
+			url:  server.URL + "/linked",
+			want: []string{server.URL + "/linked", server.URL + "/linked?page=2", server.URL + "/linked?page=3"},
+		},
+		{
+			name: "page placeholder",
+			url:  server.URL + "/numbered?page=" + PagePlaceholder,
+			want: []string{server.URL + "/numbered?page=1", server.URL + "/numbered?page=2", server.URL + "/numbered?page=3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := DownloadContentContext(context.Background(), tt.url, DownloadOptions{AllowHTTP: true, FollowPagination: true})
+			if err != nil {
+				t.Fatalf("DownloadContentContext: %v", err)
+			}
+			if !slices.Equal(result.PageURLs, tt.want) {
+				t.Errorf("PageURLs = %q, want %q", result.PageURLs, tt.want)
+			}
+
+			// The fetched URLs are recorded in the signed payload
+			payload, err := CreateAttestationPayload("2024-01-01T00:00:00Z", "", nil, result.URL, result.Content, result.ContentDigest, result.ContentSize, result.PayloadOptions()...)
+			if err != nil {
+				t.Fatalf("CreateAttestationPayload: %v", err)
+			}
+			if !slices.Equal(payload.PageURLs, tt.want) {
+				t.Errorf("payload PageURLs = %q, want %q", payload.PageURLs, tt.want)
+			}
+			if payload.FinalPageURL != tt.want[len(tt.want)-1] {
+				t.Errorf("FinalPageURL = %s, want %s", payload.FinalPageURL, tt.want[len(tt.want)-1])
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package attestation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+)
+
+// Policy restricts the PK token claims of the attestations that verify, e.g. to a repository's prod environment
+type Policy struct {
+	// Claims maps IDTokenClaims JSON names, e.g. repository, actor or environment, to the values allowed for them.
+	// A claim the token does not carry has the empty value.
+	Claims map[string][]string `json:"claims"`
+}
+
+// LoadPolicy reads a JSON policy file, rejecting rules for claims IDTokenClaims does not have
+func LoadPolicy(policyFile string) (*Policy, error) {
+	data, err := os.ReadFile(policyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var policy Policy
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+
+	known, err := claimValues(&IDTokenClaims{})
+	if err != nil {
+		return nil, err
+	}
+	for claim, allowed := range policy.Claims {
+		if _, ok := known[claim]; !ok {
+			return nil, fmt.Errorf("policy has a rule for unknown claim %q", claim)
+		}
+		if len(allowed) == 0 {
+			return nil, fmt.Errorf("policy rule for claim %q allows no values", claim)
+		}
+	}
+	return &policy, nil
+}
+
+// Evaluate checks claims against every rule of the policy, returning one error per unsatisfied rule in claim
+// name order
+func (p *Policy) Evaluate(claims *IDTokenClaims) ([]string, error) {
+	values, err := claimValues(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(p.Claims))
+	for claim := range p.Claims {
+		names = append(names, claim)
+	}
+	sort.Strings(names)
+
+	var violations []string
+	for _, claim := range names {
+		allowed := p.Claims[claim]
+		if !slices.Contains(allowed, values[claim]) {
+			violations = append(violations, fmt.Sprintf("claim %s %q is not one of %q", claim, values[claim], allowed))
+		}
+	}
+	return violations, nil
+}
+
+// claimValues returns every claim by its JSON name, formatted as a string
+func claimValues(claims *IDTokenClaims) (map[string]string, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal claims: %w", err)
+	}
+	// Numbers such as iat are kept as written rather than formatted as floats
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("failed to parse claims: %w", err)
+	}
+
+	values := make(map[string]string, len(fields))
+	for name, value := range fields {
+		values[name] = fmt.Sprint(value)
+	}
+	return values, nil
+}
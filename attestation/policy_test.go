@@ -0,0 +1,156 @@
+package attestation
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    *Policy
+		wantErr string
+	}{
+		{
+			name:    "claim allowlists",
+			content: `{"claims": {"repository": ["owner/repo"], "environment": ["prod", "staging"]}}`,
+			want:    &Policy{Claims: map[string][]string{"repository": {"owner/repo"}, "environment": {"prod", "staging"}}},
+		},
+		{name: "no rules", content: `{}`, want: &Policy{}},
+		{name: "unknown claim", content: `{"claims": {"repo": ["owner/repo"]}}`, wantErr: `policy has a rule for unknown claim "repo"`},
+		{name: "rule allowing nothing", content: `{"claims": {"actor": []}}`, wantErr: `policy rule for claim "actor" allows no values`},
+		{name: "unknown field", content: `{"claims": {}, "issuers": ["x"]}`, wantErr: "failed to parse policy"},
+		{name: "not JSON", content: `claims: {}`, wantErr: "failed to parse policy"},
+		{name: "missing file", wantErr: "failed to read policy file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_")+".json")
+			if tt.content != "" {
+				writeTestFile(t, path, tt.content)
+			}
+			policy, err := LoadPolicy(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPolicy: %v", err)
+			}
+			if !reflect.DeepEqual(policy, tt.want) {
+				t.Errorf("LoadPolicy = %+v, want %+v", policy, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	claims := &IDTokenClaims{
+		IAT:         1700000000,
+		Repository:  "owner/repo",
+		Actor:       "octocat",
+		Environment: "prod",
+	}
+	tests := []struct {
+		name  string
+		rules map[string][]string
+		want  []string
+	}{
+		{name: "no rules"},
+		{name: "allowed value", rules: map[string][]string{"repository": {"owner/repo"}}},
+		{name: "one of several allowed values", rules: map[string][]string{"environment": {"staging", "prod"}}},
+		{name: "numeric claim", rules: map[string][]string{"iat": {"1700000000"}}},
+		{name: "missing claim allowed empty", rules: map[string][]string{"ref_type": {"", "branch"}}},
+		{
+			name:  "disallowed value",
+			rules: map[string][]string{"actor": {"hubot"}},
+			want:  []string{`claim actor "octocat" is not one of ["hubot"]`},
+		},
+		{
+			name:  "missing claim",
+			rules: map[string][]string{"ref_type": {"branch"}},
+			want:  []string{`claim ref_type "" is not one of ["branch"]`},
+		},
+		{
+			name:  "every failing rule in claim order",
+			rules: map[string][]string{"repository": {"owner/other"}, "environment": {"staging"}, "actor": {"octocat"}},
+			want: []string{
+				`claim environment "prod" is not one of ["staging"]`,
+				`claim repository "owner/repo" is not one of ["owner/other"]`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := (&Policy{Claims: tt.rules}).Evaluate(claims)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if !reflect.DeepEqual(violations, tt.want) {
+				t.Errorf("Evaluate = %q, want %q", violations, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyPolicy(t *testing.T) {
+	op := newTestOP(t)
+	download := testDownload("https://example.com/", []byte("content"))
+	prodOnly := &Policy{Claims: map[string][]string{"repository": {"owner/repo"}, "environment": {"prod"}}}
+
+	tests := []struct {
+		name           string
+		claims         map[string]any
+		policy         *Policy
+		wantVerified   bool
+		wantViolations []string
+	}{
+		{name: "no policy"},
+		{name: "prod environment", claims: map[string]any{"environment": "prod"}, policy: prodOnly, wantVerified: true},
+		{
+			name:           "staging environment",
+			claims:         map[string]any{"environment": "staging"},
+			policy:         prodOnly,
+			wantViolations: []string{`claim environment "staging" is not one of ["prod"]`},
+		},
+		{
+			name:           "no environment",
+			policy:         prodOnly,
+			wantViolations: []string{`claim environment "" is not one of ["prod"]`},
+		},
+		{
+			name:           "another repository",
+			claims:         map[string]any{"environment": "prod", "repository": "owner/fork"},
+			policy:         prodOnly,
+			wantViolations: []string{`claim repository "owner/fork" is not one of ["owner/repo"]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := op.attestWithClaims(t, tt.claims, download, nil)
+			opts := op.verifyOptions()
+			opts.Policy = tt.policy
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.PolicyVerified != tt.wantVerified {
+				t.Errorf("PolicyVerified = %v, want %v (errors %q)", result.PolicyVerified, tt.wantVerified, result.Errors)
+			}
+			if !reflect.DeepEqual(result.PolicyViolations, tt.wantViolations) {
+				t.Errorf("PolicyViolations = %q, want %q", result.PolicyViolations, tt.wantViolations)
+			}
+			for _, violation := range tt.wantViolations {
+				if !hasError(result, "Policy verification failed: "+violation) {
+					t.Errorf("expected error for %s, got %q", violation, result.Errors)
+				}
+			}
+			if result.IsVerificationSuccessful() != (len(tt.wantViolations) == 0) {
+				t.Errorf("IsVerificationSuccessful = %v with violations %q", result.IsVerificationSuccessful(), tt.wantViolations)
+			}
+		})
+	}
+}
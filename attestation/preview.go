@@ -0,0 +1,68 @@
+package attestation
+
+import (
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Content preview limits
+const (
+	contentPreviewLength    = 200
+	contentPreviewHexLength = 16
+)
+
+// ContentPreview returns a short, printable preview of content for diagnosing failed verifications. Text is
+// quoted and truncated to 200 bytes; binary content, or text of a non-textual content type, is described
+// by its size and leading bytes in hex. The content is untrusted until verification succeeds.
+func ContentPreview(content []byte, contentType string) string {
+	if len(content) == 0 {
+		return "empty content"
+	}
+	if !isTextual(content, contentType) {
+		head := content[:min(len(content), contentPreviewHexLength)]
+		return fmt.Sprintf("binary content, %d bytes, starting %s", len(content), hex.EncodeToString(head))
+	}
+
+	preview := content
+	if len(preview) > contentPreviewLength {
+		// Back off to a rune boundary so the truncated text stays valid UTF-8
+		cut := contentPreviewLength
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		preview = content[:cut]
+	}
+	quoted := strconv.Quote(string(preview))
+	if len(preview) < len(content) {
+		return fmt.Sprintf("%s… (%d bytes)", quoted, len(content))
+	}
+	return quoted
+}
+
+// isTextual reports whether content should be previewed as text: it must be valid UTF-8 and, when a
+// content type is known, be text/* or a JSON, XML, YAML or JavaScript type
+func isTextual(content []byte, contentType string) bool {
+	if !utf8.Valid(content) {
+		return false
+	}
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	for _, suffix := range []string{"json", "xml", "yaml", "javascript"} {
+		if strings.HasSuffix(mediaType, "/"+suffix) || strings.HasSuffix(mediaType, "+"+suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package attestation
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestContentPreview(t *testing.T) {
+	long := strings.Repeat("a", contentPreviewLength+50)
+	// A multi-byte rune straddling the cut is dropped rather than split
+	straddling := strings.Repeat("a", contentPreviewLength-1) + "é" + "tail"
+
+	tests := []struct {
+		name        string
+		content     []byte
+		contentType string
+		want        string
+	}{
+		{name: "empty", content: nil, want: "empty content"},
+		{name: "short text", content: []byte(`{"keys":[]}`), contentType: "application/json", want: `"{\"keys\":[]}"`},
+		{name: "control characters are escaped", content: []byte("line1\nline2\x1b[31m"), want: `"line1\nline2\x1b[31m"`},
+		{name: "text without a content type", content: []byte("plain"), want: `"plain"`},
+		{name: "structured syntax suffix", content: []byte("{}"), contentType: "application/jwk-set+json", want: `"{}"`},
+		{name: "text with parameters", content: []byte("<a/>"), contentType: "text/html; charset=utf-8", want: `"<a/>"`},
+		{
+			name:    "long text is truncated",
+			content: []byte(long),
+			want:    `"` + long[:contentPreviewLength] + `"… (250 bytes)`,
+		},
+		{
+			name:    "truncated on a rune boundary",
+			content: []byte(straddling),
+			want:    `"` + straddling[:contentPreviewLength-1] + `"… (205 bytes)`,
+		},
+		{
+			name:    "invalid UTF-8",
+			content: []byte{0xff, 0xfe, 'a'},
+			want:    "binary content, 3 bytes, starting fffe61",
+		},
+		{
+			name:        "text of a binary content type",
+			content:     []byte(long),
+			contentType: "application/octet-stream",
+			want:        "binary content, 250 bytes, starting 61616161616161616161616161616161",
+		},
+		{
+			name:        "malformed content type",
+			content:     []byte("text"),
+			contentType: "text/",
+			want:        "binary content, 4 bytes, starting 74657874",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ContentPreview(tt.content, tt.contentType)
+			if got != tt.want {
+				t.Errorf("ContentPreview = %s, want %s", got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("preview %q is not valid UTF-8", got)
+			}
+		})
+	}
+}
+
+func TestVerifyContentPreview(t *testing.T) {
+	op := newTestOP(t)
+	download := testDownload("https://example.com/", []byte("content"))
+
+	// A verified attestation keeps its preview out of the summary
+	result := verifyTestAttestation(t, op.attest(t, download, nil), op.verifyOptions())
+	if strings.Contains(result.GetSummary(), "Content preview") {
+		t.Errorf("summary of a verified attestation shows a preview:\n%s", result.GetSummary())
+	}
+
+	// A failed one shows it, marked untrusted, to help diagnose the failure
+	attestation := op.attest(t, download, nil)
+	attestation.Payload.Content = []byte("tampered")
+	result = verifyTestAttestation(t, attestation, op.verifyOptions())
+	if result.ContentPreview != `"tampered"` {
+		t.Errorf("ContentPreview = %s, want the stored content", result.ContentPreview)
+	}
+	if summary := result.GetSummary(); !strings.Contains(summary, `Content preview (untrusted): "tampered"`) {
+		t.Errorf("summary of a failed verification has no preview:\n%s", summary)
+	}
+}
@@ -0,0 +1,83 @@
+package attestation
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/openpubkey/openpubkey/discover"
+	"github.com/openpubkey/openpubkey/providers"
+	"github.com/openpubkey/openpubkey/verifier"
+)
+
+// Supported OIDC providers
+const (
+	ProviderGithub = "github"
+	ProviderGitlab = "gitlab"
+)
+
+const (
+	gitlabIssuer = "https://gitlab.com"
+)
+
+// ProviderIssuer returns the default OIDC issuer for the named provider
+func ProviderIssuer(provider string) (string, error) {
+	switch provider {
+	case ProviderGithub:
+		return githubIssuer, nil
+	case ProviderGitlab:
+		return gitlabIssuer, nil
+	default:
+		return "", fmt.Errorf("unsupported provider: %s", provider)
+	}
+}
+
+// NewProviderVerifier creates a PK token verifier for the named provider and issuer.
+// If issuer is empty the provider's default issuer is used.
+func NewProviderVerifier(provider string, issuer string) (verifier.ProviderVerifier, error) {
+	return newProviderVerifier(provider, issuer, nil)
+}
+
+// newProviderVerifier creates a PK token verifier that looks up OP keys with keyFinder,
+// or the issuer's published JWKS when keyFinder is nil
+func newProviderVerifier(provider string, issuer string, keyFinder *discover.PublicKeyFinder) (verifier.ProviderVerifier, error) {
+	if issuer == "" {
+		var err error
+		if issuer, err = ProviderIssuer(provider); err != nil {
+			return nil, err
+		}
+	}
+
+	// Mirror the checks the openpubkey providers perform, without needing their token request configuration
+	switch provider {
+	case ProviderGithub:
+		return providers.NewProviderVerifier(issuer, providers.ProviderVerifierOpts{
+			CommitType:        providers.CommitTypesEnum.AUD_CLAIM,
+			GQOnly:            true,
+			SkipClientIDCheck: true,
+			DiscoverPublicKey: keyFinder,
+		}), nil
+	case ProviderGitlab:
+		return providers.NewProviderVerifier(issuer, providers.ProviderVerifierOpts{
+			CommitType:        providers.CommitTypesEnum.GQ_BOUND,
+			GQOnly:            true,
+			SkipClientIDCheck: true,
+			DiscoverPublicKey: keyFinder,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+}
+
+// jwksFileFinder returns a public key finder that reads the OP's JWKS from a local file
+func jwksFileFinder(jwksPath string) *discover.PublicKeyFinder {
+	return &discover.PublicKeyFinder{
+		JwksFunc: func(ctx context.Context, issuer string) ([]byte, error) {
+			jwks, err := os.ReadFile(jwksPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+			}
+			return jwks, nil
+		},
+	}
+}
@@ -0,0 +1,85 @@
+package attestation
+
+import (
+	"testing"
+)
+
+const testGitlabConfigRef = "gitlab.com/group/project//.gitlab-ci.yml@refs/heads/main"
+
+// gitlabClaims are GitLab CI ID token claims, added to the test OP's defaults
+func gitlabClaims() map[string]any {
+	return map[string]any{
+		"ci_config_ref_uri": testGitlabConfigRef,
+		"ci_config_sha":     testWorkflowSHA,
+		"pipeline_id":       "42",
+		"project_path":      "group/project",
+		"namespace_path":    "group",
+		"pipeline_source":   "push",
+	}
+}
+
+func TestProviderIssuer(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     string
+		wantErr  bool
+	}{
+		{provider: ProviderGithub, want: githubIssuer},
+		{provider: ProviderGitlab, want: gitlabIssuer},
+		{provider: "bitbucket", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			issuer, err := ProviderIssuer(tt.provider)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for provider %s", tt.provider)
+				}
+				if _, err := NewProviderVerifier(tt.provider, ""); err == nil {
+					t.Errorf("expected NewProviderVerifier to reject provider %s", tt.provider)
+				}
+				return
+			}
+			if err != nil || issuer != tt.want {
+				t.Fatalf("ProviderIssuer = %q, %v; want %q", issuer, err, tt.want)
+			}
+			if _, err := NewProviderVerifier(tt.provider, ""); err != nil {
+				t.Errorf("NewProviderVerifier: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyGitlabAttestation(t *testing.T) {
+	op := newTestOP(t)
+	pkToken, signer := op.pkToken(t, gitlabClaims())
+	attestation, err := BuildAttestation(pkToken, signer, GitlabExtractor{}, testDownload("https://example.com/", []byte("content")), nil)
+	if err != nil {
+		t.Fatalf("BuildAttestation: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		provider    string
+		workflowRef string
+		wantOK      bool
+	}{
+		{name: "GitLab provider and config ref", provider: ProviderGitlab, workflowRef: testGitlabConfigRef, wantOK: true},
+		{name: "GitLab provider and another config ref", provider: ProviderGitlab, workflowRef: "gitlab.com/group/other//.gitlab-ci.yml@refs/heads/main"},
+		{name: "GitHub provider ignores the GitLab config ref", provider: ProviderGithub, workflowRef: testGitlabConfigRef},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := op.verifyOptions()
+			opts.Provider = tt.provider
+			opts.ExpectedWorkflowRef = tt.workflowRef
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.IsVerificationSuccessful() != tt.wantOK {
+				t.Errorf("verified = %v, want %v (errors %q)", result.IsVerificationSuccessful(), tt.wantOK, result.Errors)
+			}
+			if !result.PKTokenVerified {
+				t.Errorf("PKTokenVerified = false (errors %q)", result.Errors)
+			}
+		})
+	}
+}
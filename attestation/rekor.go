@@ -0,0 +1,137 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// rekorEntriesPath is the Rekor API path log entries are submitted to
+const rekorEntriesPath = "/api/v1/log/entries"
+
+// RekorEntry records where an attestation was entered in a Rekor transparency log
+type RekorEntry struct {
+	LogURL         string `json:"log_url"`
+	UUID           string `json:"uuid"`
+	LogIndex       int64  `json:"log_index"`
+	LogID          string `json:"log_id"`
+	IntegratedTime int64  `json:"integrated_time"`
+}
+
+type rekorHashedRekord struct {
+	APIVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Spec       rekorHashedRekordSpec `json:"spec"`
+}
+
+type rekorHashedRekordSpec struct {
+	Signature rekorSignature `json:"signature"`
+	Data      rekorData      `json:"data"`
+}
+
+type rekorSignature struct {
+	Content   string         `json:"content"`
+	PublicKey rekorPublicKey `json:"publicKey"`
+}
+
+type rekorPublicKey struct {
+	Content string `json:"content"`
+}
+
+type rekorData struct {
+	Hash rekorHash `json:"hash"`
+}
+
+type rekorHash struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"value"`
+}
+
+// rekorLogEntry is a log entry as returned by the Rekor API, keyed by its UUID
+type rekorLogEntry struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// SubmitToRekor enters artifact in the Rekor transparency log at rekorURL as a hashedrekord entry, signed
+// by signer. For attestations the artifact is the signed payload digest and the signer is the ephemeral key
+// bound to the PK token, so the entry can be tied back to the attestation.
+func SubmitToRekor(ctx context.Context, rekorURL string, artifact []byte, signer crypto.Signer) (*RekorEntry, error) {
+	digest := sha256.Sum256(artifact)
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign Rekor entry: %w", err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+
+	body, err := json.Marshal(rekorHashedRekord{
+		APIVersion: "0.0.1",
+		Kind:       "hashedrekord",
+		Spec: rekorHashedRekordSpec{
+			Signature: rekorSignature{
+				Content:   base64.StdEncoding.EncodeToString(signature),
+				PublicKey: rekorPublicKey{Content: base64.StdEncoding.EncodeToString(publicKeyPEM)},
+			},
+			Data: rekorData{Hash: rekorHash{Algorithm: "sha256", Value: hex.EncodeToString(digest[:])}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Rekor entry: %w", err)
+	}
+
+	logURL := strings.TrimSuffix(rekorURL, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, logURL+rekorEntriesPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Rekor request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit to Rekor: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to submit to Rekor: %w", newBadStatusError(resp, defaultMaxBodySnippet))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Rekor response: %w", err)
+	}
+	var entries map[string]rekorLogEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse Rekor response: %w", err)
+	}
+	if len(entries) != 1 {
+		return nil, fmt.Errorf("expected one Rekor log entry, got %d", len(entries))
+	}
+	for uuid, entry := range entries {
+		return &RekorEntry{
+			LogURL:         logURL,
+			UUID:           uuid,
+			LogIndex:       entry.LogIndex,
+			LogID:          entry.LogID,
+			IntegratedTime: entry.IntegratedTime,
+		}, nil
+	}
+	return nil, nil
+}
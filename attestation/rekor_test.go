@@ -0,0 +1,578 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// checkHashedRekord checks a submitted hashedrekord entry is well-formed and its signature verifies
+func checkHashedRekord(body []byte) (*rekorHashedRekord, error) {
+	var entry rekorHashedRekord
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Kind != "hashedrekord" || entry.APIVersion != "0.0.1" || entry.Spec.Data.Hash.Algorithm != "sha256" {
+		return nil, errors.New("unexpected entry kind")
+	}
+	keyPEM, err := base64.StdEncoding.DecodeString(entry.Spec.Signature.PublicKey.Content)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("public key is not PEM")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := hex.DecodeString(entry.Spec.Data.Hash.Value)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.StdEncoding.DecodeString(entry.Spec.Signature.Content)
+	if err != nil {
+		return nil, err
+	}
+	if !ecdsa.VerifyASN1(publicKey.(*ecdsa.PublicKey), digest, signature) {
+		return nil, errors.New("signature does not verify")
+	}
+	return &entry, nil
+}
+
+func TestSubmitToRekor(t *testing.T) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	artifact := []byte("signed payload digest")
+	artifactDigest := sha256.Sum256(artifact)
+	created := `{"24296fb24b8ad77a":{"body":"e30=","integratedTime":1700000000,"logID":"c0d23d6a","logIndex":42}}`
+
+	tests := []struct {
+		name      string
+		trailing  bool
+		status    int
+		response  string
+		want      *RekorEntry
+		wantErr   string
+		badStatus int
+	}{
+		{
+			name:     "created",
+			status:   http.StatusCreated,
+			response: created,
+			want:     &RekorEntry{UUID: "24296fb24b8ad77a", LogIndex: 42, LogID: "c0d23d6a", IntegratedTime: 1700000000},
+		},
+		{
+			name:     "log URL with a trailing slash",
+			trailing: true,
+			status:   http.StatusCreated,
+			response: created,
+			want:     &RekorEntry{UUID: "24296fb24b8ad77a", LogIndex: 42, LogID: "c0d23d6a", IntegratedTime: 1700000000},
+		},
+		{
+			name:      "entry already exists",
+			status:    http.StatusConflict,
+			response:  `{"code":409,"message":"an equivalent entry already exists"}`,
+			wantErr:   "failed to submit to Rekor",
+			badStatus: http.StatusConflict,
+		},
+		{
+			name:      "server error",
+			status:    http.StatusInternalServerError,
+			response:  `{"code":500}`,
+			wantErr:   "failed to submit to Rekor",
+			badStatus: http.StatusInternalServerError,
+		},
+		{
+			name:      "OK instead of created",
+			status:    http.StatusOK,
+			response:  created,
+			wantErr:   "failed to submit to Rekor",
+			badStatus: http.StatusOK,
+		},
+		{name: "malformed response", status: http.StatusCreated, response: `[`, wantErr: "failed to parse Rekor response"},
+		{name: "no entries", status: http.StatusCreated, response: `{}`, wantErr: "expected one Rekor log entry, got 0"},
+		{
+			name:     "several entries",
+			status:   http.StatusCreated,
+			response: `{"a":{"logIndex":1},"b":{"logIndex":2}}`,
+			wantErr:  "expected one Rekor log entry, got 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var submitted *rekorHashedRekord
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != rekorEntriesPath || r.Header.Get("Content-Type") != "application/json" {
+					http.Error(w, "unexpected request", http.StatusBadRequest)
+					return
+				}
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				entry, err := checkHashedRekord(body)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				submitted = entry
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			rekorURL := server.URL
+			if tt.trailing {
+				rekorURL += "/"
+			}
+			entry, err := SubmitToRekor(context.Background(), rekorURL, artifact, signer)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				var badStatus *BadStatusError
+				if tt.badStatus != 0 && (!errors.As(err, &badStatus) || badStatus.Code != tt.badStatus) {
+					t.Errorf("expected a %d BadStatusError, got %v", tt.badStatus, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SubmitToRekor: %v", err)
+			}
+			tt.want.LogURL = server.URL
+			if *entry != *tt.want {
+				t.Errorf("entry = %+v, want %+v", entry, tt.want)
+			}
+			if submitted == nil || submitted.Spec.Data.Hash.Value != hex.EncodeToString(artifactDigest[:]) {
+				t.Errorf("submitted entry %+v is not for the artifact digest", submitted)
+			}
+		})
+	}
+
+	t.Run("unreachable log", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		if _, err := SubmitToRekor(context.Background(), server.URL, artifact, signer); err == nil || !strings.Contains(err.Error(), "failed to submit to Rekor") {
+			t.Fatalf("expected a submission error, got %v", err)
+		}
+	})
+}
+
+// mockRekorLog is a Rekor log serving hashedrekord entries with RFC 6962 inclusion proofs and checkpoints
+// signed by key
+type mockRekorLog struct {
+	*httptest.Server
+	key     crypto.Signer
+	bodies  [][]byte
+	uuids   []string
+	tamper  func(entry *rekorProvedEntry)
+	failing bool
+}
+
+func newMockRekorLog(t *testing.T, key crypto.Signer) *mockRekorLog {
+	t.Helper()
+	log := &mockRekorLog{key: key}
+	log.Server = httptest.NewServer(http.HandlerFunc(log.serve))
+	t.Cleanup(log.Close)
+	return log
+}
+
+// add enters a hashedrekord entry for artifact uploaded by signer, returning its uuid
+func (l *mockRekorLog) add(t *testing.T, artifact []byte, signer crypto.PublicKey) string {
+	t.Helper()
+	digest := sha256.Sum256(artifact)
+	var entry rekorHashedRekord
+	entry.APIVersion, entry.Kind = "0.0.1", "hashedrekord"
+	entry.Spec.Data.Hash = rekorHash{Algorithm: "sha256", Value: hex.EncodeToString(digest[:])}
+	if signer != nil {
+		publicKey, err := x509.MarshalPKIXPublicKey(signer)
+		if err != nil {
+			t.Fatalf("failed to marshal signer key: %v", err)
+		}
+		publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+		entry.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString(publicKeyPEM)
+	}
+	body, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("failed to marshal log entry: %v", err)
+	}
+	uuid := hex.EncodeToString(merkleLeafHash(body))
+	l.bodies = append(l.bodies, body)
+	l.uuids = append(l.uuids, uuid)
+	return uuid
+}
+
+func (l *mockRekorLog) serve(w http.ResponseWriter, r *http.Request) {
+	if l.failing {
+		http.Error(w, "log unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == rekorIndexPath:
+		var query struct {
+			Hash string `json:"hash"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		uuids := []string{}
+		for i, body := range l.bodies {
+			var entry rekorHashedRekord
+			if json.Unmarshal(body, &entry) == nil && "sha256:"+entry.Spec.Data.Hash.Value == query.Hash {
+				uuids = append(uuids, l.uuids[i])
+			}
+		}
+		json.NewEncoder(w).Encode(uuids)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, rekorEntriesPath+"/"):
+		uuid := strings.TrimPrefix(r.URL.Path, rekorEntriesPath+"/")
+		for i := range l.uuids {
+			if l.uuids[i] != uuid {
+				continue
+			}
+			entry, err := l.provedEntry(i)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if l.tamper != nil {
+				l.tamper(entry)
+			}
+			json.NewEncoder(w).Encode(map[string]*rekorProvedEntry{uuid: entry})
+			return
+		}
+		http.NotFound(w, r)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+// provedEntry returns the entry at index with its inclusion proof in the whole log
+func (l *mockRekorLog) provedEntry(index int) (*rekorProvedEntry, error) {
+	leaves := make([][]byte, len(l.bodies))
+	for i, body := range l.bodies {
+		leaves[i] = merkleLeafHash(body)
+	}
+	root := testMerkleRoot(leaves)
+	checkpoint, err := l.checkpoint(len(leaves), root)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	for _, hash := range testInclusionPath(index, leaves) {
+		hashes = append(hashes, hex.EncodeToString(hash))
+	}
+	entry := &rekorProvedEntry{rekorLogEntry: rekorLogEntry{
+		Body:           base64.StdEncoding.EncodeToString(l.bodies[index]),
+		IntegratedTime: 1700000000,
+		LogID:          "c0d23d6a",
+		LogIndex:       int64(index),
+	}}
+	entry.Verification.InclusionProof = &rekorInclusionProof{
+		Checkpoint: checkpoint,
+		Hashes:     hashes,
+		LogIndex:   int64(index),
+		RootHash:   hex.EncodeToString(root),
+		TreeSize:   int64(len(leaves)),
+	}
+	return entry, nil
+}
+
+// checkpoint returns a signed note committing to root at size
+func (l *mockRekorLog) checkpoint(size int, root []byte) (string, error) {
+	text := fmt.Sprintf("rekor.example.com - 1\n%d\n%s\n", size, base64.StdEncoding.EncodeToString(root))
+	var signature []byte
+	var err error
+	if key, ok := l.key.(ed25519.PrivateKey); ok {
+		signature = ed25519.Sign(key, []byte(text))
+	} else {
+		digest := sha256.Sum256([]byte(text))
+		signature, err = l.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			return "", err
+		}
+	}
+	keyHint := []byte{0xc0, 0xd2, 0x3d, 0x6a}
+	return text + "\n— rekor.example.com " + base64.StdEncoding.EncodeToString(append(keyHint, signature...)) + "\n", nil
+}
+
+// testMerkleRoot computes the RFC 6962 root of leaves by definition
+func testMerkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	split := testMerkleSplit(len(leaves))
+	return merkleNodeHash(testMerkleRoot(leaves[:split]), testMerkleRoot(leaves[split:]))
+}
+
+// testInclusionPath computes the RFC 6962 audit path of the leaf at index by definition
+func testInclusionPath(index int, leaves [][]byte) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	split := testMerkleSplit(len(leaves))
+	if index < split {
+		return append(testInclusionPath(index, leaves[:split]), testMerkleRoot(leaves[split:]))
+	}
+	return append(testInclusionPath(index-split, leaves[split:]), testMerkleRoot(leaves[:split]))
+}
+
+// testMerkleSplit returns the largest power of two smaller than n
+func testMerkleSplit(n int) int {
+	split := 1
+	for split*2 < n {
+		split *= 2
+	}
+	return split
+}
+
+func TestRootFromInclusionProof(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		leaves := make([][]byte, size)
+		for i := range leaves {
+			leaves[i] = merkleLeafHash([]byte{byte(i)})
+		}
+		root := testMerkleRoot(leaves)
+		for index := 0; index < size; index++ {
+			computed, err := rootFromInclusionProof(uint64(index), uint64(size), leaves[index], testInclusionPath(index, leaves))
+			if err != nil {
+				t.Fatalf("rootFromInclusionProof(%d, %d): %v", index, size, err)
+			}
+			if !bytes.Equal(computed, root) {
+				t.Errorf("rootFromInclusionProof(%d, %d) = %x, want %x", index, size, computed, root)
+			}
+		}
+	}
+
+	leaves := [][]byte{merkleLeafHash([]byte{0}), merkleLeafHash([]byte{1}), merkleLeafHash([]byte{2})}
+	tests := []struct {
+		name    string
+		index   uint64
+		size    uint64
+		proof   [][]byte
+		wantErr string
+	}{
+		{name: "index outside the tree", index: 3, size: 3, wantErr: "inclusion proof index 3 is outside a tree of size 3"},
+		{name: "empty tree", index: 0, size: 0, wantErr: "is outside a tree of size 0"},
+		{name: "short proof", index: 0, size: 3, proof: testInclusionPath(0, leaves)[:1], wantErr: "inclusion proof has 1 hashes, expected 2"},
+		{name: "long proof", index: 2, size: 3, proof: append(testInclusionPath(2, leaves), leaves[0]), wantErr: "inclusion proof has 2 hashes, expected 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rootFromInclusionProof(tt.index, tt.size, leaves[0], tt.proof); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRekorInclusion(t *testing.T) {
+	logKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	edPublicKey, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	artifact := []byte("signed payload digest")
+
+	tests := []struct {
+		name      string
+		key       crypto.Signer
+		publicKey crypto.PublicKey
+		uploader  crypto.PublicKey
+		anonymous bool
+		logged    bool
+		byHash    bool
+		uuid      string
+		tamper    func(entry *rekorProvedEntry)
+		failing   bool
+		wantErr   string
+		notFound  bool
+	}{
+		{name: "by uuid", logged: true},
+		{name: "by artifact hash", logged: true, byHash: true},
+		{name: "ed25519 log key", key: edKey, publicKey: edPublicKey, logged: true},
+		{name: "not in log", byHash: true, notFound: true},
+		{name: "unknown uuid", uuid: "0000", notFound: true},
+		{
+			name:   "tampered proof hash",
+			logged: true,
+			tamper: func(entry *rekorProvedEntry) {
+				entry.Verification.InclusionProof.Hashes[0] = hex.EncodeToString(make([]byte, 32))
+			},
+			wantErr: "inclusion proof does not lead to root hash",
+		},
+		{
+			name:   "truncated proof",
+			logged: true,
+			tamper: func(entry *rekorProvedEntry) {
+				entry.Verification.InclusionProof.Hashes = entry.Verification.InclusionProof.Hashes[1:]
+			},
+			wantErr: "inclusion proof has",
+		},
+		{
+			name:    "proof for another index",
+			logged:  true,
+			tamper:  func(entry *rekorProvedEntry) { entry.Verification.InclusionProof.LogIndex = 0 },
+			wantErr: "inclusion proof does not lead to root hash",
+		},
+		{
+			name:    "no inclusion proof",
+			logged:  true,
+			tamper:  func(entry *rekorProvedEntry) { entry.Verification.InclusionProof = nil },
+			wantErr: "has no inclusion proof",
+		},
+		{
+			name: "entry for another artifact",
+			tamper: func(entry *rekorProvedEntry) {
+				entry.Body = base64.StdEncoding.EncodeToString([]byte(`{"kind":"hashedrekord","spec":{"data":{"hash":{"value":"00"}}}}`))
+			},
+			logged:  true,
+			wantErr: "is not for this attestation",
+		},
+		{
+			name:   "checkpoint for another tree",
+			logged: true,
+			tamper: func(entry *rekorProvedEntry) {
+				entry.Verification.InclusionProof.Checkpoint = "rekor.example.com - 1\n1\nAAAA\n\n"
+			},
+			wantErr: "checkpoint does not match the inclusion proof's tree",
+		},
+		{
+			name:    "malformed checkpoint",
+			logged:  true,
+			tamper:  func(entry *rekorProvedEntry) { entry.Verification.InclusionProof.Checkpoint = "unsigned" },
+			wantErr: "malformed checkpoint",
+		},
+		{name: "checkpoint signed by another key", publicKey: &otherKey.PublicKey, logged: true, wantErr: "checkpoint is not signed by the transparency log key"},
+		{name: "log unavailable", logged: true, failing: true, wantErr: "failed to query transparency log"},
+		{name: "entry uploaded by another signer", uploader: &otherKey.PublicKey, logged: true, wantErr: "is not signed by the attestation's signer"},
+		{name: "entry without a public key", anonymous: true, logged: true, wantErr: "is not signed by the attestation's signer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, publicKey := tt.key, tt.publicKey
+			if key == nil {
+				key = logKey
+			}
+			if publicKey == nil {
+				publicKey = key.Public()
+			}
+			log := newMockRekorLog(t, key)
+			// Entries around the artifact's make its proof span both sides of an unbalanced tree
+			for i := 0; i < 5; i++ {
+				log.add(t, []byte(fmt.Sprintf("other artifact %d", i)), &otherKey.PublicKey)
+			}
+			uploader := tt.uploader
+			if uploader == nil && !tt.anonymous {
+				uploader = &signerKey.PublicKey
+			}
+			uuid := tt.uuid
+			if tt.logged {
+				uuid = log.add(t, artifact, uploader)
+			}
+			log.add(t, []byte("later artifact"), &signerKey.PublicKey)
+			if tt.byHash {
+				uuid = ""
+			}
+			log.tamper, log.failing = tt.tamper, tt.failing
+
+			err := VerifyRekorInclusion(context.Background(), RekorInclusion{
+				LogURL:    log.URL + "/",
+				UUID:      uuid,
+				Artifact:  artifact,
+				SignerKey: &signerKey.PublicKey,
+				LogKey:    publicKey,
+			})
+			switch {
+			case tt.notFound:
+				if !errors.Is(err, ErrNotInTransparencyLog) {
+					t.Fatalf("expected ErrNotInTransparencyLog, got %v", err)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+			case err != nil:
+				t.Fatalf("VerifyRekorInclusion: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadRekorPublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "PEM public key", content: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))},
+		{name: "not PEM", content: "not a key", wantErr: "no PEM public key found"},
+		{name: "not a public key", content: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("junk")})), wantErr: "failed to parse transparency log public key"},
+		{name: "missing file", wantErr: "failed to read transparency log public key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_")+".pem")
+			if tt.content != "" {
+				writeTestFile(t, path, tt.content)
+			}
+			publicKey, err := LoadRekorPublicKey(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadRekorPublicKey: %v", err)
+			}
+			if !key.PublicKey.Equal(publicKey) {
+				t.Errorf("LoadRekorPublicKey returned a different key")
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// rekorIndexPath is the Rekor API path entries are searched by artifact hash on
+const rekorIndexPath = "/api/v1/index/retrieve"
+
+// ErrNotInTransparencyLog is returned when the transparency log has no entry for an attestation
+var ErrNotInTransparencyLog = errors.New("attestation not found in transparency log")
+
+type rekorInclusionProof struct {
+	Checkpoint string   `json:"checkpoint"`
+	Hashes     []string `json:"hashes"`
+	LogIndex   int64    `json:"logIndex"`
+	RootHash   string   `json:"rootHash"`
+	TreeSize   int64    `json:"treeSize"`
+}
+
+type rekorVerification struct {
+	InclusionProof *rekorInclusionProof `json:"inclusionProof"`
+}
+
+// rekorProvedEntry is a log entry with its inclusion proof, as returned when fetching an entry
+type rekorProvedEntry struct {
+	rekorLogEntry
+	Verification rekorVerification `json:"verification"`
+}
+
+// LoadRekorPublicKey reads a transparency log's PEM public key
+func LoadRekorPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transparency log public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM public key found in %s", path)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transparency log public key: %w", err)
+	}
+	return publicKey, nil
+}
+
+// RekorInclusion is the transparency log entry VerifyRekorInclusion requires
+type RekorInclusion struct {
+	// LogURL is the root URL of the Rekor log
+	LogURL string
+	// UUID is the entry's UUID. The entry is found by the artifact hash when it is empty.
+	UUID string
+	// Artifact is what the entry's hash must be of, e.g. an attestation's signed payload digest
+	Artifact []byte
+	// SignerKey is the public key the entry must record, e.g. the key bound to an attestation's PK token,
+	// so that an entry for the same artifact uploaded by anyone else does not count
+	SignerKey crypto.PublicKey
+	// LogKey is the transparency log's public key, which must sign the checkpoint
+	LogKey crypto.PublicKey
+	// Client sends the log requests, defaults to http.DefaultClient
+	Client *http.Client
+}
+
+// VerifyRekorInclusion checks that a Rekor log holds a hashedrekord entry for inclusion.Artifact signed by
+// inclusion.SignerKey, and that the entry's inclusion proof leads to a checkpoint signed by inclusion.LogKey.
+// Returns ErrNotInTransparencyLog when there is no entry.
+func VerifyRekorInclusion(ctx context.Context, inclusion RekorInclusion) error {
+	if inclusion.SignerKey == nil {
+		return fmt.Errorf("no signer key to match the transparency log entry against")
+	}
+	signerKey, err := x509.MarshalPKIXPublicKey(inclusion.SignerKey)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signer public key: %w", err)
+	}
+	client := inclusion.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	logURL := strings.TrimSuffix(inclusion.LogURL, "/")
+	artifact, uuid := inclusion.Artifact, inclusion.UUID
+	digest := sha256.Sum256(artifact)
+	artifactHash := hex.EncodeToString(digest[:])
+
+	if uuid == "" {
+		var uuids []string
+		query, err := json.Marshal(map[string]string{"hash": "sha256:" + artifactHash})
+		if err != nil {
+			return fmt.Errorf("failed to marshal transparency log search: %w", err)
+		}
+		if err := rekorRequest(ctx, client, http.MethodPost, logURL+rekorIndexPath, query, &uuids); err != nil {
+			return err
+		}
+		if len(uuids) == 0 {
+			return ErrNotInTransparencyLog
+		}
+		uuid = uuids[0]
+	}
+
+	var entries map[string]rekorProvedEntry
+	if err := rekorRequest(ctx, client, http.MethodGet, logURL+rekorEntriesPath+"/"+url.PathEscape(uuid), nil, &entries); err != nil {
+		return err
+	}
+	if len(entries) != 1 {
+		return fmt.Errorf("expected one transparency log entry, got %d", len(entries))
+	}
+	var entry rekorProvedEntry
+	for _, e := range entries {
+		entry = e
+	}
+
+	// The entry must be for this artifact
+	body, err := base64.StdEncoding.DecodeString(entry.Body)
+	if err != nil {
+		return fmt.Errorf("failed to decode transparency log entry: %w", err)
+	}
+	var record rekorHashedRekord
+	if err := json.Unmarshal(body, &record); err != nil {
+		return fmt.Errorf("failed to parse transparency log entry: %w", err)
+	}
+	if record.Kind != "hashedrekord" || record.Spec.Data.Hash.Value != artifactHash {
+		return fmt.Errorf("transparency log entry %s is not for this attestation", uuid)
+	}
+	// and uploaded by the signer
+	recordedKey, err := base64.StdEncoding.DecodeString(record.Spec.Signature.PublicKey.Content)
+	if err != nil {
+		return fmt.Errorf("failed to decode transparency log entry public key: %w", err)
+	}
+	block, _ := pem.Decode(recordedKey)
+	if block == nil || !bytes.Equal(block.Bytes, signerKey) {
+		return fmt.Errorf("transparency log entry %s is not signed by the attestation's signer", uuid)
+	}
+
+	proof := entry.Verification.InclusionProof
+	if proof == nil {
+		return fmt.Errorf("transparency log entry %s has no inclusion proof", uuid)
+	}
+	root, err := hex.DecodeString(proof.RootHash)
+	if err != nil {
+		return fmt.Errorf("failed to decode inclusion proof root hash: %w", err)
+	}
+	hashes := make([][]byte, 0, len(proof.Hashes))
+	for _, h := range proof.Hashes {
+		decoded, err := hex.DecodeString(h)
+		if err != nil {
+			return fmt.Errorf("failed to decode inclusion proof hash: %w", err)
+		}
+		hashes = append(hashes, decoded)
+	}
+	if proof.LogIndex < 0 || proof.TreeSize < 0 {
+		return fmt.Errorf("inclusion proof has a negative index or tree size")
+	}
+	computed, err := rootFromInclusionProof(uint64(proof.LogIndex), uint64(proof.TreeSize), merkleLeafHash(body), hashes)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(computed, root) != 1 {
+		return fmt.Errorf("inclusion proof does not lead to root hash %s", proof.RootHash)
+	}
+
+	// The root must be one the log committed to
+	return verifyCheckpoint(proof.Checkpoint, uint64(proof.TreeSize), root, inclusion.LogKey)
+}
+
+// rekorRequest makes a Rekor API request with client and decodes the JSON response into v
+func rekorRequest(ctx context.Context, client *http.Client, method string, requestURL string, body []byte, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create transparency log request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query transparency log: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotInTransparencyLog
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to query transparency log: %w", newBadStatusError(resp, defaultMaxBodySnippet))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read transparency log response: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse transparency log response: %w", err)
+	}
+	return nil
+}
+
+// merkleLeafHash returns the RFC 6962 hash of a leaf
+func merkleLeafHash(leaf []byte) []byte {
+	hash := sha256.Sum256(append([]byte{0x00}, leaf...))
+	return hash[:]
+}
+
+// merkleNodeHash returns the RFC 6962 hash of an interior node
+func merkleNodeHash(left []byte, right []byte) []byte {
+	data := append(append([]byte{0x01}, left...), right...)
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+// rootFromInclusionProof computes the RFC 6962 tree root implied by an inclusion proof for the leaf at index
+// in a tree of size leaves
+func rootFromInclusionProof(index uint64, size uint64, leafHash []byte, proof [][]byte) ([]byte, error) {
+	if index >= size {
+		return nil, fmt.Errorf("inclusion proof index %d is outside a tree of size %d", index, size)
+	}
+	// The proof holds the siblings below the point where the leaf's path meets the right border of the
+	// tree, then the border nodes above it
+	inner := bits.Len64(index ^ (size - 1))
+	border := bits.OnesCount64(index >> inner)
+	if len(proof) != inner+border {
+		return nil, fmt.Errorf("inclusion proof has %d hashes, expected %d", len(proof), inner+border)
+	}
+
+	hash := leafHash
+	for i, sibling := range proof[:inner] {
+		if (index>>i)&1 == 0 {
+			hash = merkleNodeHash(hash, sibling)
+		} else {
+			hash = merkleNodeHash(sibling, hash)
+		}
+	}
+	for _, sibling := range proof[inner:] {
+		hash = merkleNodeHash(sibling, hash)
+	}
+	return hash, nil
+}
+
+// verifyCheckpoint checks that a signed note checkpoint commits to root at size and is signed by publicKey.
+// The note is its origin, size and base64 root hash lines, a blank line, then "— <name> <base64 signature>"
+// lines whose signature is prefixed with a 4 byte key hint.
+func verifyCheckpoint(checkpoint string, size uint64, root []byte, publicKey crypto.PublicKey) error {
+	text, signatures, ok := strings.Cut(checkpoint, "\n\n")
+	if !ok {
+		return fmt.Errorf("malformed checkpoint")
+	}
+	text += "\n"
+
+	lines := strings.Split(text, "\n")
+	if len(lines) < 4 {
+		return fmt.Errorf("malformed checkpoint")
+	}
+	checkpointSize, err := strconv.ParseUint(lines[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed checkpoint tree size: %w", err)
+	}
+	checkpointRoot, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return fmt.Errorf("malformed checkpoint root hash: %w", err)
+	}
+	if checkpointSize != size || subtle.ConstantTimeCompare(checkpointRoot, root) != 1 {
+		return fmt.Errorf("checkpoint does not match the inclusion proof's tree")
+	}
+
+	digest := sha256.Sum256([]byte(text))
+	for _, line := range strings.Split(signatures, "\n") {
+		fields := strings.Fields(strings.TrimPrefix(line, "— "))
+		if !strings.HasPrefix(line, "— ") || len(fields) != 2 {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(signature) < 5 {
+			continue
+		}
+		signature = signature[4:]
+		switch key := publicKey.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(key, digest[:], signature) {
+				return nil
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, []byte(text), signature) {
+				return nil
+			}
+		default:
+			return fmt.Errorf("unsupported transparency log key type %T", publicKey)
+		}
+	}
+	return fmt.Errorf("checkpoint is not signed by the transparency log key")
+}
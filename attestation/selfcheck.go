@@ -0,0 +1,85 @@
+package attestation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CheckPayloadDeterminism builds the payload of content from sourceURL twice, and rebuilds it from its JSON
+// as a verifier would, checking all three hash the same. It returns the payload digest.
+func CheckPayloadDeterminism(sourceURL string, content []byte, timestamp string) ([]byte, error) {
+	first, err := CreateAttestationPayloadFromContent(timestamp, "", nil, sourceURL, content)
+	if err != nil {
+		return nil, err
+	}
+	second, err := CreateAttestationPayloadFromContent(timestamp, "", nil, sourceURL, content)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(first)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	var decoded AttestationPayload
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to parse payload: %w", err)
+	}
+	rebuilt, err := decoded.rebuild()
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := first.Hash()
+	if err != nil {
+		return nil, err
+	}
+	builds := []struct {
+		name    string
+		payload *AttestationPayload
+	}{{"second build", second}, {"rebuild from JSON", rebuilt}}
+	for _, build := range builds {
+		other, err := build.payload.Hash()
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(digest, other) {
+			return nil, fmt.Errorf("payload digest %x differs from the %s's %x", digest, build.name, other)
+		}
+	}
+	return digest, nil
+}
+
+// CheckSignedDigest checks the attestation's signed message is its payload digest, both as recorded and as
+// rebuilt by the oracle. These are the signed message, payload digest and oracle digest checks of
+// VerifyAttestation, which need no network; the PK token is not verified against the OP.
+func CheckSignedDigest(attestation *Attestation) error {
+	if attestation.PKToken == nil {
+		return fmt.Errorf("attestation has no PK token")
+	}
+	msg, err := attestation.PKToken.VerifySignedMessage(attestation.Signature)
+	if err != nil {
+		return fmt.Errorf("signed message verification failed: %w", err)
+	}
+
+	digest, err := attestation.Payload.Hash()
+	if err != nil {
+		return err
+	}
+	if err := compareSignedDigest(msg, digest); err != nil {
+		return fmt.Errorf("attestation payload digest does not match signed message: %w", err)
+	}
+
+	rebuilt, err := attestation.Payload.rebuild()
+	if err != nil {
+		return fmt.Errorf("failed to create attestation payload: %w", err)
+	}
+	oracleDigest, err := rebuilt.Hash()
+	if err != nil {
+		return err
+	}
+	if err := compareSignedDigest(msg, oracleDigest); err != nil {
+		return fmt.Errorf("oracle generated digest does not match signed message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package attestation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPayloadDeterminism(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		content []byte
+	}{
+		{name: "JSON content", url: "https://example.com/data.json", content: []byte(`{"b": 2, "a": 1}`)},
+		{name: "empty content", url: "https://example.com/empty", content: []byte{}},
+		{name: "binary content", url: "https://example.com/blob", content: []byte{0x00, 0xff, 0x10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest, err := CheckPayloadDeterminism(tt.url, tt.content, "2026-01-01T00:00:00Z")
+			if err != nil {
+				t.Fatalf("CheckPayloadDeterminism: %v", err)
+			}
+			if len(digest) == 0 {
+				t.Errorf("expected a payload digest")
+			}
+		})
+	}
+}
+
+func TestCheckSignedDigest(t *testing.T) {
+	op := newTestOP(t)
+
+	tests := []struct {
+		name    string
+		tamper  func(a *Attestation)
+		wantErr string
+	}{
+		{name: "untouched attestation"},
+		{
+			name:    "no PK token",
+			tamper:  func(a *Attestation) { a.PKToken = nil },
+			wantErr: "attestation has no PK token",
+		},
+		{
+			name:    "payload altered after signing",
+			tamper:  func(a *Attestation) { a.Payload.ContentDigest = ContentDigest([]byte("other")) },
+			wantErr: "attestation payload digest does not match signed message",
+		},
+		{
+			name:    "signature replaced",
+			tamper:  func(a *Attestation) { a.Signature = []byte("not a signature") },
+			wantErr: "signed message verification failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+			if tt.tamper != nil {
+				tt.tamper(attestation)
+			}
+			err := CheckSignedDigest(attestation)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckSignedDigest: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyOracleDigest(t *testing.T) {
+	op := newTestOP(t)
+	attestation := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+	result := verifyTestAttestation(t, attestation, op.verifyOptions())
+	if !result.OracleDigestVerified {
+		t.Fatalf("OracleDigestVerified = false (errors %q)", result.Errors)
+	}
+
+	// A payload altered after signing no longer rebuilds to the signed digest
+	attestation.Payload.Url = "https://example.com/other"
+	result = verifyTestAttestation(t, attestation, op.verifyOptions())
+	if result.OracleDigestVerified || !hasError(result, "Oracle generated digest does not match signed message") {
+		t.Errorf("expected the oracle digest check to fail, got verified %v with errors %q", result.OracleDigestVerified, result.Errors)
+	}
+}
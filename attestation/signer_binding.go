@@ -0,0 +1,61 @@
+package attestation
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/openpubkey/openpubkey/pktoken"
+)
+
+// signedMessageType is the typ header of an OpenPubkey signed message
+const signedMessageType = "osm"
+
+// verifySignerBinding checks that signedMessage was signed by the key committed to in pkToken, rather than
+// by a key the message merely names.
+//
+// The binding is a chain: the OP signs an ID token whose nonce is the hash of the client instance claims
+// (CIC), the CIC holds the signer's public key, and the signed message must verify under that key. The
+// first link is checked by the PK token verifier and the last by PKToken.VerifySignedMessage, which only
+// ever verifies with the CIC key. This restates the headers that tie the message to this PK token, so
+// the binding does not rest on those internals alone: the message's kid must be the PK token's hash and
+// its alg the CIC key's algorithm. Only call it once VerifySignedMessage has succeeded.
+func verifySignerBinding(pkToken *pktoken.PKToken, signedMessage []byte) error {
+	header, err := parseTokenHeader(signedMessage)
+	if err != nil {
+		return fmt.Errorf("failed to parse signed message: %w", err)
+	}
+	if header.Type != signedMessageType {
+		return fmt.Errorf("signed message type %q is not %q", header.Type, signedMessageType)
+	}
+
+	pktHash, err := pkToken.Hash()
+	if err != nil {
+		return fmt.Errorf("failed to hash PK token: %w", err)
+	}
+	if header.KeyID != pktHash {
+		return fmt.Errorf("signed message key ID %q is not the PK token hash %q", header.KeyID, pktHash)
+	}
+
+	cic, err := parseTokenHeader(pkToken.CicToken)
+	if err != nil {
+		return fmt.Errorf("failed to parse PK token client instance claims: %w", err)
+	}
+	if header.Algorithm == "" || header.Algorithm != cic.Algorithm {
+		return fmt.Errorf("signed message algorithm %q is not the committed key's algorithm %q", header.Algorithm, cic.Algorithm)
+	}
+	return nil
+}
+
+// signerPublicKey returns the public key committed to in pkToken's client instance claims, which signs the
+// attestation and its transparency log entry
+func signerPublicKey(pkToken *pktoken.PKToken) (crypto.PublicKey, error) {
+	cic, err := pkToken.GetCicValues()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PK token client instance claims: %w", err)
+	}
+	var publicKey interface{}
+	if err := cic.PublicKey().Raw(&publicKey); err != nil {
+		return nil, fmt.Errorf("failed to decode PK token public key: %w", err)
+	}
+	return publicKey, nil
+}
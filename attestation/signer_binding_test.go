@@ -0,0 +1,102 @@
+package attestation
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// editTokenHeader returns compact with its protected header edited, leaving the payload and signature as is
+func editTokenHeader(t *testing.T, compact []byte, edit func(header map[string]any)) []byte {
+	t.Helper()
+	var header map[string]any
+	if err := decodeTokenHeader(compact, &header); err != nil {
+		t.Fatalf("decodeTokenHeader: %v", err)
+	}
+	edit(header)
+	data, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("failed to marshal header: %v", err)
+	}
+	_, rest, _ := strings.Cut(string(compact), ".")
+	return []byte(base64.RawURLEncoding.EncodeToString(data) + "." + rest)
+}
+
+func TestVerifySignerBinding(t *testing.T) {
+	op := newTestOP(t)
+	pkToken, signer := op.pkToken(t, nil)
+	otherPKToken, otherSigner := op.pkToken(t, nil)
+	digest := sha256.Sum256([]byte("payload"))
+
+	signedMessage, err := pkToken.NewSignedMessage(digest[:], signer)
+	if err != nil {
+		t.Fatalf("failed to sign message: %v", err)
+	}
+	otherMessage, err := otherPKToken.NewSignedMessage(digest[:], otherSigner)
+	if err != nil {
+		t.Fatalf("failed to sign message: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		signedMessage []byte
+		wantErr       string
+	}{
+		{name: "signed by the committed key", signedMessage: signedMessage},
+		{name: "signed under another PK token", signedMessage: otherMessage, wantErr: "is not the PK token hash"},
+		{
+			name:          "wrong type",
+			signedMessage: editTokenHeader(t, signedMessage, func(header map[string]any) { header["typ"] = "JWT" }),
+			wantErr:       "signed message type",
+		},
+		{
+			name:          "algorithm of another key type",
+			signedMessage: editTokenHeader(t, signedMessage, func(header map[string]any) { header["alg"] = "RS256" }),
+			wantErr:       "is not the committed key's algorithm",
+		},
+		{
+			name:          "no algorithm",
+			signedMessage: editTokenHeader(t, signedMessage, func(header map[string]any) { delete(header, "alg") }),
+			wantErr:       "is not the committed key's algorithm",
+		},
+		{name: "not a compact JWS", signedMessage: []byte("not a token"), wantErr: "failed to parse signed message"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignerBinding(pkToken, tt.signedMessage)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifySignerBinding: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("verifySignerBinding error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySignerBindingResult(t *testing.T) {
+	op := newTestOP(t)
+	download := testDownload("https://example.com/", []byte("content"))
+
+	attestation := op.attest(t, download, nil)
+	result := verifyTestAttestation(t, attestation, op.verifyOptions())
+	if !result.SignerBindingVerified {
+		t.Errorf("SignerBindingVerified = false for a well-formed attestation (errors %q)", result.Errors)
+	}
+
+	// A signature made under another PK token does not verify with this one's key, so the binding is not checked
+	other := op.attest(t, download, nil)
+	attestation.Signature = other.Signature
+	result = verifyTestAttestation(t, attestation, op.verifyOptions())
+	if result.SignerBindingVerified || result.IsVerificationSuccessful() {
+		t.Errorf("signature from another PK token was bound to this one (errors %q)", result.Errors)
+	}
+	if !hasError(result, "Signer binding verification failed") {
+		t.Errorf("expected a signer binding error, got %q", result.Errors)
+	}
+}
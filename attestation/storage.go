@@ -0,0 +1,309 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Object store URL schemes accepted by NewStorage
+const (
+	StorageSchemeS3  = "s3"
+	StorageSchemeGCS = "gs"
+)
+
+// Storage writes attestation files to a location, returning the URL the written object can be fetched from
+type Storage interface {
+	Write(ctx context.Context, path string, data []byte) (string, error)
+}
+
+// NewStorage selects a Storage for an output location by its scheme and returns the path within it.
+// s3://bucket/key and gs://bucket/key write to object stores, anything else is a local file path.
+func NewStorage(location string) (Storage, string, error) {
+	parsed, err := url.Parse(location)
+	if err != nil || (parsed.Scheme != StorageSchemeS3 && parsed.Scheme != StorageSchemeGCS) {
+		return FileStorage{}, location, nil
+	}
+
+	key := strings.TrimPrefix(parsed.Path, "/")
+	if parsed.Host == "" || key == "" {
+		return nil, "", fmt.Errorf("object store location %s must be %s://bucket/key", location, parsed.Scheme)
+	}
+	path := parsed.Host + "/" + key
+
+	if parsed.Scheme == StorageSchemeS3 {
+		return NewS3StorageFromEnv(), path, nil
+	}
+	return NewGCSStorageFromEnv(), path, nil
+}
+
+// urlHashLength is how many hex characters of the URL's sha256 digest AttestationFileName starts with
+const urlHashLength = 12
+
+// URLHash returns the first 12 hex characters of the sha256 digest of sourceURL, the prefix AttestationFileName
+// gives every attestation of that URL
+func URLHash(sourceURL string) string {
+	return sha256Hex([]byte(sourceURL))[:urlHashLength]
+}
+
+// AttestationFileName returns a deterministic file name for the attestation of sourceURL issued at timestamp,
+// <URLHash(sourceURL)>-<UTC timestamp as 20060102T150405Z>.json, so attestations of one URL share a prefix
+// and sort by time
+func AttestationFileName(sourceURL string, timestamp time.Time) string {
+	return URLHash(sourceURL) + "-" + timestamp.UTC().Format("20060102T150405Z") + ".json"
+}
+
+// FileStorage writes to the local filesystem
+type FileStorage struct{}
+
+// Write atomically writes data to path, creating its directory if needed
+func (FileStorage) Write(ctx context.Context, path string, data []byte) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := WriteFileAtomic(path, data, 0644); err != nil {
+		return "", err
+	}
+	fileURL := url.URL{Scheme: fileScheme, Path: filepath.ToSlash(path)}
+	return fileURL.String(), nil
+}
+
+// WriteFileAtomic writes data to a temporary file in path's directory and renames it into place, so readers
+// see either the previous file or the complete new one, never a partial write
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	// Removing fails harmlessly once the file has been renamed
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to move file into place: %w", err)
+	}
+	return nil
+}
+
+// S3Storage writes to Amazon S3, or an S3-compatible store when Endpoint is set, with SigV4-signed PUTs
+type S3Storage struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Endpoint is the base URL of an S3-compatible store, addressed path-style. Defaults to AWS.
+	Endpoint   string
+	HTTPClient *http.Client
+	now        func() time.Time
+}
+
+// NewS3StorageFromEnv returns an S3Storage configured from the standard AWS_* environment variables
+func NewS3StorageFromEnv() *S3Storage {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	return &S3Storage{
+		Region:          region,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Endpoint:        os.Getenv("AWS_ENDPOINT_URL_S3"),
+	}
+}
+
+// Write uploads data to path, given as bucket/key
+func (s *S3Storage) Write(ctx context.Context, path string, data []byte) (string, error) {
+	bucket, key, ok := strings.Cut(path, "/")
+	if !ok || bucket == "" || key == "" {
+		return "", fmt.Errorf("S3 path %s must be bucket/key", path)
+	}
+	if s.Region == "" || s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return "", fmt.Errorf("S3 upload requires AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	var objectURL string
+	if s.Endpoint != "" {
+		objectURL = strings.TrimSuffix(s.Endpoint, "/") + "/" + bucket + "/" + awsURIEncode(key)
+	} else {
+		objectURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, s.Region, awsURIEncode(key))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.sign(req, data)
+
+	if err := doUpload(s.HTTPClient, req); err != nil {
+		return "", fmt.Errorf("failed to upload to S3: %w", err)
+	}
+	return objectURL, nil
+}
+
+// sign adds AWS Signature Version 4 headers for the S3 service to req
+func (s *S3Storage) sign(req *http.Request, payload []byte) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	timestamp := now().UTC()
+	amzDate := timestamp.Format("20060102T150405Z")
+	date := timestamp.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// GCSStorage writes to Google Cloud Storage with the JSON API's media upload
+type GCSStorage struct {
+	// Token is an OAuth2 access token with write access to the bucket
+	Token string
+	// Endpoint defaults to https://storage.googleapis.com
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+// NewGCSStorageFromEnv returns a GCSStorage authenticated with GOOGLE_OAUTH_ACCESS_TOKEN
+func NewGCSStorageFromEnv() *GCSStorage {
+	return &GCSStorage{Token: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")}
+}
+
+// Write uploads data to path, given as bucket/object
+func (g *GCSStorage) Write(ctx context.Context, path string, data []byte) (string, error) {
+	bucket, object, ok := strings.Cut(path, "/")
+	if !ok || bucket == "" || object == "" {
+		return "", fmt.Errorf("GCS path %s must be bucket/object", path)
+	}
+	if g.Token == "" {
+		return "", fmt.Errorf("GCS upload requires GOOGLE_OAUTH_ACCESS_TOKEN")
+	}
+
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	uploadURL := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", endpoint, url.PathEscape(bucket), url.Values{
+		"uploadType": {"media"},
+		"name":       {object},
+	}.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+g.Token)
+
+	if err := doUpload(g.HTTPClient, req); err != nil {
+		return "", fmt.Errorf("failed to upload to GCS: %w", err)
+	}
+	return fmt.Sprintf("%s/%s/%s", endpoint, bucket, object), nil
+}
+
+// doUpload sends an upload request and fails on any non-2xx response
+func doUpload(client *http.Client, req *http.Request) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newBadStatusError(resp, defaultMaxBodySnippet)
+	}
+	return nil
+}
+
+// awsURIEncode escapes an object key for a SigV4 canonical URI, keeping '/' separators
+func awsURIEncode(key string) string {
+	var builder strings.Builder
+	for _, b := range []byte(key) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/':
+			builder.WriteByte(b)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", b)
+		}
+	}
+	return builder.String()
+}
+
+func sha256Hex(data []byte) string {
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
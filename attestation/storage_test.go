@@ -0,0 +1,384 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewStorage(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		wantType string
+		wantPath string
+		wantErr  bool
+	}{
+		{name: "local path", location: "out/attestation.json", wantType: "attestation.FileStorage", wantPath: "out/attestation.json"},
+		{name: "S3 object", location: "s3://bucket/attestations/a.json", wantType: "*attestation.S3Storage", wantPath: "bucket/attestations/a.json"},
+		{name: "GCS object", location: "gs://bucket/a.json", wantType: "*attestation.GCSStorage", wantPath: "bucket/a.json"},
+		{name: "object store location without a key", location: "s3://bucket", wantErr: true},
+		{name: "object store location without a bucket", location: "gs:///a.json", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, path, err := NewStorage(tt.location)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %s", tt.location)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewStorage: %v", err)
+			}
+			if got := fmt.Sprintf("%T", storage); got != tt.wantType {
+				t.Errorf("storage is %s, want %s", got, tt.wantType)
+			}
+			if path != tt.wantPath {
+				t.Errorf("path = %s, want %s", path, tt.wantPath)
+			}
+		})
+	}
+}
+
+// objectStore is a mock object store recording the uploads it receives
+type objectStore struct {
+	server  *httptest.Server
+	status  int
+	mu      sync.Mutex
+	uploads []objectUpload
+}
+
+type objectUpload struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   []byte
+}
+
+func TestAttestationFileName(t *testing.T) {
+	issuedAt := time.Date(2026, 1, 10, 12, 30, 45, 0, time.UTC)
+	tests := []struct {
+		name      string
+		url       string
+		timestamp time.Time
+		want      string
+	}{
+		{name: "url and timestamp", url: "https://example.com/jwks", timestamp: issuedAt, want: "ad79df8807ca-20260110T123045Z.json"},
+		{name: "timestamp in UTC", url: "https://example.com/jwks", timestamp: issuedAt.In(time.FixedZone("PST", -8*3600)), want: "ad79df8807ca-20260110T123045Z.json"},
+		{name: "sub-second precision dropped", url: "https://example.com/jwks", timestamp: issuedAt.Add(999 * time.Millisecond), want: "ad79df8807ca-20260110T123045Z.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AttestationFileName(tt.url, tt.timestamp); got != tt.want {
+				t.Errorf("AttestationFileName(%q, %v) = %q, want %q", tt.url, tt.timestamp, got, tt.want)
+			}
+			if got := AttestationFileName(tt.url, tt.timestamp); got != tt.want {
+				t.Errorf("AttestationFileName is not deterministic: %q then %q", tt.want, got)
+			}
+			if !strings.HasPrefix(tt.want, URLHash(tt.url)+"-") {
+				t.Errorf("%q does not start with the URL hash %s", tt.want, URLHash(tt.url))
+			}
+		})
+	}
+
+	// Different URLs or issue times never share a name, and names of one URL sort by time
+	urls := []string{"https://example.com/jwks", "https://example.com/jwks/", "https://example.com/JWKS", "http://example.com/jwks"}
+	seen := map[string]string{}
+	for _, url := range urls {
+		var previous string
+		for i := 0; i < 3; i++ {
+			name := AttestationFileName(url, issuedAt.Add(time.Duration(i)*time.Hour))
+			if other, ok := seen[name]; ok {
+				t.Errorf("%s and %s at %d share the file name %s", other, url, i, name)
+			}
+			seen[name] = url
+			if name <= previous {
+				t.Errorf("%s does not sort after %s", name, previous)
+			}
+			previous = name
+		}
+	}
+}
+
+func newObjectStore(t *testing.T, status int) *objectStore {
+	t.Helper()
+	store := &objectStore{status: status}
+	store.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		store.mu.Lock()
+		store.uploads = append(store.uploads, objectUpload{method: r.Method, path: r.URL.EscapedPath(), query: r.URL.RawQuery, header: r.Header, body: body})
+		store.mu.Unlock()
+		w.WriteHeader(store.status)
+	}))
+	t.Cleanup(store.server.Close)
+	return store
+}
+
+func TestObjectStorageWrite(t *testing.T) {
+	data := []byte(`{"payload":{}}`)
+	fixedTime := func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		storage    func(endpoint string) Storage
+		path       string
+		status     int
+		wantMethod string
+		wantPath   string
+		wantQuery  string
+		wantURL    func(endpoint string) string
+		wantHeader map[string]string
+		wantErr    string
+	}{
+		{
+			name: "S3 path-style PUT",
+			storage: func(endpoint string) Storage {
+				return &S3Storage{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: endpoint, now: fixedTime}
+			},
+			path:       "bucket/attestations/a b.json",
+			status:     http.StatusOK,
+			wantMethod: http.MethodPut,
+			wantPath:   "/bucket/attestations/a%20b.json",
+			wantURL:    func(endpoint string) string { return endpoint + "/bucket/attestations/a%20b.json" },
+			wantHeader: map[string]string{
+				"X-Amz-Date":           "20240102T030405Z",
+				"X-Amz-Content-Sha256": sha256Hex(data),
+				"Authorization":        "AWS4-HMAC-SHA256 Credential=AKID/20240102/us-east-1/s3/aws4_request, ",
+			},
+		},
+		{
+			name: "S3 session token",
+			storage: func(endpoint string) Storage {
+				return &S3Storage{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session", Endpoint: endpoint, now: fixedTime}
+			},
+			path:       "bucket/a.json",
+			status:     http.StatusOK,
+			wantMethod: http.MethodPut,
+			wantPath:   "/bucket/a.json",
+			wantURL:    func(endpoint string) string { return endpoint + "/bucket/a.json" },
+			wantHeader: map[string]string{"X-Amz-Security-Token": "session"},
+		},
+		{
+			name:       "GCS media upload",
+			storage:    func(endpoint string) Storage { return &GCSStorage{Token: "token", Endpoint: endpoint} },
+			path:       "bucket/attestations/a.json",
+			status:     http.StatusOK,
+			wantMethod: http.MethodPost,
+			wantPath:   "/upload/storage/v1/b/bucket/o",
+			wantQuery:  "name=attestations%2Fa.json&uploadType=media",
+			wantURL:    func(endpoint string) string { return endpoint + "/bucket/attestations/a.json" },
+			wantHeader: map[string]string{"Authorization": "Bearer token", "Content-Type": "application/json"},
+		},
+		{
+			name: "S3 upload rejected",
+			storage: func(endpoint string) Storage {
+				return &S3Storage{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: endpoint}
+			},
+			path:    "bucket/a.json",
+			status:  http.StatusForbidden,
+			wantErr: "failed to upload to S3",
+		},
+		{
+			name:    "S3 credentials missing",
+			storage: func(endpoint string) Storage { return &S3Storage{Endpoint: endpoint} },
+			path:    "bucket/a.json",
+			wantErr: "S3 upload requires",
+		},
+		{
+			name:    "GCS token missing",
+			storage: func(endpoint string) Storage { return &GCSStorage{Endpoint: endpoint} },
+			path:    "bucket/a.json",
+			wantErr: "GCS upload requires",
+		},
+		{
+			name:    "GCS path without an object",
+			storage: func(endpoint string) Storage { return &GCSStorage{Token: "token", Endpoint: endpoint} },
+			path:    "bucket",
+			wantErr: "must be bucket/object",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newObjectStore(t, tt.status)
+			objectURL, err := tt.storage(store.server.URL).Write(context.Background(), tt.path, data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				var badStatus *BadStatusError
+				if tt.status != 0 && !errors.As(err, &badStatus) {
+					t.Errorf("expected a BadStatusError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if want := tt.wantURL(store.server.URL); objectURL != want {
+				t.Errorf("object URL = %s, want %s", objectURL, want)
+			}
+			if len(store.uploads) != 1 {
+				t.Fatalf("store received %d uploads, want 1", len(store.uploads))
+			}
+			upload := store.uploads[0]
+			if upload.method != tt.wantMethod || upload.path != tt.wantPath || upload.query != tt.wantQuery {
+				t.Errorf("upload %s %s?%s, want %s %s?%s", upload.method, upload.path, upload.query, tt.wantMethod, tt.wantPath, tt.wantQuery)
+			}
+			if string(upload.body) != string(data) {
+				t.Errorf("uploaded %q, want %q", upload.body, data)
+			}
+			for name, want := range tt.wantHeader {
+				if got := upload.header.Get(name); !strings.HasPrefix(got, want) {
+					t.Errorf("%s header = %q, want prefix %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStorageWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "attestation.json")
+	fileURL, err := FileStorage{}.Write(context.Background(), path, []byte("attestation"))
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if want := "file://" + filepath.ToSlash(path); fileURL != want {
+		t.Errorf("file URL = %s, want %s", fileURL, want)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read written file: %v", err)
+	}
+	if string(written) != "attestation" {
+		t.Errorf("wrote %q, want %q", written, "attestation")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, path string)
+		want    string
+		wantErr string
+	}{
+		{name: "new file", want: "new"},
+		{
+			name:  "replaces an existing file",
+			setup: func(t *testing.T, path string) { writeTestFile(t, path, "old") },
+			want:  "new",
+		},
+		{
+			name: "stale temporary file from an interrupted write",
+			setup: func(t *testing.T, path string) {
+				writeTestFile(t, path, "old")
+				writeTestFile(t, filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp-123"), "partial")
+			},
+			want: "new",
+		},
+		{
+			name:    "rename onto a directory fails",
+			setup:   func(t *testing.T, path string) { os.MkdirAll(filepath.Join(path, "child"), 0755) },
+			wantErr: "failed to move file into place",
+		},
+		{
+			name:    "missing directory",
+			setup:   func(t *testing.T, path string) { os.Remove(filepath.Dir(path)) },
+			wantErr: "failed to create temporary file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "out")
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatalf("failed to create directory: %v", err)
+			}
+			path := filepath.Join(dir, "attestation.json")
+			if tt.setup != nil {
+				tt.setup(t, path)
+			}
+			before, _ := os.ReadDir(dir)
+
+			err := WriteFileAtomic(path, []byte("new"), 0640)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				// A failed write leaves no temporary file behind
+				if after, _ := os.ReadDir(dir); len(after) != len(before) {
+					t.Errorf("directory has %d entries after a failed write, had %d", len(after), len(before))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WriteFileAtomic: %v", err)
+			}
+			written, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read written file: %v", err)
+			}
+			if string(written) != tt.want {
+				t.Errorf("wrote %q, want %q", written, tt.want)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0640 {
+				t.Errorf("file mode = %v (%v), want 0640", info.Mode().Perm(), err)
+			}
+		})
+	}
+}
+
+func TestInterruptedWriteLeavesAttestation(t *testing.T) {
+	op := newTestOP(t)
+	path := filepath.Join(t.TempDir(), "attestation.json")
+	original := op.attest(t, testDownload("https://example.com/jwks", []byte("v1")), nil)
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("failed to marshal attestation: %v", err)
+	}
+	if _, err := (FileStorage{}).Write(context.Background(), path, data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// Interrupt a save of the next attestation after it wrote half of its temporary file, before the rename
+	next, err := json.Marshal(op.attest(t, testDownload("https://example.com/jwks", []byte("v2")), nil))
+	if err != nil {
+		t.Fatalf("failed to marshal attestation: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		t.Fatalf("failed to create temporary file: %v", err)
+	}
+	tmp.Write(next[:len(next)/2])
+	tmp.Close()
+
+	loaded, err := LoadAttestation(path)
+	if err != nil {
+		t.Fatalf("LoadAttestation after an interrupted write: %v", err)
+	}
+	if loaded.Payload.ContentDigest != original.Payload.ContentDigest {
+		t.Errorf("loaded attestation of %s, want the original %s", loaded.Payload.ContentDigest, original.Payload.ContentDigest)
+	}
+	if _, err := LoadAttestation(tmp.Name()); err == nil {
+		t.Error("the interrupted temporary file parsed as an attestation")
+	}
+}
+
+// writeTestFile writes content to path, failing the test on error
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
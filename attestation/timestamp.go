@@ -0,0 +1,324 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// RFC 3161 media types
+const (
+	timestampQueryType = "application/timestamp-query"
+	timestampReplyType = "application/timestamp-reply"
+)
+
+// maxTimestampReply bounds how much of a TSA response is read
+const maxTimestampReply = 1 << 20
+
+var (
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+)
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString asn1.RawValue  `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+// contentInfo holds its content with the [0] EXPLICIT tag, which is unwrapped when it is parsed
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// accuracy is parsed as a SEQUENCE so that, when absent, an optional field cannot take the nonce in its place
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+	Accuracy       accuracy  `asn1:"optional"`
+	Ordering       bool      `asn1:"optional"`
+	Nonce          *big.Int  `asn1:"optional"`
+}
+
+// RequestTimestamp asks the RFC 3161 timestamp authority at tsaURL to timestamp data, returning the DER
+// timestamp token. The TSA is asked to include its certificate so the token can be verified offline.
+func RequestTimestamp(ctx context.Context, tsaURL string, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	query, err := asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+			HashedMessage: digest[:],
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode timestamp request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tsaURL, bytes.NewReader(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create timestamp request: %w", err)
+	}
+	req.Header.Set("Content-Type", timestampQueryType)
+	req.Header.Set("Accept", timestampReplyType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send timestamp request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newBadStatusError(resp, defaultMaxBodySnippet)
+	}
+	reply, err := io.ReadAll(io.LimitReader(resp.Body, maxTimestampReply))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read timestamp response: %w", err)
+	}
+
+	var tsResp timeStampResp
+	if _, err := asn1.Unmarshal(reply, &tsResp); err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp response: %w", err)
+	}
+	// 0 is granted and 1 granted with modifications
+	if tsResp.Status.Status > 1 {
+		return nil, fmt.Errorf("timestamp authority rejected the request with status %d", tsResp.Status.Status)
+	}
+	if len(tsResp.TimeStampToken.FullBytes) == 0 {
+		return nil, fmt.Errorf("timestamp response has no token")
+	}
+
+	token := tsResp.TimeStampToken.FullBytes
+	info, _, err := parseTimestampToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(info.MessageImprint.HashedMessage, digest[:]) != 1 {
+		return nil, fmt.Errorf("timestamp token is for a different message")
+	}
+	// A token without the request's nonce may be a replayed reply to another request
+	if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+		return nil, fmt.Errorf("timestamp token nonce does not match the request")
+	}
+	return token, nil
+}
+
+// VerifyTimestampToken checks that token is an RFC 3161 timestamp of data signed by a TSA certificate that
+// chains to roots, returning the time the TSA attested
+func VerifyTimestampToken(token []byte, data []byte, roots *x509.CertPool) (time.Time, error) {
+	info, signed, err := parseTimestampToken(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	hash, err := timestampHash(info.MessageImprint.HashAlgorithm.Algorithm)
+	if err != nil {
+		return time.Time{}, err
+	}
+	hasher := hash.New()
+	hasher.Write(data)
+	if subtle.ConstantTimeCompare(info.MessageImprint.HashedMessage, hasher.Sum(nil)) != 1 {
+		return time.Time{}, fmt.Errorf("timestamp token is for a different message")
+	}
+
+	if len(signed.SignerInfos) != 1 {
+		return time.Time{}, fmt.Errorf("expected one timestamp signer, found %d", len(signed.SignerInfos))
+	}
+	signer := signed.SignerInfos[0]
+	if len(signer.SignedAttrs.Bytes) == 0 {
+		return time.Time{}, fmt.Errorf("timestamp signer has no signed attributes")
+	}
+
+	// The signed attributes commit to the TSTInfo through the message digest attribute
+	signerHash, err := timestampHash(signer.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return time.Time{}, err
+	}
+	hasher = signerHash.New()
+	hasher.Write(signed.EncapContentInfo.EContent)
+	contentDigest := hasher.Sum(nil)
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(signer.SignedAttrs.FullBytes, &attrs, "tag:0,set"); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse timestamp signed attributes: %w", err)
+	}
+	digestMatched := false
+	for _, attr := range attrs {
+		if !attr.Type.Equal(oidMessageDigest) {
+			continue
+		}
+		var value []byte
+		if _, err := asn1.Unmarshal(attr.Values.Bytes, &value); err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse timestamp message digest: %w", err)
+		}
+		digestMatched = subtle.ConstantTimeCompare(value, contentDigest) == 1
+	}
+	if !digestMatched {
+		return time.Time{}, fmt.Errorf("timestamp signed attributes do not match the timestamp content")
+	}
+
+	// The signature is over the DER of the signed attributes as a SET, not with their implicit [0] tag
+	signedAttrs := append([]byte{}, signer.SignedAttrs.FullBytes...)
+	signedAttrs[0] = 0x31
+
+	certs, err := x509.ParseCertificates(signed.Certificates.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse timestamp certificates: %w", err)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs {
+		intermediates.AddCert(cert)
+	}
+	for _, cert := range certs {
+		algorithm, err := timestampSignatureAlgorithm(cert, signer.DigestAlgorithm.Algorithm)
+		if err != nil {
+			continue
+		}
+		if err := cert.CheckSignature(algorithm, signedAttrs, signer.Signature); err != nil {
+			continue
+		}
+		if _, err := cert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   info.GenTime,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		}); err != nil {
+			return time.Time{}, fmt.Errorf("timestamp signer certificate is not trusted: %w", err)
+		}
+		return info.GenTime, nil
+	}
+	return time.Time{}, fmt.Errorf("no certificate in the timestamp token verifies its signature")
+}
+
+// parseTimestampToken decodes the TSTInfo and SignedData of a DER timestamp token
+func parseTimestampToken(token []byte) (*tstInfo, *signedData, error) {
+	var info contentInfo
+	if _, err := asn1.Unmarshal(token, &info); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse timestamp token: %w", err)
+	}
+	if !info.ContentType.Equal(oidSignedData) || info.Content.Class != asn1.ClassContextSpecific || info.Content.Tag != 0 {
+		return nil, nil, fmt.Errorf("timestamp token is not signed data")
+	}
+	var signed signedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &signed); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse timestamp signed data: %w", err)
+	}
+	if !signed.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, nil, fmt.Errorf("timestamp token does not hold TSTInfo")
+	}
+	var tst tstInfo
+	if _, err := asn1.Unmarshal(signed.EncapContentInfo.EContent, &tst); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse TSTInfo: %w", err)
+	}
+	return &tst, &signed, nil
+}
+
+// timestampHash returns the hash for a digest algorithm OID
+func timestampHash(algorithm asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case algorithm.Equal(oidSHA256):
+		return crypto.SHA256, nil
+	case algorithm.Equal(oidSHA384):
+		return crypto.SHA384, nil
+	case algorithm.Equal(oidSHA512):
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported timestamp digest algorithm %s", algorithm)
+	}
+}
+
+// timestampSignatureAlgorithm returns the x509 signature algorithm for a certificate's key and a digest algorithm
+func timestampSignatureAlgorithm(cert *x509.Certificate, digest asn1.ObjectIdentifier) (x509.SignatureAlgorithm, error) {
+	hash, err := timestampHash(digest)
+	if err != nil {
+		return x509.UnknownSignatureAlgorithm, err
+	}
+	algorithms := map[x509.PublicKeyAlgorithm]map[crypto.Hash]x509.SignatureAlgorithm{
+		x509.RSA:   {crypto.SHA256: x509.SHA256WithRSA, crypto.SHA384: x509.SHA384WithRSA, crypto.SHA512: x509.SHA512WithRSA},
+		x509.ECDSA: {crypto.SHA256: x509.ECDSAWithSHA256, crypto.SHA384: x509.ECDSAWithSHA384, crypto.SHA512: x509.ECDSAWithSHA512},
+	}
+	if cert.PublicKeyAlgorithm == x509.Ed25519 {
+		return x509.PureEd25519, nil
+	}
+	algorithm, ok := algorithms[cert.PublicKeyAlgorithm][hash]
+	if !ok {
+		return x509.UnknownSignatureAlgorithm, fmt.Errorf("unsupported timestamp key algorithm %s", cert.PublicKeyAlgorithm)
+	}
+	return algorithm, nil
+}
@@ -0,0 +1,414 @@
+package attestation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockTSA is an RFC 3161 timestamp authority whose certificate chains to its own root
+type mockTSA struct {
+	server *httptest.Server
+	roots  *x509.CertPool
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	now    time.Time
+
+	// status is the PKIStatus of responses, 0 (granted) by default
+	status int
+	// tamper edits the TSTInfo after the request is parsed, before it is signed
+	tamper func(info *tstInfo)
+	// tamperDigest replaces the message digest signed attribute
+	tamperDigest []byte
+}
+
+func newMockTSA(t *testing.T, usages []x509.ExtKeyUsage) *mockTSA {
+	t.Helper()
+	tsa := &mockTSA{now: time.Now().UTC().Truncate(time.Second)}
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test TSA Root"},
+		NotBefore:             tsa.now.Add(-time.Hour),
+		NotAfter:              tsa.now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("failed to create root certificate: %v", err)
+	}
+	root, _ := x509.ParseCertificate(rootDER)
+	tsa.roots = x509.NewCertPool()
+	tsa.roots.AddCert(root)
+
+	if tsa.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test TSA"},
+		NotBefore:    tsa.now.Add(-time.Hour),
+		NotAfter:     tsa.now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usages,
+	}, root, &tsa.key.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("failed to create TSA certificate: %v", err)
+	}
+	tsa.cert, _ = x509.ParseCertificate(leafDER)
+
+	tsa.server = httptest.NewServer(http.HandlerFunc(tsa.serve))
+	t.Cleanup(tsa.server.Close)
+	return tsa
+}
+
+func (tsa *mockTSA) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req timeStampReq
+	if r.Header.Get("Content-Type") != timestampQueryType {
+		http.Error(w, "expected a timestamp query", http.StatusUnsupportedMediaType)
+		return
+	}
+	if _, err := asn1.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := timeStampResp{Status: pkiStatusInfo{Status: tsa.status}}
+	if tsa.status <= 1 {
+		token, err := tsa.token(req.MessageImprint, req.Nonce)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.TimeStampToken = asn1.RawValue{FullBytes: token}
+	}
+	reply, err := asn1.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", timestampReplyType)
+	w.Write(reply)
+}
+
+// token returns a DER timestamp token for imprint echoing nonce, signed by the TSA certificate
+func (tsa *mockTSA) token(imprint messageImprint, nonce *big.Int) ([]byte, error) {
+	info := tstInfo{
+		Version:        1,
+		Policy:         asn1.ObjectIdentifier{1, 2, 3, 4},
+		MessageImprint: imprint,
+		SerialNumber:   big.NewInt(42),
+		GenTime:        tsa.now,
+		Nonce:          nonce,
+	}
+	if tsa.tamper != nil {
+		tsa.tamper(&info)
+	}
+	infoDER, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	// The signed attributes commit to the TSTInfo through its message digest
+	digest := sha256.Sum256(infoDER)
+	signedDigest := digest[:]
+	if tsa.tamperDigest != nil {
+		signedDigest = tsa.tamperDigest
+	}
+	digestValue, _ := asn1.Marshal(signedDigest)
+	attr, err := asn1.Marshal(attribute{
+		Type:   oidMessageDigest,
+		Values: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: digestValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	attrsSet, _ := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: attr})
+	attrsDigest := sha256.Sum256(attrsSet)
+	signature, err := ecdsa.SignASN1(rand.Reader, tsa.key, attrsDigest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	sha256Algorithm := pkix.AlgorithmIdentifier{Algorithm: oidSHA256}
+	algorithmDER, _ := asn1.Marshal(sha256Algorithm)
+	sid, _ := asn1.Marshal(struct {
+		Issuer asn1.RawValue
+		Serial *big.Int
+	}{asn1.RawValue{FullBytes: tsa.cert.RawIssuer}, tsa.cert.SerialNumber})
+	signed, err := asn1.Marshal(signedData{
+		Version:          3,
+		DigestAlgorithms: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: algorithmDER},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: oidTSTInfo, EContent: infoDER},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: tsa.cert.Raw},
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                asn1.RawValue{FullBytes: sid},
+			DigestAlgorithm:    sha256Algorithm,
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attr},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+			Signature:          signature,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signed},
+	})
+}
+
+func TestRequestTimestamp(t *testing.T) {
+	timestamping := []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}
+	tests := []struct {
+		name    string
+		setup   func(tsa *mockTSA)
+		wantErr string
+	}{
+		{name: "granted"},
+		{name: "granted with modifications", setup: func(tsa *mockTSA) { tsa.status = 1 }},
+		{
+			name:    "rejected",
+			setup:   func(tsa *mockTSA) { tsa.status = 2 },
+			wantErr: "timestamp authority rejected the request with status 2",
+		},
+		{
+			name: "token for another message",
+			setup: func(tsa *mockTSA) {
+				tsa.tamper = func(info *tstInfo) { info.MessageImprint.HashedMessage = make([]byte, sha256.Size) }
+			},
+			wantErr: "timestamp token is for a different message",
+		},
+		{
+			name: "wrong nonce",
+			setup: func(tsa *mockTSA) {
+				tsa.tamper = func(info *tstInfo) { info.Nonce = new(big.Int).Add(info.Nonce, big.NewInt(1)) }
+			},
+			wantErr: "timestamp token nonce does not match the request",
+		},
+		{
+			name:    "missing nonce",
+			setup:   func(tsa *mockTSA) { tsa.tamper = func(info *tstInfo) { info.Nonce = nil } },
+			wantErr: "timestamp token nonce does not match the request",
+		},
+		{
+			name: "nonce after accuracy and ordering",
+			setup: func(tsa *mockTSA) {
+				tsa.tamper = func(info *tstInfo) { info.Accuracy, info.Ordering = accuracy{Seconds: 1}, true }
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tsa := newMockTSA(t, timestamping)
+			if tt.setup != nil {
+				tt.setup(tsa)
+			}
+			token, err := RequestTimestamp(context.Background(), tsa.server.URL, []byte("signature"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RequestTimestamp: %v", err)
+			}
+			genTime, err := VerifyTimestampToken(token, []byte("signature"), tsa.roots)
+			if err != nil {
+				t.Fatalf("VerifyTimestampToken: %v", err)
+			}
+			if !genTime.Equal(tsa.now) {
+				t.Errorf("timestamp time %s, want %s", genTime, tsa.now)
+			}
+		})
+	}
+
+	t.Run("TSA error status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		var badStatus *BadStatusError
+		if _, err := RequestTimestamp(context.Background(), server.URL, []byte("signature")); !errors.As(err, &badStatus) || badStatus.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected a 503 BadStatusError, got %v", err)
+		}
+	})
+}
+
+func TestVerifyTimestampToken(t *testing.T) {
+	timestamping := []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}
+	otherRoots := newMockTSA(t, timestamping).roots
+	tests := []struct {
+		name    string
+		usages  []x509.ExtKeyUsage
+		setup   func(tsa *mockTSA)
+		data    string
+		roots   func(tsa *mockTSA) *x509.CertPool
+		edit    func(token []byte) []byte
+		wantErr string
+	}{
+		{name: "valid token"},
+		{name: "different data", data: "other signature", wantErr: "timestamp token is for a different message"},
+		{
+			name:    "untrusted TSA",
+			roots:   func(*mockTSA) *x509.CertPool { return otherRoots },
+			wantErr: "timestamp signer certificate is not trusted",
+		},
+		{
+			name:    "certificate without timestamping usage",
+			usages:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			wantErr: "timestamp signer certificate is not trusted",
+		},
+		{
+			name:    "signed attributes for other content",
+			setup:   func(tsa *mockTSA) { tsa.tamperDigest = make([]byte, sha256.Size) },
+			wantErr: "timestamp signed attributes do not match the timestamp content",
+		},
+		{
+			name:    "outside the certificate validity",
+			setup:   func(tsa *mockTSA) { tsa.now = tsa.now.Add(2 * time.Hour) },
+			wantErr: "timestamp signer certificate is not trusted",
+		},
+		{
+			name: "tampered signature",
+			edit: func(token []byte) []byte {
+				var info contentInfo
+				asn1.Unmarshal(token, &info)
+				var signed signedData
+				asn1.Unmarshal(info.Content.Bytes, &signed)
+				signed.SignerInfos[0].Signature[len(signed.SignerInfos[0].Signature)-1] ^= 0xff
+				signedDER, _ := asn1.Marshal(signed)
+				info.Content = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedDER}
+				token, _ = asn1.Marshal(info)
+				return token
+			},
+			wantErr: "no certificate in the timestamp token verifies its signature",
+		},
+		{
+			name:    "not a timestamp token",
+			edit:    func([]byte) []byte { return []byte("not a token") },
+			wantErr: "failed to parse timestamp token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usages := tt.usages
+			if usages == nil {
+				usages = timestamping
+			}
+			tsa := newMockTSA(t, usages)
+			if tt.setup != nil {
+				tt.setup(tsa)
+			}
+			digest := sha256.Sum256([]byte("signature"))
+			token, err := tsa.token(messageImprint{HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256}, HashedMessage: digest[:]}, nil)
+			if err != nil {
+				t.Fatalf("failed to create token: %v", err)
+			}
+			if tt.edit != nil {
+				token = tt.edit(token)
+			}
+			roots := tsa.roots
+			if tt.roots != nil {
+				roots = tt.roots(tsa)
+			}
+			data := tt.data
+			if data == "" {
+				data = "signature"
+			}
+
+			_, err = VerifyTimestampToken(token, []byte(data), roots)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyTimestampToken: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyAttestationTimestamp(t *testing.T) {
+	op := newTestOP(t)
+	tsa := newMockTSA(t, []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping})
+	tests := []struct {
+		name         string
+		timestamp    bool
+		edit         func(attestation *Attestation)
+		roots        *x509.CertPool
+		wantVerified bool
+		wantError    string
+	}{
+		{name: "not required", roots: nil},
+		{name: "timestamped", timestamp: true, roots: tsa.roots, wantVerified: true},
+		{
+			name:      "missing timestamp token",
+			roots:     tsa.roots,
+			wantError: "Timestamp token verification failed: attestation has no timestamp token",
+		},
+		{
+			name:      "timestamp of another signature",
+			timestamp: true,
+			edit: func(attestation *Attestation) {
+				token, _ := RequestTimestamp(context.Background(), tsa.server.URL, []byte("another signature"))
+				attestation.TimestampToken = token
+			},
+			roots:     tsa.roots,
+			wantError: "Timestamp token verification failed: timestamp token is for a different message",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := op.attest(t, testDownload("https://example.com/jwks", []byte(`{"keys":[]}`)), nil)
+			if tt.timestamp {
+				token, err := RequestTimestamp(context.Background(), tsa.server.URL, attestation.Signature)
+				if err != nil {
+					t.Fatalf("RequestTimestamp: %v", err)
+				}
+				attestation.TimestampToken = token
+			}
+			if tt.edit != nil {
+				tt.edit(attestation)
+			}
+
+			opts := op.verifyOptions()
+			opts.TSARoots = tt.roots
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.TimestampTokenVerified != tt.wantVerified {
+				t.Errorf("TimestampTokenVerified = %v, want %v (errors %q)", result.TimestampTokenVerified, tt.wantVerified, result.Errors)
+			}
+			if tt.wantVerified && !result.TimestampTokenTime.Equal(tsa.now) {
+				t.Errorf("TimestampTokenTime = %s, want %s", result.TimestampTokenTime, tsa.now)
+			}
+			if tt.wantError == "" && len(result.Errors) > 0 {
+				t.Errorf("unexpected errors: %v", result.Errors)
+			}
+			if tt.wantError != "" && !hasError(result, tt.wantError) {
+				t.Errorf("expected an error starting %q, got %q", tt.wantError, result.Errors)
+			}
+		})
+	}
+}
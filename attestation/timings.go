@@ -0,0 +1,61 @@
+package attestation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Phases timed while generating and verifying attestations
+const (
+	PhaseDownload      = "download"
+	PhaseAuth          = "oidc_auth"
+	PhasePreviousFetch = "previous_fetch"
+	PhaseSigning       = "signing"
+	PhaseVerify        = "verify"
+)
+
+// PhaseTiming is how long one phase took
+type PhaseTiming struct {
+	Phase    string        `json:"phase"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Timings accumulates phase durations through a generate or verify run
+type Timings struct {
+	Phases []PhaseTiming `json:"phases"`
+	Total  time.Duration `json:"total_ns"`
+	clock  func() time.Time
+	start  time.Time
+}
+
+// NewTimings starts timing a run. clock defaults to time.Now and can be replaced for tests.
+func NewTimings(clock func() time.Time) *Timings {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &Timings{clock: clock, start: clock()}
+}
+
+// Start begins timing a phase and returns a function that records it when called
+func (t *Timings) Start(phase string) func() {
+	started := t.clock()
+	return func() {
+		t.Phases = append(t.Phases, PhaseTiming{Phase: phase, Duration: t.clock().Sub(started)})
+	}
+}
+
+// Finish records the total duration of the run
+func (t *Timings) Finish() {
+	t.Total = t.clock().Sub(t.start)
+}
+
+// String formats the timings as one line per phase
+func (t *Timings) String() string {
+	var builder strings.Builder
+	for _, phase := range t.Phases {
+		fmt.Fprintf(&builder, "  %s: %s\n", phase.Phase, phase.Duration)
+	}
+	fmt.Fprintf(&builder, "  total: %s\n", t.Total)
+	return builder.String()
+}
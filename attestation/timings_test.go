@@ -0,0 +1,71 @@
+package attestation
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// fakeClock advances by step every time it is read
+type fakeClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *fakeClock) read() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func TestTimings(t *testing.T) {
+	phases := []string{PhaseDownload, PhaseAuth, PhasePreviousFetch, PhaseSigning}
+
+	tests := []struct {
+		name      string
+		step      time.Duration
+		wantPhase time.Duration
+		wantTotal time.Duration
+	}{
+		// Each phase reads the clock when it starts and stops; the run reads it when created and finished
+		{name: "one second per clock read", step: time.Second, wantPhase: time.Second, wantTotal: 9 * time.Second},
+		{name: "one millisecond per clock read", step: time.Millisecond, wantPhase: time.Millisecond, wantTotal: 9 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), step: tt.step}
+			timings := NewTimings(clock.read)
+			for _, phase := range phases {
+				stop := timings.Start(phase)
+				stop()
+			}
+			timings.Finish()
+
+			if len(timings.Phases) != len(phases) {
+				t.Fatalf("recorded %d phases, want %d", len(timings.Phases), len(phases))
+			}
+			for i, phase := range timings.Phases {
+				if phase.Phase != phases[i] {
+					t.Errorf("phase %d = %s, want %s", i, phase.Phase, phases[i])
+				}
+				if phase.Duration != tt.wantPhase {
+					t.Errorf("%s took %s, want %s", phase.Phase, phase.Duration, tt.wantPhase)
+				}
+			}
+			if timings.Total != tt.wantTotal {
+				t.Errorf("Total = %s, want %s", timings.Total, tt.wantTotal)
+			}
+
+			data, err := json.Marshal(timings)
+			if err != nil {
+				t.Fatalf("failed to marshal timings: %v", err)
+			}
+			var decoded Timings
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("failed to unmarshal timings: %v", err)
+			}
+			if decoded.Total != timings.Total || len(decoded.Phases) != len(phases) {
+				t.Errorf("JSON round trip gave %+v, want %+v", decoded, *timings)
+			}
+		})
+	}
+}
@@ -0,0 +1,850 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/openpubkey/openpubkey/pktoken"
+	"github.com/openpubkey/openpubkey/verifier"
+)
+
+// maxClockSkew is how far in the future an attestation timestamp may be before it is rejected
+const maxClockSkew = 5 * time.Minute
+
+// VerifyOptions configures the checks performed by VerifyAttestation
+type VerifyOptions struct {
+	// Provider is the OIDC provider that issued the PK token, defaults to ProviderGithub
+	Provider string
+	// Issuer is the OIDC issuer the PK token must be issued by, defaults to the provider's issuer.
+	// It is checked against the iss claim even when ProviderVerifier is set.
+	Issuer string
+	// TrustedIssuers are further issuers accepted alongside Issuer, e.g. while migrating between issuers.
+	// The PK token is verified against whichever trusted issuer minted it.
+	TrustedIssuers []string
+	// AcceptedIssuers replaces Issuer, TrustedIssuers and JWKSPath with a list of issuers, each with its own
+	// provider and JWKS source. The PK token must verify under one of the entries for its iss claim.
+	AcceptedIssuers []AcceptedIssuer
+	// ExpectedWorkflowRef is the job_workflow_ref the PK token must carry. A ref name of RefWildcard
+	// (e.g. @refs/tags/*) accepts any branch or tag of that workflow.
+	ExpectedWorkflowRef string
+	// ExpectedWorkflowRefs are further job_workflow_refs accepted in addition to ExpectedWorkflowRef
+	ExpectedWorkflowRefs []string
+	// ExpectedRepository is the owner/name repository claim the PK token must carry, unchecked when empty
+	ExpectedRepository string
+	// ExpectedRepositoryOwner is the repository_owner claim the PK token must carry, unchecked when empty
+	ExpectedRepositoryOwner string
+	// AllowedEventNames is an allowlist for the event_name claim (e.g. push, schedule), unchecked when empty
+	AllowedEventNames []string
+	// AllowedSignatureAlgorithms is an allowlist for the algorithm of the signer's key (e.g. ES256), rejecting
+	// attestations signed with a weaker algorithm. Unchecked when empty.
+	AllowedSignatureAlgorithms []string
+	// Policy restricts the PK token claims, unchecked when nil
+	Policy *Policy
+	// JWKSPath verifies the PK token against a JWKS file instead of fetching the issuer's keys
+	JWKSPath string
+	// KeyLogDir requires the OP signing key to be in the key log at this directory (see ExportKeyLog).
+	// Unless JWKSPath is set, the PK token is verified against the logged keys instead of the live JWKS,
+	// so attestations signed with since-rotated keys still verify.
+	KeyLogDir string
+	// MaxAge rejects attestations older than this duration, disabled when 0
+	MaxAge time.Duration
+	// ExpectedTLSFingerprint is the sha256 fingerprint the recorded leaf certificate must have
+	ExpectedTLSFingerprint string
+	// ExpectedTLSIssuer is the issuer distinguished name the recorded leaf certificate must have
+	ExpectedTLSIssuer string
+	// CheckPreviousArtifact walks the chain of previous attestations, downloading each from its recorded artifact
+	// URL and comparing its digest with the recorded one. An expired (404/410) first artifact is reported as
+	// unavailable, not as a failure; a later expired artifact ends the walk.
+	CheckPreviousArtifact bool
+	// ArtifactToken authenticates artifact downloads, e.g. a GitHub token for the artifacts API
+	ArtifactToken string
+	// ArtifactOptions are the download options for previous artifacts outside the GitHub API: the private
+	// address guard, proxy and CA roots. Artifact URLs come from attestations, so NewVerifyOptions blocks
+	// private addresses.
+	ArtifactOptions DownloadOptions
+	// SignerThreshold is how many distinct signers, the primary signer included, must verify. Defaults to all
+	// of them. The primary signer's checks are always required, as its PK token carries the workflow claims.
+	// Signers are told apart by their PK token's issuer and subject, and cosigners must use another provider
+	// than the primary signer.
+	SignerThreshold int
+	// TSARoots requires an RFC 3161 timestamp token over the signature, signed by a TSA certificate chaining to
+	// these roots. Unchecked when nil.
+	TSARoots *x509.CertPool
+	// VerifyInclusion requires the signed payload digest to be in a Rekor transparency log, in an entry signed
+	// by the key bound to the PK token, with an inclusion proof leading to a checkpoint signed by RekorPublicKey.
+	// It is checked once the PK token, issuer and signed payload digest have verified. RekorURL defaults to the
+	// attestation's recorded log.
+	VerifyInclusion bool
+	RekorURL        string
+	RekorPublicKey  crypto.PublicKey
+	// RekorOptions are the download options for a transparency log URL recorded in the attestation: the
+	// schemes allowed, the private address guard, proxy and CA roots. The recorded URL is not signed, so only
+	// https is allowed unless these allow more; NewVerifyOptions also blocks private addresses.
+	RekorOptions DownloadOptions
+	// ExpectedURL requires the attestation to be for this URL, compared after NormalizeURL
+	ExpectedURL string
+	// RecheckContent re-downloads the attested URL and compares it to the recorded digest, once the PK token,
+	// issuer and signed payload digest have verified
+	RecheckContent bool
+	// RecheckOptions are the download options RecheckContent uses: the schemes allowed, the private address
+	// guard, proxy and CA roots. The URL comes from the attestation, so only https is allowed unless these
+	// allow more; NewVerifyOptions also blocks private addresses.
+	RecheckOptions DownloadOptions
+	// RequestBody is replayed by RecheckContent for attestations of a request with a body, and must match
+	// the recorded request body digest
+	RequestBody []byte
+	// ProviderVerifier overrides the verifier built from Issuer, e.g. to verify against a mock OP
+	ProviderVerifier verifier.ProviderVerifier
+	// CosignerVerifiers override the verifier built for each cosigner's provider, keyed by provider
+	CosignerVerifiers map[string]verifier.ProviderVerifier
+	// ClaimsExtractor overrides the extractor registered for Provider
+	ClaimsExtractor ClaimsExtractor
+}
+
+// NewVerifyOptions returns VerifyOptions with the defaults used by verify_attestation
+func NewVerifyOptions() VerifyOptions {
+	return VerifyOptions{
+		Provider:        ProviderGithub,
+		RecheckOptions:  DownloadOptions{BlockPrivateAddresses: true},
+		ArtifactOptions: DownloadOptions{BlockPrivateAddresses: true},
+		RekorOptions:    DownloadOptions{BlockPrivateAddresses: true},
+	}
+}
+
+// VerificationResult contains the results of attestation verification
+type VerificationResult struct {
+	PKTokenVerified              bool
+	IssuerVerified               bool
+	MatchedIssuer                string          // the trusted issuer that minted the PK token, when it is trusted
+	MatchedAcceptedIssuer        *AcceptedIssuer // the accepted issuer the PK token verified under, with AcceptedIssuers
+	SignedMessageVerified        bool
+	SignerBindingVerified        bool               // the message was signed by the key committed to in the PK token
+	Algorithms                   *SigningAlgorithms // the OP and signer algorithms, when the PK token headers parse
+	SignatureAlgorithmVerified   bool
+	PayloadDigestVerified        bool
+	OracleDigestVerified         bool
+	WorkflowRefVerified          bool
+	WorkflowSHAVerified          bool
+	TimestampVerified            bool
+	TimestampConsistencyVerified bool
+	ContentRecheckVerified       bool
+	TLSCertificateVerified       bool
+	ContentSizeVerified          bool
+	ContentDigestConsistent      bool
+	KeyInLogVerified             bool
+	PreviousArtifactVerified     bool
+	PreviousArtifactUnavailable  bool // the previous attestation's artifact has expired
+	PreviousChainLength          int  // how many previous attestation links were walked and verified
+	RepositoryVerified           bool
+	RepositoryOwnerVerified      bool
+	StatementType                string // the payload's statement type, StatementTypeURLContent when it has none
+	JWKSSnapshotVerified         bool
+	NormalizationVerified        bool
+	TimestampTokenVerified       bool
+	TimestampTokenTime           time.Time // the time attested by the timestamp token, when verified
+	InclusionVerified            bool
+	HasValidityWindow            bool // the payload declares a not_before or not_after validity window
+	ValidityWindowVerified       bool
+	URLVerified                  bool
+	SignerCount                  int // the primary signer and any cosigners
+	SignersVerified              int
+	SignerThresholdMet           bool
+	CosignerErrors               []string // why cosigners failed, also in Errors when the threshold was not met
+	EventNameVerified            bool
+	PolicyVerified               bool
+	PolicyViolations             []string // the policy rules the claims failed, also in Errors
+	ContentPreview               string   // ContentPreview of the stored content, untrusted unless verification succeeded
+	Errors                       []string
+}
+
+// VerifyAttestationFile loads an attestation from disk and verifies it
+func VerifyAttestationFile(attestationFile string, opts VerifyOptions) (*VerificationResult, error) {
+	attestation, err := LoadAttestation(attestationFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attestation: %w", err)
+	}
+	return VerifyAttestation(attestation, opts)
+}
+
+// VerifyAttestation performs all verification steps on an attestation
+func VerifyAttestation(attestation *Attestation, opts VerifyOptions) (*VerificationResult, error) {
+	return VerifyAttestationContext(context.Background(), attestation, opts)
+}
+
+// VerifyAttestationContext performs all verification steps like VerifyAttestation, using ctx for
+// fetching OP keys and re-downloading content
+func VerifyAttestationContext(ctx context.Context, attestation *Attestation, opts VerifyOptions) (*VerificationResult, error) {
+	result := &VerificationResult{
+		Errors: make([]string, 0),
+	}
+
+	if attestation.PKToken == nil {
+		return nil, fmt.Errorf("attestation has no PK token")
+	}
+
+	// Verify that PK Token is issued by the OP you wish to use. An untrusted token is verified against the
+	// expected issuer, so it fails PK token verification too.
+	issuer, issuerErr := opts.matchIssuer(attestation.PKToken)
+	var err error
+	if len(opts.AcceptedIssuers) > 0 {
+		// Try each accepted issuer's keys in turn, then apply the claims of the provider that verified
+		result.MatchedAcceptedIssuer, err = opts.verifyAcceptedIssuers(ctx, attestation.PKToken, issuer)
+		if result.MatchedAcceptedIssuer != nil && result.MatchedAcceptedIssuer.Provider != "" {
+			opts.Provider = result.MatchedAcceptedIssuer.Provider
+		}
+	} else {
+		providerVerifier, verifierErr := opts.providerVerifier(issuer)
+		if verifierErr != nil {
+			return nil, verifierErr
+		}
+		err = verifyPKToken(ctx, providerVerifier, attestation.PKToken)
+	}
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("PK Token verification failed: %v", err))
+	} else {
+		result.PKTokenVerified = true
+	}
+
+	// Independently check the iss claim, in addition to the checks made by the PK token verifier
+	if issuerErr != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Issuer verification failed: %v", issuerErr))
+	} else {
+		result.IssuerVerified = true
+		result.MatchedIssuer = issuer
+	}
+
+	// Check that the message verifies under the user's public key in the PK Token
+	msg, err := attestation.PKToken.VerifySignedMessage(attestation.Signature)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Signed message verification failed: %v", err))
+	} else {
+		result.SignedMessageVerified = true
+	}
+
+	// Check explicitly that the signer is the key the PK token commits to, so the binding is visible
+	if !result.PKTokenVerified || !result.SignedMessageVerified {
+		result.Errors = append(result.Errors, "Signer binding verification failed: the PK token and signed message must both verify")
+	} else if err := verifySignerBinding(attestation.PKToken, attestation.Signature); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Signer binding verification failed: %v", err))
+	} else {
+		result.SignerBindingVerified = true
+	}
+
+	// Report the algorithms protecting the attestation, and check the signer's against the allowlist
+	algorithms, err := ReadSigningAlgorithms(attestation.PKToken)
+	if err != nil && len(opts.AllowedSignatureAlgorithms) > 0 {
+		result.Errors = append(result.Errors, fmt.Sprintf("Signature algorithm verification failed: %v", err))
+	}
+	if algorithms != nil {
+		result.Algorithms = algorithms
+		if len(opts.AllowedSignatureAlgorithms) > 0 {
+			if !slices.Contains(opts.AllowedSignatureAlgorithms, algorithms.SignatureAlgorithm) {
+				result.Errors = append(result.Errors, fmt.Sprintf("Signature algorithm verification failed: %q is not one of %q", algorithms.SignatureAlgorithm, opts.AllowedSignatureAlgorithms))
+			} else {
+				result.SignatureAlgorithmVerified = true
+			}
+		}
+	}
+
+	// Check that msg is the same as the attestation payload digest
+	digest, err := attestation.Payload.Hash()
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to generate attestation payload digest: %v", err))
+	} else if err := compareSignedDigest(msg, digest); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Attestation payload digest does not match signed message: %v", err))
+	} else {
+		result.PayloadDigestVerified = true
+	}
+
+	// Check each cosigner signed the same payload digest, and that enough distinct signers verified. A
+	// signer repeated, or the primary signer copied in as a cosigner, counts once.
+	result.SignerCount = 1 + len(attestation.Cosigners)
+	signers := make(map[string]bool)
+	if result.SignerBindingVerified && result.PayloadDigestVerified {
+		result.SignersVerified++
+		if identity, err := signerIdentity(attestation.PKToken); err == nil {
+			signers[identity] = true
+		}
+	}
+	for i, cosigner := range attestation.Cosigners {
+		if digest == nil {
+			break
+		}
+		identity, err := verifyCosigner(ctx, cosigner, digest, opts)
+		if err == nil && signers[identity] {
+			err = fmt.Errorf("signer %s has already signed", identity)
+		}
+		if err != nil {
+			result.CosignerErrors = append(result.CosignerErrors, fmt.Sprintf("Cosigner %d (%s) verification failed: %v", i+1, cosigner.Provider, err))
+		} else {
+			signers[identity] = true
+			result.SignersVerified++
+		}
+	}
+	// Cosigner failures only fail verification when they leave too few signers
+	if threshold := opts.signerThreshold(result.SignerCount); threshold > result.SignerCount {
+		result.Errors = append(result.Errors, fmt.Sprintf("Signer threshold %d exceeds the %d signers", threshold, result.SignerCount))
+	} else if result.SignersVerified < threshold {
+		result.Errors = append(result.Errors, result.CosignerErrors...)
+		result.Errors = append(result.Errors, fmt.Sprintf("Only %d of %d signers verified, %d required", result.SignersVerified, result.SignerCount, threshold))
+	} else {
+		result.SignerThresholdMet = true
+	}
+
+	// Check that the attestation payload is valid by recreating it and comparing digests
+	// This verifies that the oracle generated the attestation correctly
+	if toverify, err := attestation.Payload.rebuild(); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to create attestation payload: %v", err))
+	} else if digestToVerify, err := toverify.Hash(); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to generate oracle digest: %v", err))
+	} else if err := compareSignedDigest(msg, digestToVerify); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Oracle generated digest does not match signed message: %v", err))
+	} else {
+		result.OracleDigestVerified = true
+	}
+
+	// Decompress stored content before checking it, as the digest and size are of the uncompressed content
+	content, err := attestation.Payload.DecodedContent()
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Content decompression failed: %v", err))
+	}
+	if content != nil {
+		result.ContentPreview = ContentPreview(content, attestation.Payload.ContentType)
+	}
+
+	// Reject statement types this verifier does not know how to validate rather than accepting them as url-content
+	result.StatementType = attestation.Payload.statementType()
+	switch result.StatementType {
+	case StatementTypeURLContent:
+	case StatementTypeJWKSSnapshot:
+		// A snapshot must hold a well-formed key set (skipped when content is not stored)
+		if content != nil {
+			if err := ValidateJWKS(content); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("JWKS snapshot verification failed: %v", err))
+			} else {
+				result.JWKSSnapshotVerified = true
+			}
+		}
+	case StatementTypeURLHead:
+		// Head-only attestations record metadata in place of content, so there is no content digest to check
+		if attestation.Payload.Head == nil {
+			result.Errors = append(result.Errors, "Head-only attestation has no head metadata")
+		}
+		if attestation.Payload.Content != nil || attestation.Payload.ContentDigest != "" {
+			result.Errors = append(result.Errors, "Head-only attestation must not record content or a content digest")
+		}
+	default:
+		result.Errors = append(result.Errors, fmt.Sprintf("Unknown statement type %q", result.StatementType))
+	}
+
+	// Verify the recorded size matches the embedded content (skipped when content is not stored)
+	if content != nil {
+		if int64(len(content)) != attestation.Payload.ContentSize {
+			result.Errors = append(result.Errors, fmt.Sprintf("Content size %d does not match recorded content size %d", len(content), attestation.Payload.ContentSize))
+		} else {
+			result.ContentSizeVerified = true
+		}
+
+		// Verify the recorded digest is the digest of the embedded content
+		if digest := ContentDigest(content); !digestsEqual(digest, attestation.Payload.ContentDigest) {
+			result.Errors = append(result.Errors, fmt.Sprintf("Content digest %s does not match recorded content digest %s", digest, attestation.Payload.ContentDigest))
+		} else {
+			result.ContentDigestConsistent = true
+		}
+
+		// Verify normalized content is already in its canonical form, so the recorded normalization is reproducible
+		if attestation.Payload.Normalization != "" {
+			if normalized, err := NormalizeContent(content, attestation.Payload.Normalization); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("Normalization verification failed: %v", err))
+			} else if !bytes.Equal(normalized, content) {
+				result.Errors = append(result.Errors, fmt.Sprintf("Content is not in %s normalized form", attestation.Payload.Normalization))
+			} else {
+				result.NormalizationVerified = true
+			}
+		}
+	}
+
+	// Verify the multihash, if recorded, is the same digest as the canonical content digest
+	if attestation.Payload.ContentDigestMultihash != "" {
+		if multihash, err := ContentDigestMultihash(attestation.Payload.ContentDigest); err != nil || multihash != attestation.Payload.ContentDigestMultihash {
+			result.Errors = append(result.Errors, "Content digest multihash does not match recorded content digest")
+		}
+	}
+
+	// Parse the PK token claims once for the workflow and timestamp checks
+	claims, claimsErr := opts.extractClaims(attestation.PKToken)
+
+	// Verify PK token workflow reference matches expected workflow
+	if claimsErr != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Workflow reference verification failed: %v", claimsErr))
+	} else if verifyWorkflowRef(claims, opts.expectedWorkflowRefs()) {
+		result.WorkflowRefVerified = true
+	} else {
+		result.Errors = append(result.Errors, fmt.Sprintf("PK token workflow reference does not match expected workflow %q", opts.expectedWorkflowRefs()))
+	}
+
+	// Verify PK token workflow SHA matches commit SHA
+	if claimsErr != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Workflow SHA verification failed: %v", claimsErr))
+	} else if claims.JobWorkflowSHA == attestation.Payload.CommitSHA {
+		result.WorkflowSHAVerified = true
+	} else {
+		result.Errors = append(result.Errors, "PK token workflow SHA does not match commit SHA")
+	}
+
+	// Verify the payload timestamp was derived from the PK token iat claim
+	if claimsErr != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Timestamp consistency verification failed: %v", claimsErr))
+	} else if time.Unix(claims.IAT, 0).UTC().Format(time.RFC3339) == attestation.Payload.Timestamp {
+		result.TimestampConsistencyVerified = true
+	} else {
+		result.Errors = append(result.Errors, "Attestation timestamp does not match PK token iat claim")
+	}
+
+	// Verify the attestation is for the expected URL (only when requested)
+	if opts.ExpectedURL != "" {
+		// Compare in the normalized form the oracle records, so e.g. a missing trailing slash still matches
+		if expectedURL, err := NormalizeURL(opts.ExpectedURL); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("URL verification failed: %v", err))
+		} else if attestation.Payload.Url != expectedURL {
+			result.Errors = append(result.Errors, fmt.Sprintf("URL verification failed: attestation is for %s, not %s", attestation.Payload.Url, expectedURL))
+		} else {
+			result.URLVerified = true
+		}
+	}
+
+	// Verify the source repository and its owner, so a fork running the same workflow is rejected (only when requested)
+	if opts.ExpectedRepository != "" {
+		if claimsErr != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Repository verification failed: %v", claimsErr))
+		} else if claims.Repository != opts.ExpectedRepository {
+			result.Errors = append(result.Errors, fmt.Sprintf("PK token repository %q does not match expected repository %q", claims.Repository, opts.ExpectedRepository))
+		} else {
+			result.RepositoryVerified = true
+		}
+	}
+	if opts.ExpectedRepositoryOwner != "" {
+		if claimsErr != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Repository owner verification failed: %v", claimsErr))
+		} else if claims.RepositoryOwner != opts.ExpectedRepositoryOwner {
+			result.Errors = append(result.Errors, fmt.Sprintf("PK token repository owner %q does not match expected owner %q", claims.RepositoryOwner, opts.ExpectedRepositoryOwner))
+		} else {
+			result.RepositoryOwnerVerified = true
+		}
+	}
+
+	// Verify the workflow was triggered by a trusted event, e.g. not a pull request (only when requested)
+	if len(opts.AllowedEventNames) > 0 {
+		if claimsErr != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Event name verification failed: %v", claimsErr))
+		} else if !slices.Contains(opts.AllowedEventNames, claims.EventName) {
+			result.Errors = append(result.Errors, fmt.Sprintf("PK token event name %q is not one of %q", claims.EventName, opts.AllowedEventNames))
+		} else {
+			result.EventNameVerified = true
+		}
+	}
+
+	// Verify the claims satisfy every rule of the policy (only when requested)
+	if opts.Policy != nil {
+		if claimsErr != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Policy verification failed: %v", claimsErr))
+		} else if violations, err := opts.Policy.Evaluate(claims); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Policy verification failed: %v", err))
+		} else if len(violations) > 0 {
+			result.PolicyViolations = violations
+			for _, violation := range violations {
+				result.Errors = append(result.Errors, fmt.Sprintf("Policy verification failed: %s", violation))
+			}
+		} else {
+			result.PolicyVerified = true
+		}
+	}
+
+	// Verify the current time is within the oracle's declared validity window, which is always enforced
+	if attestation.Payload.NotBefore != "" || attestation.Payload.NotAfter != "" {
+		result.HasValidityWindow = true
+		if err := verifyValidityWindow(&attestation.Payload, time.Now()); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Validity window verification failed: %v", err))
+		} else {
+			result.ValidityWindowVerified = true
+		}
+	}
+
+	// Verify the attestation timestamp is fresh (only when a maximum age is requested)
+	if opts.MaxAge > 0 {
+		if err := verifyTimestamp(attestation.Payload.Timestamp, opts.MaxAge, time.Now()); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Timestamp verification failed: %v", err))
+		} else {
+			result.TimestampVerified = true
+		}
+	}
+
+	// Verify the recorded server certificate matches expectations (only when requested)
+	if opts.ExpectedTLSFingerprint != "" || opts.ExpectedTLSIssuer != "" {
+		if err := verifyTLSCertificate(&attestation.Payload, opts.ExpectedTLSFingerprint, opts.ExpectedTLSIssuer); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("TLS certificate verification failed: %v", err))
+		} else {
+			result.TLSCertificateVerified = true
+		}
+	}
+
+	// Verify the RFC 3161 timestamp token proves the signature existed at its time (only when requested)
+	if opts.TSARoots != nil {
+		if attestation.TimestampToken == nil {
+			result.Errors = append(result.Errors, "Timestamp token verification failed: attestation has no timestamp token")
+		} else if genTime, err := VerifyTimestampToken(attestation.TimestampToken, attestation.Signature, opts.TSARoots); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Timestamp token verification failed: %v", err))
+		} else {
+			result.TimestampTokenVerified = true
+			result.TimestampTokenTime = genTime
+		}
+	}
+
+	// Check the OP signing key against the published key log (only when requested)
+	if opts.KeyLogDir != "" {
+		if err := VerifyKeyInLog(opts.KeyLogDir, attestation.PKToken); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Key log verification failed: %v", err))
+		} else {
+			result.KeyInLogVerified = true
+		}
+	}
+
+	// Check the previous attestation is still retrievable and matches its recorded digest (only when requested).
+	// The artifact URL comes from the payload, so it is only followed once the payload is known to be signed
+	// by the expected issuer and, as any workflow can get a token from the issuer, the expected workflow.
+	if opts.CheckPreviousArtifact && len(attestation.Payload.PreviousAttestation) > 0 {
+		workflowRefExpected := opts.ExpectedWorkflowRef != "" || len(opts.ExpectedWorkflowRefs) > 0
+		if !result.PKTokenVerified || !result.IssuerVerified || !result.SignerBindingVerified || !result.PayloadDigestVerified ||
+			(workflowRefExpected && !result.WorkflowRefVerified) {
+			result.Errors = append(result.Errors, "Previous artifact verification skipped: the PK token, issuer, signed payload digest and workflow reference must verify first")
+		} else if chain, err := newPreviousChain(opts.ArtifactToken, opts.ArtifactOptions); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Previous artifact verification failed: %v", err))
+		} else if links, unavailable, err := chain.verify(attestation); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Previous artifact verification failed: %v", err))
+		} else if unavailable {
+			result.PreviousArtifactUnavailable = true
+		} else {
+			result.PreviousArtifactVerified = true
+			result.PreviousChainLength = links
+		}
+	}
+
+	// Confirm the signed payload digest is in the transparency log (only when requested). The entry must be
+	// signed by the PK token's key, so it is only looked up once the PK token and payload are known to be good.
+	if opts.VerifyInclusion {
+		if !result.PKTokenVerified || !result.IssuerVerified || !result.SignerBindingVerified || !result.PayloadDigestVerified {
+			result.Errors = append(result.Errors, "Inclusion verification failed: skipped as the PK token, issuer and signed payload digest must verify first")
+		} else if err := verifyInclusion(ctx, attestation, opts); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Inclusion verification failed: %v", err))
+		} else {
+			result.InclusionVerified = true
+		}
+	}
+
+	// Re-download the attested URL and compare it with the recorded digest (only when requested). The URL
+	// comes from the payload, so it is only fetched once the payload is known to be signed by the expected
+	// issuer, and then only as the verifier's own options allow.
+	if opts.RecheckContent {
+		var download *DownloadResult
+		var err error
+		if !result.PKTokenVerified || !result.IssuerVerified || !result.SignerBindingVerified || !result.PayloadDigestVerified {
+			err = fmt.Errorf("skipped as the PK token, issuer and signed payload digest must verify first")
+		} else if result.StatementType == StatementTypeJWKSSnapshot {
+			// Snapshots record the issuer, whose JWKS is found through discovery
+			download, err = DownloadJWKSSnapshotWithOptions(ctx, attestation.Payload.Url, opts.RecheckOptions)
+		} else {
+			// Only the digest is compared, so the content is not kept
+			recheckOpts := opts.RecheckOptions
+			recheckOpts.Normalize = attestation.Payload.Normalization
+			recheckOpts.HeadOnly = result.StatementType == StatementTypeURLHead
+			recheckOpts.DigestOnly = true
+			download, err = recheckRequest(ctx, &attestation.Payload, opts.RequestBody, recheckOpts)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Content recheck failed: %v", err))
+		} else if result.StatementType == StatementTypeURLHead {
+			if attestation.Payload.Head == nil || *download.Head != *attestation.Payload.Head {
+				result.Errors = append(result.Errors, fmt.Sprintf("Current head metadata %+v does not match attested metadata %+v", *download.Head, attestation.Payload.Head))
+			} else {
+				result.ContentRecheckVerified = true
+			}
+		} else if !digestsEqual(download.ContentDigest, attestation.Payload.ContentDigest) {
+			result.Errors = append(result.Errors, fmt.Sprintf("Current content digest %s does not match attested digest %s", download.ContentDigest, attestation.Payload.ContentDigest))
+		} else {
+			result.ContentRecheckVerified = true
+		}
+	}
+
+	return result, nil
+}
+
+// recheckRequest re-downloads the attested URL, replaying the recorded request method and body. The body
+// is not recorded, only its digest, so it must be given and match.
+func recheckRequest(ctx context.Context, payload *AttestationPayload, body []byte, opts DownloadOptions) (*DownloadResult, error) {
+	opts.Method = payload.Method
+	if payload.RequestBodyDigest != "" {
+		if body == nil {
+			return nil, fmt.Errorf("the attested request had a body, which must be given to replay it")
+		}
+		if digest := ContentDigest(body); !digestsEqual(digest, payload.RequestBodyDigest) {
+			return nil, fmt.Errorf("request body digest %s does not match attested request body digest %s", digest, payload.RequestBodyDigest)
+		}
+		opts.RequestBody = body
+		opts.RequestContentType = payload.RequestContentType
+	}
+	return DownloadContentContext(ctx, payload.Url, opts)
+}
+
+// verifyInclusion checks the attestation's signed payload digest is in the configured or recorded Rekor log
+func verifyInclusion(ctx context.Context, attestation *Attestation, opts VerifyOptions) error {
+	if opts.RekorPublicKey == nil {
+		return fmt.Errorf("no transparency log public key configured")
+	}
+	inclusion := RekorInclusion{LogURL: opts.RekorURL, LogKey: opts.RekorPublicKey}
+	if entry := attestation.TransparencyLog; entry != nil {
+		if inclusion.LogURL == "" {
+			// The recorded log is outside the signed payload, so it is only reached as RekorOptions allow
+			if err := validateURL(entry.LogURL, opts.RekorOptions); err != nil {
+				return err
+			}
+			client, err := opts.RekorOptions.httpClient()
+			if err != nil {
+				return err
+			}
+			inclusion.LogURL, inclusion.Client = entry.LogURL, client
+		}
+		if strings.TrimSuffix(inclusion.LogURL, "/") == entry.LogURL {
+			inclusion.UUID = entry.UUID
+		}
+	}
+	if inclusion.LogURL == "" {
+		return fmt.Errorf("no transparency log URL configured or recorded")
+	}
+
+	digest, err := attestation.Payload.Hash()
+	if err != nil {
+		return err
+	}
+	inclusion.Artifact = digest
+	inclusion.SignerKey, err = signerPublicKey(attestation.PKToken)
+	if err != nil {
+		return err
+	}
+	return VerifyRekorInclusion(ctx, inclusion)
+}
+
+// provider returns the configured provider, defaulting to GitHub Actions
+func (o VerifyOptions) provider() string {
+	if o.Provider == "" {
+		return ProviderGithub
+	}
+	return o.Provider
+}
+
+// issuer returns the configured issuer, defaulting to the provider's issuer
+func (o VerifyOptions) issuer() (string, error) {
+	if o.Issuer != "" {
+		return o.Issuer, nil
+	}
+	return ProviderIssuer(o.provider())
+}
+
+// expectedWorkflowRefs returns every accepted workflow reference
+func (o VerifyOptions) expectedWorkflowRefs() []string {
+	return append([]string{o.ExpectedWorkflowRef}, o.ExpectedWorkflowRefs...)
+}
+
+// extractClaims extracts the PK token claims using the configured or registered claims extractor
+func (o VerifyOptions) extractClaims(pkToken *pktoken.PKToken) (*IDTokenClaims, error) {
+	if o.ClaimsExtractor != nil {
+		return o.ClaimsExtractor.ExtractClaims(pkToken)
+	}
+	return ExtractProviderClaims(o.provider(), pkToken)
+}
+
+// verifyPKToken verifies pkToken with the OP checks and keys of providerVerifier
+func verifyPKToken(ctx context.Context, providerVerifier verifier.ProviderVerifier, pkToken *pktoken.PKToken) error {
+	pktVerifier, err := verifier.New(providerVerifier)
+	if err != nil {
+		return fmt.Errorf("failed to create PK Token verifier: %w", err)
+	}
+	return pktVerifier.VerifyPKToken(ctx, pkToken)
+}
+
+// providerVerifier returns the configured provider verifier or one for the configured provider and issuer
+func (o VerifyOptions) providerVerifier(issuer string) (verifier.ProviderVerifier, error) {
+	if o.ProviderVerifier != nil {
+		return o.ProviderVerifier, nil
+	}
+	if o.JWKSPath != "" {
+		return newProviderVerifier(o.provider(), issuer, jwksFileFinder(o.JWKSPath))
+	}
+	if o.KeyLogDir != "" {
+		return newProviderVerifier(o.provider(), issuer, keyLogFinder(o.KeyLogDir))
+	}
+	return NewProviderVerifier(o.provider(), issuer)
+}
+
+// IsVerificationSuccessful checks if all verification steps passed
+func (vr *VerificationResult) IsVerificationSuccessful() bool {
+	return vr.PKTokenVerified &&
+		vr.IssuerVerified &&
+		vr.SignedMessageVerified &&
+		vr.SignerBindingVerified &&
+		vr.PayloadDigestVerified &&
+		vr.OracleDigestVerified &&
+		vr.WorkflowRefVerified &&
+		vr.WorkflowSHAVerified &&
+		vr.TimestampConsistencyVerified &&
+		len(vr.Errors) == 0
+}
+
+// GetSummary returns a summary of verification results
+func (vr *VerificationResult) GetSummary() string {
+	if vr.IsVerificationSuccessful() {
+		return "✅ All verification steps passed successfully"
+	}
+
+	summary := "❌ Verification failed:\n"
+	for _, err := range vr.Errors {
+		summary += fmt.Sprintf("  - %s\n", err)
+	}
+	if vr.ContentPreview != "" {
+		summary += fmt.Sprintf("  Content preview (untrusted): %s\n", vr.ContentPreview)
+	}
+	return summary
+}
+
+// compareSignedDigest compares a verified signed message with a payload digest in constant time, telling
+// a message that is not a sha256 digest at all apart from a digest of a different payload
+func compareSignedDigest(msg []byte, digest []byte) error {
+	if msg == nil {
+		return fmt.Errorf("no verified signed message")
+	}
+	if len(msg) != sha256.Size {
+		return fmt.Errorf("signed message is %d bytes, not a %d-byte sha256 digest", len(msg), sha256.Size)
+	}
+	if subtle.ConstantTimeCompare(msg, digest) != 1 {
+		return fmt.Errorf("signed digest %x, payload digest %x", msg, digest)
+	}
+	return nil
+}
+
+// digestsEqual compares two encoded digests in constant time, so verifying a digest an attacker controls
+// does not leak how much of it matched
+func digestsEqual(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// verifyWorkflowRef checks if the job_workflow_ref claim matches one of the expected workflows
+func verifyWorkflowRef(claims *IDTokenClaims, expectedWorkflowRefs []string) bool {
+	for _, expectedWorkflowRef := range expectedWorkflowRefs {
+		if MatchWorkflowRef(claims.JobWorkflowRef, expectedWorkflowRef) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyTimestamp checks that the RFC3339 timestamp is no older than maxAge and not ahead of now by more than maxClockSkew
+func verifyTimestamp(timestamp string, maxAge time.Duration, now time.Time) error {
+	issuedAt, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to parse timestamp %q: %w", timestamp, err)
+	}
+
+	if issuedAt.After(now.Add(maxClockSkew)) {
+		return fmt.Errorf("timestamp %s is in the future", timestamp)
+	}
+	if now.Sub(issuedAt) > maxAge {
+		return fmt.Errorf("timestamp %s is older than the maximum age of %s", timestamp, maxAge)
+	}
+
+	return nil
+}
+
+// verifyValidityWindow checks now is within the payload's not_before and not_after, allowing maxClockSkew
+func verifyValidityWindow(payload *AttestationPayload, now time.Time) error {
+	if payload.NotBefore != "" {
+		notBefore, err := time.Parse(time.RFC3339, payload.NotBefore)
+		if err != nil {
+			return fmt.Errorf("failed to parse not_before %q: %w", payload.NotBefore, err)
+		}
+		if now.Add(maxClockSkew).Before(notBefore) {
+			return fmt.Errorf("attestation is not valid before %s", payload.NotBefore)
+		}
+	}
+	if payload.NotAfter != "" {
+		notAfter, err := time.Parse(time.RFC3339, payload.NotAfter)
+		if err != nil {
+			return fmt.Errorf("failed to parse not_after %q: %w", payload.NotAfter, err)
+		}
+		if now.Add(-maxClockSkew).After(notAfter) {
+			return fmt.Errorf("attestation expired at %s", payload.NotAfter)
+		}
+	}
+	return nil
+}
+
+// matchIssuer returns the trusted or accepted issuer matching the PK token's iss claim. When the claim matches
+// none, it returns the expected issuer, empty if the provider has none, or with AcceptedIssuers the claimed
+// issuer, along with the error.
+func (o VerifyOptions) matchIssuer(pkToken *pktoken.PKToken) (string, error) {
+	if len(o.AcceptedIssuers) > 0 {
+		issuer, err := pkToken.Issuer()
+		if err != nil {
+			return "", fmt.Errorf("failed to get PK token issuer: %w", err)
+		}
+		for _, accepted := range o.AcceptedIssuers {
+			if issuer == accepted.Issuer {
+				return issuer, nil
+			}
+		}
+		return issuer, fmt.Errorf("PK token issuer %s is not one of the accepted issuers %s", issuer, o.acceptedIssuerNames())
+	}
+
+	expected, err := o.issuer()
+	if err != nil {
+		return o.Issuer, err
+	}
+	issuer, err := pkToken.Issuer()
+	if err != nil {
+		return expected, fmt.Errorf("failed to get PK token issuer: %w", err)
+	}
+	if issuer == expected {
+		return issuer, nil
+	}
+	for _, trusted := range o.TrustedIssuers {
+		if issuer == trusted {
+			return issuer, nil
+		}
+	}
+	if len(o.TrustedIssuers) > 0 {
+		return expected, fmt.Errorf("PK token issuer %s is not one of the trusted issuers %s, %s",
+			issuer, expected, strings.Join(o.TrustedIssuers, ", "))
+	}
+	return expected, fmt.Errorf("PK token issuer %s does not match expected issuer %s", issuer, expected)
+}
+
+// verifyTLSCertificate checks the recorded leaf certificate against the expected fingerprint and issuer, when set
+func verifyTLSCertificate(payload *AttestationPayload, expectedFingerprint string, expectedIssuer string) error {
+	leafDigest := payload.TLSCertDigest()
+	if leafDigest == "" {
+		return fmt.Errorf("attestation does not record a TLS certificate")
+	}
+	if expectedFingerprint != "" && !strings.EqualFold(leafDigest, expectedFingerprint) {
+		return fmt.Errorf("leaf certificate fingerprint %s does not match expected %s", leafDigest, expectedFingerprint)
+	}
+	if expectedIssuer != "" && payload.TLSLeafIssuer != expectedIssuer {
+		return fmt.Errorf("leaf certificate issuer %q does not match expected %q", payload.TLSLeafIssuer, expectedIssuer)
+	}
+	return nil
+}
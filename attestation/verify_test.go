@@ -0,0 +1,1570 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// previousDetails returns marshaled details pointing at previous as served from artifactURL
+func previousDetails(t *testing.T, previous *Attestation, artifactURL string) []byte {
+	t.Helper()
+	digest, err := previous.Digest()
+	if err != nil {
+		t.Fatalf("Digest: %v", err)
+	}
+	details, err := json.Marshal(AttestationDetails{Digest: digest, ArtifactURL: artifactURL})
+	if err != nil {
+		t.Fatalf("failed to marshal details: %v", err)
+	}
+	return details
+}
+
+func TestVerifyPreviousArtifact(t *testing.T) {
+	op := newTestOP(t)
+	previous := op.attest(t, testDownload("https://example.com/", []byte("v1")), nil)
+	other := op.attest(t, testDownload("https://example.com/", []byte("other")), nil)
+
+	tests := []struct {
+		name            string
+		serve           func(w http.ResponseWriter)
+		tamper          bool
+		claims          map[string]any
+		blockPrivate    bool
+		wantVerified    bool
+		wantUnavailable bool
+		wantError       string
+		wantRequests    int32
+	}{
+		{
+			name:         "reachable and matching",
+			serve:        func(w http.ResponseWriter) { json.NewEncoder(w).Encode(previous) },
+			wantVerified: true,
+			wantRequests: 1,
+		},
+		{
+			name:         "mismatching digest",
+			serve:        func(w http.ResponseWriter) { json.NewEncoder(w).Encode(other) },
+			wantError:    "Previous artifact verification failed",
+			wantRequests: 1,
+		},
+		{
+			name:            "expired artifact",
+			serve:           func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) },
+			wantUnavailable: true,
+			wantRequests:    1,
+		},
+		{
+			name:         "server error",
+			serve:        func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+			wantError:    "Previous artifact verification failed",
+			wantRequests: 1,
+		},
+		{
+			name:         "unverified signature is not followed",
+			serve:        func(w http.ResponseWriter) { json.NewEncoder(w).Encode(previous) },
+			tamper:       true,
+			wantError:    "Previous artifact verification skipped",
+			wantRequests: 0,
+		},
+		{
+			name:         "another workflow's attestation is not followed",
+			serve:        func(w http.ResponseWriter) { json.NewEncoder(w).Encode(previous) },
+			claims:       map[string]any{"job_workflow_ref": "attacker/repo/.github/workflows/x.yml@refs/heads/main"},
+			wantError:    "Previous artifact verification skipped",
+			wantRequests: 0,
+		},
+		{
+			name:         "private address is blocked by default",
+			serve:        func(w http.ResponseWriter) { json.NewEncoder(w).Encode(previous) },
+			blockPrivate: true,
+			wantError:    "Previous artifact verification failed",
+			wantRequests: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if r.Header.Get("Authorization") != "" {
+					t.Errorf("artifact host received Authorization %q", r.Header.Get("Authorization"))
+				}
+				tt.serve(w)
+			}))
+			defer server.Close()
+
+			attestation := op.attestWithClaims(t, tt.claims, testDownload("https://example.com/", []byte("v2")), previousDetails(t, previous, server.URL+"/artifact"))
+			if tt.tamper {
+				// Re-point the signed payload at the artifact host without re-signing it
+				attestation.Payload.Url = "https://example.com/tampered"
+			}
+
+			opts := op.verifyOptions()
+			opts.CheckPreviousArtifact = true
+			opts.ArtifactToken = "secret"
+			// The artifact host is a local test server
+			opts.ArtifactOptions.BlockPrivateAddresses = tt.blockPrivate
+			result := verifyTestAttestation(t, attestation, opts)
+
+			if result.PreviousArtifactVerified != tt.wantVerified {
+				t.Errorf("PreviousArtifactVerified = %v, want %v (errors %q)", result.PreviousArtifactVerified, tt.wantVerified, result.Errors)
+			}
+			if result.PreviousArtifactUnavailable != tt.wantUnavailable {
+				t.Errorf("PreviousArtifactUnavailable = %v, want %v", result.PreviousArtifactUnavailable, tt.wantUnavailable)
+			}
+			if tt.wantError != "" && !hasError(result, tt.wantError) {
+				t.Errorf("expected an error starting %q, got %q", tt.wantError, result.Errors)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("artifact server received %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestVerifyRecheckContent(t *testing.T) {
+	op := newTestOP(t)
+	content := []byte("v1")
+
+	var requests atomic.Int32
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(content)
+	}))
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(content)
+	}))
+	defer plainServer.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+
+	tests := []struct {
+		name         string
+		url          string
+		tamper       bool
+		recheck      func(opts *DownloadOptions)
+		wantVerified bool
+		wantError    string
+		wantDetail   string
+		wantRequests int32
+	}{
+		{
+			name:         "https on an allowed private address",
+			url:          tlsServer.URL + "/",
+			recheck:      func(opts *DownloadOptions) { opts.BlockPrivateAddresses = false },
+			wantVerified: true,
+			wantRequests: 1,
+		},
+		{
+			name:       "private address is blocked by default",
+			url:        tlsServer.URL + "/",
+			wantError:  "Content recheck failed",
+			wantDetail: ErrPrivateAddress.Error(),
+		},
+		{
+			name:      "plain http is refused by default",
+			url:       plainServer.URL + "/",
+			recheck:   func(opts *DownloadOptions) { opts.BlockPrivateAddresses = false },
+			wantError: "Content recheck failed: " + ErrUnsupportedScheme.Error(),
+		},
+		{
+			name: "plain http when allowed",
+			url:  plainServer.URL + "/",
+			recheck: func(opts *DownloadOptions) {
+				opts.AllowHTTP = true
+				opts.BlockPrivateAddresses = false
+			},
+			wantVerified: true,
+			wantRequests: 1,
+		},
+		{
+			name:      "file URL is refused by default",
+			url:       "file:///etc/passwd",
+			wantError: "Content recheck failed: " + ErrUnsupportedScheme.Error(),
+		},
+		{
+			name:      "unverified signature is not rechecked",
+			url:       tlsServer.URL + "/",
+			tamper:    true,
+			recheck:   func(opts *DownloadOptions) { opts.BlockPrivateAddresses = false },
+			wantError: "Content recheck failed: skipped",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			attestation := op.attest(t, testDownload("https://example.com/", content), nil)
+			// Point the recheck at the test servers, re-signing unless testing a tampered payload
+			if tt.tamper {
+				attestation.Payload.Url = tt.url
+			} else {
+				attestation = op.attest(t, testDownload(tt.url, content), nil)
+			}
+
+			opts := op.verifyOptions()
+			opts.RecheckContent = true
+			opts.RecheckOptions.RootCAs = roots
+			if tt.recheck != nil {
+				tt.recheck(&opts.RecheckOptions)
+			}
+			result := verifyTestAttestation(t, attestation, opts)
+
+			if result.ContentRecheckVerified != tt.wantVerified {
+				t.Errorf("ContentRecheckVerified = %v, want %v (errors %q)", result.ContentRecheckVerified, tt.wantVerified, result.Errors)
+			}
+			if tt.wantError != "" && !hasError(result, tt.wantError) {
+				t.Errorf("expected an error starting %q, got %q", tt.wantError, result.Errors)
+			}
+			if !strings.Contains(strings.Join(result.Errors, "\n"), tt.wantDetail) {
+				t.Errorf("expected an error containing %q, got %q", tt.wantDetail, result.Errors)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("content servers received %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestVerifyRecheckRequestBody(t *testing.T) {
+	op := newTestOP(t)
+	query := []byte(`{"query":"{ viewer { login } }"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(append([]byte("response to "), body...))
+	}))
+	defer server.Close()
+
+	download, err := DownloadContentContext(context.Background(), server.URL, DownloadOptions{AllowHTTP: true, Method: http.MethodPost, RequestBody: query})
+	if err != nil {
+		t.Fatalf("DownloadContentContext: %v", err)
+	}
+	attestation := op.attest(t, download, nil)
+	if attestation.Payload.Method != http.MethodPost || attestation.Payload.RequestBodyDigest != ContentDigest(query) {
+		t.Fatalf("payload records request %q %q", attestation.Payload.Method, attestation.Payload.RequestBodyDigest)
+	}
+
+	tests := []struct {
+		name         string
+		body         []byte
+		wantVerified bool
+		wantError    string
+	}{
+		{name: "replayed with the attested body", body: query, wantVerified: true},
+		{name: "no body given", wantError: "Content recheck failed: the attested request had a body"},
+		{name: "another body", body: []byte(`{"query":"{ viewer { email } }"}`), wantError: "Content recheck failed: request body digest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := op.verifyOptions()
+			opts.RecheckContent = true
+			opts.RecheckOptions = DownloadOptions{AllowHTTP: true}
+			opts.RequestBody = tt.body
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.ContentRecheckVerified != tt.wantVerified {
+				t.Errorf("ContentRecheckVerified = %v, want %v (errors %q)", result.ContentRecheckVerified, tt.wantVerified, result.Errors)
+			}
+			if tt.wantError != "" && !hasError(result, tt.wantError) {
+				t.Errorf("expected an error starting %q, got %q", tt.wantError, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyTimestamp(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		timestamp string
+		maxAge    time.Duration
+		wantErr   string
+	}{
+		{name: "fresh", timestamp: "2026-01-10T11:00:00Z", maxAge: 24 * time.Hour},
+		{name: "exactly the maximum age", timestamp: "2026-01-09T12:00:00Z", maxAge: 24 * time.Hour},
+		{name: "stale", timestamp: "2026-01-01T12:00:00Z", maxAge: 24 * time.Hour, wantErr: "older than the maximum age of 24h0m0s"},
+		{name: "within clock skew", timestamp: "2026-01-10T12:04:00Z", maxAge: time.Hour},
+		{name: "future", timestamp: "2026-01-10T13:00:00Z", maxAge: time.Hour, wantErr: "is in the future"},
+		{name: "not RFC3339", timestamp: "10 Jan 2026", maxAge: time.Hour, wantErr: "failed to parse timestamp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyTimestamp(tt.timestamp, tt.maxAge, now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyTimestamp: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyMaxAge(t *testing.T) {
+	op := newTestOP(t)
+	tests := []struct {
+		name         string
+		iatShift     time.Duration
+		maxAge       time.Duration
+		wantVerified bool
+	}{
+		{name: "fresh attestation", maxAge: time.Hour, wantVerified: true},
+		{name: "stale attestation", iatShift: -2 * time.Hour, maxAge: time.Hour},
+		{name: "future attestation", iatShift: time.Hour, maxAge: 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]any{"iat": time.Now().Add(tt.iatShift).Unix()}
+			attestation := op.attestWithClaims(t, claims, testDownload("https://example.com/", []byte("content")), nil)
+			opts := op.verifyOptions()
+			opts.MaxAge = tt.maxAge
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.TimestampVerified != tt.wantVerified {
+				t.Errorf("TimestampVerified = %v, want %v (errors %q)", result.TimestampVerified, tt.wantVerified, result.Errors)
+			}
+			if !tt.wantVerified && !hasError(result, "Timestamp verification failed") {
+				t.Errorf("expected a timestamp verification error, got %q", result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyValidityWindow(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		notBefore string
+		notAfter  string
+		wantErr   string
+	}{
+		{name: "no window"},
+		{name: "within the window", notBefore: "2026-01-10T00:00:00Z", notAfter: "2026-01-11T00:00:00Z"},
+		{name: "open ended", notBefore: "2026-01-01T00:00:00Z"},
+		{name: "expired", notBefore: "2026-01-01T00:00:00Z", notAfter: "2026-01-10T11:00:00Z", wantErr: "attestation expired at 2026-01-10T11:00:00Z"},
+		{name: "expired within clock skew", notAfter: "2026-01-10T11:56:00Z"},
+		{name: "not yet valid", notBefore: "2026-01-10T13:00:00Z", wantErr: "attestation is not valid before 2026-01-10T13:00:00Z"},
+		{name: "not yet valid within clock skew", notBefore: "2026-01-10T12:04:00Z"},
+		{name: "malformed not_before", notBefore: "10 Jan 2026", wantErr: "failed to parse not_before"},
+		{name: "malformed not_after", notAfter: "tomorrow", wantErr: "failed to parse not_after"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyValidityWindow(&AttestationPayload{NotBefore: tt.notBefore, NotAfter: tt.notAfter}, now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyValidityWindow: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyValidity(t *testing.T) {
+	op := newTestOP(t)
+	download := testDownload("https://example.com/", []byte("content"))
+	now := time.Now()
+
+	tests := []struct {
+		name         string
+		opts         []PayloadOption
+		edit         func(payload *AttestationPayload)
+		wantWindow   bool
+		wantVerified bool
+		wantError    string
+	}{
+		{name: "no window"},
+		{name: "in window", opts: []PayloadOption{WithValidity(now.Add(-time.Hour), now.Add(time.Hour))}, wantWindow: true, wantVerified: true},
+		{
+			name:       "expired",
+			opts:       []PayloadOption{WithValidity(now.Add(-2*time.Hour), now.Add(-time.Hour))},
+			wantWindow: true,
+			wantError:  "Validity window verification failed: attestation expired at",
+		},
+		{
+			name:       "not yet valid",
+			opts:       []PayloadOption{WithValidity(now.Add(time.Hour), now.Add(2*time.Hour))},
+			wantWindow: true,
+			wantError:  "Validity window verification failed: attestation is not valid before",
+		},
+		// The extended window holds, but is not the one signed
+		{
+			name:         "extended after signing",
+			opts:         []PayloadOption{WithValidity(now.Add(-2*time.Hour), now.Add(-time.Hour))},
+			edit:         func(payload *AttestationPayload) { payload.NotAfter = now.Add(time.Hour).UTC().Format(time.RFC3339) },
+			wantWindow:   true,
+			wantVerified: true,
+			wantError:    "Oracle generated digest does not match signed message",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := op.attest(t, download, nil, tt.opts...)
+			if tt.edit != nil {
+				tt.edit(&attestation.Payload)
+			}
+			result := verifyTestAttestation(t, attestation, op.verifyOptions())
+			if result.HasValidityWindow != tt.wantWindow || result.ValidityWindowVerified != tt.wantVerified {
+				t.Errorf("HasValidityWindow, ValidityWindowVerified = %v, %v; want %v, %v (errors %q)",
+					result.HasValidityWindow, result.ValidityWindowVerified, tt.wantWindow, tt.wantVerified, result.Errors)
+			}
+			if tt.wantError != "" {
+				if !hasError(result, tt.wantError) {
+					t.Errorf("expected error %q, got %q", tt.wantError, result.Errors)
+				}
+				return
+			}
+			if !result.IsVerificationSuccessful() {
+				t.Errorf("attestation did not verify: %q", result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyTimestampConsistency(t *testing.T) {
+	op := newTestOP(t)
+	download := testDownload("https://example.com/", []byte("content"))
+
+	tests := []struct {
+		name         string
+		edit         func(payload *AttestationPayload)
+		wantVerified bool
+	}{
+		{name: "timestamp from the PK token iat", wantVerified: true},
+		{
+			name: "backdated timestamp",
+			edit: func(payload *AttestationPayload) { payload.Timestamp = "2020-01-01T00:00:00Z" },
+		},
+		{
+			name: "timestamp in another format",
+			edit: func(payload *AttestationPayload) {
+				issuedAt, _ := time.Parse(time.RFC3339, payload.Timestamp)
+				payload.Timestamp = issuedAt.Format(time.RFC1123)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edit := tt.edit
+			if edit == nil {
+				edit = func(*AttestationPayload) {}
+			}
+			attestation := op.attestEdited(t, download, edit)
+			result := verifyTestAttestation(t, attestation, op.verifyOptions())
+			if result.TimestampConsistencyVerified != tt.wantVerified {
+				t.Errorf("TimestampConsistencyVerified = %v, want %v (errors %q)", result.TimestampConsistencyVerified, tt.wantVerified, result.Errors)
+			}
+			if !tt.wantVerified && !hasError(result, "Attestation timestamp does not match PK token iat claim") {
+				t.Errorf("expected a timestamp consistency error, got %q", result.Errors)
+			}
+			// The edited payload is still validly signed
+			if !result.PayloadDigestVerified {
+				t.Errorf("PayloadDigestVerified = false (errors %q)", result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyAttestation(t *testing.T) {
+	op := newTestOP(t)
+
+	tests := []struct {
+		name      string
+		tamper    func(a *Attestation)
+		options   func(opts *VerifyOptions)
+		wantError string
+	}{
+		{name: "authentic attestation"},
+		{
+			name:      "unexpected issuer",
+			options:   func(opts *VerifyOptions) { opts.Issuer = "https://issuer.example" },
+			wantError: "Issuer verification failed",
+		},
+		{
+			name: "unexpected workflow",
+			options: func(opts *VerifyOptions) {
+				opts.ExpectedWorkflowRef = "owner/repo/.github/workflows/other.yml@refs/heads/main"
+			},
+			wantError: "PK token workflow reference does not match expected workflow",
+		},
+		{
+			name:      "payload altered after signing",
+			tamper:    func(a *Attestation) { a.Payload.Url = "https://example.com/other" },
+			wantError: "Attestation payload digest does not match signed message",
+		},
+		{
+			name:      "commit SHA altered after signing",
+			tamper:    func(a *Attestation) { a.Payload.CommitSHA = "fedcba9876543210fedcba9876543210fedcba98" },
+			wantError: "PK token workflow SHA does not match commit SHA",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+			if tt.tamper != nil {
+				tt.tamper(attestation)
+			}
+			opts := op.verifyOptions()
+			if tt.options != nil {
+				tt.options(&opts)
+			}
+			result := verifyTestAttestation(t, attestation, opts)
+			if tt.wantError == "" {
+				if !result.IsVerificationSuccessful() {
+					t.Fatalf("verification failed: %q", result.Errors)
+				}
+				return
+			}
+			if result.IsVerificationSuccessful() {
+				t.Fatalf("verification succeeded, want an error starting %q", tt.wantError)
+			}
+			if !hasError(result, tt.wantError) {
+				t.Errorf("expected an error starting %q, got %q", tt.wantError, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyAttestationWithoutPKToken(t *testing.T) {
+	if _, err := VerifyAttestation(&Attestation{}, NewVerifyOptions()); err == nil {
+		t.Fatalf("expected an error for an attestation without a PK token")
+	}
+}
+
+func TestVerifyAttestationFile(t *testing.T) {
+	op := newTestOP(t)
+	attestation := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+	data, err := json.Marshal(attestation)
+	if err != nil {
+		t.Fatalf("failed to marshal attestation: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "attestation.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write attestation: %v", err)
+	}
+
+	result, err := VerifyAttestationFile(path, op.verifyOptions())
+	if err != nil {
+		t.Fatalf("VerifyAttestationFile: %v", err)
+	}
+	if !result.IsVerificationSuccessful() {
+		t.Errorf("verification failed: %q", result.Errors)
+	}
+
+	if _, err := VerifyAttestationFile(filepath.Join(t.TempDir(), "missing.json"), op.verifyOptions()); err == nil {
+		t.Errorf("expected an error for a missing attestation file")
+	}
+}
+
+func TestNewVerifyOptions(t *testing.T) {
+	opts := NewVerifyOptions()
+	if opts.Provider != ProviderGithub {
+		t.Errorf("Provider = %q, want %q", opts.Provider, ProviderGithub)
+	}
+	if !opts.RecheckOptions.BlockPrivateAddresses || opts.RecheckOptions.AllowHTTP || opts.RecheckOptions.AllowFile {
+		t.Errorf("RecheckOptions = %+v, want https to public addresses only", opts.RecheckOptions)
+	}
+	if !opts.ArtifactOptions.BlockPrivateAddresses {
+		t.Errorf("ArtifactOptions = %+v, want private addresses blocked", opts.ArtifactOptions)
+	}
+	if !opts.RekorOptions.BlockPrivateAddresses || opts.RekorOptions.AllowHTTP {
+		t.Errorf("RekorOptions = %+v, want https to public addresses only", opts.RekorOptions)
+	}
+	if opts.MaxAge != 0 || opts.RecheckContent || opts.CheckPreviousArtifact || opts.JWKSPath != "" {
+		t.Errorf("optional checks are enabled by default: %+v", opts)
+	}
+}
+
+func TestVerifyOptionsSetResultFields(t *testing.T) {
+	op := newGitHubLikeOP(t)
+	other := newGitHubLikeOP(t)
+	attestation := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+
+	// Options verifying against the OP's JWKS file, as verify_attestation --jwks does offline
+	offline := func() VerifyOptions {
+		opts := NewVerifyOptions()
+		opts.Issuer = op.issuer()
+		opts.JWKSPath = op.jwksFile(t)
+		opts.ExpectedWorkflowRef = testWorkflowRef
+		return opts
+	}
+
+	tests := []struct {
+		name    string
+		options func(opts *VerifyOptions)
+		field   func(r *VerificationResult) bool
+		want    bool
+		wantOK  bool
+	}{
+		{
+			name: "defaults leave optional checks unset",
+			field: func(r *VerificationResult) bool {
+				return r.TimestampVerified || r.ContentRecheckVerified || r.PreviousArtifactVerified
+			},
+			wantOK: true,
+		},
+		{
+			name:    "JWKS file with the OP's keys",
+			options: func(opts *VerifyOptions) {},
+			field:   func(r *VerificationResult) bool { return r.PKTokenVerified },
+			want:    true,
+			wantOK:  true,
+		},
+		{
+			name:    "JWKS file with another OP's keys",
+			options: func(opts *VerifyOptions) { opts.JWKSPath = other.jwksFile(t) },
+			field:   func(r *VerificationResult) bool { return r.PKTokenVerified },
+		},
+		{
+			name:    "max age",
+			options: func(opts *VerifyOptions) { opts.MaxAge = time.Hour },
+			field:   func(r *VerificationResult) bool { return r.TimestampVerified },
+			want:    true,
+			wantOK:  true,
+		},
+		{
+			name: "further accepted workflow ref",
+			options: func(opts *VerifyOptions) {
+				opts.ExpectedWorkflowRef = "owner/repo/.github/workflows/other.yml@refs/heads/main"
+				opts.ExpectedWorkflowRefs = []string{testWorkflowRef}
+			},
+			field:  func(r *VerificationResult) bool { return r.WorkflowRefVerified },
+			want:   true,
+			wantOK: true,
+		},
+		{
+			name: "unexpected workflow ref",
+			options: func(opts *VerifyOptions) {
+				opts.ExpectedWorkflowRef = "owner/repo/.github/workflows/other.yml@refs/heads/main"
+			},
+			field: func(r *VerificationResult) bool { return r.WorkflowRefVerified },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := offline()
+			if tt.options != nil {
+				tt.options(&opts)
+			}
+			result := verifyTestAttestation(t, attestation, opts)
+			if got := tt.field(result); got != tt.want {
+				t.Errorf("result field = %v, want %v (errors %q)", got, tt.want, result.Errors)
+			}
+			if result.IsVerificationSuccessful() != tt.wantOK {
+				t.Errorf("verified = %v, want %v (errors %q)", result.IsVerificationSuccessful(), tt.wantOK, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyTLSCertificate(t *testing.T) {
+	op := newTestOP(t)
+	leaf := ContentDigest([]byte("leaf certificate"))
+	download := testDownload("https://example.com/", []byte("content"))
+	download.TLSCertFingerprints = []string{leaf, ContentDigest([]byte("intermediate"))}
+	download.TLSLeafIssuer = "CN=Test CA,O=Example"
+	withTLS := op.attest(t, download, nil)
+	withoutTLS := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+
+	tests := []struct {
+		name        string
+		attestation *Attestation
+		fingerprint string
+		issuer      string
+		wantError   string
+	}{
+		{name: "leaf fingerprint", attestation: withTLS, fingerprint: leaf},
+		{name: "leaf fingerprint in upper case", attestation: withTLS, fingerprint: strings.ToUpper(leaf)},
+		{name: "leaf issuer", attestation: withTLS, issuer: "CN=Test CA,O=Example"},
+		{name: "fingerprint and issuer", attestation: withTLS, fingerprint: leaf, issuer: "CN=Test CA,O=Example"},
+		{name: "intermediate is not the leaf", attestation: withTLS, fingerprint: ContentDigest([]byte("intermediate")), wantError: "leaf certificate fingerprint"},
+		{name: "other issuer", attestation: withTLS, issuer: "CN=Other CA", wantError: "leaf certificate issuer"},
+		{name: "no recorded certificate", attestation: withoutTLS, fingerprint: leaf, wantError: "attestation does not record a TLS certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := op.verifyOptions()
+			opts.ExpectedTLSFingerprint = tt.fingerprint
+			opts.ExpectedTLSIssuer = tt.issuer
+			result := verifyTestAttestation(t, tt.attestation, opts)
+			if tt.wantError == "" {
+				if !result.TLSCertificateVerified || !result.IsVerificationSuccessful() {
+					t.Errorf("TLSCertificateVerified = %v (errors %q)", result.TLSCertificateVerified, result.Errors)
+				}
+				return
+			}
+			if result.TLSCertificateVerified || !hasError(result, "TLS certificate verification failed: "+tt.wantError) {
+				t.Errorf("expected a TLS certificate error containing %q, got %q", tt.wantError, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyContentSize(t *testing.T) {
+	op := newTestOP(t)
+	download := testDownload("https://example.com/", []byte("content"))
+
+	tests := []struct {
+		name         string
+		edit         func(payload *AttestationPayload)
+		wantVerified bool
+		wantError    bool
+	}{
+		{name: "size of the content", edit: func(*AttestationPayload) {}, wantVerified: true},
+		{
+			name:      "size disagrees with the content",
+			edit:      func(payload *AttestationPayload) { payload.ContentSize = 1 << 20 },
+			wantError: true,
+		},
+		{
+			name: "digest-only attestation skips the check",
+			edit: func(payload *AttestationPayload) {
+				payload.Content = nil
+				payload.ContentSize = 1 << 20
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := op.attestEdited(t, download, tt.edit)
+			result := verifyTestAttestation(t, attestation, op.verifyOptions())
+			if result.ContentSizeVerified != tt.wantVerified {
+				t.Errorf("ContentSizeVerified = %v, want %v (errors %q)", result.ContentSizeVerified, tt.wantVerified, result.Errors)
+			}
+			if hasError(result, "Content size") != tt.wantError {
+				t.Errorf("content size error = %v, want %v (errors %q)", !tt.wantError, tt.wantError, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyContentDigestConsistent(t *testing.T) {
+	op := newTestOP(t)
+	download := testDownload("https://example.com/", []byte("content"))
+
+	tests := []struct {
+		name           string
+		edit           func(payload *AttestationPayload)
+		wantConsistent bool
+		wantError      bool
+	}{
+		{name: "digest of the content", edit: func(*AttestationPayload) {}, wantConsistent: true},
+		{
+			name:      "corrupted content",
+			edit:      func(payload *AttestationPayload) { payload.Content = []byte("CONTENT") },
+			wantError: true,
+		},
+		{
+			name:      "corrupted digest",
+			edit:      func(payload *AttestationPayload) { payload.ContentDigest = ContentDigest([]byte("other content")) },
+			wantError: true,
+		},
+		{
+			name: "digest-only attestation skips the check",
+			edit: func(payload *AttestationPayload) { payload.Content = nil },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The corrupted pairs are signed, so only the consistency check can catch them
+			attestation := op.attestEdited(t, download, tt.edit)
+			result := verifyTestAttestation(t, attestation, op.verifyOptions())
+			if result.ContentDigestConsistent != tt.wantConsistent {
+				t.Errorf("ContentDigestConsistent = %v, want %v (errors %q)", result.ContentDigestConsistent, tt.wantConsistent, result.Errors)
+			}
+			if hasError(result, "Content digest") != tt.wantError {
+				t.Errorf("content digest error = %v, want %v (errors %q)", !tt.wantError, tt.wantError, result.Errors)
+			}
+			if tt.wantError && !result.PayloadDigestVerified {
+				t.Errorf("signed digest of the corrupted payload did not verify (errors %q)", result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyDigestOnlyAttestation(t *testing.T) {
+	op := newTestOP(t)
+	content := []byte(strings.Repeat("large artifact ", 1<<12))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	download, err := DownloadContentContext(context.Background(), server.URL, DownloadOptions{AllowHTTP: true, DigestOnly: true})
+	if err != nil {
+		t.Fatalf("DownloadContentContext: %v", err)
+	}
+	attestation := op.attest(t, download, nil)
+	if attestation.Payload.Content != nil {
+		t.Fatalf("digest-only attestation stored %d bytes of content", len(attestation.Payload.Content))
+	}
+	if attestation.Payload.ContentDigest != ContentDigest(content) || attestation.Payload.ContentSize != int64(len(content)) {
+		t.Fatalf("payload records digest %s and size %d, want those of the streamed content", attestation.Payload.ContentDigest, attestation.Payload.ContentSize)
+	}
+
+	// The attestation survives a save and load without gaining content
+	data, err := json.Marshal(attestation)
+	if err != nil {
+		t.Fatalf("failed to marshal attestation: %v", err)
+	}
+	loaded, err := LoadAttestationReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("LoadAttestation: %v", err)
+	}
+
+	opts := op.verifyOptions()
+	opts.RecheckContent = true
+	opts.RecheckOptions = DownloadOptions{AllowHTTP: true}
+	result := verifyTestAttestation(t, loaded, opts)
+	if !result.IsVerificationSuccessful() {
+		t.Fatalf("digest-only attestation failed verification: %q", result.Errors)
+	}
+	if !result.ContentRecheckVerified {
+		t.Error("recheck of the streamed content did not verify")
+	}
+	if result.ContentDigestConsistent || result.ContentSizeVerified {
+		t.Errorf("embedded content checks ran without content: digest %v, size %v", result.ContentDigestConsistent, result.ContentSizeVerified)
+	}
+}
+
+func TestVerifyIssuer(t *testing.T) {
+	op := newTestOP(t)
+	attestation := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+
+	tests := []struct {
+		name       string
+		issuer     string
+		wantIssuer string
+		wantError  bool
+	}{
+		{name: "token from the expected issuer", issuer: op.issuer(), wantIssuer: op.issuer()},
+		{name: "defaults to the GitHub issuer", issuer: "", wantError: true},
+		{name: "token from another issuer", issuer: "https://accounts.other.example.com", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The PK token verifier trusts the test OP, so only the independent iss check can reject it
+			opts := op.verifyOptions()
+			opts.Issuer = tt.issuer
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.IssuerVerified == tt.wantError {
+				t.Errorf("IssuerVerified = %v, want %v (errors %q)", result.IssuerVerified, !tt.wantError, result.Errors)
+			}
+			if result.MatchedIssuer != tt.wantIssuer {
+				t.Errorf("MatchedIssuer = %q, want %q", result.MatchedIssuer, tt.wantIssuer)
+			}
+			if hasError(result, "Issuer verification failed") != tt.wantError {
+				t.Errorf("issuer error = %v, want %v (errors %q)", !tt.wantError, tt.wantError, result.Errors)
+			}
+			if tt.wantError && result.IsVerificationSuccessful() {
+				t.Error("verification succeeded for a token from an unexpected issuer")
+			}
+		})
+	}
+}
+
+func TestVerifyTrustedIssuers(t *testing.T) {
+	current := newGitHubLikeOPIssuedBy(t, "https://token.actions.example.com")
+	next := newGitHubLikeOPIssuedBy(t, "https://oidc.next.example.com")
+	untrusted := newGitHubLikeOPIssuedBy(t, "https://oidc.untrusted.example.com")
+
+	// One JWKS file holds the keys of every OP, so only the issuer check tells them apart. Mock OPs all
+	// number their key IDs from kid-0, so each OP's are renamed to keep them apart.
+	var keys []json.RawMessage
+	for i, op := range []*testOP{current, next, untrusted} {
+		prefix := fmt.Sprintf("op%d-", i)
+		var kids []string
+		for kid := range op.backend.ProviderPublicKeySet {
+			kids = append(kids, kid)
+		}
+		for _, kid := range kids {
+			op.backend.ProviderPublicKeySet[prefix+kid] = op.backend.ProviderPublicKeySet[kid]
+			op.backend.ProviderSigningKeySet[prefix+kid] = op.backend.ProviderSigningKeySet[kid]
+			delete(op.backend.ProviderPublicKeySet, kid)
+			delete(op.backend.ProviderSigningKeySet, kid)
+		}
+		op.template.KeyID = prefix + op.template.KeyID
+
+		var keySet struct {
+			Keys []json.RawMessage `json:"keys"`
+		}
+		data, err := os.ReadFile(op.jwksFile(t))
+		if err != nil {
+			t.Fatalf("failed to read JWKS: %v", err)
+		}
+		if err := json.Unmarshal(data, &keySet); err != nil {
+			t.Fatalf("failed to parse JWKS: %v", err)
+		}
+		keys = append(keys, keySet.Keys...)
+	}
+	jwks, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatalf("failed to marshal JWKS: %v", err)
+	}
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksPath, jwks, 0644); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		op             *testOP
+		trustedIssuers []string
+		wantIssuer     string
+		wantError      string
+	}{
+		{name: "expected issuer", op: current, wantIssuer: current.issuer()},
+		{name: "expected issuer with trusted issuers", op: current, trustedIssuers: []string{next.issuer()}, wantIssuer: current.issuer()},
+		{name: "trusted issuer", op: next, trustedIssuers: []string{next.issuer()}, wantIssuer: next.issuer()},
+		{name: "one of several trusted issuers", op: next, trustedIssuers: []string{untrusted.issuer() + "/other", next.issuer()}, wantIssuer: next.issuer()},
+		{
+			name:      "other issuer without trusted issuers",
+			op:        next,
+			wantError: "Issuer verification failed: PK token issuer https://oidc.next.example.com does not match expected issuer https://token.actions.example.com",
+		},
+		{
+			name:           "untrusted issuer",
+			op:             untrusted,
+			trustedIssuers: []string{next.issuer()},
+			wantError:      "Issuer verification failed: PK token issuer https://oidc.untrusted.example.com is not one of the trusted issuers https://token.actions.example.com, https://oidc.next.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := tt.op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+			opts := NewVerifyOptions()
+			opts.Issuer = current.issuer()
+			opts.TrustedIssuers = tt.trustedIssuers
+			opts.JWKSPath = jwksPath
+			opts.ExpectedWorkflowRef = testWorkflowRef
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.MatchedIssuer != tt.wantIssuer {
+				t.Errorf("MatchedIssuer = %q, want %q", result.MatchedIssuer, tt.wantIssuer)
+			}
+			if tt.wantError != "" {
+				if result.IssuerVerified || result.PKTokenVerified || !hasError(result, tt.wantError) {
+					t.Errorf("expected an untrusted PK token and error %q, got %q", tt.wantError, result.Errors)
+				}
+				return
+			}
+			if !result.IsVerificationSuccessful() {
+				t.Errorf("attestation from a trusted issuer did not verify: %q", result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyTagWorkflowRef(t *testing.T) {
+	const tagRef = "owner/repo/.github/workflows/release.yml@refs/tags/v1.2.3"
+	op := newTestOP(t)
+	attestation := op.attestWithClaims(t, map[string]any{
+		"job_workflow_ref": tagRef,
+		"workflow_ref":     tagRef,
+		"ref":              "refs/tags/v1.2.3",
+	}, testDownload("https://example.com/", []byte("content")), nil)
+
+	tests := []struct {
+		name     string
+		expected string
+		want     bool
+	}{
+		{name: "expected tag", expected: tagRef, want: true},
+		{name: "any tag", expected: "owner/repo/.github/workflows/release.yml@refs/tags/*", want: true},
+		{name: "expected branch", expected: "owner/repo/.github/workflows/release.yml@refs/heads/main"},
+		{name: "any branch", expected: "owner/repo/.github/workflows/release.yml@refs/heads/*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := op.verifyOptions()
+			opts.ExpectedWorkflowRef = tt.expected
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.WorkflowRefVerified != tt.want {
+				t.Errorf("WorkflowRefVerified = %v, want %v (errors %q)", result.WorkflowRefVerified, tt.want, result.Errors)
+			}
+			if result.IsVerificationSuccessful() != tt.want {
+				t.Errorf("verified = %v, want %v (errors %q)", result.IsVerificationSuccessful(), tt.want, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyRepository(t *testing.T) {
+	op := newTestOP(t)
+	attestation := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+	// A fork running the same workflow file carries its own repository claims
+	fork := op.attestWithClaims(t, map[string]any{"repository": "fork/repo", "repository_owner": "fork"},
+		testDownload("https://example.com/", []byte("content")), nil)
+
+	tests := []struct {
+		name          string
+		attestation   *Attestation
+		repository    string
+		owner         string
+		wantRepo      bool
+		wantOwner     bool
+		wantError     string
+		wantSucceeded bool
+	}{
+		{name: "matching repository", attestation: attestation, repository: "owner/repo", wantRepo: true, wantSucceeded: true},
+		{name: "matching owner", attestation: attestation, owner: "owner", wantOwner: true, wantSucceeded: true},
+		{name: "matching repository and owner", attestation: attestation, repository: "owner/repo", owner: "owner", wantRepo: true, wantOwner: true, wantSucceeded: true},
+		{name: "fork repository", attestation: fork, repository: "owner/repo", wantError: "PK token repository \"fork/repo\""},
+		{name: "fork owner", attestation: fork, owner: "owner", wantError: "PK token repository owner \"fork\""},
+		{name: "unchecked by default", attestation: fork, wantSucceeded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := op.verifyOptions()
+			opts.ExpectedRepository = tt.repository
+			opts.ExpectedRepositoryOwner = tt.owner
+			result := verifyTestAttestation(t, tt.attestation, opts)
+			if result.RepositoryVerified != tt.wantRepo || result.RepositoryOwnerVerified != tt.wantOwner {
+				t.Errorf("RepositoryVerified = %v, RepositoryOwnerVerified = %v; want %v, %v (errors %q)",
+					result.RepositoryVerified, result.RepositoryOwnerVerified, tt.wantRepo, tt.wantOwner, result.Errors)
+			}
+			if tt.wantError != "" && !hasError(result, tt.wantError) {
+				t.Errorf("errors %q, want one starting %q", result.Errors, tt.wantError)
+			}
+			if result.IsVerificationSuccessful() != tt.wantSucceeded {
+				t.Errorf("verified = %v, want %v (errors %q)", result.IsVerificationSuccessful(), tt.wantSucceeded, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyEventName(t *testing.T) {
+	op := newTestOP(t)
+	download := testDownload("https://example.com/", []byte("content"))
+	push := op.attest(t, download, nil)
+	pullRequest := op.attestWithClaims(t, map[string]any{"event_name": "pull_request"}, download, nil)
+
+	tests := []struct {
+		name         string
+		attestation  *Attestation
+		allowed      []string
+		wantVerified bool
+		wantError    bool
+	}{
+		{name: "allowed event", attestation: push, allowed: []string{"push", "schedule"}, wantVerified: true},
+		{name: "pull request not allowed", attestation: pullRequest, allowed: []string{"push", "schedule"}, wantError: true},
+		{name: "event names are case sensitive", attestation: push, allowed: []string{"Push"}, wantError: true},
+		{name: "unchecked by default", attestation: pullRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := op.verifyOptions()
+			opts.AllowedEventNames = tt.allowed
+			result := verifyTestAttestation(t, tt.attestation, opts)
+			if result.EventNameVerified != tt.wantVerified {
+				t.Errorf("EventNameVerified = %v, want %v (errors %q)", result.EventNameVerified, tt.wantVerified, result.Errors)
+			}
+			if hasError(result, "PK token event name") != tt.wantError {
+				t.Errorf("event name error = %v, want %v (errors %q)", !tt.wantError, tt.wantError, result.Errors)
+			}
+			if result.IsVerificationSuccessful() == tt.wantError {
+				t.Errorf("verified = %v, want %v (errors %q)", result.IsVerificationSuccessful(), !tt.wantError, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyStatementType(t *testing.T) {
+	op := newTestOP(t)
+	jwks := testDownload("https://example.com/jwks", []byte(`{"keys":[`+fixtureJWK1+`]}`))
+	notJWKS := testDownload("https://example.com/", []byte("content"))
+
+	tests := []struct {
+		name          string
+		download      *DownloadResult
+		statementType string
+		wantType      string
+		wantError     string
+	}{
+		{name: "payload without a statement type", download: notJWKS, wantType: StatementTypeURLContent},
+		{name: "url content", download: notJWKS, statementType: StatementTypeURLContent, wantType: StatementTypeURLContent},
+		{name: "JWKS snapshot", download: jwks, statementType: StatementTypeJWKSSnapshot, wantType: StatementTypeJWKSSnapshot},
+		{name: "JWKS snapshot of other content", download: notJWKS, statementType: StatementTypeJWKSSnapshot, wantType: StatementTypeJWKSSnapshot, wantError: "JWKS snapshot verification failed"},
+		{name: "unknown statement type", download: notJWKS, statementType: "sbom", wantType: "sbom", wantError: `Unknown statement type "sbom"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := op.attestEdited(t, tt.download, func(payload *AttestationPayload) { payload.StatementType = tt.statementType })
+			result := verifyTestAttestation(t, attestation, op.verifyOptions())
+			if result.StatementType != tt.wantType {
+				t.Errorf("StatementType = %q, want %q", result.StatementType, tt.wantType)
+			}
+			if tt.wantError == "" {
+				if !result.IsVerificationSuccessful() {
+					t.Errorf("verification failed: %q", result.Errors)
+				}
+				return
+			}
+			if !hasError(result, tt.wantError) {
+				t.Errorf("errors %q, want one starting %q", result.Errors, tt.wantError)
+			}
+			if result.IsVerificationSuccessful() {
+				t.Error("verification succeeded")
+			}
+		})
+	}
+}
+
+func TestCompareSignedDigest(t *testing.T) {
+	digest := sha256.Sum256([]byte("payload"))
+	other := sha256.Sum256([]byte("other payload"))
+	tests := []struct {
+		name    string
+		msg     []byte
+		wantErr string
+	}{
+		{name: "matching digest", msg: digest[:]},
+		{name: "no message", msg: nil, wantErr: "no verified signed message"},
+		{name: "empty message", msg: []byte{}, wantErr: "signed message is 0 bytes, not a 32-byte sha256 digest"},
+		{name: "trailing data", msg: append(digest[:], '\n'), wantErr: "signed message is 33 bytes, not a 32-byte sha256 digest"},
+		{name: "hex encoded digest", msg: []byte(hex.EncodeToString(digest[:])), wantErr: "signed message is 64 bytes"},
+		{name: "digest of another payload", msg: other[:], wantErr: "signed digest " + hex.EncodeToString(other[:]) + ", payload digest " + hex.EncodeToString(digest[:])},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compareSignedDigest(tt.msg, digest[:])
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("compareSignedDigest: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDigestsEqual(t *testing.T) {
+	digest := ContentDigest([]byte("content"))
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{name: "same digest", a: digest, b: digest, want: true},
+		{name: "digest of other content", a: digest, b: ContentDigest([]byte("other")), want: false},
+		{name: "prefix of the digest", a: digest, b: digest[:len(digest)-1], want: false},
+		{name: "trailing data", a: digest, b: digest + "0", want: false},
+		{name: "different case", a: digest, b: strings.ToUpper(digest), want: false},
+		{name: "empty recorded digest", a: digest, b: "", want: false},
+		{name: "both empty", a: "", b: "", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := digestsEqual(tt.a, tt.b); got != tt.want {
+				t.Errorf("digestsEqual(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifySignedMessageDiagnostics(t *testing.T) {
+	op := newTestOP(t)
+	other := sha256.Sum256([]byte("another payload"))
+	tests := []struct {
+		name      string
+		message   func(digest []byte) []byte
+		wantError string
+	}{
+		{
+			name:      "digest of another payload",
+			message:   func([]byte) []byte { return other[:] },
+			wantError: "Attestation payload digest does not match signed message: signed digest " + hex.EncodeToString(other[:]),
+		},
+		{
+			name:      "digest with trailing data",
+			message:   func(digest []byte) []byte { return append(append([]byte{}, digest...), 0) },
+			wantError: "Attestation payload digest does not match signed message: signed message is 33 bytes, not a 32-byte sha256 digest",
+		},
+		{
+			name:      "hex encoded digest",
+			message:   func(digest []byte) []byte { return []byte(hex.EncodeToString(digest)) },
+			wantError: "Attestation payload digest does not match signed message: signed message is 64 bytes, not a 32-byte sha256 digest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := op.attest(t, testDownload("https://example.com/jwks", []byte(`{"keys":[]}`)), nil)
+			digest, err := attestation.Payload.Hash()
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			pkToken, signer := op.pkToken(t, nil)
+			if attestation.Signature, err = pkToken.NewSignedMessage(tt.message(digest), signer); err != nil {
+				t.Fatalf("failed to sign message: %v", err)
+			}
+			attestation.PKToken = pkToken
+
+			result := verifyTestAttestation(t, attestation, op.verifyOptions())
+			if !result.SignedMessageVerified {
+				t.Fatalf("signed message did not verify: %v", result.Errors)
+			}
+			if result.PayloadDigestVerified || result.OracleDigestVerified {
+				t.Error("digests verified against the wrong signed message")
+			}
+			if !hasError(result, tt.wantError) {
+				t.Errorf("expected an error starting with %q, got %v", tt.wantError, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyHeadOnly(t *testing.T) {
+	op := newTestOP(t)
+	var etag atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1048576")
+		w.Header().Set("ETag", etag.Load().(string))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		edit         func(payload *AttestationPayload)
+		recheck      bool
+		currentETag  string
+		wantVerified bool
+		wantError    string
+	}{
+		{name: "head metadata"},
+		{name: "recheck unchanged", recheck: true, currentETag: `"v1"`, wantVerified: true},
+		{name: "recheck changed", recheck: true, currentETag: `"v2"`, wantError: "Current head metadata"},
+		{
+			name:      "head metadata missing",
+			edit:      func(payload *AttestationPayload) { payload.Head = nil },
+			wantError: "Head-only attestation has no head metadata",
+		},
+		{
+			name:      "content digest recorded",
+			edit:      func(payload *AttestationPayload) { payload.ContentDigest = ContentDigest(nil) },
+			wantError: "Head-only attestation must not record content or a content digest",
+		},
+		{
+			name:      "content recorded",
+			edit:      func(payload *AttestationPayload) { payload.Content = []byte("body") },
+			wantError: "Head-only attestation must not record content or a content digest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			etag.Store(`"v1"`)
+			download, err := DownloadContentContext(context.Background(), server.URL+"/large.bin", DownloadOptions{AllowHTTP: true, HeadOnly: true})
+			if err != nil {
+				t.Fatalf("DownloadContentContext: %v", err)
+			}
+			edit := tt.edit
+			if edit == nil {
+				edit = func(*AttestationPayload) {}
+			}
+			attestation := op.attestEdited(t, download, edit)
+
+			opts := op.verifyOptions()
+			if tt.recheck {
+				etag.Store(tt.currentETag)
+				opts.RecheckContent = true
+				opts.RecheckOptions.AllowHTTP = true
+				opts.RecheckOptions.BlockPrivateAddresses = false
+			}
+			result := verifyTestAttestation(t, attestation, opts)
+			if result.StatementType != StatementTypeURLHead {
+				t.Errorf("StatementType = %q, want %q", result.StatementType, StatementTypeURLHead)
+			}
+			if result.ContentRecheckVerified != tt.wantVerified {
+				t.Errorf("ContentRecheckVerified = %v, want %v (errors %q)", result.ContentRecheckVerified, tt.wantVerified, result.Errors)
+			}
+			if tt.wantError == "" && len(result.Errors) > 0 {
+				t.Errorf("unexpected errors: %v", result.Errors)
+			}
+			if tt.wantError != "" && !hasError(result, tt.wantError) {
+				t.Errorf("expected an error starting %q, got %q", tt.wantError, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyNormalization(t *testing.T) {
+	op := newTestOP(t)
+	canonical := []byte(`{"keys":[{"kid":"a","kty":"RSA"}]}`)
+	reordered := []byte(`{"keys": [{"kty": "RSA", "kid": "a"}]}`)
+	// setContent replaces the payload content, keeping its digest and size consistent with it
+	setContent := func(content []byte) func(payload *AttestationPayload) {
+		return func(payload *AttestationPayload) {
+			payload.Content = content
+			payload.ContentDigest = ContentDigest(content)
+			payload.ContentSize = int64(len(content))
+		}
+	}
+
+	tests := []struct {
+		name          string
+		normalization string
+		edit          func(payload *AttestationPayload)
+		wantVerified  bool
+		wantError     string
+	}{
+		{name: "not normalized", edit: setContent(reordered)},
+		{name: "canonical JSON", normalization: NormalizeJSON, wantVerified: true},
+		{
+			name:          "JSON not in canonical form",
+			normalization: NormalizeJSON,
+			edit:          setContent(reordered),
+			wantError:     "Content is not in json normalized form",
+		},
+		{
+			name:          "content is not JSON",
+			normalization: NormalizeJSON,
+			edit:          setContent([]byte("not json")),
+			wantError:     "Normalization verification failed: failed to parse JSON content",
+		},
+		{
+			name:          "unsupported normalization",
+			normalization: "xml",
+			wantError:     "Normalization verification failed: unsupported normalization: xml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			download := testDownload("https://example.com/jwks", canonical)
+			download.Normalization = tt.normalization
+			edit := tt.edit
+			if edit == nil {
+				edit = func(*AttestationPayload) {}
+			}
+			result := verifyTestAttestation(t, op.attestEdited(t, download, edit), op.verifyOptions())
+			if result.NormalizationVerified != tt.wantVerified {
+				t.Errorf("NormalizationVerified = %v, want %v (errors %q)", result.NormalizationVerified, tt.wantVerified, result.Errors)
+			}
+			if tt.wantError == "" && len(result.Errors) > 0 {
+				t.Errorf("unexpected errors: %v", result.Errors)
+			}
+			if tt.wantError != "" && !hasError(result, tt.wantError) {
+				t.Errorf("expected an error starting %q, got %q", tt.wantError, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyNormalizedRecheck(t *testing.T) {
+	op := newTestOP(t)
+	var body atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body.Load().(string)))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		current      string
+		wantVerified bool
+	}{
+		{name: "reformatted", current: "{\n  \"keys\": [{\"kty\": \"RSA\", \"kid\": \"a\"}]\n}\n", wantVerified: true},
+		{name: "changed", current: `{"keys":[{"kid":"b","kty":"RSA"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body.Store(`{"keys":[{"kid":"a","kty":"RSA"}]}`)
+			download, err := DownloadContentContext(context.Background(), server.URL+"/jwks", DownloadOptions{AllowHTTP: true, Normalize: NormalizeJSON})
+			if err != nil {
+				t.Fatalf("DownloadContentContext: %v", err)
+			}
+			attestation := op.attest(t, download, nil)
+
+			body.Store(tt.current)
+			opts := op.verifyOptions()
+			opts.RecheckContent = true
+			opts.RecheckOptions.AllowHTTP = true
+			opts.RecheckOptions.BlockPrivateAddresses = false
+			result := verifyTestAttestation(t, attestation, opts)
+			if !result.NormalizationVerified {
+				t.Errorf("normalization did not verify: %v", result.Errors)
+			}
+			if result.ContentRecheckVerified != tt.wantVerified {
+				t.Errorf("ContentRecheckVerified = %v, want %v (errors %q)", result.ContentRecheckVerified, tt.wantVerified, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyContentDigestMultihash(t *testing.T) {
+	op := newTestOP(t)
+	content := []byte(`{"keys":[]}`)
+	multihash, err := ContentDigestMultihash(ContentDigest(content))
+	if err != nil {
+		t.Fatalf("ContentDigestMultihash: %v", err)
+	}
+	otherMultihash, err := ContentDigestMultihash(ContentDigest([]byte("other")))
+	if err != nil {
+		t.Fatalf("ContentDigestMultihash: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		multihash string
+		wantError bool
+	}{
+		{name: "not recorded"},
+		{name: "matching multihash", multihash: multihash},
+		{name: "multihash of other content", multihash: otherMultihash, wantError: true},
+		{name: "malformed multihash", multihash: "not a multihash", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			download := testDownload("https://example.com/jwks", content)
+			download.ContentDigestMultihash = tt.multihash
+			result := verifyTestAttestation(t, op.attest(t, download, nil), op.verifyOptions())
+			if got := hasError(result, "Content digest multihash does not match recorded content digest"); got != tt.wantError {
+				t.Errorf("multihash error = %v, want %v (errors %q)", got, tt.wantError, result.Errors)
+			}
+			if !tt.wantError && len(result.Errors) > 0 {
+				t.Errorf("unexpected errors: %v", result.Errors)
+			}
+			// The canonical digest is what verification checks the content against
+			if !result.ContentDigestConsistent {
+				t.Errorf("content digest did not verify: %v", result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyInclusion(t *testing.T) {
+	op := newTestOP(t)
+	logKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	log := newMockRekorLog(t, logKey)
+	otherLog := newMockRekorLog(t, logKey)
+	foreignLog := newMockRekorLog(t, logKey)
+	logged := op.attest(t, testDownload("https://example.com/jwks", []byte(`{"keys":[]}`)), nil)
+	digest, err := logged.Payload.Hash()
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	signerKey, err := signerPublicKey(logged.PKToken)
+	if err != nil {
+		t.Fatalf("signerPublicKey: %v", err)
+	}
+	uuid := log.add(t, digest, signerKey)
+	otherLog.add(t, digest, signerKey)
+	// Anyone can enter the same digest under their own key
+	foreignLog.add(t, digest, &otherKey.PublicKey)
+	unlogged := op.attest(t, testDownload("https://example.com/jwks", []byte(`{"keys":["unlogged"]}`)), nil)
+
+	// The mock logs are plain http on loopback, which recorded logs must be allowed to use
+	allowLocal := DownloadOptions{AllowHTTP: true}
+
+	tests := []struct {
+		name         string
+		attestation  *Attestation
+		entry        *RekorEntry
+		rekorURL     string
+		rekorOptions *DownloadOptions
+		tamper       bool
+		noKey        bool
+		wantError    string
+	}{
+		{name: "recorded log", attestation: logged, entry: &RekorEntry{LogURL: log.URL, UUID: uuid}},
+		{name: "configured log", attestation: logged, rekorURL: log.URL},
+		// The recorded uuid is for another log, so the configured one is searched by hash
+		{name: "configured log overrides recorded", attestation: logged, entry: &RekorEntry{LogURL: otherLog.URL, UUID: "unknown"}, rekorURL: log.URL + "/"},
+		{name: "not in log", attestation: unlogged, rekorURL: log.URL, wantError: "Inclusion verification failed: attestation not found in transparency log"},
+		{name: "recorded uuid not in log", attestation: logged, entry: &RekorEntry{LogURL: log.URL, UUID: "unknown"}, wantError: "Inclusion verification failed: attestation not found"},
+		{name: "no log", attestation: logged, wantError: "Inclusion verification failed: no transparency log URL configured or recorded"},
+		{name: "no log key", attestation: logged, rekorURL: log.URL, noKey: true, wantError: "Inclusion verification failed: no transparency log public key configured"},
+		{
+			name:        "entry uploaded by another signer",
+			attestation: logged,
+			rekorURL:    foreignLog.URL,
+			wantError:   "Inclusion verification failed: transparency log entry",
+		},
+		{
+			name:         "recorded private log blocked by default",
+			attestation:  logged,
+			entry:        &RekorEntry{LogURL: log.URL, UUID: uuid},
+			rekorOptions: &DownloadOptions{AllowHTTP: true, BlockPrivateAddresses: true},
+			wantError:    "Inclusion verification failed: failed to query transparency log",
+		},
+		{
+			name:         "recorded http log",
+			attestation:  logged,
+			entry:        &RekorEntry{LogURL: log.URL, UUID: uuid},
+			rekorOptions: &DownloadOptions{},
+			wantError:    "Inclusion verification failed: " + ErrUnsupportedScheme.Error(),
+		},
+		{
+			name:        "unverified payload skips the log",
+			attestation: logged,
+			entry:       &RekorEntry{LogURL: log.URL, UUID: uuid},
+			tamper:      true,
+			wantError:   "Inclusion verification failed: skipped",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestation := *tt.attestation
+			attestation.TransparencyLog = tt.entry
+			if tt.tamper {
+				attestation.Payload.Content = []byte(`{"keys":["injected"]}`)
+			}
+			opts := op.verifyOptions()
+			opts.VerifyInclusion = true
+			opts.RekorURL = tt.rekorURL
+			opts.RekorOptions = allowLocal
+			if tt.rekorOptions != nil {
+				opts.RekorOptions = *tt.rekorOptions
+			}
+			if !tt.noKey {
+				opts.RekorPublicKey = logKey.Public()
+			}
+			result := verifyTestAttestation(t, &attestation, opts)
+			if tt.wantError != "" {
+				if result.InclusionVerified || !hasError(result, tt.wantError) {
+					t.Errorf("expected error %q, got %v", tt.wantError, result.Errors)
+				}
+				return
+			}
+			if !result.InclusionVerified || !result.IsVerificationSuccessful() {
+				t.Errorf("inclusion not verified: %v", result.Errors)
+			}
+		})
+	}
+}
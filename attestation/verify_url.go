@@ -0,0 +1,27 @@
+package attestation
+
+import (
+	"context"
+	"fmt"
+)
+
+// VerifyURLContext verifies the content of contentURL in one step: it downloads the attestation at
+// attestationURL (a GitHub artifacts API URL, or the attestation JSON itself), verifies it with opts, requires
+// it to be for contentURL as normalized by NormalizeURL, and re-downloads contentURL to confirm it still serves
+// the attested content. opts.ArtifactToken is only sent to the GitHub API host. Returns an error only if
+// contentURL is invalid or the attestation can't be fetched.
+func VerifyURLContext(ctx context.Context, contentURL string, attestationURL string, opts VerifyOptions) (*VerificationResult, error) {
+	expectedURL, err := NormalizeURL(contentURL)
+	if err != nil {
+		return nil, err
+	}
+
+	attestation, err := NewGitHubClient(opts.ArtifactToken).FetchArtifactAttestation(attestationURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch attestation: %w", err)
+	}
+
+	opts.ExpectedURL = expectedURL
+	opts.RecheckContent = true
+	return VerifyAttestationContext(ctx, attestation, opts)
+}
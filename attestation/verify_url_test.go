@@ -0,0 +1,148 @@
+package attestation
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifyURLContext(t *testing.T) {
+	op := newTestOP(t)
+
+	content := []byte(`{"version": 1}`)
+	served := content
+	contentServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(served)
+	}))
+	defer contentServer.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(contentServer.Certificate())
+
+	// The oracle records the normalized URL, with a trailing slash the server URL lacks
+	recordedURL, err := NormalizeURL(contentServer.URL)
+	if err != nil {
+		t.Fatalf("NormalizeURL: %v", err)
+	}
+	attestation := op.attest(t, testDownload(recordedURL, content), nil)
+
+	var attestationAuthorization string
+	attestationServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attestationAuthorization = r.Header.Get("Authorization")
+		if r.URL.Path != "/attestation.json" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(attestation)
+	}))
+	defer attestationServer.Close()
+
+	tests := []struct {
+		name           string
+		contentURL     string
+		attestationURL string
+		served         []byte
+		wantVerified   bool
+		wantError      string
+		wantFetchError bool
+	}{
+		{
+			name:           "content matches",
+			contentURL:     contentServer.URL,
+			attestationURL: attestationServer.URL + "/attestation.json",
+			served:         content,
+			wantVerified:   true,
+		},
+		{
+			name:           "URL in another case still matches",
+			contentURL:     strings.Replace(contentServer.URL, "https://", "HTTPS://", 1) + "/",
+			attestationURL: attestationServer.URL + "/attestation.json",
+			served:         content,
+			wantVerified:   true,
+		},
+		{
+			name:           "content drifted",
+			contentURL:     contentServer.URL,
+			attestationURL: attestationServer.URL + "/attestation.json",
+			served:         []byte(`{"version": 2}`),
+			wantError:      "Current content digest",
+		},
+		{
+			name:           "attestation for another URL",
+			contentURL:     contentServer.URL + "/other",
+			attestationURL: attestationServer.URL + "/attestation.json",
+			served:         content,
+			wantError:      "URL verification failed",
+		},
+		{
+			name:           "attestation not found",
+			contentURL:     contentServer.URL,
+			attestationURL: attestationServer.URL + "/missing.json",
+			wantFetchError: true,
+		},
+		{
+			name:           "invalid content URL",
+			contentURL:     "http://[::1",
+			attestationURL: attestationServer.URL + "/attestation.json",
+			wantFetchError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served = tt.served
+			opts := op.verifyOptions()
+			opts.ArtifactToken = "secret"
+			// The content server is on loopback
+			opts.RecheckOptions = DownloadOptions{RootCAs: roots}
+			result, err := VerifyURLContext(context.Background(), tt.contentURL, tt.attestationURL, opts)
+			if tt.wantFetchError {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyURLContext: %v", err)
+			}
+			if attestationAuthorization != "" {
+				t.Errorf("attestation host received Authorization %q", attestationAuthorization)
+			}
+			if result.IsVerificationSuccessful() != tt.wantVerified {
+				t.Errorf("verified = %v, want %v (errors %q)", result.IsVerificationSuccessful(), tt.wantVerified, result.Errors)
+			}
+			if tt.wantError != "" && !hasError(result, tt.wantError) {
+				t.Errorf("expected an error starting %q, got %q", tt.wantError, result.Errors)
+			}
+		})
+	}
+}
+
+func TestVerifyExpectedURLNormalized(t *testing.T) {
+	op := newTestOP(t)
+	attestation := op.attest(t, testDownload("https://example.com/", []byte("content")), nil)
+
+	tests := []struct {
+		expectedURL string
+		want        bool
+	}{
+		{"https://example.com/", true},
+		{"https://example.com", true},
+		{"https://EXAMPLE.com:443", true},
+		{"HTTPS://example.com/#fragment", true},
+		{"https://example.com:8443/", false},
+		{"http://example.com/", false},
+		{"https://example.com/other", false},
+	}
+	for _, tt := range tests {
+		opts := op.verifyOptions()
+		opts.ExpectedURL = tt.expectedURL
+		result := verifyTestAttestation(t, attestation, opts)
+		if result.URLVerified != tt.want {
+			t.Errorf("URLVerified for %s = %v, want %v (errors %q)", tt.expectedURL, result.URLVerified, tt.want, result.Errors)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package attestation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// workflowsDir is the directory GitHub Actions workflow files live in
+const workflowsDir = ".github/workflows/"
+
+// Git ref types a workflow can run on
+const (
+	RefTypeBranch = "branch"
+	RefTypeTag    = "tag"
+	RefTypePull   = "pull"
+)
+
+// RefWildcard as the ref name of an expected workflow reference matches any ref of that type,
+// e.g. owner/repo/.github/workflows/release.yml@refs/tags/*
+const RefWildcard = "*"
+
+// WorkflowRef is a parsed GitHub Actions workflow_ref / job_workflow_ref claim,
+// e.g. owner/repo/.github/workflows/build.yml@refs/heads/main
+type WorkflowRef struct {
+	Owner        string
+	Repo         string
+	WorkflowFile string
+	// Ref is the full git ref, e.g. refs/tags/v1.2.3
+	Ref string
+	// RefType is RefTypeBranch, RefTypeTag or RefTypePull
+	RefType string
+	// RefName is the branch or tag name, or "<number>/merge" style names for pull requests
+	RefName string
+}
+
+// Repository returns the owner/repo name of the workflow's repository
+func (w *WorkflowRef) Repository() string {
+	return w.Owner + "/" + w.Repo
+}
+
+// ParseWorkflowRef parses a workflow reference. Branch and tag names may contain slashes, and any
+// path segments before owner/repo (as seen on some enterprise hosts) are ignored.
+func ParseWorkflowRef(workflowRef string) (*WorkflowRef, error) {
+	workflowPath, ref, ok := strings.Cut(workflowRef, "@")
+	if !ok || ref == "" {
+		return nil, fmt.Errorf("workflow reference %q has no ref", workflowRef)
+	}
+
+	repoPath, workflowFile, ok := strings.Cut(workflowPath, "/"+workflowsDir)
+	if !ok || workflowFile == "" || strings.Contains(workflowFile, "/") {
+		return nil, fmt.Errorf("workflow reference %q does not point at a file in %s", workflowRef, workflowsDir)
+	}
+
+	repoParts := strings.Split(repoPath, "/")
+	if len(repoParts) < 2 {
+		return nil, fmt.Errorf("workflow reference %q has no owner/repo", workflowRef)
+	}
+	parsed := &WorkflowRef{
+		Owner:        repoParts[len(repoParts)-2],
+		Repo:         repoParts[len(repoParts)-1],
+		WorkflowFile: workflowFile,
+		Ref:          ref,
+	}
+	if parsed.Owner == "" || parsed.Repo == "" {
+		return nil, fmt.Errorf("workflow reference %q has no owner/repo", workflowRef)
+	}
+
+	switch {
+	case strings.HasPrefix(ref, "refs/heads/"):
+		parsed.RefType = RefTypeBranch
+		parsed.RefName = strings.TrimPrefix(ref, "refs/heads/")
+	case strings.HasPrefix(ref, "refs/tags/"):
+		parsed.RefType = RefTypeTag
+		parsed.RefName = strings.TrimPrefix(ref, "refs/tags/")
+	case strings.HasPrefix(ref, "refs/pull/"):
+		parsed.RefType = RefTypePull
+		parsed.RefName = strings.TrimPrefix(ref, "refs/pull/")
+	default:
+		return nil, fmt.Errorf("workflow reference %q has unsupported ref %q", workflowRef, ref)
+	}
+	if parsed.RefName == "" {
+		return nil, fmt.Errorf("workflow reference %q has an empty ref name", workflowRef)
+	}
+
+	return parsed, nil
+}
+
+// MatchWorkflowRef reports whether a workflow reference matches an expected one, either exactly or,
+// when the expected ref name is RefWildcard, by workflow and ref type
+func MatchWorkflowRef(workflowRef string, expected string) bool {
+	if workflowRef == expected {
+		return true
+	}
+
+	expectedRef, err := ParseWorkflowRef(expected)
+	if err != nil || expectedRef.RefName != RefWildcard {
+		return false
+	}
+	actualRef, err := ParseWorkflowRef(workflowRef)
+	if err != nil {
+		return false
+	}
+	return actualRef.Repository() == expectedRef.Repository() &&
+		actualRef.WorkflowFile == expectedRef.WorkflowFile &&
+		actualRef.RefType == expectedRef.RefType
+}
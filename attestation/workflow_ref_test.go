@@ -0,0 +1,102 @@
+package attestation
+
+import (
+	"testing"
+)
+
+func TestParseWorkflowRef(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		want    WorkflowRef
+		wantErr bool
+	}{
+		{
+			name: "branch",
+			ref:  "owner/repo/.github/workflows/build.yml@refs/heads/main",
+			want: WorkflowRef{Owner: "owner", Repo: "repo", WorkflowFile: "build.yml", Ref: "refs/heads/main", RefType: RefTypeBranch, RefName: "main"},
+		},
+		{
+			name: "branch with slashes",
+			ref:  "owner/repo/.github/workflows/build.yml@refs/heads/feature/x",
+			want: WorkflowRef{Owner: "owner", Repo: "repo", WorkflowFile: "build.yml", Ref: "refs/heads/feature/x", RefType: RefTypeBranch, RefName: "feature/x"},
+		},
+		{
+			name: "tag",
+			ref:  "owner/repo/.github/workflows/release.yml@refs/tags/v1.2.3",
+			want: WorkflowRef{Owner: "owner", Repo: "repo", WorkflowFile: "release.yml", Ref: "refs/tags/v1.2.3", RefType: RefTypeTag, RefName: "v1.2.3"},
+		},
+		{
+			name: "pull request merge ref",
+			ref:  "owner/repo/.github/workflows/ci.yml@refs/pull/42/merge",
+			want: WorkflowRef{Owner: "owner", Repo: "repo", WorkflowFile: "ci.yml", Ref: "refs/pull/42/merge", RefType: RefTypePull, RefName: "42/merge"},
+		},
+		{
+			name: "enterprise path prefix",
+			ref:  "ghe.example.com/org/owner/repo/.github/workflows/build.yml@refs/heads/main",
+			want: WorkflowRef{Owner: "owner", Repo: "repo", WorkflowFile: "build.yml", Ref: "refs/heads/main", RefType: RefTypeBranch, RefName: "main"},
+		},
+		{name: "no ref", ref: "owner/repo/.github/workflows/build.yml", wantErr: true},
+		{name: "empty ref", ref: "owner/repo/.github/workflows/build.yml@", wantErr: true},
+		{name: "not a workflow file", ref: "owner/repo/build.yml@refs/heads/main", wantErr: true},
+		{name: "nested workflow path", ref: "owner/repo/.github/workflows/dir/build.yml@refs/heads/main", wantErr: true},
+		{name: "no owner", ref: "repo/.github/workflows/build.yml@refs/heads/main", wantErr: true},
+		{name: "empty owner", ref: "/repo/.github/workflows/build.yml@refs/heads/main", wantErr: true},
+		{name: "unsupported ref", ref: "owner/repo/.github/workflows/build.yml@refs/notes/x", wantErr: true},
+		{name: "empty branch name", ref: "owner/repo/.github/workflows/build.yml@refs/heads/", wantErr: true},
+		{name: "empty", ref: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWorkflowRef(tt.ref)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseWorkflowRef(%q) = %+v, want an error", tt.ref, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseWorkflowRef: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseWorkflowRef(%q) = %+v, want %+v", tt.ref, *got, tt.want)
+			}
+			if got.Repository() != tt.want.Owner+"/"+tt.want.Repo {
+				t.Errorf("Repository() = %q", got.Repository())
+			}
+		})
+	}
+}
+
+func TestMatchWorkflowRef(t *testing.T) {
+	const (
+		release = "owner/repo/.github/workflows/release.yml@refs/tags/v1.2.3"
+		main    = "owner/repo/.github/workflows/release.yml@refs/heads/main"
+	)
+
+	tests := []struct {
+		name     string
+		ref      string
+		expected string
+		want     bool
+	}{
+		{name: "exact tag", ref: release, expected: release, want: true},
+		{name: "exact branch", ref: main, expected: main, want: true},
+		{name: "another tag", ref: release, expected: "owner/repo/.github/workflows/release.yml@refs/tags/v1.2.4"},
+		{name: "tag wildcard", ref: release, expected: "owner/repo/.github/workflows/release.yml@refs/tags/*", want: true},
+		{name: "branch wildcard", ref: main, expected: "owner/repo/.github/workflows/release.yml@refs/heads/*", want: true},
+		{name: "tag wildcard does not match branches", ref: main, expected: "owner/repo/.github/workflows/release.yml@refs/tags/*"},
+		{name: "branch wildcard does not match tags", ref: release, expected: "owner/repo/.github/workflows/release.yml@refs/heads/*"},
+		{name: "wildcard for another workflow", ref: release, expected: "owner/repo/.github/workflows/build.yml@refs/tags/*"},
+		{name: "wildcard for another repository", ref: release, expected: "owner/fork/.github/workflows/release.yml@refs/tags/*"},
+		{name: "wildcard only matches whole ref names", ref: release, expected: "owner/repo/.github/workflows/release.yml@refs/tags/v1*"},
+		{name: "malformed ref", ref: "owner/repo@refs/tags/v1", expected: "owner/repo/.github/workflows/release.yml@refs/tags/*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchWorkflowRef(tt.ref, tt.expected); got != tt.want {
+				t.Errorf("MatchWorkflowRef(%q, %q) = %v, want %v", tt.ref, tt.expected, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+// Command export_keys exports the OP's signing keys to a key log, the same as url-oracle export-keys
+package main
+
+import (
+	"os"
+
+	"url-oracle/internal/cli"
+)
+
+func main() {
+	cli.ExportKeys(os.Args[1:])
+}
@@ -0,0 +1,12 @@
+// Command index_keys indexes the keys in JWKS snapshot attestations, the same as url-oracle index-keys
+package main
+
+import (
+	"os"
+
+	"url-oracle/internal/cli"
+)
+
+func main() {
+	cli.IndexKeys(os.Args[1:])
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"url-oracle/attestation"
+)
+
+// Inspection is a summary of an attestation that can be produced without verifying it
+type Inspection struct {
+	StatementType       string                          `json:"statement_type,omitempty"`
+	URL                 string                          `json:"url"`
+	Method              string                          `json:"method,omitempty"`
+	RequestBodyDigest   string                          `json:"request_body_digest,omitempty"`
+	ContentDigest       string                          `json:"content_digest"`
+	ContentSize         int64                           `json:"content_size"`
+	ContentEncoding     string                          `json:"content_encoding,omitempty"`
+	ContentType         string                          `json:"content_type,omitempty"`
+	ContentCompression  string                          `json:"content_compression,omitempty"`
+	Normalization       string                          `json:"normalization,omitempty"`
+	ResponseHeaders     string                          `json:"response_headers_digest,omitempty"`
+	Head                *attestation.HeadMetadata       `json:"head,omitempty"`
+	PageCount           int                             `json:"page_count,omitempty"`
+	FinalPageURL        string                          `json:"final_page_url,omitempty"`
+	PageURLs            []string                        `json:"page_urls,omitempty"`
+	FetchStartedAt      string                          `json:"fetch_started_at,omitempty"`
+	FetchDurationMs     int64                           `json:"fetch_duration_ms,omitempty"`
+	Timestamp           string                          `json:"timestamp"`
+	NotBefore           string                          `json:"not_before,omitempty"`
+	NotAfter            string                          `json:"not_after,omitempty"`
+	CommitSHA           string                          `json:"commit_sha"`
+	WorkflowRef         string                          `json:"workflow_ref,omitempty"`
+	PreviousAttestation *attestation.AttestationDetails `json:"previous_attestation,omitempty"`
+	TransparencyLog     *attestation.RekorEntry         `json:"transparency_log,omitempty"`
+	Claims              map[string]any                  `json:"claims,omitempty"`
+}
+
+func main() {
+	var (
+		attestationFile = flag.String("attestation-file", "", "Path to attestation file to inspect")
+		output          = flag.String("output", "text", "Output format (text or json)")
+	)
+	flag.Parse()
+
+	if *attestationFile == "" {
+		fmt.Println("Error: attestation-file flag is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Printf("Error: unsupported output format: %s\n", *output)
+		os.Exit(1)
+	}
+
+	att, err := attestation.LoadAttestation(*attestationFile)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	inspection, err := inspect(att)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "json" {
+		data, err := json.MarshalIndent(inspection, "", "  ")
+		if err != nil {
+			fmt.Printf("❌ Error: failed to marshal inspection: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	printInspection(inspection)
+}
+
+// inspect summarises an attestation and decodes its PK token claims without verifying them
+func inspect(att *attestation.Attestation) (*Inspection, error) {
+	inspection := &Inspection{
+		StatementType:      att.Payload.StatementType,
+		URL:                att.Payload.Url,
+		Method:             att.Payload.Method,
+		RequestBodyDigest:  att.Payload.RequestBodyDigest,
+		ContentDigest:      att.Payload.ContentDigest,
+		ContentSize:        att.Payload.ContentSize,
+		ContentEncoding:    att.Payload.ContentEncoding,
+		ContentType:        att.Payload.ContentType,
+		ContentCompression: att.Payload.ContentCompression,
+		Normalization:      att.Payload.Normalization,
+		ResponseHeaders:    att.Payload.ResponseHeadersDigest,
+		Head:               att.Payload.Head,
+		PageCount:          att.Payload.PageCount,
+		FinalPageURL:       att.Payload.FinalPageURL,
+		PageURLs:           att.Payload.PageURLs,
+		FetchStartedAt:     att.Payload.FetchStartedAt,
+		FetchDurationMs:    att.Payload.FetchDurationMs,
+		Timestamp:          att.Payload.Timestamp,
+		NotBefore:          att.Payload.NotBefore,
+		NotAfter:           att.Payload.NotAfter,
+		CommitSHA:          att.Payload.CommitSHA,
+		TransparencyLog:    att.TransparencyLog,
+	}
+
+	if len(att.Payload.PreviousAttestation) > 0 {
+		var details attestation.AttestationDetails
+		if err := json.Unmarshal(att.Payload.PreviousAttestation, &details); err != nil {
+			return nil, fmt.Errorf("failed to parse previous attestation details: %w", err)
+		}
+		inspection.PreviousAttestation = &details
+	}
+
+	if att.PKToken != nil {
+		if err := json.Unmarshal(att.PKToken.Payload, &inspection.Claims); err != nil {
+			return nil, fmt.Errorf("failed to parse PK token payload: %w", err)
+		}
+		if workflowRef, ok := inspection.Claims["job_workflow_ref"].(string); ok {
+			inspection.WorkflowRef = workflowRef
+		}
+	}
+
+	return inspection, nil
+}
+
+// printInspection prints a human-readable summary of the attestation
+func printInspection(inspection *Inspection) {
+	fmt.Println("📄 Attestation:")
+	if inspection.StatementType != "" {
+		fmt.Printf("  Statement Type: %s\n", inspection.StatementType)
+	}
+	fmt.Printf("  URL: %s\n", inspection.URL)
+	if inspection.Method != "" {
+		fmt.Printf("  Request: %s (body digest: %s)\n", inspection.Method, inspection.RequestBodyDigest)
+	}
+	fmt.Printf("  Content Digest: %s\n", inspection.ContentDigest)
+	fmt.Printf("  Content Size: %d bytes\n", inspection.ContentSize)
+	if inspection.ContentEncoding != "" {
+		fmt.Printf("  Content Encoding: %s\n", inspection.ContentEncoding)
+	}
+	if inspection.ContentCompression != "" {
+		fmt.Printf("  Content Compression: %s\n", inspection.ContentCompression)
+	}
+	if inspection.ContentType != "" {
+		fmt.Printf("  Content Type: %s\n", inspection.ContentType)
+	}
+	if inspection.Normalization != "" {
+		fmt.Printf("  Normalization: %s\n", inspection.Normalization)
+	}
+	if inspection.Head != nil {
+		fmt.Printf("  Head: status %d, Content-Length %d, ETag %s, Last-Modified %s\n", inspection.Head.StatusCode, inspection.Head.ContentLength, inspection.Head.ETag, inspection.Head.LastModified)
+	}
+	if inspection.ResponseHeaders != "" {
+		fmt.Printf("  Response Headers Digest: %s\n", inspection.ResponseHeaders)
+	}
+	if inspection.PageCount > 0 {
+		fmt.Printf("  Pages: %d (last: %s)\n", inspection.PageCount, inspection.FinalPageURL)
+		for _, pageURL := range inspection.PageURLs {
+			fmt.Printf("    - %s\n", pageURL)
+		}
+	}
+	if inspection.FetchStartedAt != "" {
+		fmt.Printf("  Fetch: started %s, took %dms\n", inspection.FetchStartedAt, inspection.FetchDurationMs)
+	}
+	fmt.Printf("  Timestamp: %s\n", inspection.Timestamp)
+	if inspection.NotBefore != "" || inspection.NotAfter != "" {
+		fmt.Printf("  Valid: %s to %s\n", inspection.NotBefore, inspection.NotAfter)
+	}
+	fmt.Printf("  Commit SHA: %s\n", inspection.CommitSHA)
+	fmt.Printf("  Workflow Reference: %s\n", inspection.WorkflowRef)
+	if inspection.TransparencyLog != nil {
+		fmt.Printf("  Transparency Log: %s entry %s (index %d)\n", inspection.TransparencyLog.LogURL, inspection.TransparencyLog.UUID, inspection.TransparencyLog.LogIndex)
+	}
+	if inspection.PreviousAttestation != nil {
+		fmt.Printf("  Previous Attestation: %s (%s)\n", inspection.PreviousAttestation.Digest, inspection.PreviousAttestation.ArtifactURL)
+	} else {
+		fmt.Println("  Previous Attestation: none")
+	}
+
+	fmt.Println()
+	fmt.Println("🔑 PK Token Claims (unverified):")
+	keys := make([]string, 0, len(inspection.Claims))
+	for key := range inspection.Claims {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("  %s: %v\n", key, inspection.Claims[key])
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openpubkey/openpubkey/pktoken"
+
+	"url-oracle/attestation"
+)
+
+// sampleAttestation is an unsigned attestation file chaining to a previous attestation, whose details are
+// base64 encoded as in attestation files
+const sampleAttestation = `{
+  "payload": {
+    "timestamp": "2026-01-02T03:04:05Z",
+    "commit_sha": "0123456789abcdef0123456789abcdef01234567",
+    "previous_attestation": "eyJkaWdlc3QiOiJzaGEyNTY6YWJjIiwiYXJ0aWZhY3RfdXJsIjoiaHR0cHM6Ly9hcGkuZ2l0aHViLmNvbS9yZXBvcy9vL3IvYWN0aW9ucy9hcnRpZmFjdHMvMS96aXAifQ==",
+    "url": "https://example.com/",
+    "content_digest": "sha256:def",
+    "content_size": 42
+  },
+  "pk_token": null,
+  "signature": null
+}`
+
+func TestInspectSampleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attestation.json")
+	if err := os.WriteFile(path, []byte(sampleAttestation), 0644); err != nil {
+		t.Fatalf("failed to write sample: %v", err)
+	}
+	att, err := attestation.LoadAttestation(path)
+	if err != nil {
+		t.Fatalf("LoadAttestation: %v", err)
+	}
+
+	inspection, err := inspect(att)
+	if err != nil {
+		t.Fatalf("inspect: %v", err)
+	}
+	if inspection.URL != "https://example.com/" || inspection.ContentDigest != "sha256:def" || inspection.ContentSize != 42 {
+		t.Errorf("unexpected summary %+v", inspection)
+	}
+	if inspection.Timestamp != "2026-01-02T03:04:05Z" || inspection.CommitSHA != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("unexpected timestamp or commit in %+v", inspection)
+	}
+	if inspection.PreviousAttestation == nil || inspection.PreviousAttestation.Digest != "sha256:abc" {
+		t.Errorf("previous attestation = %+v, want digest sha256:abc", inspection.PreviousAttestation)
+	}
+	if inspection.Claims != nil {
+		t.Errorf("claims = %v for an attestation without a PK token", inspection.Claims)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		name            string
+		attestation     *attestation.Attestation
+		wantWorkflowRef string
+		wantClaims      int
+		wantErr         bool
+	}{
+		{
+			name: "PK token claims",
+			attestation: &attestation.Attestation{
+				Payload: attestation.AttestationPayload{Url: "https://example.com/"},
+				PKToken: &pktoken.PKToken{Payload: []byte(`{"iss": "https://token.actions.githubusercontent.com", "job_workflow_ref": "o/r/.github/workflows/w.yml@refs/heads/main"}`)},
+			},
+			wantWorkflowRef: "o/r/.github/workflows/w.yml@refs/heads/main",
+			wantClaims:      2,
+		},
+		{
+			name: "no previous attestation",
+			attestation: &attestation.Attestation{
+				Payload: attestation.AttestationPayload{Url: "https://example.com/"},
+			},
+		},
+		{
+			name: "malformed previous attestation details",
+			attestation: &attestation.Attestation{
+				Payload: attestation.AttestationPayload{PreviousAttestation: json.RawMessage(`"not details"`)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed PK token payload",
+			attestation: &attestation.Attestation{
+				PKToken: &pktoken.PKToken{Payload: []byte(`not json`)},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inspection, err := inspect(tt.attestation)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("inspect: %v", err)
+			}
+			if inspection.WorkflowRef != tt.wantWorkflowRef || len(inspection.Claims) != tt.wantClaims {
+				t.Errorf("workflow ref %q with %d claims, want %q with %d", inspection.WorkflowRef, len(inspection.Claims), tt.wantWorkflowRef, tt.wantClaims)
+			}
+
+			// The JSON output round-trips
+			data, err := json.Marshal(inspection)
+			if err != nil {
+				t.Fatalf("failed to marshal inspection: %v", err)
+			}
+			var decoded Inspection
+			if err := json.Unmarshal(data, &decoded); err != nil || decoded.URL != inspection.URL {
+				t.Errorf("JSON output did not round-trip: %s", data)
+			}
+		})
+	}
+}
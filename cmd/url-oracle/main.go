@@ -0,0 +1,51 @@
+// Command url-oracle generates, verifies and compares URL content attestations
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"url-oracle/internal/cli"
+)
+
+// commands maps each subcommand to its entry point
+var commands = map[string]func(args []string){
+	"generate":    cli.Generate,
+	"verify":      cli.Verify,
+	"verify-url":  cli.VerifyURL,
+	"diff":        cli.Diff,
+	"export-keys": cli.ExportKeys,
+	"index-keys":  cli.IndexKeys,
+	"selfcheck":   cli.SelfCheck,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: url-oracle <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  generate     Download content and sign an attestation of it")
+	fmt.Fprintln(os.Stderr, "  verify       Verify an attestation or a directory of attestations")
+	fmt.Fprintln(os.Stderr, "  verify-url   Download an attestation and verify a URL still serves its content")
+	fmt.Fprintln(os.Stderr, "  diff         Compare the content recorded by two attestations")
+	fmt.Fprintln(os.Stderr, "  export-keys  Merge the OP's current signing keys into a key log directory")
+	fmt.Fprintln(os.Stderr, "  index-keys   Index the keys recorded by a directory of JWKS snapshot attestations")
+	fmt.Fprintln(os.Stderr, "  selfcheck    Check offline that payloads are reproducible and signed digests match")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run url-oracle <command> -h for the command's flags.")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] != "-h" && os.Args[1] != "--help" && os.Args[1] != "help" {
+			fmt.Fprintf(os.Stderr, "Error: unknown command %s\n\n", os.Args[1])
+		}
+		usage()
+		os.Exit(1)
+	}
+	command(os.Args[2:])
+}
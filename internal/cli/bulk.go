@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"url-oracle/attestation"
+)
+
+// BulkResult is the verification outcome of a single file in --dir mode
+type BulkResult struct {
+	File           string   `json:"file"`
+	Verified       bool     `json:"verified"`
+	Errors         []string `json:"errors,omitempty"`
+	ContentPreview string   `json:"content_preview_untrusted,omitempty"` // failed verifications only
+	// The algorithms protecting the attestation, see attestation.SigningAlgorithms
+	SignatureAlgorithm string `json:"signature_algorithm,omitempty"`
+	SigningKeyType     string `json:"signing_key_type,omitempty"`
+	ProviderAlgorithm  string `json:"provider_algorithm,omitempty"`
+}
+
+// BulkSummary aggregates the results of verifying every attestation in a directory
+type BulkSummary struct {
+	Total   int          `json:"total"`
+	Passed  int          `json:"passed"`
+	Failed  int          `json:"failed"`
+	Results []BulkResult `json:"results"`
+	// exitCode is the most severe verification exit code across the results
+	exitCode int
+}
+
+// verifyDirectory verifies every *.json attestation in dir
+func verifyDirectory(dir string, opts attestation.VerifyOptions) (*BulkSummary, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attestations in %s: %w", dir, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.json attestations found in %s", dir)
+	}
+
+	summary := &BulkSummary{Results: make([]BulkResult, 0, len(files))}
+	for _, file := range files {
+		fileResult := BulkResult{File: file}
+		result, err := attestation.VerifyAttestationFile(file, opts)
+		if err != nil {
+			fileResult.Errors = []string{err.Error()}
+			summary.exitCode = mostSevereExitCode(summary.exitCode, exitIOError)
+		} else {
+			summary.exitCode = mostSevereExitCode(summary.exitCode, verificationExitCode(result, opts))
+			fileResult.Verified = result.IsVerificationSuccessful()
+			fileResult.Errors = result.Errors
+			if !fileResult.Verified {
+				fileResult.ContentPreview = result.ContentPreview
+			}
+			if algorithms := result.Algorithms; algorithms != nil {
+				fileResult.SignatureAlgorithm = algorithms.SignatureAlgorithm
+				fileResult.SigningKeyType = algorithms.KeyType
+				fileResult.ProviderAlgorithm = algorithms.ProviderAlgorithm
+			}
+		}
+
+		summary.Total++
+		if fileResult.Verified {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+		summary.Results = append(summary.Results, fileResult)
+	}
+	return summary, nil
+}
+
+// printBulkSummary prints a per-file status table and the totals, or the summary as JSON or SARIF
+func printBulkSummary(summary *BulkSummary, output string) error {
+	if output == "sarif" {
+		return printSARIFReport(summary.Results)
+	}
+	if output == "json" {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal summary: %w", err)
+		}
+		logger.Resultf("%s\n", string(data))
+		return nil
+	}
+
+	logger.Resultf("🔍 Verification Results:\n")
+	for _, result := range summary.Results {
+		logger.Resultf("  %s %s\n", getStatusIcon(result.Verified), result.File)
+		for _, err := range result.Errors {
+			logger.Resultf("      - %s\n", err)
+		}
+		if result.ContentPreview != "" {
+			logger.Resultf("      Content preview (untrusted): %s\n", result.ContentPreview)
+		}
+	}
+	logger.Resultf("\n")
+	logger.Resultf("📋 %d verified, %d failed, %d total\n", summary.Passed, summary.Failed, summary.Total)
+	return nil
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"url-oracle/attestation"
+)
+
+func TestVerifyDirectory(t *testing.T) {
+	signer := newTestSigner(t)
+	valid := signer.attest(t, "https://example.com/jwks", []byte(`{"keys":[]}`))
+	tampered := signer.attest(t, "https://example.com/jwks", []byte(`{"keys":[]}`))
+	tampered.Payload.Content = []byte(`{"keys":["injected"]}`)
+
+	tests := []struct {
+		name            string
+		files           map[string]any
+		wantPassed      int
+		wantFailed      int
+		wantFailedFiles []string
+		wantExitCode    int
+		wantErr         string
+	}{
+		{
+			name:            "valid and tampered attestations",
+			files:           map[string]any{"a.json": valid, "b.json": tampered, "c.json": valid},
+			wantPassed:      2,
+			wantFailed:      1,
+			wantFailedFiles: []string{"b.json"},
+			wantExitCode:    exitSignatureFailure,
+		},
+		{
+			name:            "unparseable attestation",
+			files:           map[string]any{"a.json": valid, "broken.json": "{not json"},
+			wantPassed:      1,
+			wantFailed:      1,
+			wantFailedFiles: []string{"broken.json"},
+			wantExitCode:    exitIOError,
+		},
+		{
+			name:            "signature failure outranks an unparseable attestation",
+			files:           map[string]any{"b.json": tampered, "broken.json": "{not json"},
+			wantFailed:      2,
+			wantFailedFiles: []string{"b.json", "broken.json"},
+			wantExitCode:    exitSignatureFailure,
+		},
+		{name: "non-JSON files are ignored", files: map[string]any{"a.json": valid, "notes.txt": "notes"}, wantPassed: 1},
+		{name: "no attestations", files: map[string]any{"notes.txt": "notes"}, wantErr: "no *.json attestations found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if att, ok := content.(*attestation.Attestation); ok {
+					writeAttestation(t, att, path)
+				} else if err := os.WriteFile(path, []byte(content.(string)), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			summary, err := verifyDirectory(dir, signer.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyDirectory: %v", err)
+			}
+			if summary.Passed != tt.wantPassed || summary.Failed != tt.wantFailed || summary.Total != tt.wantPassed+tt.wantFailed {
+				t.Errorf("summary %d passed, %d failed, %d total; want %d passed, %d failed", summary.Passed, summary.Failed, summary.Total, tt.wantPassed, tt.wantFailed)
+			}
+			var failed []string
+			for _, result := range summary.Results {
+				if !result.Verified {
+					failed = append(failed, filepath.Base(result.File))
+					if len(result.Errors) == 0 {
+						t.Errorf("%s failed without errors", result.File)
+					}
+				}
+			}
+			if strings.Join(failed, ",") != strings.Join(tt.wantFailedFiles, ",") {
+				t.Errorf("failed files %q, want %q", failed, tt.wantFailedFiles)
+			}
+			if summary.exitCode != tt.wantExitCode {
+				t.Errorf("exit code %d, want %d", summary.exitCode, tt.wantExitCode)
+			}
+		})
+	}
+}
+
+func TestPrintBulkSummaryJSON(t *testing.T) {
+	out, _ := captureLogger(t, attestation.LogNormal)
+	summary := &BulkSummary{
+		Total:  2,
+		Passed: 1,
+		Failed: 1,
+		Results: []BulkResult{
+			{File: "a.json", Verified: true},
+			{File: "b.json", Errors: []string{"Content digest mismatch"}},
+		},
+	}
+	if err := printBulkSummary(summary, "json"); err != nil {
+		t.Fatalf("printBulkSummary: %v", err)
+	}
+	var decoded BulkSummary
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not a JSON summary: %v\n%s", err, out)
+	}
+	if decoded.Total != 2 || decoded.Passed != 1 || decoded.Failed != 1 || len(decoded.Results) != 2 {
+		t.Errorf("decoded summary %+v, want %+v", decoded, *summary)
+	}
+}
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandSharedFlags(t *testing.T) {
+	for command := range testCommands {
+		t.Run(command, func(t *testing.T) {
+			code, _, stderr := runCommand(t, nil, command, "-h")
+			if code != 0 {
+				t.Errorf("-h exited with %d, want 0", code)
+			}
+			if !containsAll(stderr, "Usage of "+command+":", "-provider", "-quiet", "-verbose") {
+				t.Errorf("usage does not list the shared flags:\n%s", stderr)
+			}
+
+			code, _, stderr = runCommand(t, nil, command, "--bogus")
+			if code != 2 || !strings.Contains(stderr, "flag provided but not defined: -bogus") {
+				t.Errorf("--bogus exited with %d: %s", code, stderr)
+			}
+
+			code, _, stderr = runCommand(t, nil, command, "--quiet", "--verbose")
+			if code != 1 || !strings.Contains(stderr, "quiet and verbose flags are mutually exclusive") {
+				t.Errorf("--quiet --verbose exited with %d: %s", code, stderr)
+			}
+		})
+	}
+}
+
+func TestCommandFlags(t *testing.T) {
+	githubEnv := []string{"ACTIONS_ID_TOKEN_REQUEST_URL=https://token.example.com", "ACTIONS_ID_TOKEN_REQUEST_TOKEN=token"}
+	tests := []struct {
+		name       string
+		env        []string
+		command    string
+		args       []string
+		wantCode   int
+		wantStderr string
+	}{
+		{
+			name:       "generate without an OIDC token request",
+			command:    "generate",
+			args:       []string{"--url", "https://example.com", "--attestation-file", "att.json"},
+			wantCode:   1,
+			wantStderr: "missing ACTIONS_ID_TOKEN_REQUEST_URL or ACTIONS_ID_TOKEN_REQUEST_TOKEN",
+		},
+		{
+			name:       "generate with an unsupported provider",
+			command:    "generate",
+			args:       []string{"--provider", "bogus", "--url", "https://example.com", "--attestation-file", "att.json"},
+			wantCode:   1,
+			wantStderr: "unsupported provider: bogus",
+		},
+		{
+			name:       "generate for gitlab without an ID token",
+			command:    "generate",
+			args:       []string{"--provider", "gitlab", "--url", "https://example.com", "--attestation-file", "att.json"},
+			wantCode:   1,
+			wantStderr: "missing OPENPUBKEY_JWT",
+		},
+		{
+			name:       "generate without a url",
+			env:        githubEnv,
+			command:    "generate",
+			args:       []string{"--attestation-file", "att.json"},
+			wantCode:   1,
+			wantStderr: "exactly one of attestation-file or output-dir, and exactly one of url or jwks-issuer flags are required",
+		},
+		{
+			name:       "generate with both attestation-file and output-dir",
+			env:        githubEnv,
+			command:    "generate",
+			args:       []string{"--attestation-file", "att.json", "--output-dir", "attestations", "--url", "https://example.com"},
+			wantCode:   1,
+			wantStderr: "exactly one of attestation-file or output-dir",
+		},
+		{
+			name:       "generate with both url and jwks-issuer",
+			env:        githubEnv,
+			command:    "generate",
+			args:       []string{"--attestation-file", "att.json", "--url", "https://example.com", "--jwks-issuer", "https://issuer.example.com"},
+			wantCode:   1,
+			wantStderr: "exactly one of attestation-file or output-dir",
+		},
+		{
+			name:       "generate with a negative validity",
+			command:    "generate",
+			args:       []string{"--valid-for", "-1h"},
+			wantCode:   1,
+			wantStderr: "valid-for must not be negative",
+		},
+		{
+			name:       "verify without an attestation",
+			command:    "verify",
+			wantCode:   exitUsage,
+			wantStderr: "exactly one of attestation-file or dir flags is required",
+		},
+		{
+			name:       "verify with both a file and a directory",
+			command:    "verify",
+			args:       []string{"--attestation-file", "att.json", "--dir", "."},
+			wantCode:   exitUsage,
+			wantStderr: "exactly one of attestation-file or dir flags is required",
+		},
+		{
+			name:       "verify with an unsupported output format",
+			command:    "verify",
+			args:       []string{"--output", "xml", "att.json"},
+			wantCode:   exitUsage,
+			wantStderr: "unsupported output format: xml",
+		},
+		{
+			name:       "verify inclusion without a log key",
+			env:        []string{"EXPECTED_WORKFLOW_REF=" + testWorkflowRef},
+			command:    "verify",
+			args:       []string{"--attestation-file", "att.json", "--verify-inclusion"},
+			wantCode:   exitUsage,
+			wantStderr: "--verify-inclusion requires --rekor-pubkey",
+		},
+		{
+			name:       "verify inclusion with a missing log key",
+			env:        []string{"EXPECTED_WORKFLOW_REF=" + testWorkflowRef},
+			command:    "verify",
+			args:       []string{"--attestation-file", "att.json", "--verify-inclusion", "--rekor-pubkey", "missing.pem"},
+			wantCode:   exitUsage,
+			wantStderr: "failed to read transparency log public key",
+		},
+		{
+			name:       "verify-url without an attestation url",
+			command:    "verify-url",
+			args:       []string{"--url", "https://example.com"},
+			wantCode:   exitUsage,
+			wantStderr: "url and attestation-url flags are required",
+		},
+		{
+			name:       "diff without the new attestation",
+			command:    "diff",
+			args:       []string{"--old", "old.json"},
+			wantCode:   1,
+			wantStderr: "old and new flags are required",
+		},
+		{
+			name:       "export-keys without an output directory",
+			command:    "export-keys",
+			wantCode:   1,
+			wantStderr: "output-dir flag is required",
+		},
+		{
+			name:       "export-keys with an unsupported provider",
+			command:    "export-keys",
+			args:       []string{"--provider", "bogus", "--output-dir", "keys"},
+			wantCode:   1,
+			wantStderr: "unsupported provider: bogus",
+		},
+		{
+			name:       "index-keys without an output path",
+			command:    "index-keys",
+			args:       []string{"--dir", "."},
+			wantCode:   1,
+			wantStderr: "dir and output flags are required",
+		},
+		{
+			name:       "index-keys with no attestations",
+			command:    "index-keys",
+			args:       []string{"--dir", "/nonexistent", "--output", "index.json"},
+			wantCode:   1,
+			wantStderr: "no *.json attestations found in /nonexistent",
+		},
+		{
+			name:       "selfcheck with a url but no content",
+			command:    "selfcheck",
+			args:       []string{"--url", "https://example.com"},
+			wantCode:   1,
+			wantStderr: "url and content-file, attestation-file, or both are required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runCommand(t, tt.env, tt.command, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exited with %d, want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("expected stderr containing %q, got:\n%s", tt.wantStderr, stderr)
+			}
+		})
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+	signer := newTestSigner(t)
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old.json")
+	sameFile := filepath.Join(dir, "same.json")
+	newFile := filepath.Join(dir, "new.json")
+	writeAttestation(t, signer.attest(t, "https://example.com/jwks", []byte("v1")), oldFile)
+	writeAttestation(t, signer.attest(t, "https://example.com/jwks", []byte("v1")), sameFile)
+	writeAttestation(t, signer.attest(t, "https://example.com/jwks", []byte("v2")), newFile)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+	}{
+		{name: "unchanged", args: []string{"--old", oldFile, "--new", sameFile}, wantStdout: "✅ Content unchanged"},
+		{name: "changed", args: []string{"--old", oldFile, "--new", newFile}, wantCode: 1, wantStdout: "📝 Content changed"},
+		{name: "changed as json", args: []string{"--old", oldFile, "--new", newFile, "--output", "json"}, wantCode: 1, wantStdout: `"content_changed": true`},
+		{name: "missing attestation", args: []string{"--old", oldFile, "--new", filepath.Join(dir, "missing.json")}, wantCode: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, _ := runCommand(t, nil, "diff", tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exited with %d, want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(stdout, tt.wantStdout) {
+				t.Errorf("expected stdout containing %q, got:\n%s", tt.wantStdout, stdout)
+			}
+		})
+	}
+}
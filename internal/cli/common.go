@@ -0,0 +1,79 @@
+// Package cli implements the url-oracle commands, shared by the url-oracle binary and the standalone
+// generate_attestation, verify_attestation and export_keys programs
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"url-oracle/attestation"
+)
+
+// logger routes progress to stderr and results to stdout, at the level set by --quiet/--verbose
+var logger = attestation.NewLogger(attestation.LogNormal)
+
+// commonFlags are the flags every command accepts
+type commonFlags struct {
+	provider *string
+	quiet    *bool
+	verbose  *bool
+}
+
+// registerCommonFlags adds the shared --provider, --quiet and --verbose flags to fs
+func registerCommonFlags(fs *flag.FlagSet) *commonFlags {
+	return &commonFlags{
+		provider: fs.String("provider", attestation.ProviderGithub, "OIDC provider (github or gitlab)"),
+		quiet:    fs.Bool("quiet", false, "Only print errors and results"),
+		verbose:  fs.Bool("verbose", false, "Also print details such as the decoded encoding, TLS certificate and expected workflow references"),
+	}
+}
+
+// recheckFlags restrict the schemes and addresses a content recheck may reach
+type recheckFlags struct {
+	allowHTTP    *bool
+	allowFile    *bool
+	allowPrivate *bool
+}
+
+// registerRecheckFlags adds the --allow-http, --allow-file and --allow-private-addresses recheck flags to fs
+func registerRecheckFlags(fs *flag.FlagSet) *recheckFlags {
+	return &recheckFlags{
+		allowHTTP:    fs.Bool("allow-http", false, "Allow rechecking plain http:// URLs"),
+		allowFile:    fs.Bool("allow-file", false, "Allow rechecking file:// URLs and absolute local paths"),
+		allowPrivate: fs.Bool("allow-private-addresses", false, "Allow rechecking URLs on loopback, private and link-local addresses"),
+	}
+}
+
+// apply sets the recheck download options of opts from the flags
+func (r *recheckFlags) apply(opts *attestation.VerifyOptions) {
+	opts.RecheckOptions.AllowHTTP = *r.allowHTTP
+	opts.RecheckOptions.AllowFile = *r.allowFile
+	opts.RecheckOptions.BlockPrivateAddresses = !*r.allowPrivate
+}
+
+// apply sets the log level from --quiet/--verbose, exiting if both are set
+func (c *commonFlags) apply() {
+	level, err := attestation.LogLevelFromFlags(*c.quiet, *c.verbose)
+	if err != nil {
+		logger.Errorf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	logger.Level = level
+}
+
+// printTimings prints how long each phase took, as JSON when asJSON is set
+func printTimings(timings *attestation.Timings, asJSON bool) {
+	if asJSON {
+		data, err := json.MarshalIndent(timings, "", "  ")
+		if err != nil {
+			logger.Warnf("⚠️  Warning: failed to marshal timings: %v\n", err)
+			return
+		}
+		logger.Resultf("%s\n", string(data))
+		return
+	}
+	logger.Progressf("\n")
+	logger.Progressf("⏱️  Timings:\n")
+	logger.Progressf("%s", timings.String())
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"url-oracle/attestation"
+)
+
+// Diff runs the diff command, comparing what two attestations recorded about their content.
+// It exits 0 when the content digests match and 1 when they differ.
+func Diff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	var (
+		oldFile = fs.String("old", "", "Path to the earlier attestation")
+		newFile = fs.String("new", "", "Path to the later attestation")
+		output  = fs.String("output", "text", "Output format: text or json")
+	)
+	fs.Parse(args)
+	common.apply()
+
+	if *oldFile == "" || *newFile == "" {
+		logger.Errorf("Error: old and new flags are required\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	oldAttestation, err := attestation.LoadAttestation(*oldFile)
+	if err != nil {
+		logger.Errorf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+	newAttestation, err := attestation.LoadAttestation(*newFile)
+	if err != nil {
+		logger.Errorf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	diff := oldAttestation.Diff(newAttestation)
+	if *output == "json" {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			logger.Errorf("❌ Error: Failed to marshal diff: %v\n", err)
+			os.Exit(1)
+		}
+		logger.Resultf("%s\n", string(data))
+	} else {
+		logger.Resultf("🔍 Differences:\n")
+		for _, change := range diff.Changes {
+			logger.Resultf("  %s: %s -> %s\n", change.Field, change.Old, change.New)
+		}
+		if diff.ContentChanged {
+			logger.Resultf("📝 Content changed\n")
+		} else {
+			logger.Resultf("✅ Content unchanged\n")
+		}
+	}
+
+	if diff.ContentChanged {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
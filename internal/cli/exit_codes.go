@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"slices"
+
+	"url-oracle/attestation"
+)
+
+// Verification exit codes, so CI can react differently to each class of failure
+const (
+	exitVerified = 0
+	// exitUsage is used for invalid flags and other errors made before verifying
+	exitUsage = 1
+	// exitSignatureFailure means a PK token, signature, signer binding, payload digest or signer threshold check failed
+	exitSignatureFailure = 10
+	// exitPolicyFailure means the attestation is authentic but a workflow, repository, issuer, freshness
+	// or other policy check failed
+	exitPolicyFailure = 11
+	// exitContentDrift means the attestation is authentic but the --recheck of the attested URL failed,
+	// whether or not policy checks failed too
+	exitContentDrift = 12
+	// exitIOError means the attestation could not be read or parsed
+	exitIOError = 20
+)
+
+// verificationExitCode classifies a verification result. Signature failures take precedence over content
+// drift, which takes precedence over policy failures.
+func verificationExitCode(result *attestation.VerificationResult, opts attestation.VerifyOptions) int {
+	if result.IsVerificationSuccessful() {
+		return exitVerified
+	}
+	if !result.PKTokenVerified || !result.SignedMessageVerified || !result.SignerBindingVerified ||
+		!result.PayloadDigestVerified ||
+		!result.OracleDigestVerified || !result.SignerThresholdMet {
+		return exitSignatureFailure
+	}
+	if opts.RecheckContent && !result.ContentRecheckVerified {
+		return exitContentDrift
+	}
+	return exitPolicyFailure
+}
+
+// exitCodeSeverity orders the verification exit codes from least to most severe, so that --dir can exit with
+// the most severe across files. Signature failures outrank an unreadable attestation, which outranks drift.
+var exitCodeSeverity = []int{exitVerified, exitPolicyFailure, exitContentDrift, exitIOError, exitSignatureFailure}
+
+// mostSevereExitCode returns whichever of the verification exit codes a and b is more severe
+func mostSevereExitCode(a int, b int) int {
+	if slices.Index(exitCodeSeverity, b) > slices.Index(exitCodeSeverity, a) {
+		return b
+	}
+	return a
+}
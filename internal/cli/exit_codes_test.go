@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"testing"
+
+	"url-oracle/attestation"
+)
+
+// authenticResult returns a result whose every check passed
+func authenticResult() *attestation.VerificationResult {
+	return &attestation.VerificationResult{
+		PKTokenVerified:              true,
+		IssuerVerified:               true,
+		SignedMessageVerified:        true,
+		SignerBindingVerified:        true,
+		PayloadDigestVerified:        true,
+		OracleDigestVerified:         true,
+		SignerThresholdMet:           true,
+		WorkflowRefVerified:          true,
+		WorkflowSHAVerified:          true,
+		TimestampConsistencyVerified: true,
+		ContentRecheckVerified:       true,
+	}
+}
+
+func TestVerificationExitCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		recheck bool
+		fail    func(r *attestation.VerificationResult)
+		want    int
+	}{
+		{name: "verified", want: exitVerified},
+		{name: "verified with recheck", recheck: true, want: exitVerified},
+		{
+			name: "signature failure",
+			fail: func(r *attestation.VerificationResult) { r.SignedMessageVerified = false },
+			want: exitSignatureFailure,
+		},
+		{
+			name: "signer threshold not met",
+			fail: func(r *attestation.VerificationResult) { r.SignerThresholdMet = false },
+			want: exitSignatureFailure,
+		},
+		{
+			name: "policy failure",
+			fail: func(r *attestation.VerificationResult) { r.WorkflowRefVerified = false },
+			want: exitPolicyFailure,
+		},
+		{
+			name:    "content drift",
+			recheck: true,
+			fail:    func(r *attestation.VerificationResult) { r.ContentRecheckVerified = false },
+			want:    exitContentDrift,
+		},
+		{
+			name:    "signature failure outranks content drift",
+			recheck: true,
+			fail: func(r *attestation.VerificationResult) {
+				r.PKTokenVerified = false
+				r.ContentRecheckVerified = false
+			},
+			want: exitSignatureFailure,
+		},
+		{
+			name:    "content drift outranks policy failure",
+			recheck: true,
+			fail: func(r *attestation.VerificationResult) {
+				r.WorkflowRefVerified = false
+				r.ContentRecheckVerified = false
+			},
+			want: exitContentDrift,
+		},
+		{
+			name: "policy failure without recheck",
+			fail: func(r *attestation.VerificationResult) {
+				r.TimestampConsistencyVerified = false
+				r.ContentRecheckVerified = false
+			},
+			want: exitPolicyFailure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := authenticResult()
+			if tt.fail != nil {
+				tt.fail(result)
+				result.Errors = []string{"failed"}
+			}
+			opts := attestation.NewVerifyOptions()
+			opts.RecheckContent = tt.recheck
+			if got := verificationExitCode(result, opts); got != tt.want {
+				t.Errorf("verificationExitCode = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMostSevereExitCode(t *testing.T) {
+	tests := []struct {
+		a, b int
+		want int
+	}{
+		{a: exitVerified, b: exitVerified, want: exitVerified},
+		{a: exitVerified, b: exitPolicyFailure, want: exitPolicyFailure},
+		{a: exitContentDrift, b: exitPolicyFailure, want: exitContentDrift},
+		{a: exitContentDrift, b: exitIOError, want: exitIOError},
+		{a: exitSignatureFailure, b: exitIOError, want: exitSignatureFailure},
+	}
+	for _, tt := range tests {
+		if got := mostSevereExitCode(tt.a, tt.b); got != tt.want {
+			t.Errorf("mostSevereExitCode(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
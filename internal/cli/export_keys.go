@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"url-oracle/attestation"
+)
+
+// ExportKeys runs the export-keys command, merging the provider's current JWKS into a key log directory
+func ExportKeys(args []string) {
+	fs := flag.NewFlagSet("export-keys", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	outputDir := fs.String("output-dir", "", "Key log directory to create or update")
+	fs.Parse(args)
+	common.apply()
+
+	if *outputDir == "" {
+		logger.Errorf("Error: output-dir flag is required\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	issuer, err := attestation.ProviderIssuer(*common.provider)
+	if err != nil {
+		logger.Errorf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger.Progressf("📥 Fetching JWKS...\n")
+	jwksContent, err := attestation.GetIssuerJWKS(context.Background(), issuer)
+	if err != nil {
+		logger.Errorf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	index, err := attestation.ExportKeyLog(*outputDir, issuer, jwksContent, time.Now())
+	if err != nil {
+		logger.Errorf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger.Resultf("💾 Key log with %d keys saved to: %s\n", len(index.Keys), *outputDir)
+}
@@ -0,0 +1,720 @@
+package cli
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+	"url-oracle/attestation"
+
+	"github.com/openpubkey/openpubkey/client"
+	"github.com/openpubkey/openpubkey/pktoken"
+	"github.com/openpubkey/openpubkey/providers"
+)
+
+// Exit codes signalling how the content compares with the previous attestation
+const (
+	// exitUnchanged is used by --fail-on-unchanged when the content matches the previous attestation, and by
+	// --skip-if-not-modified when the server answers 304 Not Modified
+	exitUnchanged = 3
+	// exitContentChanged is used by --fail-on-content-change, after attesting, when the content differs
+	exitContentChanged = 4
+)
+
+// previousAttestationDetailsFile returns the default previous-details path for an attestation file name,
+// e.g. previous_attestation_details.json for attestation.json
+func previousAttestationDetailsFile(attestationFileName string) string {
+	name := strings.TrimSuffix(attestationFileName, filepath.Ext(attestationFileName))
+	return "previous_" + name + "_details.json"
+}
+
+// fetchPreviousAttestationDetails attempts to fetch a previous attestation details using the workflow reference
+func fetchPreviousAttestationDetails(claims *attestation.IDTokenClaims, attestationFileName string, detailsFile string, selector attestation.PreviousSelector) (*attestation.AttestationDetails, error) {
+	// Example: kipz/url-oracle/.github/workflows/create-attestation.yml@refs/heads/main
+	workflowRef, err := attestation.ParseWorkflowRef(claims.WorkflowRef)
+	if err != nil {
+		logger.Warnf("⚠️  Warning: Unexpected workflow_ref format: %s\n", claims.WorkflowRef)
+		return nil, err
+	}
+	// Pull request runs don't build on each other, so there is nothing to chain from
+	if workflowRef.RefType == attestation.RefTypePull {
+		logger.Warnf("⚠️  Warning: Not fetching previous attestation for pull request ref %s\n", workflowRef.RefName)
+		return nil, nil
+	}
+	repoFull := workflowRef.Repository()
+	workflowFile := workflowRef.WorkflowFile
+	// The runs API matches head_branch, which holds the tag name for tag runs
+	branch := workflowRef.RefName
+
+	client := attestation.NewGitHubClient(os.Getenv("CALLER_TOKEN"))
+	logger.Progressf("🔎 Attempting to fetch previous attestation from %s %s %s...\n", repoFull, workflowFile, branch)
+	prevAttestation, details, err := client.FetchPreviousAttestationMatching(repoFull, workflowFile, branch, attestationFileName, selector)
+	if err != nil {
+		// A missing previous attestation is not a fatal error
+		var notFound *attestation.PreviousAttestationNotFoundError
+		if errors.As(err, &notFound) {
+			logger.Warnf("⚠️  Warning: %v\n", err)
+			return nil, nil
+		}
+		logger.Warnf("⚠️  Warning: Could not fetch previous attestation: %v\n", err)
+		return nil, fmt.Errorf("failed to fetch previous attestation: %w", err)
+	}
+
+	logger.Progressf("🔍 Verifying previous attestation...\n")
+	opts := attestation.NewVerifyOptions()
+	opts.ExpectedWorkflowRef = claims.JobWorkflowRef
+	result, err := attestation.VerifyAttestation(prevAttestation, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify previous attestation: %w", err)
+	}
+	if !result.IsVerificationSuccessful() {
+		return nil, fmt.Errorf("previous attestation verification failed: %s", strings.Join(result.Errors, "; "))
+	}
+
+	if err := attestation.SaveAttestationDetails(details, detailsFile); err != nil {
+		return nil, err
+	}
+	logger.Progressf("✅ Loaded previous attestation from %s\n", details.ArtifactURL)
+	return details, nil
+}
+
+// previousCacheValidators returns the ETag and Last-Modified recorded by the previous attestation, read from
+// previousFile or, in GitHub Actions, fetched from the latest run of the current workflow. The previous
+// attestation is not verified, as its validators can at worst skip one run's attestation.
+func previousCacheValidators(previousFile string, attestationFileName string, selector attestation.PreviousSelector) (string, string, error) {
+	var previous *attestation.Attestation
+	if previousFile != "" {
+		var err error
+		previous, err = attestation.LoadAttestation(previousFile)
+		if err != nil {
+			return "", "", err
+		}
+	} else {
+		// The ID token is not requested yet, so the workflow comes from the runner's environment
+		workflowRef, err := attestation.ParseWorkflowRef(os.Getenv("GITHUB_WORKFLOW_REF"))
+		if err != nil {
+			return "", "", fmt.Errorf("skip-if-not-modified needs previous-attestation-file outside GitHub Actions: %w", err)
+		}
+		client := attestation.NewGitHubClient(os.Getenv("CALLER_TOKEN"))
+		previous, _, err = client.FetchPreviousAttestationMatching(workflowRef.Repository(), workflowRef.WorkflowFile, workflowRef.RefName, attestationFileName, selector)
+		var notFound *attestation.PreviousAttestationNotFoundError
+		if errors.As(err, &notFound) {
+			return "", "", nil
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to fetch previous attestation: %w", err)
+		}
+	}
+	return previous.Payload.ETag, previous.Payload.LastModified, nil
+}
+
+// Generate runs the generate command, downloading content and signing an attestation of it
+func Generate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	var (
+		attestationFile = fs.String("attestation-file", "", "Output attestation file path, or s3://bucket/key or gs://bucket/object")
+		outputDir       = fs.String("output-dir", "", "Write the attestation to <url hash>-<timestamp>.json in this directory, or s3://bucket/prefix or gs://bucket/prefix, instead of --attestation-file")
+		url             = fs.String("url", "", "Some URL (e.g., https://vstoken.actions.githubusercontent.com/.well-known/jwks), file:// URL or absolute local path with --allow-file")
+		jwksIssuer      = fs.String("jwks-issuer", "", "Attest a snapshot of this OIDC issuer's JWKS instead of a URL")
+		headOnly        = fs.Bool("head-only", false, "Attest the status, Content-Length, ETag and Last-Modified of a HEAD request instead of the content")
+		rekorURL        = fs.String("rekor-url", "", "Rekor transparency log URL to enter the signed payload digest in (e.g. https://rekor.sigstore.dev)")
+		tsaURL          = fs.String("tsa-url", "", "RFC 3161 timestamp authority URL to timestamp the signature with")
+		cosigners       = fs.String("cosign-provider", "", "Comma-separated OIDC providers whose PK tokens also sign the attestation")
+		skipPrevious    = fs.Bool("skip-previous", false, "Skip attempting to fetch and reference previous attestation")
+		previousFile    = fs.String("previous-attestation-file", "", "Previous attestation to compare the content with for --fail-on-unchanged")
+		failUnchanged   = fs.Bool("fail-on-unchanged", false, fmt.Sprintf("Exit with code %d without attesting if the content digest matches the previous attestation", exitUnchanged))
+		skipNotModified = fs.Bool("skip-if-not-modified", false, fmt.Sprintf("Make a conditional request with the previous attestation's ETag and Last-Modified, exiting with code %d without attesting on 304 Not Modified", exitUnchanged))
+		failChanged     = fs.Bool("fail-on-content-change", false, fmt.Sprintf("Attest, then exit with code %d if the content digest differs from the previous attestation", exitContentChanged))
+		previousRunID   = fs.Int64("previous-run-id", 0, "Chain from the attestation of this workflow run instead of the latest")
+		previousBefore  = fs.String("previous-before", "", "Chain from the latest attestation of a run created before this RFC 3339 timestamp")
+		previousDigest  = fs.String("previous-digest", "", "Chain from the attestation with this digest among recent runs")
+		previousDetails = fs.String("previous-details-file", "", "Where to write the fetched previous attestation details (defaults to previous_<attestation file name>_details.json)")
+		contentTypes    = fs.String("expect-content-type", "", "Comma-separated allowlist of content types the response must match (e.g. application/json)")
+		basicAuth       = fs.String("basic-auth", "", "Basic auth credentials as user:pass, given as env:VAR or file:PATH")
+		bearerToken     = fs.String("bearer-token", "", "Bearer token, given as env:VAR or file:PATH")
+		authFromEnv     = fs.String("auth-from-env", "", fmt.Sprintf("Authenticate with credentials from the environment: bearer (%s) or basic (%s as user:pass)", attestation.EnvBearerToken, attestation.EnvBasicAuth))
+		allowHTTP       = fs.Bool("allow-http", false, "Allow plain http:// URLs")
+		allowFile       = fs.Bool("allow-file", false, "Allow file:// URLs and absolute local paths")
+		allowPrivate    = fs.Bool("allow-private-addresses", false, "Allow fetching from loopback, private and link-local addresses")
+		proxyURL        = fs.String("proxy", "", "Proxy URL for downloads (defaults to HTTPS_PROXY/HTTP_PROXY)")
+		caFile          = fs.String("ca-file", "", "PEM bundle of CA certificates to trust instead of the system roots")
+		pinCert         = fs.String("pin-cert", "", "Require the server's leaf certificate or public key to have this sha256 digest")
+		followPages     = fs.Bool("follow-pagination", false, "Follow Link rel=\"next\" headers (or fill in {page} in the URL) and attest the concatenated pages")
+		maxPages        = fs.Int("max-pages", 0, "Maximum number of pages to follow (default 100)")
+		method          = fs.String("method", "", "HTTP method to request the URL with (default GET), e.g. POST for a GraphQL or JSON-RPC query")
+		requestBody     = fs.String("request-body-file", "", "Send this file as the request body, recording its digest in the attestation (requires --method)")
+		requestType     = fs.String("request-content-type", "", "Content-Type of the request body (default application/json)")
+		maxSize         = fs.Int64("max-size", 0, "Fail if the content is larger than this many bytes (0 for no limit)")
+		validFor        = fs.Duration("valid-for", 0, "Declare the attestation valid for this long after its timestamp (e.g. 720h); verifiers reject it outside that window")
+		fetchTiming     = fs.Bool("record-fetch-timing", false, "Record when the fetch started and how long it took in the signed payload")
+		noContent       = fs.Bool("no-content", false, "Stream the content through the digest without storing it in the attestation, for very large content")
+		compress        = fs.Bool("compress-content", false, "Store the content gzip-compressed in the attestation (the digest stays that of the uncompressed content)")
+		multihash       = fs.Bool("multihash-digest", false, "Also record the content digest as a base64 multihash for SBOM tooling")
+		normalize       = fs.String("normalize", "", "Canonicalize content before digesting it (json)")
+		timingsJSON     = fs.Bool("timings", false, "Print phase timings as JSON")
+		contentOutput   = fs.String("content-output", "", "Also write the downloaded content (the exact bytes that were digested) to this path")
+		logFile         = fs.String("log-file", "", "Append the new attestation's digest and timestamp to this JSON lines ledger")
+		detailsFile     = fs.String("details-file", "", "Also write the digest and artifact URL of the new attestation to this path")
+	)
+	fs.Parse(args)
+	common.apply()
+
+	if *validFor < 0 {
+		logger.Errorf("Error: valid-for must not be negative\n")
+		os.Exit(1)
+	}
+
+	selector := attestation.PreviousSelector{RunID: *previousRunID, Digest: *previousDigest}
+	if *previousBefore != "" {
+		before, err := time.Parse(time.RFC3339, *previousBefore)
+		if err != nil {
+			logger.Errorf("Error: invalid previous-before timestamp: %v\n", err)
+			os.Exit(1)
+		}
+		selector.Before = before
+	}
+
+	timings := attestation.NewTimings(nil)
+
+	// Cancel in-flight downloads and token requests on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	signer, err := newTokenSigner(*common.provider)
+	if err != nil {
+		logger.Errorf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	var cosignOps []cosignOp
+	if *cosigners != "" {
+		for _, cosignProvider := range strings.Split(*cosigners, ",") {
+			cosignSigner, err := newTokenSigner(cosignProvider)
+			if err != nil {
+				logger.Errorf("Error: cosigner: %v\n", err)
+				os.Exit(1)
+			}
+			cosignOps = append(cosignOps, cosignOp{provider: cosignProvider, signer: cosignSigner})
+		}
+	}
+	if (*attestationFile == "") == (*outputDir == "") || (*url == "") == (*jwksIssuer == "") {
+		logger.Errorf("Error: exactly one of attestation-file or output-dir, and exactly one of url or jwks-issuer flags are required\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *headOnly && (*jwksIssuer != "" || *contentOutput != "") {
+		logger.Errorf("Error: head-only cannot be combined with jwks-issuer or content-output\n")
+		os.Exit(1)
+	}
+	if *noContent && (*jwksIssuer != "" || *contentOutput != "" || *compress) {
+		logger.Errorf("Error: no-content cannot be combined with jwks-issuer, content-output or compress-content\n")
+		os.Exit(1)
+	}
+	if *detailsFile != "" {
+		// Local files only become workflow artifacts after this step, with no URL yet that a chain walk could fetch
+		if storage, _, err := attestation.NewStorage(*attestationFile + *outputDir); err == nil {
+			if _, local := storage.(attestation.FileStorage); local {
+				logger.Errorf("Error: details-file needs an s3:// or gs:// attestation-file or output-dir to record as the artifact URL\n")
+				os.Exit(1)
+			}
+		}
+	}
+	// Timestamped names differ on every run, so previous attestations are looked up by the URL hash they share
+	attestationFileName := filepath.Base(*attestationFile)
+	if *outputDir != "" {
+		attestationFileName = attestation.URLHash(*url+*jwksIssuer) + ".json"
+	}
+	if *previousDetails == "" {
+		*previousDetails = previousAttestationDetailsFile(attestationFileName)
+	}
+	logger.Progressf("📥 Downloading content from URL...\n")
+	downloadOpts := attestation.DownloadOptions{
+		AllowHTTP:             *allowHTTP,
+		AllowFile:             *allowFile,
+		BlockPrivateAddresses: !*allowPrivate,
+		PinnedCert:            *pinCert,
+		ProxyURL:              *proxyURL,
+		MaxSize:               *maxSize,
+		FollowPagination:      *followPages,
+		MaxPages:              *maxPages,
+		Normalize:             *normalize,
+		HeadOnly:              *headOnly,
+		RecordFetchTiming:     *fetchTiming,
+		Method:                *method,
+		RequestContentType:    *requestType,
+	}
+	if *requestBody != "" {
+		body, err := os.ReadFile(*requestBody)
+		if err != nil {
+			logger.Errorf("❌ Error: Failed to read request body: %v\n", err)
+			os.Exit(1)
+		}
+		downloadOpts.RequestBody = body
+	}
+	if *caFile != "" {
+		rootCAs, err := attestation.LoadCertPool(*caFile)
+		if err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		downloadOpts.RootCAs = rootCAs
+	}
+	if *contentTypes != "" {
+		downloadOpts.ExpectedContentTypes = strings.Split(*contentTypes, ",")
+	}
+	if *authFromEnv != "" {
+		if *basicAuth != "" || *bearerToken != "" {
+			logger.Errorf("Error: auth-from-env cannot be combined with basic-auth or bearer-token\n")
+			os.Exit(1)
+		}
+		if err := downloadOpts.SetAuthFromEnv(*authFromEnv); err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *basicAuth != "" {
+		credentials, err := readSecret(*basicAuth)
+		if err != nil {
+			logger.Errorf("❌ Error: Failed to read basic auth credentials: %v\n", err)
+			os.Exit(1)
+		}
+		username, password, ok := strings.Cut(credentials, ":")
+		if !ok {
+			logger.Errorf("❌ Error: Basic auth credentials must be in user:pass format\n")
+			os.Exit(1)
+		}
+		downloadOpts.BasicAuth = &attestation.BasicAuth{Username: username, Password: password}
+	}
+	if *bearerToken != "" {
+		token, err := readSecret(*bearerToken)
+		if err != nil {
+			logger.Errorf("❌ Error: Failed to read bearer token: %v\n", err)
+			os.Exit(1)
+		}
+		downloadOpts.BearerToken = token
+	}
+	if *skipNotModified {
+		if *jwksIssuer != "" || *headOnly || *followPages {
+			logger.Errorf("Error: skip-if-not-modified cannot be combined with jwks-issuer, head-only or follow-pagination\n")
+			os.Exit(1)
+		}
+		downloadOpts.IfNoneMatch, downloadOpts.IfModifiedSince, err = previousCacheValidators(*previousFile, attestationFileName, selector)
+		if err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		if downloadOpts.IfNoneMatch == "" && downloadOpts.IfModifiedSince == "" {
+			logger.Progressf("   No previous ETag or Last-Modified recorded, downloading unconditionally\n")
+		}
+	}
+	var fetcher attestation.Fetcher = &attestation.HTTPFetcher{Options: downloadOpts}
+	source := *url
+	if *jwksIssuer != "" {
+		fetcher, source = attestation.JWKSFetcher{}, *jwksIssuer
+	}
+	stopDownload := timings.Start(attestation.PhaseDownload)
+	var download *attestation.DownloadResult
+	if *noContent {
+		// Fetchers return the content, so digest-only downloads go straight to DownloadContentContext
+		downloadOpts.DigestOnly = true
+		download, err = attestation.DownloadContentContext(ctx, source, downloadOpts)
+	} else {
+		download, err = attestation.FetchContent(ctx, fetcher, source)
+	}
+	stopDownload()
+	if errors.Is(err, attestation.ErrNotModified) {
+		logger.Progressf("⏭️  Content not modified since previous attestation (ETag %s, Last-Modified %s), not attesting\n", downloadOpts.IfNoneMatch, downloadOpts.IfModifiedSince)
+		os.Exit(exitUnchanged)
+	}
+	if err != nil {
+		logger.Errorf("❌ Error: Failed to download content from %s: %v\n", attestation.RedactURL(source), err)
+		os.Exit(1)
+	}
+
+	var contentChanged bool
+	if *failUnchanged || *failChanged {
+		if *previousFile == "" {
+			logger.Errorf("Error: fail-on-unchanged and fail-on-content-change require previous-attestation-file\n")
+			os.Exit(1)
+		}
+		contentChanged, err = attestation.CheckContentChanges(download, *previousFile)
+		if err != nil {
+			logger.Errorf("❌ Error: Failed to compare with previous attestation: %v\n", err)
+			os.Exit(1)
+		}
+		if *failUnchanged && !contentChanged {
+			logger.Progressf("⏭️  Content unchanged since previous attestation (%s), not attesting\n", download.ContentDigest)
+			os.Exit(exitUnchanged)
+		}
+	}
+
+	if *multihash && download.ContentDigest != "" {
+		download.ContentDigestMultihash, err = attestation.ContentDigestMultihash(download.ContentDigest)
+		if err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *contentOutput != "" {
+		if err := saveContent(download.Content, *contentOutput); err != nil {
+			logger.Errorf("❌ Error saving content: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *compress && download.Content != nil {
+		download.Content, err = attestation.CompressContent(download.Content)
+		if err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		download.ContentCompression = attestation.ContentCompressionGzip
+	}
+
+	if download.Head != nil {
+		logger.Progressf("✅ Fetched metadata: Content-Length %d, ETag %s, Last-Modified %s\n", download.Head.ContentLength, download.Head.ETag, download.Head.LastModified)
+	} else {
+		logger.Progressf("✅ Downloaded content: %d bytes, digest: %s\n", download.ContentSize, download.ContentDigest)
+	}
+	if download.ContentEncoding != "" {
+		logger.Verbosef("   Decoded Content-Encoding: %s\n", download.ContentEncoding)
+	}
+	if len(download.TLSCertFingerprints) > 0 {
+		logger.Verbosef("   TLS leaf certificate: %s (issuer: %s)\n", download.TLSCertFingerprints[0], download.TLSLeafIssuer)
+	}
+
+	logger.Progressf("🔍 Creating attestation payload...\n")
+
+	logger.Progressf("🔍 Generating OpenPubkey token...\n")
+
+	token, err := createAttestation(ctx, download, signer, attestationRequest{
+		provider:            *common.provider,
+		cosignOps:           cosignOps,
+		attestationFileName: attestationFileName,
+		skipPrevious:        *skipPrevious,
+		previousDetailsFile: *previousDetails,
+		selector:            selector,
+		rekorURL:            *rekorURL,
+		validFor:            *validFor,
+		timings:             timings,
+	})
+	if err != nil {
+		logger.Errorf("❌ Error: OpenPubkey token generation failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *tsaURL != "" {
+		logger.Progressf("🕰️  Requesting timestamp from %s...\n", *tsaURL)
+		token.TimestampToken, err = attestation.RequestTimestamp(ctx, *tsaURL, token.Signature)
+		if err != nil {
+			logger.Errorf("❌ Error: Failed to timestamp attestation: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *outputDir != "" {
+		issuedAt, err := time.Parse(time.RFC3339, token.Payload.Timestamp)
+		if err != nil {
+			logger.Errorf("❌ Error: invalid attestation timestamp: %v\n", err)
+			os.Exit(1)
+		}
+		*attestationFile = strings.TrimSuffix(*outputDir, "/") + "/" + attestation.AttestationFileName(*url+*jwksIssuer, issuedAt)
+	}
+
+	logger.Progressf("💾 Saving attestation...\n")
+	location, err := saveAttestation(ctx, token, *attestationFile)
+	if err != nil {
+		logger.Errorf("❌ Error saving attestation: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *detailsFile != "" {
+		if err := saveAttestationDetails(token, location, *detailsFile); err != nil {
+			logger.Errorf("❌ Error saving attestation details: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *logFile != "" {
+		if err := attestation.AppendToLedger(*logFile, token); err != nil {
+			logger.Errorf("❌ Error appending to ledger: %v\n", err)
+			os.Exit(1)
+		}
+		logger.Progressf("📒 Attestation appended to ledger: %s\n", *logFile)
+	}
+
+	logger.Resultf("✅ Attestation generated successfully!\n")
+	logger.Resultf("   Commit SHA: %s\n", shortSHA(token.Payload.CommitSHA))
+
+	timings.Finish()
+	printTimings(timings, *timingsJSON)
+
+	if *failChanged && contentChanged {
+		logger.Resultf("⚠️  Content changed since previous attestation (now %s)\n", token.Payload.ContentDigest)
+		os.Exit(exitContentChanged)
+	}
+}
+
+// readSecret resolves an env:VAR or file:PATH reference so secrets never appear in process arguments
+func readSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		name := strings.TrimPrefix(ref, "env:")
+		value := os.Getenv(name)
+		if value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(ref, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return "", fmt.Errorf("secret must be given as env:VAR or file:PATH")
+	}
+}
+
+// tokenSigner obtains a PK token from an OP and signs with the ephemeral key it binds. *client.OpkClient
+// implements it for the live OIDC flow, and a fake can stand in for it without CI environment variables.
+type tokenSigner interface {
+	Auth(ctx context.Context, opts ...client.AuthOpts) (*pktoken.PKToken, error)
+	GetSigner() crypto.Signer
+}
+
+// newTokenSigner creates an OpenPubkey client for the named provider's OP. It is a variable so command tests
+// can sign with a mock OP.
+var newTokenSigner = func(provider string) (tokenSigner, error) {
+	op, err := newOpenIdProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+	opkClient, err := client.New(op)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OpenPubkey client: %w", err)
+	}
+	return opkClient, nil
+}
+
+// newOpenIdProvider creates the OIDC provider used to sign attestations from the CI environment
+func newOpenIdProvider(provider string) (providers.OpenIdProvider, error) {
+	switch provider {
+	case attestation.ProviderGithub:
+		reqURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+		reqTok := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+		if reqURL == "" || reqTok == "" {
+			return nil, fmt.Errorf("missing ACTIONS_ID_TOKEN_REQUEST_URL or ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+		}
+		return providers.NewGithubOp(reqURL, reqTok), nil
+	case attestation.ProviderGitlab:
+		// GitLab CI exposes the ID token through an id_tokens entry named OPENPUBKEY_JWT
+		if os.Getenv("OPENPUBKEY_JWT") == "" {
+			return nil, fmt.Errorf("missing OPENPUBKEY_JWT")
+		}
+		return providers.NewGitlabCiOpFromEnvironmentDefault(), nil
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+}
+
+// attestationRequest configures how createAttestation signs and chains an attestation
+type attestationRequest struct {
+	// provider names the signer's OIDC provider, whose claims extractor reads the PK token
+	provider string
+	// cosignOps are the OPs that cosign the payload digest after the signer
+	cosignOps []cosignOp
+	// attestationFileName is the artifact name the previous attestation was uploaded as
+	attestationFileName string
+	// skipPrevious disables chaining from the previous attestation
+	skipPrevious bool
+	// previousDetailsFile receives the previous attestation's details when set
+	previousDetailsFile string
+	// selector chooses the run to chain from
+	selector attestation.PreviousSelector
+	// rekorURL is the transparency log to enter the signed digest in, none when empty
+	rekorURL string
+	// validFor sets the payload's validity window from the issue time, none when 0
+	validFor time.Duration
+	// timings records how long each phase takes
+	timings *attestation.Timings
+}
+
+func createAttestation(ctx context.Context, download *attestation.DownloadResult, signer tokenSigner, req attestationRequest) (*attestation.Attestation, error) {
+	// Authenticate and generate PK token
+	stopAuth := req.timings.Start(attestation.PhaseAuth)
+	pkToken, err := signer.Auth(ctx)
+	stopAuth()
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate and generate PK token: %w", err)
+	}
+
+	// Extract commit SHA and timestamp from ID token payload
+	extractor, err := attestation.GetClaimsExtractor(req.provider)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := extractor.ExtractClaims(pkToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract claims from ID token: %w", err)
+	}
+
+	// Fetch previous attestation (if not skipped)
+	var prevAttestationDetails []byte
+	if req.provider != attestation.ProviderGithub {
+		logger.Progressf("⏭️  Skipping previous attestation fetch (only supported for GitHub Actions)\n")
+	} else if !req.skipPrevious {
+		stopFetch := req.timings.Start(attestation.PhasePreviousFetch)
+		details, err := fetchPreviousAttestationDetails(claims, req.attestationFileName, req.previousDetailsFile, req.selector)
+		stopFetch()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch previous attestation: %w", err)
+		}
+		if details != nil {
+			prevAttestationDetails, err = json.Marshal(details)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal previous attestation details: %w", err)
+			}
+		}
+	} else {
+		logger.Progressf("⏭️  Skipping previous attestation fetch (--skip-previous flag set)\n")
+	}
+
+	// The validity window starts when the attestation is issued
+	var payloadOpts []attestation.PayloadOption
+	if req.validFor > 0 {
+		issuedAt, err := time.Parse(time.RFC3339, claims.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse attestation timestamp: %w", err)
+		}
+		payloadOpts = append(payloadOpts, attestation.WithValidity(issuedAt, issuedAt.Add(req.validFor)))
+	}
+
+	// Create and sign the attestation payload with the PK token's claims
+	stopSigning := req.timings.Start(attestation.PhaseSigning)
+	token, err := attestation.BuildAttestation(pkToken, signer.GetSigner(), extractor, download, prevAttestationDetails, payloadOpts...)
+	stopSigning()
+	if err != nil {
+		return nil, err
+	}
+	msg, err := token.Payload.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate attestation digest: %w", err)
+	}
+
+	// Enter the signed digest in the transparency log with the key bound to the PK token
+	if req.rekorURL != "" {
+		logger.Progressf("📜 Submitting to transparency log %s...\n", req.rekorURL)
+		token.TransparencyLog, err = attestation.SubmitToRekor(ctx, req.rekorURL, msg, signer.GetSigner())
+		if err != nil {
+			return nil, err
+		}
+		logger.Progressf("✅ Transparency log entry %s at index %d\n", token.TransparencyLog.UUID, token.TransparencyLog.LogIndex)
+	}
+
+	for _, cosign := range req.cosignOps {
+		logger.Progressf("✍️  Cosigning with %s...\n", cosign.provider)
+		cosigner, err := cosignDigest(ctx, cosign, msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to cosign with %s: %w", cosign.provider, err)
+		}
+		token.Cosigners = append(token.Cosigners, *cosigner)
+	}
+
+	return token, nil
+}
+
+// cosignOp is an OP that cosigns attestations
+type cosignOp struct {
+	provider string
+	signer   tokenSigner
+}
+
+// cosignDigest signs the payload digest with a PK token from a cosigning OP
+func cosignDigest(ctx context.Context, cosign cosignOp, digest []byte) (*attestation.Cosigner, error) {
+	pkToken, err := cosign.signer.Auth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate and generate PK token: %w", err)
+	}
+	signedMsg, err := pkToken.NewSignedMessage(digest, cosign.signer.GetSigner())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+	return &attestation.Cosigner{
+		Provider:  cosign.provider,
+		PKToken:   pkToken,
+		Signature: signedMsg,
+	}, nil
+}
+
+func saveAttestation(ctx context.Context, att *attestation.Attestation, outputFile string) (string, error) {
+	storage, path, err := attestation.NewStorage(outputFile)
+	if err != nil {
+		return "", err
+	}
+
+	// Serialize attestation
+	data, err := json.MarshalIndent(att, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal attestation: %w", err)
+	}
+
+	// Write to the local file or object store
+	location, err := storage.Write(ctx, path, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to write attestation file: %w", err)
+	}
+
+	logger.Progressf("💾 Attestation saved to: %s\n", outputFile)
+	return location, nil
+}
+
+// shortSHA abbreviates a commit SHA to 8 characters for display. Shorter SHAs, e.g. from providers with
+// other claims, are shown whole.
+func shortSHA(sha string) string {
+	if len(sha) <= 8 {
+		return sha
+	}
+	return sha[:8] + "..."
+}
+
+// saveContent writes the downloaded content so it can be published and checked against the attested digest
+func saveContent(content []byte, outputFile string) error {
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if err := attestation.WriteFileAtomic(outputFile, content, 0644); err != nil {
+		return fmt.Errorf("failed to write content file: %w", err)
+	}
+
+	logger.Progressf("💾 Content saved to: %s\n", outputFile)
+	return nil
+}
+
+// saveAttestationDetails records the digest of the new attestation and where its artifact can be found
+func saveAttestationDetails(att *attestation.Attestation, artifactURL string, detailsFile string) error {
+	digest, err := att.Digest()
+	if err != nil {
+		return err
+	}
+
+	details := &attestation.AttestationDetails{
+		Digest:      digest,
+		ArtifactURL: artifactURL,
+	}
+	if err := attestation.SaveAttestationDetails(details, detailsFile); err != nil {
+		return err
+	}
+
+	logger.Progressf("💾 Attestation details saved to: %s\n", detailsFile)
+	return nil
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/openpubkey/openpubkey/client"
+	"github.com/openpubkey/openpubkey/pktoken"
+	"github.com/openpubkey/openpubkey/providers"
+
+	"url-oracle/attestation"
+)
+
+const testWorkflowRef = "owner/repo/.github/workflows/oracle.yml@refs/heads/main"
+
+// captureLogger replaces logger for the test, returning the buffers results and progress are written to
+func captureLogger(t *testing.T, level attestation.LogLevel) (out *bytes.Buffer, progress *bytes.Buffer) {
+	t.Helper()
+	previous := logger
+	out, progress = &bytes.Buffer{}, &bytes.Buffer{}
+	logger = &attestation.Logger{Level: level, Out: out, Err: progress}
+	t.Cleanup(func() { logger = previous })
+	return out, progress
+}
+
+// testSigner signs attestations with PK tokens from a mock OpenID provider carrying GitHub Actions claims
+type testSigner struct {
+	provider *providers.MockProvider
+	opts     attestation.VerifyOptions
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+	provider, backend, template, err := providers.NewMockProvider(providers.DefaultMockProviderOpts())
+	if err != nil {
+		t.Fatalf("failed to create mock provider: %v", err)
+	}
+	template.ExtraClaims = map[string]any{
+		"job_workflow_ref": testWorkflowRef,
+		"job_workflow_sha": "0123456789abcdef0123456789abcdef01234567",
+		"workflow_ref":     testWorkflowRef,
+		"run_id":           "1",
+		"repository":       "owner/repo",
+		"repository_owner": "owner",
+		"ref":              "refs/heads/main",
+		"sha":              "0123456789abcdef0123456789abcdef01234567",
+		"event_name":       "push",
+	}
+
+	opts := attestation.NewVerifyOptions()
+	opts.Issuer = provider.Issuer()
+	opts.ProviderVerifier = providers.NewProviderVerifier(provider.Issuer(), providers.ProviderVerifierOpts{
+		CommitType:        providers.CommitTypesEnum.NONCE_CLAIM,
+		ClientID:          provider.ClientID(),
+		DiscoverPublicKey: backend.GetPublicKeyFinder(),
+	})
+	opts.ExpectedWorkflowRef = testWorkflowRef
+	return &testSigner{provider: provider, opts: opts}
+}
+
+// attest signs an attestation of content downloaded from url
+func (s *testSigner) attest(t *testing.T, url string, content []byte) *attestation.Attestation {
+	t.Helper()
+	return s.attestWithPrevious(t, url, content, nil)
+}
+
+// attestWithPrevious signs an attestation of content downloaded from url linking to the previous attestation details
+func (s *testSigner) attestWithPrevious(t *testing.T, url string, content []byte, previous []byte) *attestation.Attestation {
+	t.Helper()
+	opkClient, err := client.New(s.provider)
+	if err != nil {
+		t.Fatalf("failed to create OpenPubkey client: %v", err)
+	}
+	pkToken, err := opkClient.Auth(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get PK token: %v", err)
+	}
+	download := &attestation.DownloadResult{
+		FetchMeta:     attestation.FetchMeta{URL: url},
+		Content:       content,
+		ContentDigest: attestation.ContentDigest(content),
+		ContentSize:   int64(len(content)),
+	}
+	att, err := attestation.BuildAttestation(pkToken, opkClient.GetSigner(), attestation.GithubExtractor{}, download, previous)
+	if err != nil {
+		t.Fatalf("BuildAttestation: %v", err)
+	}
+	return att
+}
+
+// fakeTokenSigner is a tokenSigner returning a fixed PK token, or authErr, without running an OIDC flow
+type fakeTokenSigner struct {
+	pkToken *pktoken.PKToken
+	signer  crypto.Signer
+	authErr error
+	auths   int
+}
+
+func (f *fakeTokenSigner) Auth(ctx context.Context, opts ...client.AuthOpts) (*pktoken.PKToken, error) {
+	f.auths++
+	if f.authErr != nil {
+		return nil, f.authErr
+	}
+	return f.pkToken, nil
+}
+
+func (f *fakeTokenSigner) GetSigner() crypto.Signer {
+	return f.signer
+}
+
+// tokenSigner returns a fake tokenSigner holding a PK token from the mock provider
+func (s *testSigner) tokenSigner(t *testing.T) *fakeTokenSigner {
+	t.Helper()
+	opkClient, err := client.New(s.provider)
+	if err != nil {
+		t.Fatalf("failed to create OpenPubkey client: %v", err)
+	}
+	pkToken, err := opkClient.Auth(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get PK token: %v", err)
+	}
+	return &fakeTokenSigner{pkToken: pkToken, signer: opkClient.GetSigner()}
+}
+
+// writeAttestation writes att as JSON to path
+func writeAttestation(t *testing.T, att *attestation.Attestation, path string) {
+	t.Helper()
+	data, err := json.Marshal(att)
+	if err != nil {
+		t.Fatalf("failed to marshal attestation: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write attestation: %v", err)
+	}
+}
+
+// commandEnv names the command TestRunCommand runs when the test binary is re-executed by runCommand
+const commandEnv = "URL_ORACLE_TEST_COMMAND"
+
+// testCommands are the url-oracle subcommands runCommand can run
+var testCommands = map[string]func(args []string){
+	"generate":    Generate,
+	"verify":      Verify,
+	"verify-url":  VerifyURL,
+	"diff":        Diff,
+	"export-keys": ExportKeys,
+	"index-keys":  IndexKeys,
+	"selfcheck":   SelfCheck,
+}
+
+// runCommand runs a subcommand in a child process, since commands exit when done, returning its exit code
+// and output. The child's environment is env alone, plus PATH and HOME.
+func runCommand(t *testing.T, env []string, command string, args ...string) (code int, stdout string, stderr string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestRunCommand$", "--"}, args...)...)
+	cmd.Env = append([]string{commandEnv + "=" + command, "PATH=" + os.Getenv("PATH"), "HOME=" + os.Getenv("HOME")}, env...)
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		code = exitErr.ExitCode()
+	default:
+		t.Fatalf("failed to run %s: %v", command, err)
+	}
+	return code, out.String(), errOut.String()
+}
+
+// mockOPEnv makes runCommand's child process sign with a mock OP instead of the CI environment's
+const mockOPEnv = "URL_ORACLE_TEST_MOCK_OP"
+
+// TestRunCommand is the entry point of runCommand's child process, and does nothing when run as a test
+func TestRunCommand(t *testing.T) {
+	command, ok := testCommands[os.Getenv(commandEnv)]
+	if !ok {
+		return
+	}
+	if os.Getenv(mockOPEnv) != "" {
+		signer := newTestSigner(t)
+		newTokenSigner = func(string) (tokenSigner, error) {
+			return client.New(signer.provider)
+		}
+	}
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+	command(args)
+	os.Exit(0)
+}
+
+// containsAll reports whether s contains every one of substrings
+func containsAll(s string, substrings ...string) bool {
+	for _, substring := range substrings {
+		if !strings.Contains(s, substring) {
+			return false
+		}
+	}
+	return true
+}
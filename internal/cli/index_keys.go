@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"url-oracle/attestation"
+)
+
+// IndexKeys runs the index-keys command, folding a directory of JWKS snapshot attestations into an index of
+// the keys they recorded. The snapshots are not verified, so verify them with verify --dir first.
+func IndexKeys(args []string) {
+	fs := flag.NewFlagSet("index-keys", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	var (
+		dir    = fs.String("dir", "", "Directory of JWKS snapshot attestations (*.json)")
+		output = fs.String("output", "", "Path to write the kid index to")
+	)
+	fs.Parse(args)
+	common.apply()
+
+	if *dir == "" || *output == "" {
+		logger.Errorf("Error: dir and output flags are required\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.json"))
+	if err != nil {
+		logger.Errorf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		logger.Errorf("❌ Error: no *.json attestations found in %s\n", *dir)
+		os.Exit(1)
+	}
+
+	logger.Progressf("📥 Loading %d snapshots...\n", len(files))
+	snapshots := make([]*attestation.Attestation, 0, len(files))
+	for _, file := range files {
+		att, err := attestation.LoadAttestation(file)
+		if err != nil {
+			logger.Errorf("❌ Error: %s: %v\n", file, err)
+			os.Exit(1)
+		}
+		snapshots = append(snapshots, att)
+	}
+
+	index, err := attestation.BuildKeyIDIndex(snapshots)
+	if err != nil {
+		logger.Errorf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		logger.Errorf("❌ Error: failed to marshal index: %v\n", err)
+		os.Exit(1)
+	}
+	if err := attestation.WriteFileAtomic(*output, data, 0644); err != nil {
+		logger.Errorf("❌ Error: failed to write index: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger.Resultf("💾 Index of %d keys from %d snapshots saved to: %s\n", len(index.Keys), index.Snapshots, *output)
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifRule is a verification step reported as a SARIF rule. Errors are mapped to the first rule with a
+// matching message prefix, so more specific prefixes come first.
+type sarifRule struct {
+	ID          string
+	Name        string
+	Description string
+	Prefixes    []string
+}
+
+var sarifRules = []sarifRule{
+	{"URLO001", "pk-token", "The PK token must verify against the OP's keys", []string{"PK Token verification failed"}},
+	{"URLO002", "issuer", "The PK token must be issued by the expected issuer", []string{"Issuer verification failed"}},
+	{"URLO003", "signed-message", "The signature must verify under the PK token's key", []string{"Signed message verification failed"}},
+	{"URLO004", "payload-digest", "The signed message must be the payload digest", []string{"Failed to generate attestation payload digest", "Attestation payload digest"}},
+	{"URLO005", "oracle-digest", "The payload must be reproducible by the oracle", []string{"Failed to create attestation payload", "Failed to generate oracle digest", "Oracle generated digest"}},
+	{"URLO006", "signer-threshold", "Enough signers, cosigners included, must verify", []string{"Signer threshold", "Only ", "Cosigner "}},
+	{"URLO007", "statement-type", "The statement type must be known and well-formed", []string{"Unknown statement type", "JWKS snapshot", "Head-only attestation"}},
+	{"URLO008", "content-integrity", "Embedded content must match the recorded size and digests", []string{"Content size", "Content digest", "Content decompression", "Content is not in", "Normalization verification"}},
+	{"URLO009", "workflow-ref", "The PK token must carry the expected workflow reference", []string{"Workflow reference verification", "PK token workflow reference"}},
+	{"URLO010", "workflow-sha", "The PK token workflow SHA must be the commit SHA", []string{"Workflow SHA verification", "PK token workflow SHA"}},
+	{"URLO011", "timestamp-consistency", "The timestamp must be the PK token's iat claim", []string{"Timestamp consistency verification", "Attestation timestamp does not match"}},
+	{"URLO012", "repository", "The PK token must carry the expected repository and owner", []string{"Repository", "PK token repository"}},
+	{"URLO013", "event-name", "The PK token event name must be allowed", []string{"Event name verification", "PK token event name"}},
+	{"URLO014", "timestamp-token", "The RFC 3161 timestamp token must verify", []string{"Timestamp token verification"}},
+	{"URLO015", "max-age", "The attestation must not be too old", []string{"Timestamp verification failed"}},
+	{"URLO016", "tls-certificate", "The recorded TLS certificate must match", []string{"TLS certificate verification"}},
+	{"URLO017", "key-log", "The OP signing key must be in the key log", []string{"Key log verification"}},
+	{"URLO018", "previous-artifact", "The previous attestation artifact must match its digest", []string{"Previous artifact verification"}},
+	{"URLO019", "content-recheck", "The URL must still serve the attested content", []string{"Content recheck", "Current content digest", "Current head metadata"}},
+	{"URLO020", "inclusion", "The attestation must be included in the transparency log", []string{"Inclusion verification"}},
+	{"URLO021", "validity-window", "The attestation must be used within its declared validity window", []string{"Validity window verification"}},
+	{"URLO022", "url", "The attestation must be for the expected URL", []string{"URL verification"}},
+	{"URLO023", "policy", "The PK token claims must satisfy the policy", []string{"Policy verification"}},
+	{"URLO024", "signer-binding", "The message must be signed by the key committed to in the PK token", []string{"Signer binding verification"}},
+	{"URLO025", "signature-algorithm", "The signer's key algorithm must be allowed", []string{"Signature algorithm verification"}},
+}
+
+// sarifOtherRule reports errors no rule matches
+var sarifOtherRule = sarifRule{"URLO000", "verification", "The attestation must verify", nil}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string               `json:"name"`
+	InformationURI string               `json:"informationUri"`
+	Rules          []sarifReportingRule `json:"rules"`
+}
+
+type sarifReportingRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// newSARIFReport reports every verification error of each file as a SARIF result located at the file
+func newSARIFReport(results []BulkResult) *sarifLog {
+	rules := append([]sarifRule{sarifOtherRule}, sarifRules...)
+	driver := sarifDriver{
+		Name:           "url-oracle",
+		InformationURI: "https://github.com/kipz/url-oracle",
+		Rules:          make([]sarifReportingRule, 0, len(rules)),
+	}
+	for _, rule := range rules {
+		driver.Rules = append(driver.Rules, sarifReportingRule{
+			ID:               rule.ID,
+			Name:             rule.Name,
+			ShortDescription: sarifMessage{Text: rule.Description},
+		})
+	}
+
+	run := sarifRun{Tool: sarifTool{Driver: driver}, Results: []sarifResult{}}
+	for _, result := range results {
+		for _, err := range result.Errors {
+			index := sarifRuleIndex(rules, err)
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    rules[index].ID,
+				RuleIndex: index,
+				Level:     "error",
+				Message:   sarifMessage{Text: err},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: result.File},
+				}}},
+			})
+		}
+	}
+
+	return &sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}
+}
+
+// sarifRuleIndex returns the index of the first rule matching a verification error, or of the catch-all rule
+func sarifRuleIndex(rules []sarifRule, err string) int {
+	for i, rule := range rules {
+		for _, prefix := range rule.Prefixes {
+			if strings.HasPrefix(err, prefix) {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// printSARIFReport prints the SARIF report for results
+func printSARIFReport(results []BulkResult) error {
+	data, err := json.MarshalIndent(newSARIFReport(results), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SARIF report: %w", err)
+	}
+	logger.Resultf("%s\n", string(data))
+	return nil
+}
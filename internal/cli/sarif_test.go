@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+
+	"url-oracle/attestation"
+)
+
+func TestSARIFRuleIndex(t *testing.T) {
+	rules := append([]sarifRule{sarifOtherRule}, sarifRules...)
+	tests := []struct {
+		err  string
+		want string
+	}{
+		{err: "PK Token verification failed: token expired", want: "pk-token"},
+		{err: "Issuer verification failed: unexpected issuer", want: "issuer"},
+		{err: "Signed message verification failed: bad signature", want: "signed-message"},
+		{err: "Attestation payload digest does not match signed message: signed digest ab", want: "payload-digest"},
+		{err: "Oracle generated digest does not match signed message: signed digest ab", want: "oracle-digest"},
+		{err: "Only 1 of 2 signers verified, 2 required", want: "signer-threshold"},
+		{err: "Cosigner 1 (gitlab) verification failed: cosigner has no PK token", want: "signer-threshold"},
+		{err: `Unknown statement type "bogus"`, want: "statement-type"},
+		{err: "Head-only attestation has no head metadata", want: "statement-type"},
+		{err: "Content digest sha256:ab does not match recorded content digest sha256:cd", want: "content-integrity"},
+		{err: "Content digest multihash does not match recorded content digest", want: "content-integrity"},
+		{err: "Content is not in json normalized form", want: "content-integrity"},
+		{err: `PK token workflow reference does not match expected workflow "a"`, want: "workflow-ref"},
+		{err: "PK token workflow SHA does not match commit SHA", want: "workflow-sha"},
+		{err: "Attestation timestamp does not match PK token iat claim", want: "timestamp-consistency"},
+		{err: `PK token repository owner "a" does not match expected owner "b"`, want: "repository"},
+		{err: `PK token event name "pull_request" is not one of ["push"]`, want: "event-name"},
+		{err: "Timestamp token verification failed: attestation has no timestamp token", want: "timestamp-token"},
+		{err: "Timestamp verification failed: attestation is too old", want: "max-age"},
+		{err: "TLS certificate verification failed: fingerprint mismatch", want: "tls-certificate"},
+		{err: "Key log verification failed: key not logged", want: "key-log"},
+		{err: "Previous artifact verification failed: digest mismatch", want: "previous-artifact"},
+		{err: "Current head metadata {} does not match attested metadata {}", want: "content-recheck"},
+		{err: "Inclusion verification failed: no entry", want: "inclusion"},
+		{err: "Validity window verification failed: expired", want: "validity-window"},
+		{err: "URL verification failed: attestation is for a, not b", want: "url"},
+		{err: "Policy verification failed: ref not allowed", want: "policy"},
+		{err: "Signer binding verification failed: the PK token and signed message must both verify", want: "signer-binding"},
+		{err: `Signature algorithm verification failed: "RS256" is not one of ["ES256"]`, want: "signature-algorithm"},
+		{err: "Something new went wrong", want: "verification"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want+": "+tt.err, func(t *testing.T) {
+			if got := rules[sarifRuleIndex(rules, tt.err)].Name; got != tt.want {
+				t.Errorf("error mapped to rule %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSARIFReport(t *testing.T) {
+	tests := []struct {
+		name        string
+		results     []BulkResult
+		wantResults []string // rule ID and file of each result
+	}{
+		{name: "no failures", results: []BulkResult{{File: "a.json", Verified: true}}},
+		{
+			name: "failures in several files",
+			results: []BulkResult{
+				{File: "a.json", Errors: []string{"PK Token verification failed: expired", "Something new went wrong"}},
+				{File: "b.json", Verified: true},
+				{File: "c.json", Errors: []string{"Content recheck failed: 404"}},
+			},
+			wantResults: []string{"URLO001 a.json", "URLO000 a.json", "URLO019 c.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(newSARIFReport(tt.results))
+			if err != nil {
+				t.Fatalf("failed to marshal report: %v", err)
+			}
+			var report struct {
+				Schema  string `json:"$schema"`
+				Version string `json:"version"`
+				Runs    []struct {
+					Tool struct {
+						Driver struct {
+							Name  string `json:"name"`
+							Rules []struct {
+								ID               string `json:"id"`
+								Name             string `json:"name"`
+								ShortDescription struct {
+									Text string `json:"text"`
+								} `json:"shortDescription"`
+							} `json:"rules"`
+						} `json:"driver"`
+					} `json:"tool"`
+					Results []struct {
+						RuleID    string `json:"ruleId"`
+						RuleIndex int    `json:"ruleIndex"`
+						Level     string `json:"level"`
+						Message   struct {
+							Text string `json:"text"`
+						} `json:"message"`
+						Locations []struct {
+							PhysicalLocation struct {
+								ArtifactLocation struct {
+									URI string `json:"uri"`
+								} `json:"artifactLocation"`
+							} `json:"physicalLocation"`
+						} `json:"locations"`
+					} `json:"results"`
+				} `json:"runs"`
+			}
+			if err := json.Unmarshal(data, &report); err != nil {
+				t.Fatalf("failed to parse report: %v", err)
+			}
+
+			if report.Schema != sarifSchema || report.Version != "2.1.0" || len(report.Runs) != 1 {
+				t.Fatalf("report has schema %q, version %q and %d runs", report.Schema, report.Version, len(report.Runs))
+			}
+			run := report.Runs[0]
+			if run.Tool.Driver.Name != "url-oracle" {
+				t.Errorf("driver name = %q", run.Tool.Driver.Name)
+			}
+			ids := map[string]bool{}
+			for _, rule := range run.Tool.Driver.Rules {
+				if rule.ID == "" || rule.Name == "" || rule.ShortDescription.Text == "" || ids[rule.ID] {
+					t.Errorf("rule %+v is incomplete or duplicated", rule)
+				}
+				ids[rule.ID] = true
+			}
+			if run.Results == nil {
+				t.Fatal("results must be an array, even when empty")
+			}
+			if len(run.Results) != len(tt.wantResults) {
+				t.Fatalf("got %d results, want %d", len(run.Results), len(tt.wantResults))
+			}
+			for i, result := range run.Results {
+				if result.RuleIndex < 0 || result.RuleIndex >= len(run.Tool.Driver.Rules) || run.Tool.Driver.Rules[result.RuleIndex].ID != result.RuleID {
+					t.Errorf("result %d ruleIndex %d does not point at rule %s", i, result.RuleIndex, result.RuleID)
+				}
+				if result.Level != "error" || result.Message.Text == "" || len(result.Locations) != 1 {
+					t.Errorf("result %d is incomplete: %+v", i, result)
+					continue
+				}
+				if got := result.RuleID + " " + result.Locations[0].PhysicalLocation.ArtifactLocation.URI; got != tt.wantResults[i] {
+					t.Errorf("result %d is %s, want %s", i, got, tt.wantResults[i])
+				}
+			}
+		})
+	}
+}
+
+// TestSARIFRulesCoverVerificationErrors checks real verification failures map to a specific rule
+func TestSARIFRulesCoverVerificationErrors(t *testing.T) {
+	signer := newTestSigner(t)
+	rules := append([]sarifRule{sarifOtherRule}, sarifRules...)
+	tests := []struct {
+		name      string
+		tamper    func(att *attestation.Attestation)
+		opts      func(opts *attestation.VerifyOptions)
+		wantRules []string
+	}{
+		{
+			name:      "tampered content",
+			tamper:    func(att *attestation.Attestation) { att.Payload.Content = []byte("injected") },
+			wantRules: []string{"payload-digest", "signer-threshold", "content-integrity", "oracle-digest"},
+		},
+		{
+			name: "unexpected workflow and repository",
+			opts: func(opts *attestation.VerifyOptions) {
+				opts.ExpectedWorkflowRef = "other/repo/.github/workflows/x.yml@refs/heads/main"
+				opts.ExpectedRepository = "other/repo"
+			},
+			wantRules: []string{"workflow-ref", "repository"},
+		},
+		{
+			name:      "disallowed event",
+			opts:      func(opts *attestation.VerifyOptions) { opts.AllowedEventNames = []string{"schedule"} },
+			wantRules: []string{"event-name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			att := signer.attest(t, "https://example.com/jwks", []byte(`{"keys":[]}`))
+			if tt.tamper != nil {
+				tt.tamper(att)
+			}
+			opts := signer.opts
+			if tt.opts != nil {
+				tt.opts(&opts)
+			}
+			result, err := attestation.VerifyAttestation(att, opts)
+			if err != nil {
+				t.Fatalf("VerifyAttestation: %v", err)
+			}
+			if len(result.Errors) == 0 {
+				t.Fatal("verification did not fail")
+			}
+			got := map[string]bool{}
+			for _, verifyErr := range result.Errors {
+				rule := rules[sarifRuleIndex(rules, verifyErr)]
+				if rule.ID == sarifOtherRule.ID {
+					t.Errorf("error %q matches no rule", verifyErr)
+				}
+				got[rule.Name] = true
+			}
+			for _, want := range tt.wantRules {
+				if !got[want] {
+					t.Errorf("no error mapped to rule %s (errors %q)", want, result.Errors)
+				}
+			}
+		})
+	}
+}
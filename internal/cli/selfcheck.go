@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"url-oracle/attestation"
+)
+
+// SelfCheck runs the selfcheck command, a fast offline check that payloads are built deterministically and
+// that an attestation's signed message is its payload digest. It exits 1 if any check fails.
+func SelfCheck(args []string) {
+	fs := flag.NewFlagSet("selfcheck", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	var (
+		url             = fs.String("url", "", "URL to build payloads for from --content-file")
+		contentFile     = fs.String("content-file", "", "Fixture content to build the payload from twice, comparing the digests")
+		attestationFile = fs.String("attestation-file", "", "Attestation whose payload digest is re-derived and compared with the signed message")
+	)
+	fs.Parse(args)
+	common.apply()
+
+	if (*url == "") != (*contentFile == "") || (*contentFile == "" && *attestationFile == "") {
+		logger.Errorf("Error: url and content-file, attestation-file, or both are required\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	failed := false
+	if *contentFile != "" {
+		content, err := os.ReadFile(*contentFile)
+		if err != nil {
+			logger.Errorf("❌ Error: Failed to read content file: %v\n", err)
+			os.Exit(1)
+		}
+		digest, err := attestation.CheckPayloadDeterminism(*url, content, time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			logger.Resultf("❌ Payload determinism: %v\n", err)
+			failed = true
+		} else {
+			logger.Resultf("✅ Payload determinism: three builds hash to %x\n", digest)
+		}
+	}
+
+	if *attestationFile != "" {
+		att, err := attestation.LoadAttestation(*attestationFile)
+		if err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := attestation.CheckSignedDigest(att); err != nil {
+			logger.Resultf("❌ Signed digest: %v\n", err)
+			failed = true
+		} else {
+			logger.Resultf("✅ Signed digest: the signed message is the recorded and re-derived payload digest\n")
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
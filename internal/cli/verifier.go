@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"time"
+
+	"url-oracle/attestation"
+)
+
+// acceptedKeySource describes where an accepted issuer's keys come from
+func acceptedKeySource(accepted *attestation.AcceptedIssuer) string {
+	switch {
+	case accepted.JWKSPath != "":
+		return accepted.JWKSPath
+	case accepted.JWKSURL != "":
+		return accepted.JWKSURL
+	default:
+		return "published JWKS"
+	}
+}
+
+// printVerificationResult prints the outcome of each verification step
+func printVerificationResult(result *attestation.VerificationResult, opts attestation.VerifyOptions) {
+	logger.Resultf("🔍 Verification Results:\n")
+	logger.Resultf("  PK Token: %s\n", getStatusIcon(result.PKTokenVerified))
+	logger.Resultf("  Issuer: %s\n", getStatusIcon(result.IssuerVerified))
+	if len(opts.TrustedIssuers) > 0 && result.IssuerVerified {
+		logger.Resultf("    Matched issuer: %s\n", result.MatchedIssuer)
+	}
+	if accepted := result.MatchedAcceptedIssuer; accepted != nil {
+		logger.Resultf("    Accepted issuer: %s (keys: %s)\n", accepted.Issuer, acceptedKeySource(accepted))
+	}
+	logger.Resultf("  Signed Message: %s\n", getStatusIcon(result.SignedMessageVerified))
+	logger.Resultf("  Signer Binding: %s\n", getStatusIcon(result.SignerBindingVerified))
+	if algorithms := result.Algorithms; algorithms != nil {
+		logger.Resultf("    Signature algorithm: %s (%s key), ID token algorithm: %s\n", algorithms.SignatureAlgorithm, algorithms.KeyType, algorithms.ProviderAlgorithm)
+	}
+	if len(opts.AllowedSignatureAlgorithms) > 0 {
+		logger.Resultf("  Signature Algorithm: %s\n", getStatusIcon(result.SignatureAlgorithmVerified))
+	}
+	logger.Resultf("  Payload Digest: %s\n", getStatusIcon(result.PayloadDigestVerified))
+	logger.Resultf("  Oracle Digest: %s\n", getStatusIcon(result.OracleDigestVerified))
+	logger.Resultf("  Content Size: %s\n", getStatusIcon(result.ContentSizeVerified))
+	logger.Resultf("  Content Digest: %s\n", getStatusIcon(result.ContentDigestConsistent))
+	if result.NormalizationVerified {
+		logger.Resultf("  Normalization: %s\n", getStatusIcon(result.NormalizationVerified))
+	}
+	if result.StatementType == attestation.StatementTypeJWKSSnapshot {
+		logger.Resultf("  JWKS Snapshot: %s\n", getStatusIcon(result.JWKSSnapshotVerified))
+	}
+	logger.Resultf("  Workflow Reference: %s\n", getStatusIcon(result.WorkflowRefVerified))
+	logger.Resultf("  Workflow SHA: %s\n", getStatusIcon(result.WorkflowSHAVerified))
+	logger.Resultf("  Timestamp Consistency: %s\n", getStatusIcon(result.TimestampConsistencyVerified))
+	if opts.ExpectedURL != "" {
+		logger.Resultf("  URL: %s\n", getStatusIcon(result.URLVerified))
+	}
+	if opts.ExpectedRepository != "" {
+		logger.Resultf("  Repository: %s\n", getStatusIcon(result.RepositoryVerified))
+	}
+	if opts.ExpectedRepositoryOwner != "" {
+		logger.Resultf("  Repository Owner: %s\n", getStatusIcon(result.RepositoryOwnerVerified))
+	}
+	if len(opts.AllowedEventNames) > 0 {
+		logger.Resultf("  Event Name: %s\n", getStatusIcon(result.EventNameVerified))
+	}
+	if opts.Policy != nil {
+		logger.Resultf("  Policy: %s\n", getStatusIcon(result.PolicyVerified))
+		for _, violation := range result.PolicyViolations {
+			logger.Verbosef("      - %s\n", violation)
+		}
+	}
+	if result.SignerCount > 1 {
+		logger.Resultf("  Signers: %d/%d %s\n", result.SignersVerified, result.SignerCount, getStatusIcon(result.SignersVerified == result.SignerCount))
+		for _, cosignerErr := range result.CosignerErrors {
+			logger.Verbosef("      - %s\n", cosignerErr)
+		}
+	}
+	if result.HasValidityWindow {
+		logger.Resultf("  Validity Window: %s\n", getStatusIcon(result.ValidityWindowVerified))
+	}
+	if opts.MaxAge > 0 {
+		logger.Resultf("  Timestamp Freshness: %s\n", getStatusIcon(result.TimestampVerified))
+	}
+	if opts.ExpectedTLSFingerprint != "" || opts.ExpectedTLSIssuer != "" {
+		logger.Resultf("  TLS Certificate: %s\n", getStatusIcon(result.TLSCertificateVerified))
+	}
+	if opts.VerifyInclusion {
+		logger.Resultf("  Transparency Log Inclusion: %s\n", getStatusIcon(result.InclusionVerified))
+	}
+	if opts.TSARoots != nil {
+		logger.Resultf("  Timestamp Token: %s\n", getStatusIcon(result.TimestampTokenVerified))
+		if result.TimestampTokenVerified {
+			logger.Resultf("    Timestamped at: %s\n", result.TimestampTokenTime.UTC().Format(time.RFC3339))
+		}
+	}
+	if opts.KeyLogDir != "" {
+		logger.Resultf("  Key In Log: %s\n", getStatusIcon(result.KeyInLogVerified))
+	}
+	if opts.CheckPreviousArtifact {
+		if result.PreviousArtifactUnavailable {
+			logger.Resultf("  Previous Artifact: ⚠️  unavailable (expired)\n")
+		} else {
+			logger.Resultf("  Previous Artifact: %s\n", getStatusIcon(result.PreviousArtifactVerified))
+			if result.PreviousArtifactVerified {
+				logger.Resultf("    Chain links verified: %d\n", result.PreviousChainLength)
+			}
+		}
+	}
+	if opts.RecheckContent {
+		logger.Resultf("  Content Recheck: %s\n", getStatusIcon(result.ContentRecheckVerified))
+	}
+
+	logger.Resultf("\n")
+	logger.Resultf("%s\n", result.GetSummary())
+}
+
+// getStatusIcon returns an appropriate icon for the verification status
+func getStatusIcon(success bool) string {
+	if success {
+		return "✅"
+	}
+	return "❌"
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"url-oracle/attestation"
+)
+
+func TestPrintVerificationResultLevels(t *testing.T) {
+	signer := newTestSigner(t)
+	att := signer.attest(t, "https://example.com/jwks", []byte(`{"keys":[]}`))
+	result, err := attestation.VerifyAttestation(att, signer.opts)
+	if err != nil {
+		t.Fatalf("VerifyAttestation: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		level attestation.LogLevel
+	}{
+		{name: "quiet", level: attestation.LogQuiet},
+		{name: "normal", level: attestation.LogNormal},
+		{name: "verbose", level: attestation.LogVerbose},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, progress := captureLogger(t, tt.level)
+			printVerificationResult(result, signer.opts)
+
+			if !strings.Contains(out.String(), result.GetSummary()) {
+				t.Errorf("result output %q does not include the summary %q", out, result.GetSummary())
+			}
+			if tt.level == attestation.LogQuiet && progress.Len() != 0 {
+				t.Errorf("quiet output printed progress %q", progress)
+			}
+			if code := verificationExitCode(result, signer.opts); code != exitVerified {
+				t.Errorf("exit code = %d, want %d (errors %q)", code, exitVerified, result.Errors)
+			}
+		})
+	}
+}
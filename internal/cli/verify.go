@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"url-oracle/attestation"
+)
+
+// Verify runs the verify command, checking an attestation or a directory of attestations
+func Verify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	recheckAccess := registerRecheckFlags(fs)
+	var (
+		attestationFile = fs.String("attestation-file", "", "Path to attestation file to verify, or - to read it from stdin")
+		dir             = fs.String("dir", "", "Verify every *.json attestation in this directory instead of a single file")
+		output          = fs.String("output", "text", "Output format: text, json (for --dir) or sarif")
+		maxAge          = fs.Duration("max-age", 0, "Reject attestations older than this duration (e.g. 720h); disabled when 0")
+		issuer          = fs.String("issuer", "", "Expected OIDC issuer (defaults to the provider's issuer)")
+		trustedIssuers  = fs.String("trusted-issuers", "", "Comma-separated further OIDC issuers to accept alongside the expected issuer, e.g. during an issuer migration")
+		acceptedIssuers = fs.String("accepted-issuers", "", "JSON file listing the accepted issuers, each with its provider and JWKS source, instead of --issuer, --trusted-issuers and --jwks-file")
+		recheck         = fs.Bool("recheck", false, "Re-download the attested URL and compare it with the recorded digest")
+		requestBody     = fs.String("request-body-file", "", "Request body to replay with --recheck, for attestations of a request with a body")
+		tlsFingerprint  = fs.String("expect-tls-fingerprint", "", "Require the recorded leaf TLS certificate to have this sha256 fingerprint")
+		tlsIssuer       = fs.String("expect-tls-issuer", "", "Require the recorded leaf TLS certificate to have this issuer DN")
+		jwksFile        = fs.String("jwks-file", "", "Verify the PK token against a local JWKS file instead of the issuer's published keys")
+		checkPrevious   = fs.Bool("check-previous-artifact", false, "Download the previous attestation artifact and compare it with the recorded digest (uses GITHUB_TOKEN)")
+		timingsJSON     = fs.Bool("timings", false, "Print phase timings as JSON")
+		keyLogDir       = fs.String("key-log-dir", "", "Require the OP signing key to be in this key log directory and verify against its keys")
+		repository      = fs.String("expect-repository", os.Getenv("EXPECTED_REPOSITORY"), "Require the repository claim to be this owner/name (defaults to EXPECTED_REPOSITORY)")
+		repositoryOwner = fs.String("expect-repository-owner", os.Getenv("EXPECTED_REPOSITORY_OWNER"), "Require the repository_owner claim to be this owner (defaults to EXPECTED_REPOSITORY_OWNER)")
+		signerThreshold = fs.Int("signer-threshold", 0, "Number of signers, cosigners included, that must verify (default all)")
+		tsaCertFile     = fs.String("tsa-cert-file", "", "Require an RFC 3161 timestamp token signed by a TSA chaining to the PEM certificates in this file")
+		verifyInclusion = fs.Bool("verify-inclusion", false, "Require the attestation to be in a Rekor transparency log, checking its inclusion proof and signed checkpoint")
+		rekorURL        = fs.String("rekor-url", "", "Rekor transparency log to check inclusion in (defaults to the log recorded in the attestation)")
+		rekorPubKey     = fs.String("rekor-pubkey", "", "PEM public key of the Rekor transparency log, required with --verify-inclusion")
+		strict          = fs.Bool("strict", false, "Reject attestations with missing, invalid or unknown fields before verifying them")
+		policyFile      = fs.String("policy", "", "Require the PK token claims to satisfy the allowlists in this JSON policy file")
+		eventNames      = fs.String("verify-event-name", "", "Comma-separated allowlist for the event_name claim (e.g. push,schedule)")
+		signatureAlgs   = fs.String("signature-algorithm", "", "Comma-separated allowlist for the signer's key algorithm (e.g. ES256,EdDSA)")
+	)
+	fs.Parse(args)
+	common.apply()
+
+	// The attestation may also be given as the only argument, e.g. "-" to read it from stdin
+	if *attestationFile == "" && fs.NArg() == 1 {
+		*attestationFile = fs.Arg(0)
+	}
+	if (*attestationFile == "") == (*dir == "") {
+		logger.Errorf("Error: exactly one of attestation-file or dir flags is required\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if *output != "text" && *output != "json" && *output != "sarif" {
+		logger.Errorf("Error: unsupported output format: %s\n", *output)
+		os.Exit(exitUsage)
+	}
+
+	opts := attestation.NewVerifyOptions()
+	opts.Provider = *common.provider
+	opts.Issuer = *issuer
+	if *trustedIssuers != "" {
+		opts.TrustedIssuers = strings.Split(*trustedIssuers, ",")
+	}
+	// Get expected workflow references from environment variable, comma-separated to accept several
+	expectedRefs, err := applyExpectedWorkflowRefs(&opts)
+	if err != nil {
+		logger.Errorf("❌ Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if *acceptedIssuers != "" {
+		if *issuer != "" || *trustedIssuers != "" || *jwksFile != "" {
+			logger.Errorf("Error: accepted-issuers cannot be combined with issuer, trusted-issuers or jwks-file\n")
+			os.Exit(exitUsage)
+		}
+		opts.AcceptedIssuers, err = attestation.LoadAcceptedIssuers(*acceptedIssuers)
+		if err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+	opts.MaxAge = *maxAge
+	opts.RecheckContent = *recheck
+	recheckAccess.apply(&opts)
+	if *requestBody != "" {
+		body, err := os.ReadFile(*requestBody)
+		if err != nil {
+			logger.Errorf("❌ Error: Failed to read request body: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		opts.RequestBody = body
+	}
+	opts.JWKSPath = *jwksFile
+	opts.KeyLogDir = *keyLogDir
+	opts.CheckPreviousArtifact = *checkPrevious
+	opts.ArtifactToken = os.Getenv("GITHUB_TOKEN")
+	opts.ExpectedRepository = *repository
+	opts.ExpectedRepositoryOwner = *repositoryOwner
+	if *eventNames != "" {
+		opts.AllowedEventNames = strings.Split(*eventNames, ",")
+	}
+	if *signatureAlgs != "" {
+		opts.AllowedSignatureAlgorithms = strings.Split(*signatureAlgs, ",")
+	}
+	opts.SignerThreshold = *signerThreshold
+	if *policyFile != "" {
+		policy, err := attestation.LoadPolicy(*policyFile)
+		if err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		opts.Policy = policy
+	}
+	opts.ExpectedTLSFingerprint = *tlsFingerprint
+	opts.ExpectedTLSIssuer = *tlsIssuer
+	if *tsaCertFile != "" {
+		roots, err := attestation.LoadCertPool(*tsaCertFile)
+		if err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		opts.TSARoots = roots
+	}
+	if *verifyInclusion {
+		if *rekorPubKey == "" {
+			logger.Errorf("Error: --verify-inclusion requires --rekor-pubkey\n")
+			os.Exit(exitUsage)
+		}
+		publicKey, err := attestation.LoadRekorPublicKey(*rekorPubKey)
+		if err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		opts.VerifyInclusion = true
+		opts.RekorURL = *rekorURL
+		opts.RekorPublicKey = publicKey
+	}
+
+	if *dir != "" {
+		summary, err := verifyDirectory(*dir, opts)
+		if err != nil {
+			logger.Errorf("❌ Error during verification: %v\n", err)
+			os.Exit(exitIOError)
+		}
+		if err := printBulkSummary(summary, *output); err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		os.Exit(summary.exitCode)
+	}
+
+	logger.Progressf("🔍 Loading attestation...\n")
+	logger.Verbosef("   Expected workflow reference: %s\n", strings.Join(expectedRefs, ", "))
+
+	// Perform verification using the attestation library
+	timings := attestation.NewTimings(nil)
+	stopVerify := timings.Start(attestation.PhaseVerify)
+	result, err := verifyAttestationFile(*attestationFile, *strict, opts)
+	stopVerify()
+	if err != nil {
+		logger.Errorf("❌ Error during verification: %v\n", err)
+		os.Exit(exitIOError)
+	}
+
+	if *output == "sarif" {
+		if err := printSARIFReport([]BulkResult{{File: *attestationFile, Verified: result.IsVerificationSuccessful(), Errors: result.Errors}}); err != nil {
+			logger.Errorf("❌ Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	} else {
+		printVerificationResult(result, opts)
+	}
+
+	timings.Finish()
+	printTimings(timings, *timingsJSON)
+
+	os.Exit(verificationExitCode(result, opts))
+}
+
+// applyExpectedWorkflowRefs sets the expected workflow references from the comma-separated
+// EXPECTED_WORKFLOW_REF environment variable, returning them all
+func applyExpectedWorkflowRefs(opts *attestation.VerifyOptions) ([]string, error) {
+	expectedRefs := strings.Split(os.Getenv("EXPECTED_WORKFLOW_REF"), ",")
+	opts.ExpectedWorkflowRef = expectedRefs[0]
+	opts.ExpectedWorkflowRefs = expectedRefs[1:]
+	if opts.Provider == attestation.ProviderGithub {
+		for _, expectedRef := range expectedRefs {
+			if _, err := attestation.ParseWorkflowRef(expectedRef); err != nil {
+				return nil, fmt.Errorf("invalid EXPECTED_WORKFLOW_REF: %w", err)
+			}
+		}
+	}
+	return expectedRefs, nil
+}
+
+// verifyAttestationFile verifies the attestation at path, reading it from stdin when path is "-".
+// With strict, the attestation is loaded with LoadAttestationStrict, rejecting unknown fields.
+func verifyAttestationFile(path string, strict bool, opts attestation.VerifyOptions) (*attestation.VerificationResult, error) {
+	var att *attestation.Attestation
+	var err error
+	switch {
+	case path == "-" && strict:
+		att, err = attestation.LoadAttestationStrictReader(os.Stdin, true)
+	case path == "-":
+		att, err = attestation.LoadAttestationReader(os.Stdin)
+	case strict:
+		att, err = attestation.LoadAttestationStrict(path, true)
+	default:
+		return attestation.VerifyAttestationFile(path, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attestation: %w", err)
+	}
+	return attestation.VerifyAttestation(att, opts)
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyAttestationFileStdin(t *testing.T) {
+	signer := newTestSigner(t)
+	dir := t.TempDir()
+	signed := filepath.Join(dir, "attestation.json")
+	writeAttestation(t, signer.attest(t, "https://example.com/jwks", []byte(`{"keys":[]}`)), signed)
+	garbage := filepath.Join(dir, "garbage.json")
+	if err := os.WriteFile(garbage, []byte("not an attestation"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		stdin   string
+		strict  bool
+		wantErr string
+	}{
+		{name: "stdin", stdin: signed},
+		{name: "stdin strict", stdin: signed, strict: true},
+		{name: "garbage on stdin", stdin: garbage, wantErr: "failed to load attestation: failed to parse attestation"},
+		{name: "garbage on stdin strict", stdin: garbage, strict: true, wantErr: "failed to load attestation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdin, err := os.Open(tt.stdin)
+			if err != nil {
+				t.Fatalf("failed to open stdin: %v", err)
+			}
+			defer stdin.Close()
+			previous := os.Stdin
+			os.Stdin = stdin
+			defer func() { os.Stdin = previous }()
+
+			result, err := verifyAttestationFile("-", tt.strict, signer.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyAttestationFile: %v", err)
+			}
+			if len(result.Errors) > 0 {
+				t.Errorf("attestation read from stdin did not verify: %v", result.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+
+	"url-oracle/attestation"
+)
+
+// VerifyURL runs the verify-url command, downloading an attestation, verifying it and confirming the content
+// URL still serves the attested content, with a single pass/fail exit code
+func VerifyURL(args []string) {
+	fs := flag.NewFlagSet("verify-url", flag.ExitOnError)
+	common := registerCommonFlags(fs)
+	recheckAccess := registerRecheckFlags(fs)
+	var (
+		url             = fs.String("url", "", "Content URL to verify")
+		attestationURL  = fs.String("attestation-url", "", "URL of the attestation for the content: a GitHub artifacts API URL (uses GITHUB_TOKEN) or the attestation JSON")
+		issuer          = fs.String("issuer", "", "Expected OIDC issuer (defaults to the provider's issuer)")
+		maxAge          = fs.Duration("max-age", 0, "Reject attestations older than this duration (e.g. 720h); disabled when 0")
+		repository      = fs.String("expect-repository", os.Getenv("EXPECTED_REPOSITORY"), "Require the repository claim to be this owner/name (defaults to EXPECTED_REPOSITORY)")
+		repositoryOwner = fs.String("expect-repository-owner", os.Getenv("EXPECTED_REPOSITORY_OWNER"), "Require the repository_owner claim to be this owner (defaults to EXPECTED_REPOSITORY_OWNER)")
+	)
+	fs.Parse(args)
+	common.apply()
+
+	if *url == "" || *attestationURL == "" {
+		logger.Errorf("Error: url and attestation-url flags are required\n")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	opts := attestation.NewVerifyOptions()
+	opts.Provider = *common.provider
+	opts.Issuer = *issuer
+	expectedRefs, err := applyExpectedWorkflowRefs(&opts)
+	if err != nil {
+		logger.Errorf("❌ Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	opts.MaxAge = *maxAge
+	recheckAccess.apply(&opts)
+	opts.ArtifactToken = os.Getenv("GITHUB_TOKEN")
+	opts.ExpectedRepository = *repository
+	opts.ExpectedRepositoryOwner = *repositoryOwner
+
+	logger.Progressf("🔍 Downloading attestation from %s...\n", *attestationURL)
+	logger.Verbosef("   Expected workflow reference: %s\n", strings.Join(expectedRefs, ", "))
+
+	result, err := attestation.VerifyURLContext(context.Background(), *url, *attestationURL, opts)
+	if err != nil {
+		logger.Errorf("❌ Error during verification: %v\n", err)
+		os.Exit(exitIOError)
+	}
+
+	// VerifyURLContext always checks the URL and rechecks its content
+	opts.ExpectedURL = *url
+	opts.RecheckContent = true
+	printVerificationResult(result, opts)
+
+	os.Exit(verificationExitCode(result, opts))
+}